@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/afandimsr/cashbook-backend/internal/config"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	repo "github.com/afandimsr/cashbook-backend/internal/infrastructure/persistent/postgresql/repository"
+	fxUC "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
+	_ "github.com/lib/pq"
+)
+
+// Imports daily exchange rates from a CSV or JSON file:
+//
+//	go run ./cmd/import_fx rates.csv
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatalf("usage: %s <rates.csv|rates.json>", filepath.Base(os.Args[0]))
+	}
+	path := os.Args[1]
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var rates []fx.Rate
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rates, err = fxUC.ParseCSV(file)
+	case ".json":
+		rates, err = fxUC.ParseJSON(file)
+	default:
+		log.Fatalf("unsupported file type %q, expected .csv or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		log.Fatalf("failed to parse %s: %v", path, err)
+	}
+
+	cfg := config.Load()
+
+	dbURL := "postgres://" + cfg.DB.User + ":" + cfg.DB.Pass + "@" + cfg.DB.Host + ":" + cfg.DB.Port + "/" + cfg.DB.Name + "?sslmode=" + cfg.DB.SSLMode
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("failed to ping db: %v", err)
+	}
+
+	usecase := fxUC.New(repo.NewFxRateRepo(db), repo.NewUserRepo(db))
+	imported, err := usecase.ImportRates(rates)
+	if err != nil {
+		log.Fatalf("failed to import rates: %v", err)
+	}
+	log.Printf("Imported %d exchange rates from %s", imported, path)
+}
@@ -1,7 +1,15 @@
 package bootstrap
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	_ "github.com/afandimsr/cashbook-backend/docs"
 	"github.com/afandimsr/cashbook-backend/internal/config"
@@ -10,12 +18,21 @@ import (
 	"github.com/afandimsr/cashbook-backend/internal/infrastructure/apm"
 	"github.com/afandimsr/cashbook-backend/internal/infrastructure/auth"
 	"github.com/afandimsr/cashbook-backend/internal/infrastructure/external"
+	"github.com/afandimsr/cashbook-backend/internal/infrastructure/mail"
 	repo "github.com/afandimsr/cashbook-backend/internal/infrastructure/persistent/postgresql/repository"
+	"github.com/afandimsr/cashbook-backend/internal/infrastructure/storage"
 	"github.com/afandimsr/cashbook-backend/internal/pkg/jwt"
+	accountUC "github.com/afandimsr/cashbook-backend/internal/usecase/account"
+	attachmentUC "github.com/afandimsr/cashbook-backend/internal/usecase/attachment"
 	budgetUC "github.com/afandimsr/cashbook-backend/internal/usecase/budget"
+	calendarUC "github.com/afandimsr/cashbook-backend/internal/usecase/calendar"
 	categoryUC "github.com/afandimsr/cashbook-backend/internal/usecase/category"
+	forecastUC "github.com/afandimsr/cashbook-backend/internal/usecase/forecast"
+	fxUC "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
+	importerUC "github.com/afandimsr/cashbook-backend/internal/usecase/importer"
 	recurringUC "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
 	reportUC "github.com/afandimsr/cashbook-backend/internal/usecase/report"
+	tagUC "github.com/afandimsr/cashbook-backend/internal/usecase/tag"
 	transactionUC "github.com/afandimsr/cashbook-backend/internal/usecase/transaction"
 	userUC "github.com/afandimsr/cashbook-backend/internal/usecase/user"
 	"github.com/gin-contrib/cors"
@@ -24,7 +41,14 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take to finish
+// once the server is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
 func Run() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	cfg := config.Load()
 	jwt.SetSecret(cfg.JWTSecret)
 
@@ -47,34 +71,71 @@ func Run() {
 	// Repositories
 	userRepository := repo.NewUserRepo(db)
 	oauthStateRepository := repo.NewOauthStateRepo(db)
+	sessionRepository := repo.NewSessionRepo(db)
+	userTokenRepository := repo.NewUserTokenRepo(db)
+	accountRepository := repo.NewAccountRepo(db)
 	categoryRepository := repo.NewCategoryRepo(db)
 	transactionRepository := repo.NewTransactionRepo(db)
 	budgetRepository := repo.NewBudgetRepo(db)
 	recurringRepository := repo.NewRecurringRepo(db)
 	mfaSettingsRepository := repo.NewMFASettingsRepo(db)
 	mfaBackupCodeRepository := repo.NewMFABackupCodeRepo(db)
+	fxRateRepository := repo.NewFxRateRepo(db)
+	importProfileRepository := repo.NewImportProfileRepo(db)
+	tagRepository := repo.NewTagRepo(db)
+	attachmentRepository := repo.NewAttachmentRepo(db)
+	calendarRepository := repo.NewCalendarRepo(db)
+
+	attachmentStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mailer, err := mail.New(cfg.SMTP)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Use cases
-	userUsecase := userUC.New(userRepository, authClient)
+	sessionUsecase := userUC.NewSessionUsecase(sessionRepository, userRepository)
+	userUsecase := userUC.New(userRepository, authClient, sessionUsecase)
 	userUsecase.SetMFASettingsRepo(mfaSettingsRepository)
-	oauthUsecase := userUC.NewOAuthUsecase(userRepository, oauthStateRepository, googleAuth)
+	userUsecase.SetMailer(userTokenRepository, mailer, cfg.FrontendURL)
+	oauthUsecase := userUC.NewOAuthUsecase(userRepository, oauthStateRepository, googleAuth, userTokenRepository, sessionUsecase)
+	fxUsecase := fxUC.New(fxRateRepository, userRepository)
+	accountUsecase := accountUC.New(accountRepository)
 	categoryUsecase := categoryUC.New(categoryRepository)
-	transactionUsecase := transactionUC.New(transactionRepository)
-	budgetUsecase := budgetUC.New(budgetRepository)
-	reportUsecase := reportUC.New(transactionRepository)
-	recurringUsecase := recurringUC.New(recurringRepository, transactionRepository)
-	twofaUsecase := userUC.NewTwoFAUsecase(userRepository, mfaBackupCodeRepository)
+	attachmentUsecase := attachmentUC.New(attachmentRepository, transactionRepository, attachmentStorage, cfg.Storage.MaxUploadSize)
+	transactionUsecase := transactionUC.New(transactionRepository, accountRepository, categoryRepository, fxUsecase, attachmentUsecase)
+	budgetUsecase := budgetUC.New(budgetRepository, categoryRepository, transactionRepository, fxUsecase)
+	reportUsecase := reportUC.New(transactionRepository, tagRepository, fxUsecase)
+	recurringUsecase := recurringUC.New(recurringRepository, transactionRepository, accountRepository, categoryRepository)
+	forecastUsecase := forecastUC.New(accountRepository, recurringUsecase)
+	calendarUsecase := calendarUC.New(calendarRepository, recurringUsecase, accountRepository, categoryRepository)
+	importUsecase := importerUC.New(importProfileRepository, transactionRepository, accountRepository, categoryRepository)
+	tagUsecase := tagUC.New(tagRepository)
+	twofaUsecase := userUC.NewTwoFAUsecase(userRepository, mfaBackupCodeRepository, sessionUsecase)
 	mfaSettingsUsecase := userUC.NewMFASettingsUsecase(mfaSettingsRepository)
 
+	var jobs sync.WaitGroup
+	startJobs(ctx, &jobs, budgetUsecase, recurringUsecase, attachmentUsecase)
+
 	// Handlers
 	userHandler := handler.New(cfg, userUsecase, oauthUsecase)
+	sessionHandler := handler.NewSessionHandler(sessionUsecase)
+	accountHandler := handler.NewAccountHandler(accountUsecase)
 	categoryHandler := handler.NewCategoryHandler(categoryUsecase)
 	transactionHandler := handler.NewTransactionHandler(transactionUsecase)
 	budgetHandler := handler.NewBudgetHandler(budgetUsecase)
 	reportHandler := handler.NewReportHandler(reportUsecase)
 	recurringHandler := handler.NewRecurringHandler(recurringUsecase)
+	forecastHandler := handler.NewForecastHandler(forecastUsecase)
+	calendarHandler := handler.NewCalendarHandler(calendarUsecase)
 	twofaHandler := handler.NewTwoFAHandler(twofaUsecase)
 	mfaSettingsHandler := handler.NewMFASettingsHandler(mfaSettingsUsecase)
+	fxHandler := handler.NewFxHandler(fxUsecase)
+	importHandler := handler.NewImportHandler(importUsecase)
+	tagHandler := handler.NewTagHandler(tagUsecase)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentUsecase, cfg.Storage.MaxUploadSize)
 
 	r := gin.Default()
 	r.SetTrustedProxies(nil) // Trust proxies for ClientIP() to work behind Nginx
@@ -84,11 +145,28 @@ func Run() {
 		middleware.ErrorHandler(),
 	)
 
-	RegisterRoutes(r, userHandler, categoryHandler, transactionHandler, budgetHandler, reportHandler, recurringHandler, twofaHandler, mfaSettingsHandler)
+	RegisterRoutes(r, userHandler, sessionHandler, accountHandler, categoryHandler, transactionHandler, budgetHandler, reportHandler, recurringHandler, forecastHandler, calendarHandler, twofaHandler, mfaSettingsHandler, fxHandler, importHandler, tagHandler, attachmentHandler)
 	if gin.Mode() != gin.ReleaseMode {
 		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
-	log.Println("Running on port", cfg.AppPort)
-	r.Run(":" + cfg.AppPort)
+	srv := &http.Server{Addr: ":" + cfg.AppPort, Handler: r}
+	go func() {
+		log.Println("Running on port", cfg.AppPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("server shutdown:", err)
+	}
+	jobs.Wait()
+	db.Close()
 }
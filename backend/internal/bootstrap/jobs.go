@@ -0,0 +1,101 @@
+package bootstrap
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	attachmentUC "github.com/afandimsr/cashbook-backend/internal/usecase/attachment"
+	budgetUC "github.com/afandimsr/cashbook-backend/internal/usecase/budget"
+	recurringUC "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
+)
+
+// Running the jobs at start-up and then periodically catches up on work
+// missed while the server was down.
+const (
+	// budgetCopyInterval is how often the period-start budget copy checks
+	// for work.
+	budgetCopyInterval = time.Hour
+	// recurringInterval is how often due recurring transactions are
+	// generated.
+	recurringInterval = 15 * time.Minute
+	// attachmentPurgeInterval is how often the files of attachments whose
+	// transaction is gone are removed.
+	attachmentPurgeInterval = time.Hour
+)
+
+// startJobs runs the background jobs until ctx is cancelled. wg is done
+// once every job has returned, which is after any run in progress ends.
+func startJobs(ctx context.Context, wg *sync.WaitGroup, budgets budgetUC.Usecase, recurring recurringUC.Usecase, attachments attachmentUC.Usecase) {
+	every(ctx, wg, budgetCopyInterval, func(now time.Time) {
+		copyBudgets(budgets, now)
+	})
+	every(ctx, wg, recurringInterval, func(now time.Time) {
+		processRecurring(recurring, now)
+	})
+	every(ctx, wg, attachmentPurgeInterval, func(time.Time) {
+		purgeAttachments(attachments)
+	})
+}
+
+// every calls job right away and then every interval until ctx is
+// cancelled.
+func every(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, job func(now time.Time)) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			job(time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// copyBudgets copies the budgets users opted into auto-copy into the new
+// week, month, quarter or year. Budgets already copied are skipped, so
+// repeated runs are no-ops.
+func copyBudgets(budgets budgetUC.Usecase, now time.Time) {
+	copied, err := budgets.CopyDue(now)
+	if err != nil {
+		log.Printf("budget copy: %v", err)
+	}
+	if copied > 0 {
+		log.Printf("budget copy: copied %d budgets into the new period", copied)
+	}
+}
+
+// processRecurring generates the transactions of the recurring occurrences
+// that are due, including those missed while the server was down. The
+// failed occurrences are retried by the next run.
+func processRecurring(recurring recurringUC.Usecase, now time.Time) {
+	run, err := recurring.ProcessAllDue(now)
+	if err != nil {
+		log.Printf("recurring transactions: %v", err)
+	}
+	if run.Generated > 0 {
+		log.Printf("recurring transactions: created %d transactions", run.Generated)
+	}
+	if run.Failed > 0 {
+		log.Printf("recurring transactions: %d occurrences failed, see run %d", run.Failed, run.ID)
+	}
+}
+
+// purgeAttachments removes the attachments left behind by transactions
+// deleted along with their transfer or category, and by deletes whose own
+// cleanup failed.
+func purgeAttachments(attachments attachmentUC.Usecase) {
+	purged, err := attachments.PurgeOrphaned()
+	if err != nil {
+		log.Printf("attachment purge: %v", err)
+	}
+	if purged > 0 {
+		log.Printf("attachment purge: removed %d attachments", purged)
+	}
+}
@@ -6,13 +6,22 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(r *gin.Engine, userHandler *handler.UserHandler, categoryHandler *handler.CategoryHandler,
+func RegisterRoutes(r *gin.Engine, userHandler *handler.UserHandler,
+	sessionHandler *handler.SessionHandler,
+	accountHandler *handler.AccountHandler,
+	categoryHandler *handler.CategoryHandler,
 	transactionHandler *handler.TransactionHandler,
 	budgetHandler *handler.BudgetHandler,
 	reportHandler *handler.ReportHandler,
 	recurringHandler *handler.RecurringHandler,
+	forecastHandler *handler.ForecastHandler,
+	calendarHandler *handler.CalendarHandler,
 	twofaHandler *handler.TwoFAHandler,
 	mfaSettingsHandler *handler.MFASettingsHandler,
+	fxHandler *handler.FxHandler,
+	importHandler *handler.ImportHandler,
+	tagHandler *handler.TagHandler,
+	attachmentHandler *handler.AttachmentHandler,
 ) {
-	httpDelivery.RegisterRoutes(r, userHandler, categoryHandler, transactionHandler, budgetHandler, reportHandler, recurringHandler, twofaHandler, mfaSettingsHandler)
+	httpDelivery.RegisterRoutes(r, userHandler, sessionHandler, accountHandler, categoryHandler, transactionHandler, budgetHandler, reportHandler, recurringHandler, forecastHandler, calendarHandler, twofaHandler, mfaSettingsHandler, fxHandler, importHandler, tagHandler, attachmentHandler)
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -23,6 +24,8 @@ type Config struct {
 
 	DB         DBConfig
 	ElasticApm ElasticApmConfig
+	Storage    StorageConfig
+	SMTP       SMTPConfig
 }
 
 type DBConfig struct {
@@ -35,6 +38,31 @@ type DBConfig struct {
 	SSLMode string
 }
 
+// StorageConfig selects where attachment files are kept: "local" keeps them
+// below LocalPath, "s3" in an S3-compatible bucket.
+type StorageConfig struct {
+	Driver        string
+	LocalPath     string
+	MaxUploadSize int64
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+// SMTPConfig is the server account email is sent through. Without a Host
+// emails are only logged.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
 type ElasticApmConfig struct {
 	ServerURL        string
 	ServiceName      string
@@ -78,6 +106,24 @@ func Load() *Config {
 			VerifyServerCert: getEnv("ELASTIC_APM_VERIFY_SERVER_CERT", "true") == "true",
 			ServiceVersion:   getEnv("ELASTIC_APM_SERVICE_VERSION", "1.10.0"),
 		},
+		Storage: StorageConfig{
+			Driver:        getEnv("STORAGE_DRIVER", "local"),
+			LocalPath:     getEnv("STORAGE_LOCAL_PATH", "./storage/attachments"),
+			MaxUploadSize: getEnvInt64("STORAGE_MAX_UPLOAD_MB", 10) << 20,
+			S3Endpoint:    getEnv("STORAGE_S3_ENDPOINT", ""),
+			S3Region:      getEnv("STORAGE_S3_REGION", "us-east-1"),
+			S3Bucket:      getEnv("STORAGE_S3_BUCKET", ""),
+			S3AccessKey:   getEnv("STORAGE_S3_ACCESS_KEY", ""),
+			S3SecretKey:   getEnv("STORAGE_S3_SECRET_KEY", ""),
+			S3PathStyle:   getEnv("STORAGE_S3_PATH_STYLE", "false") == "true",
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "Cashbook <no-reply@localhost>"),
+		},
 		CorsAllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "*"),
 	}
 
@@ -92,6 +138,16 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+func getEnvInt64(key string, defaultVal int64) int64 {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("%s must be a positive number, using %d", key, defaultVal)
+	}
+	return defaultVal
+}
+
 func validate(cfg *Config) {
 	if cfg.DB.Name == "" {
 		log.Fatal("DB_NAME is required")
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/account"
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	usecase uc.Usecase
+}
+
+func NewAccountHandler(usecase uc.Usecase) *AccountHandler {
+	return &AccountHandler{usecase: usecase}
+}
+
+// GetAccounts godoc
+// @Summary      List accounts and wallets
+// @Description  Retrieve every cash, bank and e-wallet account of the user together with its current balance.
+// @Tags         Accounts
+// @Produce      json
+// @Success      200 {object} response.SuccessAccountResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /accounts [get]
+func (h *AccountHandler) GetAccounts(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	accounts, err := h.usecase.GetAllByUserID(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", accounts)
+}
+
+// GetAccount godoc
+// @Summary      Retrieve an account
+// @Description  Fetch a single account with its current balance.
+// @Tags         Accounts
+// @Produce      json
+// @Param        id   path      int  true  "Account ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Router       /accounts/{id} [get]
+func (h *AccountHandler) GetAccount(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	a, err := h.usecase.GetByID(c.MustGet("user_id").(int64), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", a)
+}
+
+// CreateAccount godoc
+// @Summary      Open a new account
+// @Description  Register a cash, bank, e-wallet or credit card account with an opening balance.
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        body body account.Account true "Account payload"
+// @Success      201 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /accounts [post]
+func (h *AccountHandler) CreateAccount(c *gin.Context) {
+	var req account.Account
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	a, err := h.usecase.Create(c.MustGet("user_id").(int64), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "account created", a)
+}
+
+// UpdateAccount godoc
+// @Summary      Modify account details
+// @Description  Rename an account or change its type and opening balance.
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Account ID"
+// @Param        body body account.Account true "Account payload"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Router       /accounts/{id} [put]
+func (h *AccountHandler) UpdateAccount(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	var req account.Account
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	if err := h.usecase.Update(c.MustGet("user_id").(int64), id, req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "account updated", nil)
+}
+
+// DeleteAccount godoc
+// @Summary      Close an account
+// @Description  Permanently remove an account together with its transactions.
+// @Tags         Accounts
+// @Produce      json
+// @Param        id   path      int  true  "Account ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Router       /accounts/{id} [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	if err := h.usecase.Delete(c.MustGet("user_id").(int64), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "account deleted", nil)
+}
+
+type transferRequest struct {
+	FromAccountID int64        `json:"from_account_id" binding:"required"`
+	ToAccountID   int64        `json:"to_account_id" binding:"required"`
+	Amount        money.Amount `json:"amount" binding:"required"`
+	Note          string       `json:"note"`
+	Date          string       `json:"date"`
+}
+
+// CreateTransfer godoc
+// @Summary      Move money between accounts
+// @Description  Transfer an amount from one account to another. Both legs are written atomically and are never counted as income or expense.
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        body body transferRequest true "Transfer payload"
+// @Success      201 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Router       /accounts/transfers [post]
+func (h *AccountHandler) CreateTransfer(c *gin.Context) {
+	var req transferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	tr := account.Transfer{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Note:          req.Note,
+	}
+	if req.Date != "" {
+		date, err := parseDate(req.Date)
+		if err != nil {
+			c.Error(apperror.BadRequest("invalid date format", err))
+			return
+		}
+		tr.Date = date
+	}
+
+	created, err := h.usecase.Transfer(c.MustGet("user_id").(int64), tr)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "transfer created", created)
+}
+
+// DeleteTransfer godoc
+// @Summary      Reverse a transfer
+// @Description  Delete a transfer together with both of its transaction legs.
+// @Tags         Accounts
+// @Produce      json
+// @Param        id   path      int  true  "Transfer ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Router       /accounts/transfers/{id} [delete]
+func (h *AccountHandler) DeleteTransfer(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	if err := h.usecase.DeleteTransfer(c.MustGet("user_id").(int64), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "transfer deleted", nil)
+}
+
+// parseDate accepts either an RFC3339 datetime or a date-only "2006-01-02".
+func parseDate(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/attachment"
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is allowed on top of the file size for the rest of an
+// upload request.
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	usecase uc.Usecase
+	maxSize int64
+}
+
+// NewAttachmentHandler returns the attachment handler. maxSize must match the
+// limit the usecase was created with.
+func NewAttachmentHandler(usecase uc.Usecase, maxSize int64) *AttachmentHandler {
+	if maxSize <= 0 {
+		maxSize = uc.DefaultMaxSize
+	}
+	return &AttachmentHandler{usecase: usecase, maxSize: maxSize}
+}
+
+func attachmentParams(c *gin.Context, withID bool) (transactionID, id int64, err error) {
+	transactionID, err = strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, apperror.BadRequest("invalid transaction id", err)
+	}
+	if withID {
+		id, err = strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+		if err != nil {
+			return 0, 0, apperror.BadRequest("invalid attachment id", err)
+		}
+	}
+	return transactionID, id, nil
+}
+
+// GetAttachments godoc
+// @Summary      List attachments
+// @Description  Retrieve the receipts and other files attached to a transaction.
+// @Tags         Attachments
+// @Produce      json
+// @Param        id   path      int  true  "Transaction ID"
+// @Success      200 {object} response.SuccessAttachmentResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /transactions/{id}/attachments [get]
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	transactionID, _, err := attachmentParams(c, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	attachments, err := h.usecase.GetAll(userID, transactionID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", attachments)
+}
+
+// UploadAttachment godoc
+// @Summary      Attach a file
+// @Description  Attach a receipt to a transaction. The type is detected from the file contents; JPEG, PNG, GIF, WebP and PDF files are accepted, up to the configured size limit (10 MB by default).
+// @Tags         Attachments
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id   path      int   true  "Transaction ID"
+// @Param        file formData  file  true  "Receipt image or PDF"
+// @Success      201 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      413 {object} response.ErrorSwaggerResponse
+// @Failure      415 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /transactions/{id}/attachments [post]
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	transactionID, _, err := attachmentParams(c, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.Error(apperror.BadRequest("file is required and must not exceed the size limit", err))
+		return
+	}
+	if fileHeader.Size > h.maxSize {
+		msg := "file is larger than " + strconv.FormatInt(h.maxSize>>20, 10) + " MB"
+		c.Error(apperror.New(http.StatusRequestEntityTooLarge, msg, nil).WithCode(apperror.BadRequestError))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Error(apperror.BadRequest("cannot read file", err))
+		return
+	}
+	defer file.Close()
+
+	a, err := h.usecase.Upload(userID, transactionID, fileHeader.Filename, file)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "attachment uploaded", a)
+}
+
+// DownloadAttachment godoc
+// @Summary      Download an attachment
+// @Description  Download the contents of an attached file.
+// @Tags         Attachments
+// @Produce      image/jpeg
+// @Produce      image/png
+// @Produce      application/pdf
+// @Param        id            path  int  true  "Transaction ID"
+// @Param        attachmentId  path  int  true  "Attachment ID"
+// @Success      200 {file} file
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /transactions/{id}/attachments/{attachmentId} [get]
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	transactionID, id, err := attachmentParams(c, true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	a, rc, err := h.usecase.Open(userID, transactionID, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, a.Size, a.ContentType, rc, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": a.FileName}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// DeleteAttachment godoc
+// @Summary      Delete an attachment
+// @Description  Remove an attached file from a transaction.
+// @Tags         Attachments
+// @Produce      json
+// @Param        id            path  int  true  "Transaction ID"
+// @Param        attachmentId  path  int  true  "Attachment ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /transactions/{id}/attachments/{attachmentId} [delete]
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	transactionID, id, err := attachmentParams(c, true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.usecase.Delete(userID, transactionID, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "attachment deleted", nil)
+}
@@ -20,28 +20,66 @@ func NewBudgetHandler(usecase uc.Usecase) *BudgetHandler {
 	return &BudgetHandler{usecase: usecase}
 }
 
+// budgetQuery reads the day and period budgets are listed for. Without a
+// date the month and year parameters select monthly budgets, as before
+// budgets had periods; without either it is today, in every period.
+func budgetQuery(c *gin.Context) (time.Time, string, error) {
+	period := c.Query("period")
+	if v := c.Query("date"); v != "" {
+		on, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return time.Time{}, "", apperror.BadRequest("invalid date, use YYYY-MM-DD", err)
+		}
+		return on, period, nil
+	}
+
+	now := time.Now()
+	monthStr, hasMonth := c.GetQuery("month")
+	yearStr, hasYear := c.GetQuery("year")
+	if !hasMonth && !hasYear {
+		return now, period, nil
+	}
+	if !hasMonth {
+		monthStr = strconv.Itoa(int(now.Month()))
+	}
+	if !hasYear {
+		yearStr = strconv.Itoa(now.Year())
+	}
+	month, _ := strconv.Atoi(monthStr)
+	year, _ := strconv.Atoi(yearStr)
+	if month < 1 || month > 12 {
+		return time.Time{}, "", apperror.BadRequest("month must be between 1 and 12", nil)
+	}
+	if period == "" {
+		period = budget.PeriodMonth
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), period, nil
+}
+
 // GetBudgets godoc
-// @Summary      Track budget progress
-// @Description  Retrieve monthly budget targets and current spending progress for a specific period to maintain financial discipline.
+// @Summary      List budgets
+// @Description  Retrieve the budgets whose period contains the given date, of one period or of all of them, each with its category's name and colour and its limit in the base currency. The month and year parameters list the monthly budgets of that month. The all-categories budget has category_id 0.
 // @Tags         Budgets
 // @Produce      json
-// @Param        month  query     int  false  "Month (1-12)"
-// @Param        year   query     int  false  "Year"
+// @Param        date    query     string  false  "Any day of the periods (YYYY-MM-DD), defaults to today"
+// @Param        period  query     string  false  "week, month, quarter or year; all when empty"
+// @Param        month   query     int     false  "Month (1-12)"
+// @Param        year    query     int     false  "Year"
 // @Success      200 {object} response.SuccessBudgetResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
 // @Failure      401 {object} response.ErrorSwaggerResponse
 // @Failure      500 {object} response.ErrorSwaggerResponse
 // @Router       /budgets [get]
 func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	userID := c.MustGet("user_id").(int64)
 
-	now := time.Now()
-	monthStr := c.DefaultQuery("month", strconv.Itoa(int(now.Month())))
-	yearStr := c.DefaultQuery("year", strconv.Itoa(now.Year()))
-
-	month, _ := strconv.Atoi(monthStr)
-	year, _ := strconv.Atoi(yearStr)
+	on, period, err := budgetQuery(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	budgets, err := h.usecase.GetBudgets(userID, month, year)
+	budgets, err := h.usecase.GetBudgets(userID, on, period)
 	if err != nil {
 		c.Error(err)
 		return
@@ -50,14 +88,46 @@ func (h *BudgetHandler) GetBudgets(c *gin.Context) {
 	response.Success(c, http.StatusOK, "success", budgets)
 }
 
+// GetBudgetProgress godoc
+// @Summary      Compare budgets with actual spending
+// @Description  For each budget GET /budgets returns, return the amount spent in its category (or in all categories) during its period, what remains, the percentage used, the projected spending at the current pace and whether it is on track, at risk or over. Amounts are in the base currency.
+// @Tags         Budgets
+// @Produce      json
+// @Param        date    query     string  false  "Any day of the periods (YYYY-MM-DD), defaults to today"
+// @Param        period  query     string  false  "week, month, quarter or year; all when empty"
+// @Param        month   query     int     false  "Month (1-12)"
+// @Param        year    query     int     false  "Year"
+// @Success      200 {object} response.SuccessBudgetProgressResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /budgets/progress [get]
+func (h *BudgetHandler) GetBudgetProgress(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	on, period, err := budgetQuery(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	progress, err := h.usecase.GetProgress(userID, on, period)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", progress)
+}
+
 // SetBudget godoc
-// @Summary      Establish category budget
-// @Description  Set or update a monthly spending limit for a specific financial category.
+// @Summary      Establish budget
+// @Description  Set or update the spending limit of a category, or of all categories when category_id is 0, for a week, month, quarter or year. The period is the one containing start_date; monthly budgets may give month and year instead. With rollover set to unspent or all, what is left at the end of the period (or, with all, the overspend) is carried into the next period's budget. Budgets with auto_copy are copied into the next period when it starts.
 // @Tags         Budgets
 // @Accept       json
 // @Produce      json
 // @Param        body body budget.Budget true "Budget payload"
-// @Success      200 {object} response.SuccessResponse
+// @Success      200 {object} response.SuccessBudgetItemResponse
 // @Failure      400 {object} response.ErrorSwaggerResponse
 // @Failure      401 {object} response.ErrorSwaggerResponse
 // @Failure      500 {object} response.ErrorSwaggerResponse
@@ -71,10 +141,104 @@ func (h *BudgetHandler) SetBudget(c *gin.Context) {
 		return
 	}
 
-	if err := h.usecase.SetBudget(userID, req); err != nil {
+	b, err := h.usecase.SetBudget(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "budget updated", b)
+}
+
+// UpdateBudget godoc
+// @Summary      Modify budget
+// @Description  Change the amount, currency, rollover and auto-copy of a budget. Its category and period cannot be changed.
+// @Tags         Budgets
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Budget ID"
+// @Param        body body budget.Budget true "Budget payload"
+// @Success      200 {object} response.SuccessBudgetItemResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /budgets/{id} [put]
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	var req budget.Budget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	b, err := h.usecase.UpdateBudget(c.MustGet("user_id").(int64), id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "budget updated", b)
+}
+
+// DeleteBudget godoc
+// @Summary      Remove budget
+// @Description  Permanently remove a budget.
+// @Tags         Budgets
+// @Produce      json
+// @Param        id   path      int  true  "Budget ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /budgets/{id} [delete]
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	if err := h.usecase.DeleteBudget(c.MustGet("user_id").(int64), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "budget deleted", nil)
+}
+
+// CopyBudgets godoc
+// @Summary      Copy budgets to another period
+// @Description  Copy every budget of one period into another of the same length, optionally scaled by a percentage. from and to are any day of the two periods; monthly budgets may be copied with from_month, from_year, to_month and to_year instead. Categories that already have a budget in the target period are skipped unless overwrite is set. Copying into the following period carries over what rollover budgets have left.
+// @Tags         Budgets
+// @Accept       json
+// @Produce      json
+// @Param        body body budget.CopyRequest true "Source and target period"
+// @Success      200 {object} response.SuccessBudgetCopyResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /budgets/copy [post]
+func (h *BudgetHandler) CopyBudgets(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	var req budget.CopyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	result, err := h.usecase.CopyBudgets(userID, req)
+	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	response.Success(c, http.StatusOK, "budget updated", nil)
+	response.Success(c, http.StatusOK, "budgets copied", result)
 }
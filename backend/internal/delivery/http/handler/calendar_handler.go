@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/calendar"
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	usecase uc.Usecase
+}
+
+func NewCalendarHandler(usecase uc.Usecase) *CalendarHandler {
+	return &CalendarHandler{usecase: usecase}
+}
+
+// GetFeed godoc
+// @Summary      Get the bills calendar feed
+// @Description  Tell whether the bills calendar feed is enabled and since when. The token is only shown when it is generated.
+// @Tags         Calendar
+// @Produce      json
+// @Success      200 {object} response.SuccessCalendarFeedResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /calendar/feed [get]
+func (h *CalendarHandler) GetFeed(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	feed, err := h.usecase.GetFeed(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", feed)
+}
+
+// RegenerateFeed godoc
+// @Summary      Generate the bills calendar feed URL
+// @Description  Enable the bills calendar feed, or replace its token so the previous URL stops working. Subscribe to the returned URL from a calendar app to see upcoming recurring bills.
+// @Tags         Calendar
+// @Produce      json
+// @Success      201 {object} response.SuccessCalendarTokenResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /calendar/feed [post]
+func (h *CalendarHandler) RegenerateFeed(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	token, err := h.usecase.RegenerateFeed(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "calendar feed generated", gin.H{
+		"token": token,
+		"url":   feedURL(c, token),
+	})
+}
+
+// feedURL is the absolute URL of the bills feed for token, as seen by the
+// client of this request.
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     c.Request.Host,
+		Path:     "/api/v1/calendar/bills.ics",
+		RawQuery: url.Values{"token": {token}}.Encode(),
+	}
+	return u.String()
+}
+
+// RevokeFeed godoc
+// @Summary      Revoke the bills calendar feed
+// @Description  Disable the bills calendar feed so its URL stops working.
+// @Tags         Calendar
+// @Produce      json
+// @Success      200 {object} response.SuccessResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /calendar/feed [delete]
+func (h *CalendarHandler) RevokeFeed(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	if err := h.usecase.RevokeFeed(userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "calendar feed revoked", nil)
+}
+
+// GetBills godoc
+// @Summary      Bills calendar
+// @Description  iCalendar file of the upcoming occurrences of the recurring templates over the next 180 days, one all-day event each with the amount, category and note. Skipped occurrences are cancelled events. Authenticated by the feed token instead of a bearer token, for calendar apps.
+// @Tags         Calendar
+// @Produce      text/calendar
+// @Param        token  query     string  true  "Feed token"
+// @Success      200 {string} string
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /calendar/bills.ics [get]
+func (h *CalendarHandler) GetBills(c *gin.Context) {
+	ics, err := h.usecase.Bills(c.Query("token"), time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="bills.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", ics)
+}
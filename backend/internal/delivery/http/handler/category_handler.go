@@ -98,7 +98,7 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		return
 	}
 
-	if err := h.usecase.Update(id, req); err != nil {
+	if err := h.usecase.Update(c.MustGet("user_id").(int64), id, req); err != nil {
 		c.Error(err)
 		return
 	}
@@ -125,7 +125,7 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	if err := h.usecase.Delete(id); err != nil {
+	if err := h.usecase.Delete(c.MustGet("user_id").(int64), id); err != nil {
 		c.Error(err)
 		return
 	}
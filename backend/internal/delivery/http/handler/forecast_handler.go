@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/forecast"
+	"github.com/gin-gonic/gin"
+)
+
+type ForecastHandler struct {
+	usecase uc.Usecase
+}
+
+func NewForecastHandler(usecase uc.Usecase) *ForecastHandler {
+	return &ForecastHandler{usecase: usecase}
+}
+
+// GetForecast godoc
+// @Summary      Cash-flow forecast
+// @Description  Project the balance of each account day by day over the next days, starting today, by adding the upcoming occurrences of the active recurring templates to the current balance. Days whose closing balance is below the threshold are flagged as low. Skipped occurrences are listed but do not change the balance.
+// @Tags         Forecast
+// @Produce      json
+// @Param        days       query     int     false  "Number of days, e.g. 30, 60 or 90 (default 30, at most 366)"
+// @Param        threshold  query     string  false  "Low-balance threshold in each account's currency (default 0)"
+// @Success      200 {object} response.SuccessForecastResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /forecast [get]
+func (h *ForecastHandler) GetForecast(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	var days int
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.Error(apperror.BadRequest("invalid days", err))
+			return
+		}
+		days = n
+	}
+	var threshold money.Amount
+	if v := c.Query("threshold"); v != "" {
+		t, err := money.Parse(v)
+		if err != nil {
+			c.Error(apperror.BadRequest("invalid threshold", err))
+			return
+		}
+		threshold = t
+	}
+
+	forecast, err := h.usecase.GetForecast(userID, time.Now(), days, threshold)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", forecast)
+}
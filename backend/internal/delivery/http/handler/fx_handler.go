@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
+	"github.com/gin-gonic/gin"
+)
+
+type FxHandler struct {
+	usecase uc.Usecase
+}
+
+func NewFxHandler(usecase uc.Usecase) *FxHandler {
+	return &FxHandler{usecase: usecase}
+}
+
+// ImportRates godoc
+// @Summary      Import exchange rates
+// @Description  Upload a CSV (date,base,quote,rate) or JSON file of daily exchange rates. Existing rates for the same day and pair are replaced.
+// @Tags         Admin
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file  true  "CSV or JSON rates file"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      403 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /admin/fx-rates [post]
+func (h *FxHandler) ImportRates(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.Error(apperror.BadRequest("file is required", err))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Error(apperror.BadRequest("cannot read file", err))
+		return
+	}
+	defer file.Close()
+
+	var rates []fx.Rate
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".csv":
+		rates, err = uc.ParseCSV(file)
+	case ".json":
+		rates, err = uc.ParseJSON(file)
+	default:
+		c.Error(apperror.BadRequest("file must be .csv or .json", nil))
+		return
+	}
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid rates file: "+err.Error(), err))
+		return
+	}
+
+	imported, err := h.usecase.ImportRates(rates)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "rates imported", gin.H{"imported": imported})
+}
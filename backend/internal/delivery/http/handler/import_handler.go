@@ -0,0 +1,453 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/importer"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatementSize is the largest statement file accepted for import.
+const maxStatementSize = 5 << 20
+
+type ImportHandler struct {
+	usecase uc.Usecase
+}
+
+func NewImportHandler(usecase uc.Usecase) *ImportHandler {
+	return &ImportHandler{usecase: usecase}
+}
+
+// GetProfiles godoc
+// @Summary      List import profiles
+// @Description  Retrieve the saved CSV column mappings, one per bank.
+// @Tags         Imports
+// @Produce      json
+// @Success      200 {object} response.SuccessImportProfileResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/profiles [get]
+func (h *ImportHandler) GetProfiles(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	profiles, err := h.usecase.GetProfiles(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", profiles)
+}
+
+// CreateProfile godoc
+// @Summary      Save an import profile
+// @Description  Save how a bank's CSV export is laid out: column names, date format, amount sign, debit/credit columns and decimal separator.
+// @Tags         Imports
+// @Accept       json
+// @Produce      json
+// @Param        body body importer.Profile true "Profile payload"
+// @Success      201 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/profiles [post]
+func (h *ImportHandler) CreateProfile(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	var req importer.Profile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	profile, err := h.usecase.CreateProfile(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "import profile created", profile)
+}
+
+// UpdateProfile godoc
+// @Summary      Update an import profile
+// @Description  Change the name or column mapping of a saved import profile.
+// @Tags         Imports
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Profile ID"
+// @Param        body body importer.Profile true "Profile payload"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/profiles/{id} [put]
+func (h *ImportHandler) UpdateProfile(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	var req importer.Profile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	if err := h.usecase.UpdateProfile(userID, id, req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "import profile updated", nil)
+}
+
+// DeleteProfile godoc
+// @Summary      Delete an import profile
+// @Tags         Imports
+// @Produce      json
+// @Param        id   path      int  true  "Profile ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/profiles/{id} [delete]
+func (h *ImportHandler) DeleteProfile(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	if err := h.usecase.DeleteProfile(userID, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "import profile deleted", nil)
+}
+
+// PreviewCSV godoc
+// @Summary      Preview a CSV statement import
+// @Description  Parse an uploaded CSV statement with a saved profile (profile_id) or an inline mapping (JSON), resolve categories and flag rows that likely duplicate existing transactions. Nothing is saved.
+// @Tags         Imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file         formData  file    true   "CSV statement"
+// @Param        profile_id   formData  int     false  "Saved import profile"
+// @Param        mapping      formData  string  false  "Inline column mapping as JSON, used when no profile_id is given"
+// @Param        account_id   formData  int     false  "Target account (defaults to the first account)"
+// @Param        category_id  formData  int     false  "Category for rows without a matching category"
+// @Success      200 {object} response.SuccessImportPreviewResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/csv/preview [post]
+func (h *ImportHandler) PreviewCSV(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	rows, opts, err := h.parseCSVUpload(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	preview, err := h.usecase.Preview(userID, rows, opts)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", preview)
+}
+
+// CommitCSV godoc
+// @Summary      Import a CSV statement
+// @Description  Parse the same upload as the preview and save every row in one database transaction. Likely duplicates are skipped unless include_duplicates is true; any invalid row aborts the import.
+// @Tags         Imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file                formData  file    true   "CSV statement"
+// @Param        profile_id          formData  int     false  "Saved import profile"
+// @Param        mapping             formData  string  false  "Inline column mapping as JSON, used when no profile_id is given"
+// @Param        account_id          formData  int     false  "Target account (defaults to the first account)"
+// @Param        category_id         formData  int     false  "Category for rows without a matching category"
+// @Param        include_duplicates  formData  bool    false  "Also import rows flagged as likely duplicates"
+// @Success      201 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/csv/commit [post]
+func (h *ImportHandler) CommitCSV(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	rows, opts, err := h.parseCSVUpload(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.usecase.Commit(userID, rows, opts)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "transactions imported", result)
+}
+
+// PreviewOFX godoc
+// @Summary      Preview an OFX/QFX statement import
+// @Description  Parse an uploaded OFX or QFX file. Each account statement in the file is mapped to a Cashbook account through the accounts field; transactions whose FITID was imported before are flagged as already imported. Nothing is saved.
+// @Tags         Imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file         formData  file    true   "OFX or QFX file"
+// @Param        accounts     formData  string  false  "JSON object mapping the file's account IDs to Cashbook account IDs"
+// @Param        account_id   formData  int     false  "Account for statements not listed in accounts (defaults to the first account)"
+// @Param        category_id  formData  int     false  "Category for the imported transactions"
+// @Success      200 {object} response.SuccessStatementPreviewResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/ofx/preview [post]
+func (h *ImportHandler) PreviewOFX(c *gin.Context) {
+	h.previewStatements(c, ".ofx", ".qfx")
+}
+
+// CommitOFX godoc
+// @Summary      Import an OFX/QFX statement
+// @Description  Save every statement in the file in one database transaction. Transactions whose FITID already exists on the account are always skipped, so importing the same file twice is a no-op.
+// @Tags         Imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file                formData  file    true   "OFX or QFX file"
+// @Param        accounts            formData  string  false  "JSON object mapping the file's account IDs to Cashbook account IDs"
+// @Param        account_id          formData  int     false  "Account for statements not listed in accounts (defaults to the first account)"
+// @Param        category_id         formData  int     false  "Category for the imported transactions"
+// @Param        include_duplicates  formData  bool    false  "Also import rows flagged as likely duplicates"
+// @Success      201 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/ofx/commit [post]
+func (h *ImportHandler) CommitOFX(c *gin.Context) {
+	h.commitStatements(c, ".ofx", ".qfx")
+}
+
+// PreviewQIF godoc
+// @Summary      Preview a QIF import
+// @Description  Parse an uploaded QIF file. Each !Account section is mapped to a Cashbook account through the accounts field and QIF categories are matched by name. Nothing is saved.
+// @Tags         Imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file         formData  file    true   "QIF file"
+// @Param        date_format  formData  string  false  "MM/DD (default) or DD/MM"
+// @Param        accounts     formData  string  false  "JSON object mapping the file's account names to Cashbook account IDs"
+// @Param        account_id   formData  int     false  "Account for sections not listed in accounts (defaults to the first account)"
+// @Param        category_id  formData  int     false  "Category for transactions without a matching category"
+// @Success      200 {object} response.SuccessStatementPreviewResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/qif/preview [post]
+func (h *ImportHandler) PreviewQIF(c *gin.Context) {
+	h.previewStatements(c, ".qif")
+}
+
+// CommitQIF godoc
+// @Summary      Import a QIF file
+// @Description  Save every account section of the file in one database transaction. Likely duplicates are skipped unless include_duplicates is true.
+// @Tags         Imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file                formData  file    true   "QIF file"
+// @Param        date_format         formData  string  false  "MM/DD (default) or DD/MM"
+// @Param        accounts            formData  string  false  "JSON object mapping the file's account names to Cashbook account IDs"
+// @Param        account_id          formData  int     false  "Account for sections not listed in accounts (defaults to the first account)"
+// @Param        category_id         formData  int     false  "Category for transactions without a matching category"
+// @Param        include_duplicates  formData  bool    false  "Also import rows flagged as likely duplicates"
+// @Success      201 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /imports/qif/commit [post]
+func (h *ImportHandler) CommitQIF(c *gin.Context) {
+	h.commitStatements(c, ".qif")
+}
+
+func (h *ImportHandler) previewStatements(c *gin.Context, extensions ...string) {
+	userID := c.MustGet("user_id").(int64)
+
+	stmts, opts, err := parseStatementUpload(c, extensions)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	previews, err := h.usecase.PreviewStatements(userID, stmts, opts)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", previews)
+}
+
+func (h *ImportHandler) commitStatements(c *gin.Context, extensions ...string) {
+	userID := c.MustGet("user_id").(int64)
+
+	stmts, opts, err := parseStatementUpload(c, extensions)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.usecase.CommitStatements(userID, stmts, opts)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "transactions imported", result)
+}
+
+// parseStatementUpload reads an OFX, QFX or QIF upload. The parser is
+// chosen by file extension.
+func parseStatementUpload(c *gin.Context, extensions []string) ([]importer.Statement, importer.Options, error) {
+	opts, err := importOptions(c)
+	if err != nil {
+		return nil, importer.Options{}, err
+	}
+	if raw := c.PostForm("accounts"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Accounts); err != nil {
+			return nil, importer.Options{}, apperror.BadRequest("invalid accounts", err)
+		}
+	}
+
+	var dayFirst bool
+	switch strings.ToUpper(c.PostForm("date_format")) {
+	case "", "MM/DD":
+	case "DD/MM":
+		dayFirst = true
+	default:
+		return nil, importer.Options{}, apperror.BadRequest("date_format must be MM/DD or DD/MM", nil)
+	}
+
+	file, ext, err := openStatement(c, extensions)
+	if err != nil {
+		return nil, importer.Options{}, err
+	}
+	defer file.Close()
+
+	var stmts []importer.Statement
+	if ext == ".qif" {
+		stmts, err = uc.ParseQIF(file, dayFirst)
+	} else {
+		stmts, err = uc.ParseOFX(file)
+	}
+	if err != nil {
+		return nil, importer.Options{}, apperror.BadRequest("cannot parse file: "+err.Error(), err)
+	}
+	return stmts, opts, nil
+}
+
+func openStatement(c *gin.Context, extensions []string) (io.ReadCloser, string, error) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", apperror.BadRequest("file is required", err)
+	}
+	if fileHeader.Size > maxStatementSize {
+		return nil, "", apperror.BadRequest("file is too large", nil)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if !slices.Contains(extensions, ext) {
+		return nil, "", apperror.BadRequest("file must be one of "+strings.Join(extensions, ", "), nil)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", apperror.BadRequest("cannot read file", err)
+	}
+	return file, ext, nil
+}
+
+func (h *ImportHandler) parseCSVUpload(c *gin.Context, userID int64) ([]importer.Row, importer.Options, error) {
+	var mapping importer.Mapping
+	if profileID := c.PostForm("profile_id"); profileID != "" {
+		id, err := strconv.ParseInt(profileID, 10, 64)
+		if err != nil {
+			return nil, importer.Options{}, apperror.BadRequest("invalid profile_id", err)
+		}
+		profile, err := h.usecase.GetProfile(userID, id)
+		if err != nil {
+			return nil, importer.Options{}, err
+		}
+		mapping = profile.Mapping
+	} else if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			return nil, importer.Options{}, apperror.BadRequest("invalid mapping", err)
+		}
+	} else {
+		return nil, importer.Options{}, apperror.BadRequest("profile_id or mapping is required", nil)
+	}
+
+	opts, err := importOptions(c)
+	if err != nil {
+		return nil, importer.Options{}, err
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, importer.Options{}, apperror.BadRequest("file is required", err)
+	}
+	if fileHeader.Size > maxStatementSize {
+		return nil, importer.Options{}, apperror.BadRequest("file is too large", nil)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, importer.Options{}, apperror.BadRequest("cannot read file", err)
+	}
+	defer file.Close()
+
+	rows, err := uc.ParseCSV(file, mapping)
+	if err != nil {
+		return nil, importer.Options{}, apperror.BadRequest("cannot parse file: "+err.Error(), err)
+	}
+	return rows, opts, nil
+}
+
+func importOptions(c *gin.Context) (importer.Options, error) {
+	var opts importer.Options
+	var err error
+	if v := c.PostForm("account_id"); v != "" {
+		if opts.AccountID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return opts, apperror.BadRequest("invalid account_id", err)
+		}
+	}
+	if v := c.PostForm("category_id"); v != "" {
+		if opts.CategoryID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return opts, apperror.BadRequest("invalid category_id", err)
+		}
+	}
+	if v := c.PostForm("include_duplicates"); v != "" {
+		if opts.IncludeDuplicates, err = strconv.ParseBool(v); err != nil {
+			return opts, apperror.BadRequest("invalid include_duplicates", err)
+		}
+	}
+	return opts, nil
+}
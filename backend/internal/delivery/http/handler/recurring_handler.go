@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
 	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
@@ -42,7 +43,7 @@ func (h *RecurringHandler) GetRecurring(c *gin.Context) {
 
 // CreateRecurring godoc
 // @Summary      Create automation template
-// @Description  Define a new recurring transaction pattern to automate repetitive financial entries like bills or subscriptions.
+// @Description  Define a new recurring transaction pattern to automate repetitive financial entries like bills or subscriptions. The schedule is either a frequency (daily, weekly, monthly or yearly) or an RRULE-style rule supporting FREQ, INTERVAL, BYMONTH, BYMONTHDAY, BYDAY, BYSETPOS, UNTIL and COUNT, e.g. "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1" for the last business day of each month.
 // @Tags         Recurring
 // @Accept       json
 // @Produce      json
@@ -63,13 +64,243 @@ func (h *RecurringHandler) CreateRecurring(c *gin.Context) {
 
 	rt, err := h.usecase.CreateRecurring(userID, req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create recurring transaction", err.Error())
+		c.Error(err)
 		return
 	}
 
 	response.Success(c, http.StatusCreated, "recurring transaction created", rt)
 }
 
+// UpdateRecurring godoc
+// @Summary      Edit automation template
+// @Description  Replace the amount, type, account, category, note, schedule or start date of a recurring transaction. The change applies to the occurrences not generated yet; a paused template stays paused.
+// @Tags         Recurring
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Recurring Transaction ID"
+// @Param        body body recurring_transaction.RecurringTransaction true "Recurring Transaction payload"
+// @Success      200 {object} response.SuccessRecurringItemResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /recurring/{id} [put]
+func (h *RecurringHandler) UpdateRecurring(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	var req recurring_transaction.RecurringTransaction
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	rt, err := h.usecase.UpdateRecurring(c.MustGet("user_id").(int64), id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "recurring transaction updated", rt)
+}
+
+// PauseRecurring godoc
+// @Summary      Pause automation template
+// @Description  Stop generating the transactions of a recurring template until it is resumed.
+// @Tags         Recurring
+// @Produce      json
+// @Param        id   path      int  true  "Recurring Transaction ID"
+// @Success      200 {object} response.SuccessRecurringItemResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /recurring/{id}/pause [post]
+func (h *RecurringHandler) PauseRecurring(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	rt, err := h.usecase.PauseRecurring(c.MustGet("user_id").(int64), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "recurring transaction paused", rt)
+}
+
+// ResumeRecurring godoc
+// @Summary      Resume automation template
+// @Description  Generate the transactions of a paused recurring template again from now on. The occurrences that passed while it was paused are skipped and noted in its history.
+// @Tags         Recurring
+// @Produce      json
+// @Param        id   path      int  true  "Recurring Transaction ID"
+// @Success      200 {object} response.SuccessRecurringItemResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /recurring/{id}/resume [post]
+func (h *RecurringHandler) ResumeRecurring(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	rt, err := h.usecase.ResumeRecurring(c.MustGet("user_id").(int64), id, time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "recurring transaction resumed", rt)
+}
+
+// GetOccurrences godoc
+// @Summary      Upcoming occurrences
+// @Description  List the next occurrences of a recurring template that have not been generated yet, with skipped occurrences and changed amounts applied.
+// @Tags         Recurring
+// @Produce      json
+// @Param        id     path      int  true   "Recurring Transaction ID"
+// @Param        limit  query     int  false  "Number of occurrences (default 10, at most 100)"
+// @Success      200 {object} response.SuccessOccurrencesResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /recurring/{id}/occurrences [get]
+func (h *RecurringHandler) GetOccurrences(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	occurrences, err := h.usecase.GetOccurrences(c.MustGet("user_id").(int64), id, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", occurrences)
+}
+
+// SetException godoc
+// @Summary      Skip or change an occurrence
+// @Description  Skip a single upcoming occurrence of a recurring template, or generate it with another amount, e.g. for a bill that differs this month. The date is the day of the occurrence.
+// @Tags         Recurring
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int     true  "Recurring Transaction ID"
+// @Param        date  path      string  true  "Day of the occurrence (YYYY-MM-DD)"
+// @Param        body  body      recurring_transaction.Exception true "skip, or the amount to use instead"
+// @Success      200 {object} response.SuccessExceptionResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /recurring/{id}/occurrences/{date} [put]
+func (h *RecurringHandler) SetException(c *gin.Context) {
+	id, day, ok := occurrenceParams(c)
+	if !ok {
+		return
+	}
+
+	var req recurring_transaction.Exception
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+	req.Date = day
+
+	e, err := h.usecase.SetException(c.MustGet("user_id").(int64), id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "occurrence updated", e)
+}
+
+// DeleteException godoc
+// @Summary      Restore an occurrence
+// @Description  Undo skipping or changing the amount of an occurrence, so it is generated as the template says.
+// @Tags         Recurring
+// @Produce      json
+// @Param        id    path      int     true  "Recurring Transaction ID"
+// @Param        date  path      string  true  "Day of the occurrence (YYYY-MM-DD)"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /recurring/{id}/occurrences/{date} [delete]
+func (h *RecurringHandler) DeleteException(c *gin.Context) {
+	id, day, ok := occurrenceParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.usecase.DeleteException(c.MustGet("user_id").(int64), id, day); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "occurrence restored", nil)
+}
+
+// occurrenceParams parses the template id and the occurrence day of the
+// path, reporting a bad request when they are invalid.
+func occurrenceParams(c *gin.Context) (int64, time.Time, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return 0, time.Time{}, false
+	}
+	day, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid date, use YYYY-MM-DD", err))
+		return 0, time.Time{}, false
+	}
+	return id, day, true
+}
+
+// GetEvents godoc
+// @Summary      Automation history
+// @Description  The audit log of a recurring template, newest first: its changes, pauses and exceptions, and whether each occurrence was generated, skipped or had already been generated.
+// @Tags         Recurring
+// @Produce      json
+// @Param        id   path      int  true  "Recurring Transaction ID"
+// @Success      200 {object} response.SuccessRecurringEventsResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /recurring/{id}/events [get]
+func (h *RecurringHandler) GetEvents(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	events, err := h.usecase.GetEvents(c.MustGet("user_id").(int64), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", events)
+}
+
 // DeleteRecurring godoc
 // @Summary      Discard automation template
 // @Description  Permanently remove a recurring transaction pattern from the automation schedule.
@@ -89,7 +320,7 @@ func (h *RecurringHandler) DeleteRecurring(c *gin.Context) {
 		return
 	}
 
-	if err := h.usecase.DeleteRecurring(id); err != nil {
+	if err := h.usecase.DeleteRecurring(c.MustGet("user_id").(int64), id); err != nil {
 		c.Error(err)
 		return
 	}
@@ -99,18 +330,62 @@ func (h *RecurringHandler) DeleteRecurring(c *gin.Context) {
 
 // ProcessDue godoc
 // @Summary      Execute pending automations
-// @Description  Manually trigger the processing of due recurring templates to generate actual financial transactions for the current period.
+// @Description  Generate the transactions of every due occurrence of your recurring templates right away instead of waiting for the scheduler, including occurrences missed earlier. Returns a report of what was generated, skipped or failed for each occurrence. Runs with failures are kept for the administrators to inspect.
 // @Tags         Recurring
 // @Produce      json
-// @Success      200 {object} response.SuccessResponse
+// @Success      200 {object} response.SuccessRecurringRunResponse
 // @Failure      401 {object} response.ErrorSwaggerResponse
 // @Failure      500 {object} response.ErrorSwaggerResponse
 // @Router       /recurring/process [post]
 func (h *RecurringHandler) ProcessDue(c *gin.Context) {
-	if err := h.usecase.ProcessDueTransactions(); err != nil {
-		response.Error(c, 400, "BAD_REQUEST", "failed to process due recurring transactions", err.Error())
+	run, err := h.usecase.ProcessDue(c.MustGet("user_id").(int64), time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "processed", run)
+}
+
+// ProcessAllDue godoc
+// @Summary      Execute pending automations of every user
+// @Description  Run the scheduler's job right away: generate the transactions of every due occurrence of every user's recurring templates. Returns a report of what was generated, skipped or failed for each occurrence.
+// @Tags         Admin
+// @Produce      json
+// @Success      200 {object} response.SuccessRecurringRunResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      403 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /admin/recurring/process [post]
+func (h *RecurringHandler) ProcessAllDue(c *gin.Context) {
+	run, err := h.usecase.ProcessAllDue(time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "processed", run)
+}
+
+// GetRuns godoc
+// @Summary      Failed automation runs
+// @Description  List the last processing runs, by users, administrators or the scheduler, that failed to generate some occurrence, with what they did with each one.
+// @Tags         Admin
+// @Produce      json
+// @Param        limit  query     int  false  "Number of runs (default 20, at most 100)"
+// @Success      200 {object} response.SuccessRecurringRunsResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      403 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /admin/recurring/runs [get]
+func (h *RecurringHandler) GetRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	runs, err := h.usecase.GetRuns(limit)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	response.Success(c, http.StatusOK, "processed", nil)
+	response.Success(c, http.StatusOK, "success", runs)
 }
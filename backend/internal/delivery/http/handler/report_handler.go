@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
 	uc "github.com/afandimsr/cashbook-backend/internal/usecase/report"
 	"github.com/gin-gonic/gin"
 )
@@ -47,3 +49,74 @@ func (h *ReportHandler) GetCategorySpending(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, "success", report)
 }
+
+// GetTagSpending godoc
+// @Summary      Analyze spending by tag
+// @Description  Total the expenses of each tag for a month in the base currency. A transaction with several tags counts towards each of them.
+// @Tags         Reports
+// @Produce      json
+// @Param        month  query     int  false  "Month (1-12)"
+// @Param        year   query     int  false  "Year"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /reports/spending/tags [get]
+func (h *ReportHandler) GetTagSpending(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	now := time.Now()
+	monthStr := c.DefaultQuery("month", strconv.Itoa(int(now.Month())))
+	yearStr := c.DefaultQuery("year", strconv.Itoa(now.Year()))
+
+	month, _ := strconv.Atoi(monthStr)
+	year, _ := strconv.Atoi(yearStr)
+
+	report, err := h.usecase.GetTagSpending(userID, month, year)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", report)
+}
+
+// GetTrend godoc
+// @Summary      Income and expense over time
+// @Description  Total income, expense and net per day, week, month, quarter or year in the base currency, optionally split into one series per category, transaction type or account. Periods without transactions are included with zeros.
+// @Tags         Reports
+// @Produce      json
+// @Param        from      query     string  false  "First day (YYYY-MM-DD), defaults to eleven intervals before to"
+// @Param        to        query     string  false  "Last day (YYYY-MM-DD), defaults to today"
+// @Param        interval  query     string  false  "day, week, month (default), quarter or year"
+// @Param        group_by  query     string  false  "category, type or account"
+// @Success      200 {object} response.SuccessTrendResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /reports/trend [get]
+func (h *ReportHandler) GetTrend(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	query := transaction.TrendQuery{
+		Interval: c.Query("interval"),
+		GroupBy:  c.Query("group_by"),
+	}
+	for param, dst := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.Error(apperror.BadRequest("invalid "+param+" date, use YYYY-MM-DD", err))
+				return
+			}
+			*dst = t
+		}
+	}
+
+	report, err := h.usecase.GetTrend(userID, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", report)
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/user"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	usecase *uc.SessionUsecase
+}
+
+func NewSessionHandler(usecase *uc.SessionUsecase) *SessionHandler {
+	return &SessionHandler{usecase: usecase}
+}
+
+// device is where the request comes from.
+func device(c *gin.Context) user.Device {
+	return user.Device{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+// Refresh godoc
+// @Summary      Refresh the access token
+// @Description  Exchange a refresh token for a new access token and a new refresh token, which replaces it. Presenting a refresh token that was already exchanged revokes its session.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.RefreshRequest true "Refresh payload"
+// @Success      200 {object} response.SuccessLoginResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/refresh [post]
+func (h *SessionHandler) Refresh(c *gin.Context) {
+	var req user.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	loginResp, err := h.usecase.Refresh(req.RefreshToken, device(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "token refreshed", loginResp)
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  Revoke the session of the refresh token. Its access token stays valid until it expires, within 15 minutes.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.RefreshRequest true "Logout payload"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/logout [post]
+func (h *SessionHandler) Logout(c *gin.Context) {
+	var req user.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.Logout(req.RefreshToken); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "logged out", nil)
+}
+
+// GetSessions godoc
+// @Summary      List sessions
+// @Description  List the devices you are logged in on, most recently used first. The session of this request is marked current.
+// @Tags         Auth
+// @Produce      json
+// @Success      200 {object} response.SuccessSessionsResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /me/sessions [get]
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	sessions, err := h.usecase.GetSessions(c.MustGet("user_id").(int64), c.GetInt64("session_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", sessions)
+}
+
+// RevokeOtherSessions godoc
+// @Summary      Log out other devices
+// @Description  Revoke every session but the one of this request.
+// @Tags         Auth
+// @Produce      json
+// @Success      200 {object} response.SuccessResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /me/sessions [delete]
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	if err := h.usecase.RevokeOthers(c.MustGet("user_id").(int64), c.GetInt64("session_id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "sessions revoked", nil)
+}
+
+// RevokeSession godoc
+// @Summary      Log out a device
+// @Description  Revoke one of your sessions, so its refresh token stops working.
+// @Tags         Auth
+// @Produce      json
+// @Param        id   path      int  true  "Session ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	if err := h.usecase.Revoke(c.MustGet("user_id").(int64), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "session revoked", nil)
+}
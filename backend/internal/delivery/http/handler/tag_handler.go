@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/tag"
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	usecase uc.Usecase
+}
+
+func NewTagHandler(usecase uc.Usecase) *TagHandler {
+	return &TagHandler{usecase: usecase}
+}
+
+type renameTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type mergeTagsRequest struct {
+	SourceIDs []int64 `json:"source_ids" binding:"required"`
+	TargetID  int64   `json:"target_id" binding:"required"`
+}
+
+// GetTags godoc
+// @Summary      List tags
+// @Description  Retrieve the user's tags with the number of transactions carrying each. Tags are created by adding them to a transaction.
+// @Tags         Tags
+// @Produce      json
+// @Success      200 {object} response.SuccessTagResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /tags [get]
+func (h *TagHandler) GetTags(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	tags, err := h.usecase.GetAllByUserID(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", tags)
+}
+
+// RenameTag godoc
+// @Summary      Rename a tag
+// @Description  Rename a tag on every transaction that carries it. Renaming onto an existing tag's name fails with 409; merge the tags instead.
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Tag ID"
+// @Param        body body renameTagRequest true "New name"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      409 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /tags/{id} [put]
+func (h *TagHandler) RenameTag(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	var req renameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	t, err := h.usecase.Rename(userID, id, req.Name)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "tag renamed", t)
+}
+
+// MergeTags godoc
+// @Summary      Merge tags
+// @Description  Move every transaction of the source tags to the target tag and delete the source tags.
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        body body mergeTagsRequest true "Tags to merge"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /tags/merge [post]
+func (h *TagHandler) MergeTags(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+
+	var req mergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.BadRequest("invalid request", err))
+		return
+	}
+
+	t, err := h.usecase.Merge(userID, req.SourceIDs, req.TargetID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "tags merged", t)
+}
+
+// DeleteTag godoc
+// @Summary      Delete a tag
+// @Description  Delete a tag and remove it from every transaction. The transactions themselves are kept.
+// @Tags         Tags
+// @Produce      json
+// @Param        id   path      int  true  "Tag ID"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /tags/{id} [delete]
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid id", err))
+		return
+	}
+
+	if err := h.usecase.Delete(userID, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "tag deleted", nil)
+}
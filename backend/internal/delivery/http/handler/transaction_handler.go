@@ -3,11 +3,14 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/afandimsr/cashbook-backend/internal/delivery/http/response"
 	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
 	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
 	uc "github.com/afandimsr/cashbook-backend/internal/usecase/transaction"
 	"github.com/gin-gonic/gin"
 )
@@ -30,6 +33,9 @@ func NewTransactionHandler(usecase uc.Usecase) *TransactionHandler {
 // @Param        page   query     int     false  "Page number"
 // @Param        limit  query     int     false  "Items per page"
 // @Param        q      query     string  false  "Search query"
+// @Param        account_id  query  int  false  "Account ID"
+// @Param        tags       query  string  false  "Comma-separated tag names"
+// @Param        tag_match  query  string  false  "any (default) or all of the tags"
 // @Success      200 {object} response.SuccessTransactionResponse
 // @Failure      401 {object} response.ErrorSwaggerResponse
 // @Failure      500 {object} response.ErrorSwaggerResponse
@@ -39,11 +45,30 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
+	filter := transactionFilter(c)
+
+	txs, err := h.usecase.GetAllByUserID(userID, page, limit, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", txs)
+}
+
+// transactionFilter reads the filter query parameters shared by the list and
+// export endpoints. Malformed values are ignored.
+func transactionFilter(c *gin.Context) transaction.Filter {
 	filter := transaction.Filter{
 		Search: c.Query("q"),
 		Type:   c.Query("type"),
 	}
 
+	if accID := c.Query("account_id"); accID != "" {
+		id, _ := strconv.ParseInt(accID, 10, 64)
+		filter.AccountID = id
+	}
+
 	if catID := c.Query("category_id"); catID != "" {
 		id, _ := strconv.ParseInt(catID, 10, 64)
 		filter.CategoryID = id
@@ -61,13 +86,63 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 		}
 	}
 
-	txs, err := h.usecase.GetAllByUserID(userID, page, limit, filter)
+	if tags := c.Query("tags"); tags != "" {
+		filter.Tags = tag.NormalizeNames(strings.Split(tags, ","))
+		filter.TagMatch = transaction.TagMatchAny
+		if c.Query("tag_match") == transaction.TagMatchAll {
+			filter.TagMatch = transaction.TagMatchAll
+		}
+	}
+
+	return filter
+}
+
+// ExportTransactions godoc
+// @Summary      Export transactions
+// @Description  Download every transaction matching the same filters as the list endpoint as CSV, XLSX or JSON, including account name and category name and colour. The file is streamed while rows are read.
+// @Tags         Transactions
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce      json
+// @Param        format       query  string  false  "csv (default), xlsx or json"
+// @Param        q            query  string  false  "Search query"
+// @Param        account_id   query  int     false  "Account ID"
+// @Param        category_id  query  int     false  "Category ID"
+// @Param        type         query  string  false  "Transaction type"
+// @Param        start_date   query  string  false  "Start date (YYYY-MM-DD)"
+// @Param        end_date     query  string  false  "End date (YYYY-MM-DD)"
+// @Param        tags         query  string  false  "Comma-separated tag names"
+// @Param        tag_match    query  string  false  "any (default) or all of the tags"
+// @Success      200 {file} file
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /transactions/export [get]
+func (h *TransactionHandler) ExportTransactions(c *gin.Context) {
+	userID := c.MustGet("user_id").(int64)
+	format := c.DefaultQuery("format", uc.FormatCSV)
+
+	contentType, err := uc.ExportContentType(format)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	response.Success(c, http.StatusOK, "success", txs)
+	filename := "transactions-" + time.Now().Format("20060102") + "." + format
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	if err := h.usecase.Export(userID, transactionFilter(c), format, c.Writer); err != nil {
+		if c.Writer.Written() {
+			// the file is already on its way; all we can do is cut it short
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.Error(err)
+	}
 }
 
 // CreateTransaction godoc
@@ -85,13 +160,16 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 	// Accept date as either RFC3339 datetime or date-only "2006-01-02".
 	var raw struct {
-		ID         int64   `json:"id"`
-		UserID     int64   `json:"user_id"`
-		CategoryID int64   `json:"category_id"`
-		Amount     float64 `json:"amount"`
-		Note       string  `json:"note"`
-		Date       string  `json:"date"`
-		Type       string  `json:"type"`
+		ID         int64               `json:"id"`
+		UserID     int64               `json:"user_id"`
+		AccountID  int64               `json:"account_id"`
+		CategoryID int64               `json:"category_id"`
+		Amount     money.Amount        `json:"amount"`
+		Note       string              `json:"note"`
+		Date       string              `json:"date"`
+		Type       string              `json:"type"`
+		Splits     []transaction.Split `json:"splits"`
+		Tags       []string            `json:"tags"`
 	}
 
 	if err := c.ShouldBindJSON(&raw); err != nil {
@@ -120,11 +198,14 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 	req := transaction.Transaction{
 		ID:         raw.ID,
 		UserID:     c.MustGet("user_id").(int64),
+		AccountID:  raw.AccountID,
 		CategoryID: raw.CategoryID,
 		Amount:     raw.Amount,
 		Note:       raw.Note,
 		Date:       parsedDate,
 		Type:       raw.Type,
+		Splits:     raw.Splits,
+		Tags:       raw.Tags,
 	}
 
 	if err := h.usecase.Create(req); err != nil {
@@ -178,13 +259,16 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	}
 
 	var raw struct {
-		ID         int64   `json:"id"`
-		UserID     int64   `json:"user_id"`
-		CategoryID int64   `json:"category_id"`
-		Amount     float64 `json:"amount"`
-		Note       string  `json:"note"`
-		Date       string  `json:"date"`
-		Type       string  `json:"type"`
+		ID         int64               `json:"id"`
+		UserID     int64               `json:"user_id"`
+		AccountID  int64               `json:"account_id"`
+		CategoryID int64               `json:"category_id"`
+		Amount     money.Amount        `json:"amount"`
+		Note       string              `json:"note"`
+		Date       string              `json:"date"`
+		Type       string              `json:"type"`
+		Splits     []transaction.Split `json:"splits"`
+		Tags       []string            `json:"tags"`
 	}
 
 	if err := c.ShouldBindJSON(&raw); err != nil {
@@ -213,14 +297,17 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	req := transaction.Transaction{
 		ID:         raw.ID,
 		UserID:     raw.UserID,
+		AccountID:  raw.AccountID,
 		CategoryID: raw.CategoryID,
 		Amount:     raw.Amount,
 		Note:       raw.Note,
 		Date:       parsedDate,
 		Type:       raw.Type,
+		Splits:     raw.Splits,
+		Tags:       raw.Tags,
 	}
 
-	if err := h.usecase.Update(id, req); err != nil {
+	if err := h.usecase.Update(c.MustGet("user_id").(int64), id, req); err != nil {
 		c.Error(err)
 		return
 	}
@@ -247,7 +334,7 @@ func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 		return
 	}
 
-	if err := h.usecase.Delete(id); err != nil {
+	if err := h.usecase.Delete(c.MustGet("user_id").(int64), id); err != nil {
 		c.Error(err)
 		return
 	}
@@ -71,7 +71,7 @@ func (h *TwoFAHandler) VerifySetup(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        body body user.TwoFAVerifyRequest true "Verify payload"
-// @Success      200 {object} response.SuccessSingleUserResponse
+// @Success      200 {object} response.SuccessLoginResponse
 // @Failure      401 {object} response.ErrorSwaggerResponse
 // @Router       /2fa/verify [post]
 func (h *TwoFAHandler) VerifyLogin(c *gin.Context) {
@@ -81,15 +81,13 @@ func (h *TwoFAHandler) VerifyLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := h.usecase.VerifyLogin(req.TempToken, req.Code)
+	loginResp, err := h.usecase.VerifyLogin(req.TempToken, req.Code, device(c))
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	response.Success(c, http.StatusOK, "login success", user.LoginResponse{
-		Token: token,
-	})
+	response.Success(c, http.StatusOK, "login success", loginResp)
 }
 
 // Disable2FA godoc
@@ -136,7 +134,7 @@ func (h *TwoFAHandler) GenerateBackupCodes(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        body body user.TwoFAVerifyRequest true "Backup verify payload"
-// @Success      200 {object} response.SuccessSingleUserResponse
+// @Success      200 {object} response.SuccessLoginResponse
 // @Failure      401 {object} response.ErrorSwaggerResponse
 // @Router       /2fa/backup/verify [post]
 func (h *TwoFAHandler) VerifyBackupCode(c *gin.Context) {
@@ -146,13 +144,11 @@ func (h *TwoFAHandler) VerifyBackupCode(c *gin.Context) {
 		return
 	}
 
-	token, err := h.usecase.VerifyBackupCode(req.TempToken, req.Code)
+	loginResp, err := h.usecase.VerifyBackupCode(req.TempToken, req.Code, device(c))
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	response.Success(c, http.StatusOK, "login success", user.LoginResponse{
-		Token: token,
-	})
+	response.Success(c, http.StatusOK, "login success", loginResp)
 }
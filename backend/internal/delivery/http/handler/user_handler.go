@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/afandimsr/cashbook-backend/internal/config"
@@ -124,7 +126,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        body body user.LoginRequest true "Login payload"
-// @Success      200 {object} response.SuccessSingleUserResponse
+// @Success      200 {object} response.SuccessLoginResponse
 // @Failure      400 {object} response.ErrorSwaggerResponse
 // @Failure      401 {object} response.ErrorSwaggerResponse
 // @Failure      500 {object} response.ErrorSwaggerResponse
@@ -137,7 +139,12 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	loginResp, err := h.usecase.Login(req.Email, req.Password)
+	loginResp, err := h.usecase.Login(req.Email, req.Password, device(c))
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) && appErr.ErrorCode == apperror.AuthEmailUnverified {
+		c.Error(err)
+		return
+	}
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, "400", "Username/Password Tidak Valid", err.Error())
 		return
@@ -146,9 +153,139 @@ func (h *UserHandler) Login(c *gin.Context) {
 	response.Success(c, http.StatusOK, "login success", loginResp)
 }
 
+// Register godoc
+// @Summary      Sign up
+// @Description  Create a USER account and email a link to verify its address; the account cannot log in before that. Registering an address that already has an account emails its owner instead and responds the same.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.RegisterRequest true "Registration payload"
+// @Success      202 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/register [post]
+func (h *UserHandler) Register(c *gin.Context) {
+	var req user.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.Register(req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "check your email to verify your address", nil)
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Verify the address a signup link was emailed to. Each token works once, within 24 hours.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.VerifyEmailRequest true "Token from the emailed link"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/verify-email [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req user.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.VerifyEmail(req.Token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "email verified", nil)
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Email a new verification link to an unverified account, invalidating earlier links. Responds the same whether or not the address is registered.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.EmailRequest true "Address to verify"
+// @Success      202 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/verify-email/resend [post]
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var req user.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.ResendVerification(req.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "check your email to verify your address", nil)
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Email a link to choose a new password, valid once for 30 minutes, invalidating earlier links. Responds the same whether or not the address is registered.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.EmailRequest true "Address of the account"
+// @Success      202 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/password/forgot [post]
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req user.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.ForgotPassword(req.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "if the address has an account, a reset link is on its way", nil)
+}
+
+// ResetForgottenPassword godoc
+// @Summary      Reset a forgotten password
+// @Description  Set a new password with the token from a reset link. Every session of the account is logged out.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.PasswordResetTokenRequest true "Token from the emailed link and the new password"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/password/reset [post]
+func (h *UserHandler) ResetForgottenPassword(c *gin.Context) {
+	var req user.PasswordResetTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.ResetPasswordWithToken(req.Token, req.Password); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "password reset successfully", nil)
+}
+
 // UpdateUser godoc
 // @Summary      Update user information
-// @Description  Modify existing user account details and preferences.
+// @Description  Modify existing user account details and preferences. Roles are changed through PUT /users/{id}/roles.
 // @Tags         Users
 // @Accept       json
 // @Produce      json
@@ -180,6 +317,139 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	response.Success(c, http.StatusOK, "user updated", nil)
 }
 
+// UpdateUserRoles godoc
+// @Summary      Change user roles
+// @Description  Replace the roles of a user account. Admins cannot remove their own ADMIN role.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Param        body body user.RolesRequest true "Roles payload"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /users/{id}/roles [put]
+func (h *UserHandler) UpdateUserRoles(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("invalid user id", err))
+		return
+	}
+
+	var req user.RolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.SetRoles(c.MustGet("user_id").(int64), id, req.Roles); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "user roles updated", nil)
+}
+
+// GetMe godoc
+// @Summary      Get own account
+// @Description  Retrieve the account of the authenticated user.
+// @Tags         Me
+// @Produce      json
+// @Success      200 {object} response.SuccessSingleUserResponse
+// @Failure      404 {object} response.ErrorSwaggerResponse
+// @Router       /me [get]
+func (h *UserHandler) GetMe(c *gin.Context) {
+	u, err := h.usecase.GetProfile(c.MustGet("user_id").(int64))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "success", u)
+}
+
+// UpdateMe godoc
+// @Summary      Update own account
+// @Description  Change the name, email, locale, timezone or base currency of the authenticated user; omitted fields are left alone. A new email is only used once it is confirmed through the link emailed to it, and shows as pending_email until then.
+// @Tags         Me
+// @Accept       json
+// @Produce      json
+// @Param        body body user.ProfileUpdateRequest true "Fields to change"
+// @Success      200 {object} response.SuccessSingleUserResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      409 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /me [patch]
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	var req user.ProfileUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	u, err := h.usecase.UpdateProfile(c.MustGet("user_id").(int64), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "profile updated", u)
+}
+
+// ChangeMyPassword godoc
+// @Summary      Change own password
+// @Description  Set a new password for the authenticated user after checking the current one. Every other session is logged out.
+// @Tags         Me
+// @Accept       json
+// @Produce      json
+// @Param        body body user.PasswordChangeRequest true "Current and new password"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /me/password [post]
+func (h *UserHandler) ChangeMyPassword(c *gin.Context) {
+	var req user.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.ChangePassword(c.MustGet("user_id").(int64), c.GetInt64("session_id"), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "password changed", nil)
+}
+
+// ConfirmEmailChange godoc
+// @Summary      Confirm email change
+// @Description  Make the new address a change link was emailed to the account's email. Each token works once, within 24 hours.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.VerifyEmailRequest true "Token from the emailed link"
+// @Success      200 {object} response.SuccessResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      409 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/email/confirm [post]
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	var req user.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	if err := h.usecase.ConfirmEmailChange(req.Token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "email changed", nil)
+}
+
 // DeleteUser godoc
 // @Summary      Deactivate user account
 // @Description  Permanently remove a user identity and access from the system.
@@ -230,7 +500,7 @@ func (h *UserHandler) GoogleLogin(c *gin.Context) {
 
 // GoogleCallback godoc
 // @Summary      Google OAuth2 callback
-// @Description  Handles the redirection from Google after user authorization, exchanges code for token, and authenticates user.
+// @Description  Handles the redirection from Google after user authorization and redirects to the frontend with a single-use login code, valid for one minute, to exchange through POST /auth/google/exchange.
 // @Tags         Auth
 // @Produce      json
 // @Param        code   query     string  true  "OAuth2 Code"
@@ -250,7 +520,7 @@ func (h *UserHandler) GoogleCallback(c *gin.Context) {
 	ip := c.ClientIP()
 	userAgent := c.Request.UserAgent()
 
-	token, err := h.oauthUsecase.HandleGoogleCallback(code, state, ip, userAgent)
+	loginCode, err := h.oauthUsecase.HandleGoogleCallback(code, state, ip, userAgent)
 	if err != nil {
 		c.Error(err)
 		// Redirect to login with error param using dynamic ClientAuthURL
@@ -259,11 +529,40 @@ func (h *UserHandler) GoogleCallback(c *gin.Context) {
 		return
 	}
 
-	// Redirect to frontend with token as query param using dynamic ClientAuthURL
-	frontendURL := h.cfg.FrontendURL + "/oauth/callback?token=" + token
+	// the tokens never appear in a URL, where they would end up in browser
+	// history and logs; the frontend exchanges the code for them
+	frontendURL := h.cfg.FrontendURL + "/oauth/callback?code=" + url.QueryEscape(loginCode)
 	c.Redirect(http.StatusTemporaryRedirect, frontendURL)
 }
 
+// ExchangeGoogleCode godoc
+// @Summary      Exchange a Google login code
+// @Description  Start the session of a Google login. The code comes from the redirect of /auth/google/callback and works once, within one minute.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body user.OAuthExchangeRequest true "Login code"
+// @Success      200 {object} response.SuccessLoginResponse
+// @Failure      400 {object} response.ErrorSwaggerResponse
+// @Failure      401 {object} response.ErrorSwaggerResponse
+// @Failure      500 {object} response.ErrorSwaggerResponse
+// @Router       /auth/google/exchange [post]
+func (h *UserHandler) ExchangeGoogleCode(c *gin.Context) {
+	var req user.OAuthExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload", err.Error())
+		return
+	}
+
+	loginResp, err := h.oauthUsecase.ExchangeLoginCode(req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "login success", loginResp)
+}
+
 // ResetPassword godoc
 // @Summary      Enforce password reset
 // @Description  Administrative utility to securely reset a user's password following ISO security standards.
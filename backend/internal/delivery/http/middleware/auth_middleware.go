@@ -32,6 +32,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
 		c.Set("email", claims.Email)
 		// set roles (array) and a primary role for backward compatibility
 		if len(claims.Roles) > 0 {
@@ -1,11 +1,18 @@
 package response
 
 import (
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
 	"github.com/afandimsr/cashbook-backend/internal/domain/budget"
+	"github.com/afandimsr/cashbook-backend/internal/domain/calendar"
 	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
 	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
 	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
 	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+	"github.com/afandimsr/cashbook-backend/internal/usecase/forecast"
+	"github.com/afandimsr/cashbook-backend/internal/usecase/report"
 )
 
 // Generic success response for swagger
@@ -21,6 +28,24 @@ type SuccessSingleUserResponse struct {
 	Data    user.User `json:"data"`
 }
 
+type SuccessLoginResponse struct {
+	Success bool               `json:"success" example:"true"`
+	Message string             `json:"message" example:"login success"`
+	Data    user.LoginResponse `json:"data"`
+}
+
+type SuccessSessionsResponse struct {
+	Success bool           `json:"success" example:"true"`
+	Message string         `json:"message" example:"success"`
+	Data    []user.Session `json:"data"`
+}
+
+type SuccessAccountResponse struct {
+	Success bool              `json:"success" example:"true"`
+	Message string            `json:"message" example:"success"`
+	Data    []account.Account `json:"data"`
+}
+
 type SuccessCategoryResponse struct {
 	Success bool                `json:"success" example:"true"`
 	Message string              `json:"message" example:"success"`
@@ -39,12 +64,89 @@ type SuccessBudgetResponse struct {
 	Data    []budget.Budget `json:"data"`
 }
 
+type SuccessBudgetItemResponse struct {
+	Success bool          `json:"success" example:"true"`
+	Message string        `json:"message" example:"budget updated"`
+	Data    budget.Budget `json:"data"`
+}
+
+type SuccessBudgetProgressResponse struct {
+	Success bool              `json:"success" example:"true"`
+	Message string            `json:"message" example:"success"`
+	Data    []budget.Progress `json:"data"`
+}
+
+type SuccessBudgetCopyResponse struct {
+	Success bool              `json:"success" example:"true"`
+	Message string            `json:"message" example:"budgets copied"`
+	Data    budget.CopyResult `json:"data"`
+}
+
 type SuccessRecurringResponse struct {
 	Success bool                                         `json:"success" example:"true"`
 	Message string                                       `json:"message" example:"success"`
 	Data    []recurring_transaction.RecurringTransaction `json:"data"`
 }
 
+type SuccessRecurringItemResponse struct {
+	Success bool                                       `json:"success" example:"true"`
+	Message string                                     `json:"message" example:"recurring transaction updated"`
+	Data    recurring_transaction.RecurringTransaction `json:"data"`
+}
+
+type SuccessOccurrencesResponse struct {
+	Success bool                               `json:"success" example:"true"`
+	Message string                             `json:"message" example:"success"`
+	Data    []recurring_transaction.Occurrence `json:"data"`
+}
+
+type SuccessExceptionResponse struct {
+	Success bool                            `json:"success" example:"true"`
+	Message string                          `json:"message" example:"occurrence updated"`
+	Data    recurring_transaction.Exception `json:"data"`
+}
+
+type SuccessRecurringEventsResponse struct {
+	Success bool                          `json:"success" example:"true"`
+	Message string                        `json:"message" example:"success"`
+	Data    []recurring_transaction.Event `json:"data"`
+}
+
+type SuccessRecurringRunResponse struct {
+	Success bool                      `json:"success" example:"true"`
+	Message string                    `json:"message" example:"processed"`
+	Data    recurring_transaction.Run `json:"data"`
+}
+
+type SuccessRecurringRunsResponse struct {
+	Success bool                        `json:"success" example:"true"`
+	Message string                      `json:"message" example:"success"`
+	Data    []recurring_transaction.Run `json:"data"`
+}
+
+type SuccessForecastResponse struct {
+	Success bool              `json:"success" example:"true"`
+	Message string            `json:"message" example:"success"`
+	Data    forecast.Forecast `json:"data"`
+}
+
+type SuccessCalendarFeedResponse struct {
+	Success bool          `json:"success" example:"true"`
+	Message string        `json:"message" example:"success"`
+	Data    calendar.Feed `json:"data"`
+}
+
+type CalendarToken struct {
+	Token string `json:"token" example:"3f9a0c..."`
+	URL   string `json:"url" example:"https://cashbook.example.com/api/v1/calendar/bills.ics?token=3f9a0c..."`
+}
+
+type SuccessCalendarTokenResponse struct {
+	Success bool          `json:"success" example:"true"`
+	Message string        `json:"message" example:"calendar feed generated"`
+	Data    CalendarToken `json:"data"`
+}
+
 type SuccessSummaryResponse struct {
 	Success bool                         `json:"success" example:"true"`
 	Message string                       `json:"message" example:"success"`
@@ -56,3 +158,39 @@ type ErrorSwaggerResponse struct {
 	Message string `json:"message" example:"error"`
 	Errors  string `json:"errors"`
 }
+
+type SuccessImportProfileResponse struct {
+	Success bool               `json:"success" example:"true"`
+	Message string             `json:"message" example:"success"`
+	Data    []importer.Profile `json:"data"`
+}
+
+type SuccessImportPreviewResponse struct {
+	Success bool             `json:"success" example:"true"`
+	Message string           `json:"message" example:"success"`
+	Data    importer.Preview `json:"data"`
+}
+
+type SuccessStatementPreviewResponse struct {
+	Success bool                        `json:"success" example:"true"`
+	Message string                      `json:"message" example:"success"`
+	Data    []importer.StatementPreview `json:"data"`
+}
+
+type SuccessTagResponse struct {
+	Success bool      `json:"success" example:"true"`
+	Message string    `json:"message" example:"success"`
+	Data    []tag.Tag `json:"data"`
+}
+
+type SuccessAttachmentResponse struct {
+	Success bool                    `json:"success" example:"true"`
+	Message string                  `json:"message" example:"success"`
+	Data    []attachment.Attachment `json:"data"`
+}
+
+type SuccessTrendResponse struct {
+	Success bool               `json:"success" example:"true"`
+	Message string             `json:"message" example:"success"`
+	Data    report.TrendReport `json:"data"`
+}
@@ -9,20 +9,37 @@ import (
 func RegisterRoutes(
 	r *gin.Engine,
 	userHandler *handler.UserHandler,
+	sessionHandler *handler.SessionHandler,
+	accountHandler *handler.AccountHandler,
 	categoryHandler *handler.CategoryHandler,
 	transactionHandler *handler.TransactionHandler,
 	budgetHandler *handler.BudgetHandler,
 	reportHandler *handler.ReportHandler,
 	recurringHandler *handler.RecurringHandler,
+	forecastHandler *handler.ForecastHandler,
+	calendarHandler *handler.CalendarHandler,
 	twofaHandler *handler.TwoFAHandler,
 	mfaSettingsHandler *handler.MFASettingsHandler,
+	fxHandler *handler.FxHandler,
+	importHandler *handler.ImportHandler,
+	tagHandler *handler.TagHandler,
+	attachmentHandler *handler.AttachmentHandler,
 ) {
 	api := r.Group("/api/v1")
 
 	// auth routes (public)
 	api.POST("/login", userHandler.Login)
+	api.POST("/auth/register", userHandler.Register)
+	api.POST("/auth/verify-email", userHandler.VerifyEmail)
+	api.POST("/auth/verify-email/resend", userHandler.ResendVerification)
+	api.POST("/auth/password/forgot", userHandler.ForgotPassword)
+	api.POST("/auth/password/reset", userHandler.ResetForgottenPassword)
+	api.POST("/auth/email/confirm", userHandler.ConfirmEmailChange)
 	api.GET("/auth/google/login", userHandler.GoogleLogin)
 	api.GET("/auth/google/callback", userHandler.GoogleCallback)
+	api.POST("/auth/google/exchange", userHandler.ExchangeGoogleCode)
+	api.POST("/auth/refresh", sessionHandler.Refresh)
+	api.POST("/auth/logout", sessionHandler.Logout)
 
 	// 2FA routes (public — used during login)
 	api.POST("/2fa/verify", twofaHandler.VerifyLogin)
@@ -41,6 +58,18 @@ func RegisterRoutes(
 		twofa.POST("/backup-codes", twofaHandler.GenerateBackupCodes)
 	}
 
+	// the caller's own account and sessions
+	me := api.Group("/me")
+	me.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
+	{
+		me.GET("", userHandler.GetMe)
+		me.PATCH("", userHandler.UpdateMe)
+		me.POST("/password", userHandler.ChangeMyPassword)
+		me.GET("/sessions", sessionHandler.GetSessions)
+		me.DELETE("/sessions", sessionHandler.RevokeOtherSessions)
+		me.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+	}
+
 	// user routes (protected)
 	users := api.Group("/users")
 	users.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
@@ -51,14 +80,18 @@ func RegisterRoutes(
 		users.PUT("/:id", userHandler.UpdateUser)
 		users.DELETE("/:id", userHandler.DeleteUser)
 		users.POST("/:id/reset-password", userHandler.ResetPassword)
+		users.PUT("/:id/roles", userHandler.UpdateUserRoles)
 	}
 
-	// admin MFA settings (protected + admin only)
+	// admin MFA settings, exchange rates and recurring runs (protected + admin only)
 	admin := api.Group("/admin")
 	admin.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
 	{
 		admin.GET("/mfa-settings", mfaSettingsHandler.GetSettings)
 		admin.PUT("/mfa-settings", mfaSettingsHandler.UpdateSettings)
+		admin.POST("/fx-rates", fxHandler.ImportRates)
+		admin.POST("/recurring/process", recurringHandler.ProcessAllDue)
+		admin.GET("/recurring/runs", recurringHandler.GetRuns)
 	}
 
 	// user MFA settings (protected + admin only) - alternative route
@@ -69,6 +102,19 @@ func RegisterRoutes(
 		userRoutes.PUT("/mfa-settings", mfaSettingsHandler.UpdateSettings)
 	}
 
+	// account routes
+	accounts := api.Group("/accounts")
+	accounts.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
+	{
+		accounts.GET("", accountHandler.GetAccounts)
+		accounts.POST("", accountHandler.CreateAccount)
+		accounts.POST("/transfers", accountHandler.CreateTransfer)
+		accounts.DELETE("/transfers/:id", accountHandler.DeleteTransfer)
+		accounts.GET("/:id", accountHandler.GetAccount)
+		accounts.PUT("/:id", accountHandler.UpdateAccount)
+		accounts.DELETE("/:id", accountHandler.DeleteAccount)
+	}
+
 	// category routes
 	categories := api.Group("/categories")
 	categories.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
@@ -86,8 +132,29 @@ func RegisterRoutes(
 		transactions.GET("", transactionHandler.GetTransactions)
 		transactions.POST("", transactionHandler.CreateTransaction)
 		transactions.GET("/summary", transactionHandler.GetSummary)
+		transactions.GET("/export", transactionHandler.ExportTransactions)
 		transactions.PUT("/:id", transactionHandler.UpdateTransaction)
 		transactions.DELETE("/:id", transactionHandler.DeleteTransaction)
+		transactions.GET("/:id/attachments", attachmentHandler.GetAttachments)
+		transactions.POST("/:id/attachments", attachmentHandler.UploadAttachment)
+		transactions.GET("/:id/attachments/:attachmentId", attachmentHandler.DownloadAttachment)
+		transactions.DELETE("/:id/attachments/:attachmentId", attachmentHandler.DeleteAttachment)
+	}
+
+	// statement import routes
+	imports := api.Group("/imports")
+	imports.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
+	{
+		imports.GET("/profiles", importHandler.GetProfiles)
+		imports.POST("/profiles", importHandler.CreateProfile)
+		imports.PUT("/profiles/:id", importHandler.UpdateProfile)
+		imports.DELETE("/profiles/:id", importHandler.DeleteProfile)
+		imports.POST("/csv/preview", importHandler.PreviewCSV)
+		imports.POST("/csv/commit", importHandler.CommitCSV)
+		imports.POST("/ofx/preview", importHandler.PreviewOFX)
+		imports.POST("/ofx/commit", importHandler.CommitOFX)
+		imports.POST("/qif/preview", importHandler.PreviewQIF)
+		imports.POST("/qif/commit", importHandler.CommitQIF)
 	}
 
 	// budget routes
@@ -95,7 +162,11 @@ func RegisterRoutes(
 	budgets.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
 	{
 		budgets.GET("", budgetHandler.GetBudgets)
+		budgets.GET("/progress", budgetHandler.GetBudgetProgress)
+		budgets.POST("/copy", budgetHandler.CopyBudgets)
 		budgets.POST("", budgetHandler.SetBudget)
+		budgets.PUT("/:id", budgetHandler.UpdateBudget)
+		budgets.DELETE("/:id", budgetHandler.DeleteBudget)
 	}
 
 	// report routes
@@ -103,6 +174,18 @@ func RegisterRoutes(
 	reports.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
 	{
 		reports.GET("/spending", reportHandler.GetCategorySpending)
+		reports.GET("/spending/tags", reportHandler.GetTagSpending)
+		reports.GET("/trend", reportHandler.GetTrend)
+	}
+
+	// tag routes
+	tags := api.Group("/tags")
+	tags.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
+	{
+		tags.GET("", tagHandler.GetTags)
+		tags.POST("/merge", tagHandler.MergeTags)
+		tags.PUT("/:id", tagHandler.RenameTag)
+		tags.DELETE("/:id", tagHandler.DeleteTag)
 	}
 
 	// recurring routes
@@ -111,9 +194,34 @@ func RegisterRoutes(
 	{
 		recurring.GET("", recurringHandler.GetRecurring)
 		recurring.POST("", recurringHandler.CreateRecurring)
+		recurring.PUT("/:id", recurringHandler.UpdateRecurring)
 		recurring.DELETE("/:id", recurringHandler.DeleteRecurring)
+		recurring.POST("/:id/pause", recurringHandler.PauseRecurring)
+		recurring.POST("/:id/resume", recurringHandler.ResumeRecurring)
+		recurring.GET("/:id/occurrences", recurringHandler.GetOccurrences)
+		recurring.PUT("/:id/occurrences/:date", recurringHandler.SetException)
+		recurring.DELETE("/:id/occurrences/:date", recurringHandler.DeleteException)
+		recurring.GET("/:id/events", recurringHandler.GetEvents)
 		recurring.POST("/process", recurringHandler.ProcessDue)
 	}
+
+	// forecast routes
+	forecast := api.Group("/forecast")
+	forecast.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
+	{
+		forecast.GET("", forecastHandler.GetForecast)
+	}
+
+	// calendar routes; calendar apps fetch the feed with its token instead
+	// of logging in
+	api.GET("/calendar/bills.ics", calendarHandler.GetBills)
+	calendarFeed := api.Group("/calendar/feed")
+	calendarFeed.Use(middleware.AuthMiddleware(), middleware.RoleGuard("ADMIN", "USER"))
+	{
+		calendarFeed.GET("", calendarHandler.GetFeed)
+		calendarFeed.POST("", calendarHandler.RegenerateFeed)
+		calendarFeed.DELETE("", calendarHandler.RevokeFeed)
+	}
 }
 
 func healthHandler(c *gin.Context) {
@@ -0,0 +1,895 @@
+package http_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/config"
+	delivery "github.com/afandimsr/cashbook-backend/internal/delivery/http"
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/handler"
+	"github.com/afandimsr/cashbook-backend/internal/delivery/http/middleware"
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
+	"github.com/afandimsr/cashbook-backend/internal/domain/budget"
+	"github.com/afandimsr/cashbook-backend/internal/domain/calendar"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/jwt"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	accountUC "github.com/afandimsr/cashbook-backend/internal/usecase/account"
+	attachmentUC "github.com/afandimsr/cashbook-backend/internal/usecase/attachment"
+	budgetUC "github.com/afandimsr/cashbook-backend/internal/usecase/budget"
+	calendarUC "github.com/afandimsr/cashbook-backend/internal/usecase/calendar"
+	categoryUC "github.com/afandimsr/cashbook-backend/internal/usecase/category"
+	forecastUC "github.com/afandimsr/cashbook-backend/internal/usecase/forecast"
+	fxUC "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
+	importerUC "github.com/afandimsr/cashbook-backend/internal/usecase/importer"
+	recurringUC "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
+	reportUC "github.com/afandimsr/cashbook-backend/internal/usecase/report"
+	tagUC "github.com/afandimsr/cashbook-backend/internal/usecase/tag"
+	transactionUC "github.com/afandimsr/cashbook-backend/internal/usecase/transaction"
+	userUC "github.com/afandimsr/cashbook-backend/internal/usecase/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The owner holds one of every per-user resource, all with ID 1 and all
+// carrying secret in a visible field. The intruder has an account and a
+// category of their own (ID 3) so that requests fail on the owner's IDs
+// rather than on missing data.
+const (
+	ownerID    = int64(1)
+	intruderID = int64(2)
+	secret     = "owner-secret"
+)
+
+// store is an in-memory stand-in for the database, holding the rows the
+// fake repositories below read and write.
+type store struct {
+	mu           sync.Mutex
+	nextID       int64
+	users        map[int64]user.User
+	sessions     map[int64]user.Session
+	accounts     map[int64]account.Account
+	transfers    map[int64]account.Transfer
+	categories   map[int64]category.Category
+	transactions map[int64]transaction.Transaction
+	budgets      map[int64]budget.Budget
+	recurring    map[int64]recurring_transaction.RecurringTransaction
+	tags         map[int64]tag.Tag
+	profiles     map[int64]importer.Profile
+	attachments  map[int64]attachment.Attachment
+}
+
+func newStore() *store {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	return &store{
+		nextID: 100,
+		users: map[int64]user.User{
+			ownerID:    {ID: ownerID, Name: secret, BaseCurrency: "IDR"},
+			intruderID: {ID: intruderID, Name: "intruder", BaseCurrency: "IDR"},
+		},
+		sessions: map[int64]user.Session{
+			1: {ID: 1, UserID: ownerID, Device: user.Device{UserAgent: secret, IP: "10.0.0.1"}, ExpiresAt: day.AddDate(1, 0, 0)},
+		},
+		accounts: map[int64]account.Account{
+			1: {ID: 1, UserID: ownerID, Name: secret, Type: account.Bank, Currency: "IDR"},
+			2: {ID: 2, UserID: ownerID, Name: secret + " savings", Type: account.Bank, Currency: "IDR"},
+			3: {ID: 3, UserID: intruderID, Name: "intruder cash", Type: account.Cash, Currency: "IDR"},
+		},
+		transfers: map[int64]account.Transfer{
+			1: {ID: 1, UserID: ownerID, FromAccountID: 1, ToAccountID: 2, Amount: money.FromMajor(5), Note: secret, Date: day},
+		},
+		categories: map[int64]category.Category{
+			1: {ID: 1, UserID: ownerID, Name: secret, Type: transaction.TypeExpense},
+			3: {ID: 3, UserID: intruderID, Name: "intruder food", Type: transaction.TypeExpense},
+		},
+		transactions: map[int64]transaction.Transaction{
+			1: {ID: 1, UserID: ownerID, AccountID: 1, CategoryID: 1, Amount: money.FromMajor(10), Currency: "IDR", Note: secret, Date: day, Type: transaction.TypeExpense},
+			3: {ID: 3, UserID: intruderID, AccountID: 3, CategoryID: 3, Amount: money.FromMajor(1), Currency: "IDR", Note: "intruder lunch", Date: day, Type: transaction.TypeExpense},
+		},
+		budgets: map[int64]budget.Budget{
+			1: {ID: 1, UserID: ownerID, CategoryID: 1, Amount: money.FromMajor(100), Currency: "IDR", Period: budget.PeriodMonth, StartDate: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Month: 10, Year: 2026},
+		},
+		recurring: map[int64]recurring_transaction.RecurringTransaction{
+			1: {ID: 1, UserID: ownerID, AccountID: 1, CategoryID: 1, Amount: money.FromMajor(10), Type: transaction.TypeExpense, Note: secret, Frequency: recurring_transaction.Monthly, StartDate: day},
+		},
+		tags: map[int64]tag.Tag{
+			1: {ID: 1, UserID: ownerID, Name: secret},
+		},
+		profiles: map[int64]importer.Profile{
+			1: {ID: 1, UserID: ownerID, Name: secret, Mapping: importer.Mapping{DateColumn: "date", AmountColumn: "amount"}},
+		},
+		attachments: map[int64]attachment.Attachment{
+			1: {ID: 1, UserID: ownerID, TransactionID: 1, FileName: secret + ".pdf", ContentType: "application/pdf", Size: 4, StorageKey: "1/1/receipt.pdf"},
+		},
+	}
+}
+
+func ownedBy[T any](rows map[int64]T, userID func(T) int64) map[int64]T {
+	owned := map[int64]T{}
+	for id, row := range rows {
+		if userID(row) == ownerID {
+			owned[id] = row
+		}
+	}
+	return owned
+}
+
+// ownerState serialises every row of the owner, to check that a request
+// left them untouched.
+func (s *store) ownerState(t *testing.T) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal([]interface{}{
+		ownedBy(s.users, func(u user.User) int64 { return u.ID }),
+		ownedBy(s.sessions, func(se user.Session) int64 { return se.UserID }),
+		ownedBy(s.accounts, func(a account.Account) int64 { return a.UserID }),
+		ownedBy(s.transfers, func(tr account.Transfer) int64 { return tr.UserID }),
+		ownedBy(s.categories, func(c category.Category) int64 { return c.UserID }),
+		ownedBy(s.transactions, func(tx transaction.Transaction) int64 { return tx.UserID }),
+		ownedBy(s.budgets, func(b budget.Budget) int64 { return b.UserID }),
+		ownedBy(s.recurring, func(rt recurring_transaction.RecurringTransaction) int64 { return rt.UserID }),
+		ownedBy(s.tags, func(g tag.Tag) int64 { return g.UserID }),
+		ownedBy(s.profiles, func(p importer.Profile) int64 { return p.UserID }),
+		ownedBy(s.attachments, func(a attachment.Attachment) int64 { return a.UserID }),
+	})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func (s *store) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// The fake repositories embed their interface; methods the tests should
+// never reach are left to panic through the nil embedded value.
+
+type userRepo struct {
+	user.UserRepository
+	s *store
+}
+
+func (r userRepo) FindByID(id int64) (user.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return user.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (r userRepo) Update(u user.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[u.ID]; !ok {
+		return sql.ErrNoRows
+	}
+	r.s.users[u.ID] = u
+	return nil
+}
+
+type sessionRepo struct {
+	user.SessionRepository
+	s *store
+}
+
+// FindByTokenHash knows no refresh tokens, which are random.
+func (r sessionRepo) FindByTokenHash(tokenHash string) (user.Session, bool, error) {
+	return user.Session{}, false, user.ErrSessionNotFound
+}
+
+func (r sessionRepo) FindActiveByUserID(userID int64, now time.Time) ([]user.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sessions := []user.Session{}
+	for _, se := range r.s.sessions {
+		if se.UserID == userID && se.Active(now) {
+			sessions = append(sessions, se)
+		}
+	}
+	return sessions, nil
+}
+
+func (r sessionRepo) Revoke(userID, id int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	se, ok := r.s.sessions[id]
+	if !ok || se.UserID != userID || se.RevokedAt != nil {
+		return user.ErrSessionNotFound
+	}
+	now := time.Now()
+	se.RevokedAt = &now
+	r.s.sessions[id] = se
+	return nil
+}
+
+func (r sessionRepo) RevokeAllByUserID(userID, exceptID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	for id, se := range r.s.sessions {
+		if se.UserID == userID && id != exceptID && se.RevokedAt == nil {
+			se.RevokedAt = &now
+			r.s.sessions[id] = se
+		}
+	}
+	return nil
+}
+
+type fxRepo struct {
+	fx.Repository
+}
+
+func (fxRepo) FindLatest(base, quote string, on time.Time) (fx.Rate, error) {
+	return fx.Rate{}, fx.ErrRateNotFound
+}
+
+type accountRepo struct {
+	account.Repository
+	s *store
+}
+
+func (r accountRepo) FindAllByUserID(userID int64) ([]account.Account, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var accounts []account.Account
+	for _, a := range r.s.accounts {
+		if a.UserID == userID {
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+func (r accountRepo) FindByID(id int64) (account.Account, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a, ok := r.s.accounts[id]
+	if !ok {
+		return account.Account{}, account.ErrNotFound
+	}
+	return a, nil
+}
+
+func (r accountRepo) Save(a *account.Account) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a.ID = r.s.id()
+	r.s.accounts[a.ID] = *a
+	return nil
+}
+
+func (r accountRepo) FindTransferByID(id int64) (account.Transfer, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tr, ok := r.s.transfers[id]
+	if !ok {
+		return account.Transfer{}, account.ErrTransferNotFound
+	}
+	return tr, nil
+}
+
+type categoryRepo struct {
+	category.Repository
+	s *store
+}
+
+func (r categoryRepo) FindAllByUserID(userID int64) ([]category.Category, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var categories []category.Category
+	for _, c := range r.s.categories {
+		if c.UserID == userID {
+			categories = append(categories, c)
+		}
+	}
+	return categories, nil
+}
+
+func (r categoryRepo) FindByIDForUser(userID, id int64) (category.Category, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.categories[id]
+	if !ok || c.UserID != userID {
+		return category.Category{}, category.ErrNotFound
+	}
+	return c, nil
+}
+
+func (r categoryRepo) Save(c *category.Category) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c.ID = r.s.id()
+	r.s.categories[c.ID] = *c
+	return nil
+}
+
+type transactionRepo struct {
+	transaction.Repository
+	s *store
+}
+
+func (r transactionRepo) userTransactions(userID int64) []transaction.Transaction {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var txs []transaction.Transaction
+	for _, t := range r.s.transactions {
+		if t.UserID == userID {
+			txs = append(txs, t)
+		}
+	}
+	return txs
+}
+
+func (r transactionRepo) FindAllByUserID(userID int64, limit, offset int, filter transaction.Filter) ([]transaction.Transaction, error) {
+	return r.userTransactions(userID), nil
+}
+
+func (r transactionRepo) Count(userID int64, filter transaction.Filter) (int64, error) {
+	return int64(len(r.userTransactions(userID))), nil
+}
+
+func (r transactionRepo) reportRows(userID int64) []transaction.ReportTransaction {
+	var rows []transaction.ReportTransaction
+	for _, t := range r.userTransactions(userID) {
+		rows = append(rows, transaction.ReportTransaction{
+			ID: t.ID, UserID: t.UserID, AccountID: t.AccountID, CategoryID: t.CategoryID,
+			Amount: t.Amount, Currency: t.Currency, Note: t.Note, Date: t.Date, Type: t.Type,
+		})
+	}
+	return rows
+}
+
+func (r transactionRepo) SumByCurrency(userID int64, filter transaction.Filter) ([]transaction.CurrencySummary, error) {
+	totals := map[string]*transaction.CurrencySummary{}
+	var res []transaction.CurrencySummary
+	for _, t := range r.userTransactions(userID) {
+		if totals[t.Currency] == nil {
+			totals[t.Currency] = &transaction.CurrencySummary{Currency: t.Currency}
+		}
+		if t.Type == transaction.TypeIncome {
+			totals[t.Currency].TotalIncome += t.Amount
+		} else if t.Type == transaction.TypeExpense {
+			totals[t.Currency].TotalExpense += t.Amount
+		}
+	}
+	for _, cs := range totals {
+		res = append(res, *cs)
+	}
+	return res, nil
+}
+
+func (r transactionRepo) SumByCategory(userID int64, filter transaction.Filter) ([]transaction.CategoryTotal, error) {
+	var res []transaction.CategoryTotal
+	for _, t := range r.userTransactions(userID) {
+		res = append(res, transaction.CategoryTotal{CategoryID: t.CategoryID, Currency: t.Currency, Amount: t.Amount})
+	}
+	return res, nil
+}
+
+func (r transactionRepo) Trend(userID int64, query transaction.TrendQuery) ([]transaction.TrendRow, error) {
+	var rows []transaction.TrendRow
+	for _, t := range r.userTransactions(userID) {
+		rows = append(rows, transaction.TrendRow{Period: t.Date, GroupID: t.AccountID, GroupName: "account", Currency: t.Currency, Expense: t.Amount})
+	}
+	return rows, nil
+}
+
+func (r transactionRepo) StreamReport(userID int64, filter transaction.Filter, fn func(transaction.ReportTransaction) error) error {
+	for _, row := range r.reportRows(userID) {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r transactionRepo) FindByIDForUser(userID, id int64) (transaction.Transaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, ok := r.s.transactions[id]
+	if !ok || t.UserID != userID {
+		return transaction.Transaction{}, transaction.ErrNotFound
+	}
+	return t, nil
+}
+
+func (r transactionRepo) FindSplits(ids []int64) (map[int64][]transaction.Split, error) {
+	return map[int64][]transaction.Split{}, nil
+}
+
+func (r transactionRepo) FindTags(ids []int64) (map[int64][]string, error) {
+	return map[int64][]string{}, nil
+}
+
+type budgetRepo struct {
+	budget.Repository
+	s *store
+}
+
+func (r budgetRepo) FindAllByUserID(userID int64, on time.Time, periods ...string) ([]budget.Budget, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var budgets []budget.Budget
+	for _, b := range r.s.budgets {
+		if b.UserID == userID && b.StartDate.Equal(budget.PeriodStart(b.Period, on)) {
+			budgets = append(budgets, b)
+		}
+	}
+	return budgets, nil
+}
+
+func (r budgetRepo) FindByIDForUser(userID, id int64) (budget.Budget, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	b, ok := r.s.budgets[id]
+	if !ok || b.UserID != userID {
+		return budget.Budget{}, budget.ErrNotFound
+	}
+	return b, nil
+}
+
+type recurringRepo struct {
+	recurring_transaction.Repository
+	s *store
+}
+
+func (r recurringRepo) FindAllByUserID(userID int64) ([]recurring_transaction.RecurringTransaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var rts []recurring_transaction.RecurringTransaction
+	for _, rt := range r.s.recurring {
+		if rt.UserID == userID {
+			rts = append(rts, rt)
+		}
+	}
+	return rts, nil
+}
+
+func (r recurringRepo) FindByIDForUser(userID, id int64) (recurring_transaction.RecurringTransaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	rt, ok := r.s.recurring[id]
+	if !ok || rt.UserID != userID {
+		return recurring_transaction.RecurringTransaction{}, recurring_transaction.ErrNotFound
+	}
+	return rt, nil
+}
+
+// FindDue reports nothing due, so the shared processing job leaves every
+// user's data alone.
+func (r recurringRepo) FindDue(now time.Time) ([]recurring_transaction.RecurringTransaction, error) {
+	return nil, nil
+}
+
+func (r recurringRepo) FindDueByUserID(userID int64, now time.Time) ([]recurring_transaction.RecurringTransaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var rts []recurring_transaction.RecurringTransaction
+	for _, rt := range r.s.recurring {
+		if rt.UserID == userID && !rt.Paused && !rt.NextOccurrence.IsZero() && !rt.NextOccurrence.After(now) {
+			rts = append(rts, rt)
+		}
+	}
+	return rts, nil
+}
+
+type tagRepo struct {
+	tag.Repository
+	s *store
+}
+
+func (r tagRepo) FindAllByUserID(userID int64) ([]tag.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tags := []tag.Tag{}
+	for _, g := range r.s.tags {
+		if g.UserID == userID {
+			tags = append(tags, g)
+		}
+	}
+	return tags, nil
+}
+
+func (r tagRepo) FindByID(id int64) (tag.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	g, ok := r.s.tags[id]
+	if !ok {
+		return tag.Tag{}, tag.ErrTagNotFound
+	}
+	return g, nil
+}
+
+func (r tagRepo) GetSpending(userID int64, from, to time.Time) ([]tag.Spending, error) {
+	return nil, nil
+}
+
+type profileRepo struct {
+	importer.ProfileRepository
+	s *store
+}
+
+func (r profileRepo) FindAllByUserID(userID int64) ([]importer.Profile, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	profiles := []importer.Profile{}
+	for _, p := range r.s.profiles {
+		if p.UserID == userID {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles, nil
+}
+
+func (r profileRepo) FindByID(id int64) (importer.Profile, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p, ok := r.s.profiles[id]
+	if !ok {
+		return importer.Profile{}, importer.ErrProfileNotFound
+	}
+	return p, nil
+}
+
+func (r profileRepo) Save(p *importer.Profile) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p.ID = r.s.id()
+	r.s.profiles[p.ID] = *p
+	return nil
+}
+
+type attachmentRepo struct {
+	attachment.Repository
+	s *store
+}
+
+func (r attachmentRepo) FindByID(id int64) (attachment.Attachment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a, ok := r.s.attachments[id]
+	if !ok {
+		return attachment.Attachment{}, attachment.ErrAttachmentNotFound
+	}
+	return a, nil
+}
+
+func (r attachmentRepo) FindAllByTransactionID(transactionID int64) ([]attachment.Attachment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	attachments := []attachment.Attachment{}
+	for _, a := range r.s.attachments {
+		if a.TransactionID == transactionID {
+			attachments = append(attachments, a)
+		}
+	}
+	return attachments, nil
+}
+
+// calendarRepo has no feeds, so every feed token is unknown.
+type calendarRepo struct {
+	calendar.Repository
+}
+
+func (calendarRepo) FindByTokenHash(hash string) (calendar.Feed, error) {
+	return calendar.Feed{}, calendar.ErrNotFound
+}
+
+type nopStorage struct {
+	attachment.Storage
+}
+
+// newRouter wires the real handlers and usecases to the fake repositories
+// the same way bootstrap.Run does.
+func newRouter(s *store) *gin.Engine {
+	users := userRepo{s: s}
+	accounts := accountRepo{s: s}
+	categories := categoryRepo{s: s}
+	transactions := transactionRepo{s: s}
+	tags := tagRepo{s: s}
+
+	fxUsecase := fxUC.New(fxRepo{}, users)
+	attachmentUsecase := attachmentUC.New(attachmentRepo{s: s}, transactions, nopStorage{}, 0)
+	recurringUsecase := recurringUC.New(recurringRepo{s: s}, transactions, accounts, categories)
+	sessionUsecase := userUC.NewSessionUsecase(sessionRepo{s: s}, users)
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	delivery.RegisterRoutes(r,
+		handler.New(&config.Config{}, userUC.New(users, nil, sessionUsecase), nil),
+		handler.NewSessionHandler(sessionUsecase),
+		handler.NewAccountHandler(accountUC.New(accounts)),
+		handler.NewCategoryHandler(categoryUC.New(categories)),
+		handler.NewTransactionHandler(transactionUC.New(transactions, accounts, categories, fxUsecase, attachmentUsecase)),
+		handler.NewBudgetHandler(budgetUC.New(budgetRepo{s: s}, categories, transactions, fxUsecase)),
+		handler.NewReportHandler(reportUC.New(transactions, tags, fxUsecase)),
+		handler.NewRecurringHandler(recurringUsecase),
+		handler.NewForecastHandler(forecastUC.New(accounts, recurringUsecase)),
+		handler.NewCalendarHandler(calendarUC.New(calendarRepo{}, recurringUsecase, accounts, categories)),
+		handler.NewTwoFAHandler(nil),
+		handler.NewMFASettingsHandler(nil),
+		handler.NewFxHandler(fxUsecase),
+		handler.NewImportHandler(importerUC.New(profileRepo{s: s}, transactions, accounts, categories)),
+		handler.NewTagHandler(tagUC.New(tags)),
+		handler.NewAttachmentHandler(attachmentUsecase, 0),
+	)
+	return r
+}
+
+// upload is a multipart body with a file and extra form fields.
+type upload struct {
+	fileName string
+	content  string
+	fields   map[string]string
+}
+
+func (u upload) encode(t *testing.T) (io.Reader, string) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, value := range u.fields {
+		require.NoError(t, w.WriteField(name, value))
+	}
+	part, err := w.CreateFormFile("file", u.fileName)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(u.content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return &buf, w.FormDataContentType()
+}
+
+type crossTenantCase struct {
+	method string
+	route  string // the pattern registered in route.go
+	path   string // the request path, pointing at the owner's rows
+	body   interface{}
+	status int
+}
+
+const csvStatement = "date,amount,description\n2026-10-01,-5.00,coffee\n"
+const qifStatement = "!Type:Bank\nD10/01/2026\nT-5.00\nPcoffee\n^\n"
+const ofxStatement = "<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>IDR<BANKACCTFROM><ACCTID>9</BANKACCTFROM><BANKTRANLIST><STMTTRN><DTPOSTED>20261001<TRNAMT>-5.00<FITID>1<NAME>coffee</STMTTRN></BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>"
+
+var crossTenantCases = []crossTenantCase{
+	// accounts
+	{http.MethodGet, "/api/v1/accounts", "/api/v1/accounts", nil, http.StatusOK},
+	{http.MethodPost, "/api/v1/accounts", "/api/v1/accounts", map[string]interface{}{"name": "mine", "currency": "IDR"}, http.StatusCreated},
+	{http.MethodPost, "/api/v1/accounts/transfers", "/api/v1/accounts/transfers", map[string]interface{}{"from_account_id": 1, "to_account_id": 3, "amount": "5"}, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/accounts/transfers", "/api/v1/accounts/transfers", map[string]interface{}{"from_account_id": 3, "to_account_id": 1, "amount": "5"}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/accounts/transfers/:id", "/api/v1/accounts/transfers/1", nil, http.StatusNotFound},
+	{http.MethodGet, "/api/v1/accounts/:id", "/api/v1/accounts/1", nil, http.StatusNotFound},
+	{http.MethodPut, "/api/v1/accounts/:id", "/api/v1/accounts/1", map[string]interface{}{"name": "mine"}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/accounts/:id", "/api/v1/accounts/1", nil, http.StatusNotFound},
+
+	// categories
+	{http.MethodGet, "/api/v1/categories", "/api/v1/categories", nil, http.StatusOK},
+	{http.MethodPost, "/api/v1/categories", "/api/v1/categories", map[string]interface{}{"name": "mine", "type": "expense"}, http.StatusCreated},
+	{http.MethodPut, "/api/v1/categories/:id", "/api/v1/categories/1", map[string]interface{}{"name": "mine", "type": "expense"}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/categories/:id", "/api/v1/categories/1", nil, http.StatusNotFound},
+
+	// transactions
+	{http.MethodGet, "/api/v1/transactions", "/api/v1/transactions", nil, http.StatusOK},
+	{http.MethodGet, "/api/v1/transactions", "/api/v1/transactions?account_id=1&category_id=1", nil, http.StatusOK},
+	{http.MethodPost, "/api/v1/transactions", "/api/v1/transactions", map[string]interface{}{"account_id": 3, "category_id": 1, "amount": "5", "type": "expense"}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/transactions", "/api/v1/transactions", map[string]interface{}{"account_id": 1, "category_id": 3, "amount": "5", "type": "expense"}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/transactions", "/api/v1/transactions", map[string]interface{}{"account_id": 3, "amount": "5", "type": "expense", "splits": []map[string]interface{}{
+		{"category_id": 3, "amount": "2"}, {"category_id": 1, "amount": "3"},
+	}}, http.StatusBadRequest},
+	{http.MethodGet, "/api/v1/transactions/summary", "/api/v1/transactions/summary", nil, http.StatusOK},
+	{http.MethodGet, "/api/v1/transactions/export", "/api/v1/transactions/export?format=json", nil, http.StatusOK},
+	{http.MethodPut, "/api/v1/transactions/:id", "/api/v1/transactions/1", map[string]interface{}{"category_id": 3, "amount": "1", "type": "expense"}, http.StatusNotFound},
+	{http.MethodPut, "/api/v1/transactions/:id", "/api/v1/transactions/3", map[string]interface{}{"category_id": 1, "amount": "1", "type": "expense"}, http.StatusBadRequest},
+	{http.MethodDelete, "/api/v1/transactions/:id", "/api/v1/transactions/1", nil, http.StatusNotFound},
+	{http.MethodGet, "/api/v1/transactions/:id/attachments", "/api/v1/transactions/1/attachments", nil, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/transactions/:id/attachments", "/api/v1/transactions/1/attachments", upload{fileName: "receipt.pdf", content: "%PDF-1.4"}, http.StatusNotFound},
+	{http.MethodGet, "/api/v1/transactions/:id/attachments/:attachmentId", "/api/v1/transactions/1/attachments/1", nil, http.StatusNotFound},
+	{http.MethodGet, "/api/v1/transactions/:id/attachments/:attachmentId", "/api/v1/transactions/3/attachments/1", nil, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/transactions/:id/attachments/:attachmentId", "/api/v1/transactions/1/attachments/1", nil, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/transactions/:id/attachments/:attachmentId", "/api/v1/transactions/3/attachments/1", nil, http.StatusNotFound},
+
+	// imports
+	{http.MethodGet, "/api/v1/imports/profiles", "/api/v1/imports/profiles", nil, http.StatusOK},
+	{http.MethodPost, "/api/v1/imports/profiles", "/api/v1/imports/profiles", map[string]interface{}{"name": "mine", "mapping": map[string]string{"date_column": "date", "amount_column": "amount"}}, http.StatusCreated},
+	{http.MethodPut, "/api/v1/imports/profiles/:id", "/api/v1/imports/profiles/1", map[string]interface{}{"name": "mine", "mapping": map[string]string{"date_column": "date", "amount_column": "amount"}}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/imports/profiles/:id", "/api/v1/imports/profiles/1", nil, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/imports/csv/preview", "/api/v1/imports/csv/preview", upload{"s.csv", csvStatement, map[string]string{"profile_id": "1", "account_id": "3"}}, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/imports/csv/preview", "/api/v1/imports/csv/preview", upload{"s.csv", csvStatement, map[string]string{"mapping": `{"date_column":"date","amount_column":"amount"}`, "account_id": "1"}}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/imports/csv/commit", "/api/v1/imports/csv/commit", upload{"s.csv", csvStatement, map[string]string{"profile_id": "1", "account_id": "3"}}, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/imports/csv/commit", "/api/v1/imports/csv/commit", upload{"s.csv", csvStatement, map[string]string{"mapping": `{"date_column":"date","amount_column":"amount"}`, "account_id": "3", "category_id": "1"}}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/imports/ofx/preview", "/api/v1/imports/ofx/preview", upload{"s.ofx", ofxStatement, map[string]string{"accounts": `{"9":1}`}}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/imports/ofx/commit", "/api/v1/imports/ofx/commit", upload{"s.ofx", ofxStatement, map[string]string{"account_id": "1"}}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/imports/qif/preview", "/api/v1/imports/qif/preview", upload{"s.qif", qifStatement, map[string]string{"account_id": "1"}}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/imports/qif/commit", "/api/v1/imports/qif/commit", upload{"s.qif", qifStatement, map[string]string{"account_id": "3", "category_id": "1"}}, http.StatusBadRequest},
+
+	// budgets
+	{http.MethodGet, "/api/v1/budgets", "/api/v1/budgets?month=10&year=2026", nil, http.StatusOK},
+	{http.MethodGet, "/api/v1/budgets/progress", "/api/v1/budgets/progress?month=10&year=2026", nil, http.StatusOK},
+	{http.MethodPost, "/api/v1/budgets/copy", "/api/v1/budgets/copy", map[string]interface{}{"from_month": 10, "from_year": 2026, "to_month": 11, "to_year": 2026}, http.StatusOK},
+	{http.MethodPost, "/api/v1/budgets", "/api/v1/budgets", map[string]interface{}{"category_id": 1, "amount": "50", "month": 10, "year": 2026}, http.StatusBadRequest},
+	{http.MethodPut, "/api/v1/budgets/:id", "/api/v1/budgets/1", map[string]interface{}{"amount": "50"}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/budgets/:id", "/api/v1/budgets/1", nil, http.StatusNotFound},
+
+	// reports
+	{http.MethodGet, "/api/v1/reports/spending", "/api/v1/reports/spending?month=10&year=2026", nil, http.StatusOK},
+	{http.MethodGet, "/api/v1/reports/spending/tags", "/api/v1/reports/spending/tags?month=10&year=2026", nil, http.StatusOK},
+	{http.MethodGet, "/api/v1/reports/trend", "/api/v1/reports/trend?from=2026-10-01&to=2026-10-31&interval=week&group_by=account", nil, http.StatusOK},
+
+	// tags
+	{http.MethodGet, "/api/v1/tags", "/api/v1/tags", nil, http.StatusOK},
+	{http.MethodPost, "/api/v1/tags/merge", "/api/v1/tags/merge", map[string]interface{}{"source_ids": []int64{1}, "target_id": 1}, http.StatusNotFound},
+	{http.MethodPut, "/api/v1/tags/:id", "/api/v1/tags/1", map[string]interface{}{"name": "mine"}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/tags/:id", "/api/v1/tags/1", nil, http.StatusNotFound},
+
+	// recurring
+	{http.MethodGet, "/api/v1/recurring", "/api/v1/recurring", nil, http.StatusOK},
+	{http.MethodPost, "/api/v1/recurring", "/api/v1/recurring", map[string]interface{}{"account_id": 3, "category_id": 1, "amount": "5", "type": "expense", "frequency": "monthly"}, http.StatusBadRequest},
+	{http.MethodPost, "/api/v1/recurring", "/api/v1/recurring", map[string]interface{}{"account_id": 1, "category_id": 3, "amount": "5", "type": "expense", "frequency": "monthly"}, http.StatusBadRequest},
+	{http.MethodPut, "/api/v1/recurring/:id", "/api/v1/recurring/1", map[string]interface{}{"account_id": 3, "category_id": 3, "amount": "5", "type": "expense", "frequency": "monthly"}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/recurring/:id", "/api/v1/recurring/1", nil, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/recurring/:id/pause", "/api/v1/recurring/1/pause", nil, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/recurring/:id/resume", "/api/v1/recurring/1/resume", nil, http.StatusNotFound},
+	{http.MethodGet, "/api/v1/recurring/:id/occurrences", "/api/v1/recurring/1/occurrences", nil, http.StatusNotFound},
+	{http.MethodPut, "/api/v1/recurring/:id/occurrences/:date", "/api/v1/recurring/1/occurrences/2026-11-01", map[string]interface{}{"skip": true}, http.StatusNotFound},
+	{http.MethodDelete, "/api/v1/recurring/:id/occurrences/:date", "/api/v1/recurring/1/occurrences/2026-11-01", nil, http.StatusNotFound},
+	{http.MethodGet, "/api/v1/recurring/:id/events", "/api/v1/recurring/1/events", nil, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/recurring/process", "/api/v1/recurring/process", nil, http.StatusOK},
+
+	// own account; the intruder sees and changes only their own
+	{http.MethodGet, "/api/v1/me", "/api/v1/me", nil, http.StatusOK},
+	{http.MethodPatch, "/api/v1/me", "/api/v1/me", map[string]interface{}{"name": "renamed", "timezone": "Europe/Berlin"}, http.StatusOK},
+	{http.MethodPost, "/api/v1/me/password", "/api/v1/me/password", map[string]interface{}{"current_password": "guess", "new_password": "N3w#password"}, http.StatusBadRequest},
+	// sessions
+	{http.MethodGet, "/api/v1/me/sessions", "/api/v1/me/sessions", nil, http.StatusOK},
+	{http.MethodDelete, "/api/v1/me/sessions", "/api/v1/me/sessions", nil, http.StatusOK},
+	{http.MethodDelete, "/api/v1/me/sessions/:id", "/api/v1/me/sessions/1", nil, http.StatusNotFound},
+	{http.MethodPost, "/api/v1/auth/logout", "/api/v1/auth/logout", map[string]interface{}{"refresh_token": "guess"}, http.StatusOK},
+
+	// forecast
+	{http.MethodGet, "/api/v1/forecast", "/api/v1/forecast?days=90", nil, http.StatusOK},
+
+	// calendar; the bearer token does not stand in for the feed token
+	{http.MethodGet, "/api/v1/calendar/bills.ics", "/api/v1/calendar/bills.ics?token=wrong", nil, http.StatusUnauthorized},
+}
+
+// adminPrefixes are only open to the ADMIN role, which the intruder lacks.
+var adminPrefixes = []string{"/api/v1/users", "/api/v1/admin/", "/api/v1/user/"}
+
+// selfRoutes take no resource IDs: they are public or act on the caller's
+// own user, identified by the token.
+var selfRoutes = map[string]bool{
+	"POST /api/v1/login":                    true,
+	"POST /api/v1/auth/register":            true,
+	"POST /api/v1/auth/verify-email":        true,
+	"POST /api/v1/auth/verify-email/resend": true,
+	"POST /api/v1/auth/password/forgot":     true,
+	"POST /api/v1/auth/password/reset":      true,
+	"POST /api/v1/auth/email/confirm":       true,
+	"GET /api/v1/auth/google/login":         true,
+	"GET /api/v1/auth/google/callback":      true,
+	"POST /api/v1/auth/google/exchange":     true,
+	"POST /api/v1/auth/refresh":             true,
+	"POST /api/v1/2fa/verify":               true,
+	"POST /api/v1/2fa/backup/verify":        true,
+	"GET /api/v1/health":                    true,
+	"POST /api/v1/2fa/setup":                true,
+	"POST /api/v1/2fa/setup/verify":         true,
+	"DELETE /api/v1/2fa/disable":            true,
+	"POST /api/v1/2fa/backup-codes":         true,
+	"GET /api/v1/calendar/feed":             true,
+	"POST /api/v1/calendar/feed":            true,
+	"DELETE /api/v1/calendar/feed":          true,
+}
+
+func isAdminRoute(path string) bool {
+	for _, prefix := range adminPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func token(t *testing.T, userID int64, roles ...string) string {
+	tok, err := jwt.GenerateToken(userID, 0, "user@example.com", "user", roles)
+	require.NoError(t, err)
+	return "Bearer " + tok
+}
+
+func do(t *testing.T, r *gin.Engine, method, path, auth string, body interface{}) *httptest.ResponseRecorder {
+	var reader io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case upload:
+		reader, contentType = b.encode(t)
+	default:
+		data, err := json.Marshal(b)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", auth)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCrossTenantAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwt.SetSecret("cross-tenant-test")
+	intruder := token(t, intruderID, "USER")
+
+	for _, tc := range crossTenantCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			s := newStore()
+			r := newRouter(s)
+			before := s.ownerState(t)
+
+			w := do(t, r, tc.method, tc.path, intruder, tc.body)
+
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+			assert.NotContains(t, w.Body.String(), secret)
+			if tc.status >= http.StatusBadRequest {
+				// an error from the usecases, not an unmatched route
+				assert.Contains(t, w.Body.String(), `"success":false`)
+			}
+			assert.Equal(t, before, s.ownerState(t), "the owner's data changed")
+		})
+	}
+}
+
+func TestAdminRoutesRejectUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwt.SetSecret("cross-tenant-test")
+	intruder := token(t, intruderID, "USER")
+	r := newRouter(newStore())
+
+	for _, route := range r.Routes() {
+		if !isAdminRoute(route.Path) {
+			continue
+		}
+		path := strings.ReplaceAll(route.Path, ":id", "1")
+		t.Run(route.Method+" "+path, func(t *testing.T) {
+			w := do(t, r, route.Method, path, intruder, map[string]interface{}{})
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+// TestCrossTenantCoverage fails when a route is added to route.go without a
+// cross-tenant case, so new endpoints cannot skip the ownership review.
+func TestCrossTenantCoverage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	covered := map[string]bool{}
+	for _, tc := range crossTenantCases {
+		covered[tc.method+" "+tc.route] = true
+	}
+
+	for _, route := range newRouter(newStore()).Routes() {
+		key := route.Method + " " + route.Path
+		if covered[key] || selfRoutes[key] || isAdminRoute(route.Path) {
+			continue
+		}
+		t.Errorf("route %s has no cross-tenant test case", key)
+	}
+}
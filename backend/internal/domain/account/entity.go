@@ -0,0 +1,69 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+var (
+	ErrNotFound         = errors.New("account not found")
+	ErrTransferNotFound = errors.New("transfer not found")
+	ErrInUse            = errors.New("account still has transactions or recurring transactions")
+)
+
+type Type string
+
+const (
+	Cash       Type = "cash"
+	Bank       Type = "bank"
+	EWallet    Type = "ewallet"
+	CreditCard Type = "credit_card"
+	Other      Type = "other"
+)
+
+// Valid reports whether t is one of the supported account types.
+func (t Type) Valid() bool {
+	switch t {
+	case Cash, Bank, EWallet, CreditCard, Other:
+		return true
+	}
+	return false
+}
+
+type Account struct {
+	ID             int64        `json:"id"`
+	UserID         int64        `json:"user_id"`
+	Name           string       `json:"name"`
+	Type           Type         `json:"type"`
+	Currency       string       `json:"currency"`
+	OpeningBalance money.Amount `json:"opening_balance"`
+	Balance        money.Amount `json:"balance"` // opening balance plus all posted transactions
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// Transfer moves money between two accounts of the same user that hold the
+// same currency. It is stored as a transfer row plus a "transfer_out" and a
+// "transfer_in" transaction leg.
+type Transfer struct {
+	ID            int64        `json:"id"`
+	UserID        int64        `json:"user_id"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
+	Note          string       `json:"note"`
+	Date          time.Time    `json:"date"`
+}
+
+type Repository interface {
+	FindAllByUserID(userID int64) ([]Account, error)
+	FindByID(id int64) (Account, error)
+	FindDefault(userID int64) (Account, error)
+	Save(account *Account) error
+	Update(account *Account) error
+	Delete(id int64) error
+	FindTransferByID(id int64) (Transfer, error)
+	SaveTransfer(transfer *Transfer) error
+	DeleteTransfer(id int64) error
+}
@@ -29,6 +29,7 @@ const (
 	AuthForbidden       = "AUTH_FORBIDDEN"
 	AuthInvalidPassword = "AUTH_INVALID_PASSWORD"
 	InvalidCredentials  = "INVALID_CREDENTIALS"
+	AuthEmailUnverified = "AUTH_EMAIL_UNVERIFIED"
 )
 
 // ======================
@@ -90,3 +91,10 @@ const (
 	PasswordTooShort = "PASSWORD_TOO_SHORT"
 	PasswordWeak     = "PASSWORD_WEAK"
 )
+
+// ======================
+// Currency
+// ======================
+const (
+	FxRateNotFound = "FX_RATE_NOT_FOUND"
+)
@@ -0,0 +1,45 @@
+package attachment
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrObjectNotFound is returned by a Storage for keys it does not hold.
+	ErrObjectNotFound = errors.New("stored object not found")
+)
+
+// Attachment is a receipt or other file attached to a transaction. The file
+// itself lives in a Storage under StorageKey.
+type Attachment struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"user_id"`
+	TransactionID int64     `json:"transaction_id"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	StorageKey    string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Repository interface {
+	FindAllByTransactionID(transactionID int64) ([]Attachment, error)
+	FindByID(id int64) (Attachment, error)
+	Save(a *Attachment) error
+	Delete(id int64) error
+	// FindOrphaned returns up to limit attachments whose transaction has been
+	// deleted.
+	FindOrphaned(limit int) ([]Attachment, error)
+}
+
+// Storage holds the file contents of attachments.
+type Storage interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Get returns ErrObjectNotFound when nothing is stored under key.
+	Get(key string) (io.ReadCloser, error)
+	// Delete succeeds when nothing is stored under key.
+	Delete(key string) error
+}
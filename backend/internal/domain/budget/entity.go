@@ -1,18 +1,248 @@
 package budget
 
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+var ErrNotFound = errors.New("budget not found")
+
 type Budget struct {
-	ID         int64   `json:"id"`
-	UserID     int64   `json:"user_id"`
-	CategoryID int64   `json:"category_id"`
-	Amount     float64 `json:"amount"`
-	Month      int     `json:"month"`
-	Year       int     `json:"year"`
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+	// CategoryID is 0 for a budget on the total spending of all
+	// categories.
+	CategoryID    int64        `json:"category_id"`
+	CategoryName  string       `json:"category_name"`
+	CategoryColor string       `json:"category_color"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	Period        string       `json:"period"` // "week", "month", "quarter" or "year"
+	// StartDate is the first day of the period, in UTC. Weeks start on
+	// Monday.
+	StartDate time.Time `json:"start_date"`
+	// EndDate is the last day of the period. It is filled when budgets are
+	// read.
+	EndDate time.Time `json:"end_date"`
+	// Month and Year are those of StartDate. A monthly budget may be set
+	// with them instead of StartDate.
+	Month int `json:"month"`
+	Year  int `json:"year"`
+	// Rollover decides what is left over at the end of the period is added
+	// to the budget of the same category in the next period.
+	Rollover string `json:"rollover"`
+	// CarriedOver is what the previous period's budget rolled over into
+	// this one, in Currency. It is negative when that period was
+	// overspent. The period's limit is Amount plus CarriedOver.
+	CarriedOver money.Amount `json:"carried_over"`
+	// AutoCopy opts the budget into being copied into the next period when
+	// that period starts.
+	AutoCopy bool `json:"auto_copy"`
+	// BaseAmount is the period's limit in the user's base currency,
+	// converted with Conversion. Both are filled when budgets are read.
+	BaseAmount money.Amount `json:"base_amount"`
+	Conversion *fx.Quote    `json:"conversion,omitempty"`
+}
+
+// Budget periods.
+const (
+	PeriodWeek    = "week"
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+	PeriodYear    = "year"
+)
+
+// Periods lists the budget periods from the shortest.
+var Periods = []string{PeriodWeek, PeriodMonth, PeriodQuarter, PeriodYear}
+
+func ValidPeriod(period string) bool {
+	switch period {
+	case PeriodWeek, PeriodMonth, PeriodQuarter, PeriodYear:
+		return true
+	}
+	return false
+}
+
+// PeriodStart returns the first day of the period that contains t, in
+// UTC.
+func PeriodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case PeriodQuarter:
+		return time.Date(t.Year(), (t.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case PeriodYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// NextPeriod returns the start of the period following the one starting
+// at start.
+func NextPeriod(period string, start time.Time) time.Time {
+	switch period {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodQuarter:
+		return start.AddDate(0, 3, 0)
+	case PeriodYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// PreviousPeriod returns the start of the period before the one starting
+// at start.
+func PreviousPeriod(period string, start time.Time) time.Time {
+	return PeriodStart(period, start.AddDate(0, 0, -1))
+}
+
+// End returns the start of the next period, which ends the budget's.
+func (b Budget) End() time.Time {
+	return NextPeriod(b.Period, b.StartDate)
+}
+
+// SetStart moves the budget to the period containing t and fills the
+// dates derived from it.
+func (b *Budget) SetStart(t time.Time) {
+	b.StartDate = PeriodStart(b.Period, t)
+	b.EndDate = b.End().AddDate(0, 0, -1)
+	b.Month, b.Year = int(b.StartDate.Month()), b.StartDate.Year()
+}
+
+// Rollover modes. With RolloverUnspent only savings roll over; with
+// RolloverAll an overspend also reduces the next month's limit.
+const (
+	RolloverNone    = "none"
+	RolloverUnspent = "unspent"
+	RolloverAll     = "all"
+)
+
+// Limit is what may be spent during the period in Currency.
+func (b Budget) Limit() money.Amount {
+	return b.Amount + b.CarriedOver
+}
+
+// Carry returns what rolls over into the next period when spent, in
+// Currency, was spent during this one.
+func (b Budget) Carry(spent money.Amount) money.Amount {
+	left := b.Limit() - spent
+	switch {
+	case b.Rollover == RolloverAll:
+		return left
+	case b.Rollover == RolloverUnspent && left > 0:
+		return left
+	}
+	return 0
+}
+
+// CopyRequest copies all budgets of one period into another of the same
+// length. Budgets the target period already has are skipped unless
+// Overwrite is set.
+type CopyRequest struct {
+	Period string `json:"period"` // defaults to "month"
+	// From and To are any day of the source and target periods. Monthly
+	// budgets may be copied with the month fields instead.
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	FromMonth int       `json:"from_month"`
+	FromYear  int       `json:"from_year"`
+	ToMonth   int       `json:"to_month"`
+	ToYear    int       `json:"to_year"`
+	// AdjustPercent scales every amount, e.g. 5 for 5% more or -10 for 10%
+	// less. Carried over amounts are not scaled.
+	AdjustPercent float64 `json:"adjust_percent"`
+	Overwrite     bool    `json:"overwrite"`
+}
+
+// CopyResult lists the budgets created or overwritten in the target period
+// and the categories that were skipped because they already had one. The
+// all-categories budget is category 0.
+type CopyResult struct {
+	Copied             []Budget `json:"copied"`
+	SkippedCategoryIDs []int64  `json:"skipped_category_ids"`
+}
+
+// Budget statuses. A budget is at risk when spending at the pace of the
+// period so far would exceed it by the end of the period.
+const (
+	StatusOnTrack = "on_track"
+	StatusAtRisk  = "at_risk"
+	StatusOver    = "over"
+)
+
+// Progress compares a budget with the expenses of its period. Spent,
+// Remaining and Projected are in the user's base currency, like BaseAmount.
+type Progress struct {
+	Budget
+	Spent       money.Amount `json:"spent"`
+	Remaining   money.Amount `json:"remaining"` // negative once overspent
+	PercentUsed float64      `json:"percent_used"`
+	DaysLeft    int          `json:"days_left"`
+	// Projected is what will have been spent at the end of the period if
+	// spending goes on at the same daily pace.
+	Projected money.Amount `json:"projected"`
+	Status    string       `json:"status"`
+}
+
+// Progress measures spent against BaseAmount as of now. The current day
+// counts as elapsed, so a budget's pace is known from its first day.
+func (b Budget) Progress(spent money.Amount, now time.Time) Progress {
+	days := int(b.End().Sub(b.StartDate).Hours() / 24)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	elapsed := int(today.Sub(b.StartDate).Hours()/24) + 1
+	elapsed = max(0, min(elapsed, days))
+
+	p := Progress{
+		Budget:    b,
+		Spent:     spent,
+		Remaining: b.BaseAmount - spent,
+		DaysLeft:  days - elapsed,
+		Projected: spent,
+	}
+	if elapsed > 0 {
+		p.Projected = spent.MulRatio(int64(days), int64(elapsed))
+	}
+	if b.BaseAmount > 0 {
+		p.PercentUsed = math.Round(float64(spent)/float64(b.BaseAmount)*1000) / 10
+	}
+
+	switch {
+	case spent > b.BaseAmount:
+		p.Status = StatusOver
+	case p.Projected > b.BaseAmount:
+		p.Status = StatusAtRisk
+	default:
+		p.Status = StatusOnTrack
+	}
+	return p
 }
 
 type Repository interface {
-	FindAllByUserID(userID int64, month, year int) ([]Budget, error)
-	FindByCategory(userID int64, categoryID int64, month, year int) (Budget, error)
+	// FindAllByUserID returns the budgets whose period contains on, of the
+	// given periods or of all when none are given. Budgets come with their
+	// category's name and colour.
+	FindAllByUserID(userID int64, on time.Time, periods ...string) ([]Budget, error)
+	// FindByIDForUser returns ErrNotFound when the budget does not exist or
+	// belongs to another user.
+	FindByIDForUser(userID, id int64) (Budget, error)
+	// FindByCategory returns ErrNotFound when the category, 0 for all
+	// categories, has no budget in the period.
+	FindByCategory(userID int64, categoryID int64, period string, start time.Time) (Budget, error)
+	// FindUncopied returns the auto-copy budgets of a period, of all users,
+	// whose category has no budget in the following period yet.
+	FindUncopied(period string, start time.Time) ([]Budget, error)
 	Save(budget *Budget) error
+	// SaveIfAbsent saves the budget unless its category already has one in
+	// that period, and reports whether it did.
+	SaveIfAbsent(budget *Budget) (bool, error)
+	// Update only changes the row when it belongs to budget.UserID.
 	Update(budget *Budget) error
-	Delete(id int64) error
+	Delete(userID, id int64) error
 }
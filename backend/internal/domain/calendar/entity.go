@@ -0,0 +1,27 @@
+package calendar
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("calendar feed not found")
+
+// Feed is the calendar feed of a user's upcoming recurring bills. Calendar
+// apps cannot log in, so the feed URL carries a token; only its hash is
+// stored.
+type Feed struct {
+	UserID    int64     `json:"user_id"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Repository interface {
+	// FindByUserID and FindByTokenHash return ErrNotFound when there is no
+	// such feed.
+	FindByUserID(userID int64) (Feed, error)
+	FindByTokenHash(hash string) (Feed, error)
+	// Save creates the user's feed or replaces its token.
+	Save(f *Feed) error
+	Delete(userID int64) error
+}
@@ -1,5 +1,14 @@
 package category
 
+import "errors"
+
+var (
+	ErrNotFound = errors.New("category not found")
+	// ErrInUse is returned when splits of another category's transactions
+	// still point at the category.
+	ErrInUse = errors.New("category is used by transaction splits")
+)
+
 type Category struct {
 	ID     int64  `json:"id"`
 	UserID int64  `json:"user_id"`
@@ -11,8 +20,11 @@ type Category struct {
 
 type Repository interface {
 	FindAllByUserID(userID int64) ([]Category, error)
-	FindByID(id int64) (Category, error)
+	// FindByIDForUser returns ErrNotFound when the category does not exist
+	// or belongs to another user.
+	FindByIDForUser(userID, id int64) (Category, error)
 	Save(category *Category) error
+	// Update only changes the row when it belongs to category.UserID.
 	Update(category *Category) error
-	Delete(id int64) error
+	Delete(userID, id int64) error
 }
@@ -0,0 +1,48 @@
+package fx
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// DateLayout is the format of rate dates in import files and API responses.
+const DateLayout = "2006-01-02"
+
+// Rate is a daily exchange rate: one unit of Base is worth Rate units of
+// Quote. Rate is kept as a decimal string so no precision is lost.
+type Rate struct {
+	Date  time.Time `json:"date"`
+	Base  string    `json:"base"`
+	Quote string    `json:"quote"`
+	Rate  string    `json:"rate"`
+}
+
+// Quote is the rate that was applied to convert From into To. RateDate is
+// the day of the stored rate that was used, empty when no conversion was
+// needed.
+type Quote struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Rate     string `json:"rate"`
+	RateDate string `json:"rate_date,omitempty"`
+}
+
+// Apply converts an amount in From into To.
+func (q Quote) Apply(a money.Amount) money.Amount {
+	r, ok := new(big.Rat).SetString(q.Rate)
+	if !ok {
+		return a
+	}
+	return a.MulRat(r)
+}
+
+type Repository interface {
+	// FindLatest returns the most recent base/quote rate dated on or before on.
+	FindLatest(base, quote string, on time.Time) (Rate, error)
+	SaveBatch(rates []Rate) error
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+var ErrProfileNotFound = errors.New("import profile not found")
+
+// Amount sign conventions for statements with a single amount column.
+const (
+	// SignIncomePositive treats positive amounts as money in (most bank
+	// accounts).
+	SignIncomePositive = "income_positive"
+	// SignExpensePositive treats positive amounts as money out (most credit
+	// card statements).
+	SignExpensePositive = "expense_positive"
+)
+
+// Mapping describes how to read one bank's CSV export. Columns are matched
+// by header name, case-insensitively. Either AmountColumn or at least one of
+// DebitColumn and CreditColumn must be set.
+type Mapping struct {
+	Delimiter         string `json:"delimiter"` // defaults to ","
+	SkipRows          int    `json:"skip_rows"` // lines before the header row
+	DateColumn        string `json:"date_column"`
+	DateFormat        string `json:"date_format"` // e.g. "DD/MM/YYYY", defaults to "YYYY-MM-DD"
+	DescriptionColumn string `json:"description_column"`
+	AmountColumn      string `json:"amount_column"`
+	AmountSign        string `json:"amount_sign"` // "income_positive" (default) or "expense_positive"
+	DebitColumn       string `json:"debit_column"`
+	CreditColumn      string `json:"credit_column"`
+	CategoryColumn    string `json:"category_column"`
+	DecimalSeparator  string `json:"decimal_separator"` // "." (default) or ","
+}
+
+// Profile is a saved mapping for one bank.
+type Profile struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Mapping   Mapping   `json:"mapping"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Row is one parsed statement line. Line is the 1-based line number in the
+// source file so problems can be traced back to it.
+type Row struct {
+	Line         int          `json:"line"`
+	Date         time.Time    `json:"date"`
+	Description  string       `json:"description"`
+	Amount       money.Amount `json:"amount"`
+	Type         string       `json:"type"` // "income" or "expense"
+	CategoryName string       `json:"category_name,omitempty"`
+	CategoryID   int64        `json:"category_id"`
+	ExternalID   string       `json:"external_id,omitempty"` // OFX FITID
+	Duplicate    bool         `json:"duplicate"`
+	DuplicateOf  int64        `json:"duplicate_of,omitempty"` // ID of the matching transaction
+	// AlreadyImported is set when a transaction with the same ExternalID
+	// exists on the account. Such rows are never imported again.
+	AlreadyImported bool   `json:"already_imported,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Entry is a transaction read from an OFX or QIF file, before it is
+// assigned to a Cashbook account and category.
+type Entry struct {
+	transaction.Transaction
+	CategoryName string `json:"category_name,omitempty"`
+}
+
+// Statement is the part of a file that belongs to one account. A single OFX
+// or QIF file can hold several.
+type Statement struct {
+	AccountRef string  `json:"account_ref"` // the account as named in the file (OFX ACCTID, QIF account name)
+	Currency   string  `json:"currency,omitempty"`
+	Entries    []Entry `json:"entries"`
+}
+
+type StatementPreview struct {
+	AccountRef string `json:"account_ref"`
+	AccountID  int64  `json:"account_id"`
+	Preview
+}
+
+// Options are the choices made for one import run.
+type Options struct {
+	AccountID int64 `json:"account_id"`
+	// CategoryID is used for rows whose category cannot be matched by name.
+	CategoryID int64 `json:"category_id"`
+	// IncludeDuplicates imports rows flagged as likely duplicates as well.
+	IncludeDuplicates bool `json:"include_duplicates"`
+	// Accounts maps the account references found in an OFX or QIF file to
+	// Cashbook account IDs. Unmapped references use AccountID.
+	Accounts map[string]int64 `json:"accounts,omitempty"`
+}
+
+type Preview struct {
+	Rows       []Row `json:"rows"`
+	Total      int   `json:"total"`
+	Valid      int   `json:"valid"`
+	Duplicates int   `json:"duplicates"`
+	Invalid    int   `json:"invalid"`
+}
+
+type Result struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+type ProfileRepository interface {
+	FindAllByUserID(userID int64) ([]Profile, error)
+	FindByID(id int64) (Profile, error)
+	Save(profile *Profile) error
+	Update(profile *Profile) error
+	Delete(id int64) error
+}
@@ -1,6 +1,14 @@
 package recurring_transaction
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+var ErrNotFound = errors.New("recurring transaction not found")
 
 type Frequency string
 
@@ -8,25 +16,220 @@ const (
 	Daily   Frequency = "daily"
 	Weekly  Frequency = "weekly"
 	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
 )
 
 type RecurringTransaction struct {
-	ID            int64     `json:"id"`
-	UserID        int64     `json:"user_id"`
-	CategoryID    int64     `json:"category_id"`
-	Amount        float64   `json:"amount"`
-	Type          string    `json:"type"` // "income" or "expense"
-	Note          string    `json:"note"`
-	Frequency     Frequency `json:"frequency"`
-	StartDate     time.Time `json:"start_date"`
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	AccountID  int64        `json:"account_id"`
+	CategoryID int64        `json:"category_id"`
+	Amount     money.Amount `json:"amount"`
+	Type       string       `json:"type"` // "income" or "expense"
+	Note       string       `json:"note"`
+	// Frequency is the FREQ of Rule. A template may be created with only a
+	// frequency, which repeats it every day, week, month or year from
+	// StartDate.
+	Frequency Frequency `json:"frequency"`
+	// Rule is the recurrence rule in RRULE form (see Rule), e.g.
+	// "FREQ=MONTHLY;BYMONTHDAY=25".
+	Rule      string    `json:"rule"`
+	StartDate time.Time `json:"start_date"`
+	// LastProcessed is the last occurrence a transaction was generated
+	// for. It is zero until the first one is.
 	LastProcessed time.Time `json:"last_processed"`
+	// NextOccurrence is the first occurrence after LastProcessed. It is
+	// zero once the rule has ended.
+	NextOccurrence time.Time `json:"next_occurrence"`
+	// Paused templates generate nothing. The occurrences that pass while
+	// paused are skipped on resume.
+	Paused bool `json:"paused"`
+}
+
+// Exception changes a single occurrence of a template: it is skipped, or
+// generated with Amount instead of the template's amount.
+type Exception struct {
+	RecurringID int64 `json:"recurring_id"`
+	// Date is the day of the occurrence (see Day).
+	Date   time.Time     `json:"date"`
+	Skip   bool          `json:"skip"`
+	Amount *money.Amount `json:"amount,omitempty"`
+}
+
+// Occurrence is an upcoming occurrence of a template, with its exception
+// applied.
+type Occurrence struct {
+	Date   time.Time    `json:"date"`
+	Amount money.Amount `json:"amount"`
+	Skip   bool         `json:"skip"`
+}
+
+// Upcoming is an occurrence of one of a user's templates that is not
+// generated yet.
+type Upcoming struct {
+	Occurrence
+	RecurringID int64  `json:"recurring_id"`
+	AccountID   int64  `json:"account_id"`
+	CategoryID  int64  `json:"category_id"`
+	Type        string `json:"type"`
+	Note        string `json:"note"`
+}
+
+// Event actions.
+const (
+	EventCreated   = "created"
+	EventUpdated   = "updated"
+	EventPaused    = "paused"
+	EventResumed   = "resumed"
+	EventException = "exception_set"
+	EventRestored  = "exception_removed"
+	// EventGenerated and the actions below are recorded while processing
+	// an occurrence.
+	EventGenerated = "generated"
+	EventSkipped   = "skipped"
+	EventExisted   = "already_generated"
+)
+
+// Event is an entry of the audit log of a template, recording a change to
+// it or what happened to one of its occurrences.
+type Event struct {
+	ID          int64  `json:"id"`
+	RecurringID int64  `json:"recurring_id"`
+	Action      string `json:"action"`
+	// Occurrence is set for the actions about a single occurrence.
+	Occurrence *time.Time `json:"occurrence,omitempty"`
+	Detail     string     `json:"detail"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RunFailed is the status of the items of a Run that failed. The others
+// are EventGenerated, EventSkipped and EventExisted.
+const RunFailed = "failed"
+
+// Run is the report of a run generating the transactions of the due
+// occurrences.
+type Run struct {
+	ID int64 `json:"id"`
+	// UserID is the user whose templates were processed, or nil when the
+	// run covered every user.
+	UserID     *int64    `json:"user_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Generated  int       `json:"generated"`
+	// Skipped counts the occurrences skipped as requested and those that
+	// already had a transaction.
+	Skipped int       `json:"skipped"`
+	Failed  int       `json:"failed"`
+	Items   []RunItem `json:"items"`
+}
+
+// RunItem is what a run did with an occurrence, or with a template that
+// failed before any of its occurrences could be processed.
+type RunItem struct {
+	RecurringID int64      `json:"recurring_id"`
+	UserID      int64      `json:"user_id"`
+	Occurrence  *time.Time `json:"occurrence,omitempty"`
+	Status      string     `json:"status"`
+	// TransactionID is the transaction generated, if any.
+	TransactionID int64  `json:"transaction_id,omitempty"`
+	Detail        string `json:"detail"`
+}
+
+// Add appends item to the report and counts it.
+func (r *Run) Add(item RunItem) {
+	switch item.Status {
+	case EventGenerated:
+		r.Generated++
+	case RunFailed:
+		r.Failed++
+	default:
+		r.Skipped++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// Day returns the calendar day of occurrence on, at midnight UTC, which
+// identifies it in exceptions.
+func Day(on time.Time) time.Time {
+	return time.Date(on.Year(), on.Month(), on.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Recurrence returns the parsed Rule, or the rule of Frequency when the
+// template has none.
+func (rt RecurringTransaction) Recurrence() (Rule, error) {
+	if rt.Rule == "" {
+		return RuleFor(rt.Frequency)
+	}
+	return ParseRule(rt.Rule)
+}
+
+// Schedule returns the occurrences after LastProcessed up to and including
+// now, oldest first, and the first occurrence after now, which is zero when
+// the rule ends before.
+func (rt RecurringTransaction) Schedule(now time.Time) (due []time.Time, next time.Time, err error) {
+	rule, err := rt.Recurrence()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	for on := range rule.Occurrences(rt.StartDate) {
+		if on.After(now) {
+			return due, on, nil
+		}
+		if rt.LastProcessed.IsZero() || on.After(rt.LastProcessed) {
+			due = append(due, on)
+		}
+	}
+	return due, time.Time{}, nil
+}
+
+// OccurrenceOn returns the occurrence falling on day, if there is one.
+func (rt RecurringTransaction) OccurrenceOn(day time.Time) (time.Time, bool, error) {
+	rule, err := rt.Recurrence()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	day = Day(day)
+	for on := range rule.Occurrences(rt.StartDate) {
+		switch d := Day(on); {
+		case d.Equal(day):
+			return on, true, nil
+		case d.After(day):
+			return time.Time{}, false, nil
+		}
+	}
+	return time.Time{}, false, nil
 }
 
 type Repository interface {
 	FindAllByUserID(userID int64) ([]RecurringTransaction, error)
+	// FindByIDForUser returns ErrNotFound when the recurring transaction
+	// does not exist or belongs to another user.
+	FindByIDForUser(userID, id int64) (RecurringTransaction, error)
+	// FindDue returns the templates whose NextOccurrence has come by now.
 	FindDue(now time.Time) ([]RecurringTransaction, error)
+	// FindDueByUserID is FindDue for the templates of a single user.
+	FindDueByUserID(userID int64, now time.Time) ([]RecurringTransaction, error)
 	Save(rt *RecurringTransaction) error
+	// Update only changes the row when it belongs to rt.UserID.
 	Update(rt *RecurringTransaction) error
-	Delete(id int64) error
-	UpdateLastProcessed(id int64, lastProcessed time.Time) error
+	Delete(userID, id int64) error
+	// UpdateSchedule records the last occurrence generated and the next
+	// one, which is zero once the rule has ended.
+	UpdateSchedule(id int64, lastProcessed, nextOccurrence time.Time) error
+	// FindExceptions returns the exceptions of the occurrences of template
+	// id from the day of from on, oldest first.
+	FindExceptions(id int64, from time.Time) ([]Exception, error)
+	// SaveException adds the exception or replaces the one of the same day.
+	SaveException(e *Exception) error
+	DeleteException(id int64, day time.Time) error
+	SaveEvent(e *Event) error
+	// FindEvents returns the audit log of template id, newest first.
+	FindEvents(id int64) ([]Event, error)
+	SaveRun(r *Run) error
+	// FindRuns returns the last limit runs saved, newest first.
+	FindRuns(limit int) ([]Run, error)
+	// InTx runs fn with repositories bound to a single database
+	// transaction. The transaction is committed when fn returns nil and
+	// rolled back otherwise.
+	InTx(fn func(repo Repository, txRepo transaction.Repository) error) error
 }
@@ -0,0 +1,390 @@
+package recurring_transaction
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is a recurrence rule in the style of the RFC 5545 RRULE, such as
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR" or, for the last business day of each
+// month, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1". Weeks start on
+// Monday.
+//
+// Without BYMONTHDAY or BYDAY, monthly and yearly rules fall on the day of
+// the month of the start, or on the last day of shorter months.
+type Rule struct {
+	Freq     Frequency
+	Interval int // every Interval periods; 0 means 1
+	ByMonth  []int
+	// ByMonthDay days count from the end of the month when negative, -1
+	// being the last day.
+	ByMonthDay []int
+	ByDay      []WeekdayNum
+	// BySetPos picks occurrences by position among those of each period,
+	// counting from the end when negative.
+	BySetPos []int
+	// Until is the last moment an occurrence may fall on, and Count the
+	// number of occurrences. At most one of them is set.
+	Until time.Time
+	Count int
+}
+
+// WeekdayNum is a BYDAY entry: a weekday, optionally the N-th of the month
+// or year (e.g. 2MO), counting from the end when N is negative (-1FR).
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// maxEmptyPeriods ends rules that match nothing, such as the 30th of
+// February, instead of searching forever.
+const maxEmptyPeriods = 1000
+
+// RuleFor returns the rule a template with only a frequency follows.
+func RuleFor(freq Frequency) (Rule, error) {
+	switch freq {
+	case Daily, Weekly, Monthly, Yearly:
+		return Rule{Freq: freq}, nil
+	}
+	return Rule{}, fmt.Errorf("unsupported frequency %q", freq)
+}
+
+// ParseRule parses the RRULE parts the calculator supports. A leading
+// "RRULE:" is ignored.
+func ParseRule(s string) (Rule, error) {
+	var r Rule
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return r, errors.New("empty rule")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return r, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[key] {
+			return r, fmt.Errorf("%s is given twice", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToLower(value))
+			if _, err = RuleFor(r.Freq); err != nil {
+				err = fmt.Errorf("unsupported FREQ %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = parseInt(key, value, 1, 1000)
+		case "BYMONTH":
+			r.ByMonth, err = parseInts(key, value, 1, 12, false)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(key, value, 1, 31, true)
+		case "BYDAY":
+			r.ByDay, err = parseWeekdays(value)
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(key, value, 1, 366, true)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "COUNT":
+			r.Count, err = parseInt(key, value, 1, 10000)
+		case "WKST":
+			if value != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+
+	if r.Freq == "" {
+		return Rule{}, errors.New("FREQ is required")
+	}
+	if !r.Until.IsZero() && r.Count > 0 {
+		return Rule{}, errors.New("UNTIL and COUNT cannot both be given")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return Rule{}, errors.New("numbered BYDAY entries need FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return Rule{}, errors.New("BYSETPOS needs BYDAY, BYMONTHDAY or BYMONTH")
+	}
+	return r, nil
+}
+
+func parseInt(key, value string, lo, hi int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%s must be between %d and %d", key, lo, hi)
+	}
+	return n, nil
+}
+
+// parseInts parses a list of numbers between lo and hi, or between -hi and
+// -lo as well when negative is set.
+func parseInts(key, value string, lo, hi int, negative bool) ([]int, error) {
+	var ns []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(v)
+		abs := n
+		if negative && n < 0 {
+			abs = -n
+		}
+		if err != nil || abs < lo || abs > hi {
+			if negative {
+				return nil, fmt.Errorf("%s values must be between %d and %d or between -%d and -%d", key, lo, hi, hi, lo)
+			}
+			return nil, fmt.Errorf("%s values must be between %d and %d", key, lo, hi)
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
+
+func parseWeekdays(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, v := range strings.Split(value, ",") {
+		if len(v) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", v)
+		}
+		day := slices.Index(weekdays, v[len(v)-2:])
+		if day < 0 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", v)
+		}
+		wd := WeekdayNum{Day: time.Weekday(day)}
+		if prefix := v[:len(v)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid BYDAY value %q", v)
+			}
+			wd.N = n
+		}
+		days = append(days, wd)
+	}
+	return days, nil
+}
+
+// parseUntil accepts a UTC date-time or a date, which includes the whole
+// day.
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, errors.New("UNTIL must be a date (20060102) or a UTC date-time (20060102T150405Z)")
+}
+
+// String formats the rule as an RRULE value, with its parts in a fixed
+// order.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + strings.ToUpper(string(r.Freq))}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = weekdays[wd.Day]
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+// Occurrences yields the occurrences of the rule from start on, in order.
+// They fall at the time of day of start, in its location.
+func (r Rule) Occurrences(start time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		interval := max(r.Interval, 1)
+		count, empty := 0, 0
+		for k := 0; empty < maxEmptyPeriods; k += interval {
+			days := r.expand(start, k)
+			if len(days) == 0 {
+				empty++
+				continue
+			}
+			empty = 0
+			for _, d := range days {
+				t := time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+				if t.Before(start) {
+					continue
+				}
+				if !r.Until.IsZero() && t.After(r.Until) {
+					return
+				}
+				if !yield(t) {
+					return
+				}
+				if count++; r.Count > 0 && count >= r.Count {
+					return
+				}
+			}
+		}
+	}
+}
+
+// After returns the first occurrence after t, or the zero time when the
+// rule ends before.
+func (r Rule) After(start, t time.Time) time.Time {
+	for on := range r.Occurrences(start) {
+		if on.After(t) {
+			return on
+		}
+	}
+	return time.Time{}
+}
+
+// expand returns the days of the k-th period after the one of start that
+// the rule matches, in order.
+func (r Rule) expand(start time.Time, k int) []time.Time {
+	s := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	var first, end time.Time
+	switch r.Freq {
+	case Daily:
+		first = s.AddDate(0, 0, k)
+		end = first.AddDate(0, 0, 1)
+	case Weekly:
+		first = s.AddDate(0, 0, 7*k-(int(s.Weekday())+6)%7)
+		end = first.AddDate(0, 0, 7)
+	case Monthly:
+		first = time.Date(s.Year(), s.Month()+time.Month(k), 1, 0, 0, 0, 0, time.UTC)
+		end = first.AddDate(0, 1, 0)
+	default:
+		first = time.Date(s.Year()+k, 1, 1, 0, 0, 0, 0, time.UTC)
+		end = first.AddDate(1, 0, 0)
+	}
+
+	var days []time.Time
+	for d := first; d.Before(end); d = d.AddDate(0, 0, 1) {
+		if r.matches(d, s) {
+			days = append(days, d)
+		}
+	}
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+
+	var picked []time.Time
+	for i, d := range days {
+		for _, pos := range r.BySetPos {
+			if pos == i+1 || pos == i-len(days) {
+				picked = append(picked, d)
+				break
+			}
+		}
+	}
+	return picked
+}
+
+// matches reports whether day d belongs to the rule, filling in what the
+// rule leaves open from s, the day of the start.
+func (r Rule) matches(d, s time.Time) bool {
+	if len(r.ByMonth) > 0 && !slices.Contains(r.ByMonth, int(d.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !matchesMonthDay(d, r.ByMonthDay) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchesWeekday(d) {
+		return false
+	}
+
+	if len(r.ByMonthDay) > 0 || len(r.ByDay) > 0 {
+		return true
+	}
+	switch r.Freq {
+	case Weekly:
+		return d.Weekday() == s.Weekday()
+	case Monthly:
+		return d.Day() == min(s.Day(), daysIn(d))
+	case Yearly:
+		if len(r.ByMonth) == 0 && d.Month() != s.Month() {
+			return false
+		}
+		return d.Day() == min(s.Day(), daysIn(d))
+	}
+	return true
+}
+
+func matchesMonthDay(d time.Time, days []int) bool {
+	for _, n := range days {
+		if n == d.Day() || n == d.Day()-daysIn(d)-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday matches BYDAY. Numbered entries count within the month,
+// or within the year for yearly rules without BYMONTH.
+func (r Rule) matchesWeekday(d time.Time) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day != d.Weekday() {
+			continue
+		}
+		if wd.N == 0 {
+			return true
+		}
+		index, total := d.Day(), daysIn(d)
+		if r.Freq == Yearly && len(r.ByMonth) == 0 {
+			index = d.YearDay()
+			total = time.Date(d.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		}
+		if wd.N == (index-1)/7+1 || wd.N == -((total-index)/7+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days of the month of d.
+func daysIn(d time.Time) int {
+	return time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,137 @@
+package recurring_transaction_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 9, 0, 0, 0, time.UTC)
+}
+
+// first returns the first n occurrences of rule from start.
+func first(t *testing.T, rule string, start time.Time, n int) []time.Time {
+	t.Helper()
+	r, err := recurring_transaction.ParseRule(rule)
+	require.NoError(t, err, rule)
+	var got []time.Time
+	for on := range r.Occurrences(start) {
+		if len(got) == n {
+			break
+		}
+		got = append(got, on)
+	}
+	return got
+}
+
+func TestOccurrences(t *testing.T) {
+	cases := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{"monthly keeps the day, clamped to short months", "FREQ=MONTHLY", day(2026, 1, 31),
+			[]time.Time{day(2026, 1, 31), day(2026, 2, 28), day(2026, 3, 31), day(2026, 4, 30)}},
+		{"daily", "FREQ=DAILY", day(2026, 2, 27),
+			[]time.Time{day(2026, 2, 27), day(2026, 2, 28), day(2026, 3, 1)}},
+		// 2026-01-07 is a Wednesday
+		{"every other Friday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", day(2026, 1, 7),
+			[]time.Time{day(2026, 1, 9), day(2026, 1, 23), day(2026, 2, 6)}},
+		{"Mondays and Thursdays", "FREQ=WEEKLY;BYDAY=MO,TH", day(2026, 1, 7),
+			[]time.Time{day(2026, 1, 8), day(2026, 1, 12), day(2026, 1, 15)}},
+		{"the 25th", "FREQ=MONTHLY;BYMONTHDAY=25", day(2026, 1, 26),
+			[]time.Time{day(2026, 2, 25), day(2026, 3, 25)}},
+		{"the 1st and 15th", "FREQ=MONTHLY;BYMONTHDAY=1,15", day(2026, 1, 10),
+			[]time.Time{day(2026, 1, 15), day(2026, 2, 1), day(2026, 2, 15)}},
+		{"the last day", "FREQ=MONTHLY;BYMONTHDAY=-1", day(2026, 1, 1),
+			[]time.Time{day(2026, 1, 31), day(2026, 2, 28), day(2026, 3, 31)}},
+		{"the last business day", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", day(2026, 1, 1),
+			[]time.Time{day(2026, 1, 30), day(2026, 2, 27), day(2026, 3, 31), day(2026, 4, 30), day(2026, 5, 29)}},
+		{"the last Friday", "FREQ=MONTHLY;BYDAY=-1FR", day(2026, 1, 1),
+			[]time.Time{day(2026, 1, 30), day(2026, 2, 27), day(2026, 3, 27)}},
+		{"the second Monday", "FREQ=MONTHLY;BYDAY=2MO", day(2026, 1, 1),
+			[]time.Time{day(2026, 1, 12), day(2026, 2, 9), day(2026, 3, 9)}},
+		{"every quarter", "FREQ=MONTHLY;INTERVAL=3", day(2026, 1, 15),
+			[]time.Time{day(2026, 1, 15), day(2026, 4, 15), day(2026, 7, 15)}},
+		{"yearly keeps the date", "FREQ=YEARLY", day(2024, 2, 29),
+			[]time.Time{day(2024, 2, 29), day(2025, 2, 28), day(2026, 2, 28)}},
+		{"every March 1st", "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=1", day(2026, 3, 2),
+			[]time.Time{day(2027, 3, 1), day(2028, 3, 1)}},
+		{"Thanksgiving", "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", day(2026, 1, 1),
+			[]time.Time{day(2026, 11, 26), day(2027, 11, 25)}},
+		{"COUNT", "FREQ=WEEKLY;COUNT=3", day(2026, 1, 1),
+			[]time.Time{day(2026, 1, 1), day(2026, 1, 8), day(2026, 1, 15)}},
+		{"UNTIL includes its day", "FREQ=MONTHLY;UNTIL=20260315", day(2026, 1, 15),
+			[]time.Time{day(2026, 1, 15), day(2026, 2, 15), day(2026, 3, 15)}},
+		{"a rule that never matches ends", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", day(2026, 1, 1), nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, first(t, tc.rule, tc.start, max(len(tc.want), 1)))
+		})
+	}
+}
+
+func TestOccurrencesKeepLocation(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	start := time.Date(2026, 1, 31, 23, 30, 0, 0, jakarta)
+
+	got := first(t, "FREQ=MONTHLY;BYMONTHDAY=-1", start, 2)
+
+	assert.Equal(t, []time.Time{start, time.Date(2026, 2, 28, 23, 30, 0, 0, jakarta)}, got)
+}
+
+func TestAfter(t *testing.T) {
+	r, err := recurring_transaction.ParseRule("FREQ=WEEKLY;BYDAY=FR;COUNT=2")
+	require.NoError(t, err)
+
+	// 2026-01-01 is a Thursday
+	assert.Equal(t, day(2026, 1, 2), r.After(day(2026, 1, 1), day(2026, 1, 1)))
+	assert.Equal(t, day(2026, 1, 9), r.After(day(2026, 1, 1), day(2026, 1, 2)))
+	assert.True(t, r.After(day(2026, 1, 1), day(2026, 1, 9)).IsZero(), "ended")
+}
+
+func TestParseRule(t *testing.T) {
+	r, err := recurring_transaction.ParseRule("RRULE:freq=monthly; interval=2 ;bysetpos=-1;byday=MO,-1FR;until=20261231T000000Z")
+	require.NoError(t, err)
+	assert.Equal(t, recurring_transaction.Rule{
+		Freq:     recurring_transaction.Monthly,
+		Interval: 2,
+		ByDay:    []recurring_transaction.WeekdayNum{{Day: time.Monday}, {N: -1, Day: time.Friday}},
+		BySetPos: []int{-1},
+		Until:    time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+	}, r)
+	assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2;BYDAY=MO,-1FR;BYSETPOS=-1;UNTIL=20261231T000000Z", r.String())
+
+	again, err := recurring_transaction.ParseRule(r.String())
+	require.NoError(t, err)
+	assert.Equal(t, r, again)
+
+	invalid := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=DAILY;COUNT=3;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;WKST=SU",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ",
+	}
+	for _, s := range invalid {
+		_, err := recurring_transaction.ParseRule(s)
+		assert.Error(t, err, s)
+	}
+}
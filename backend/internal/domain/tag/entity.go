@@ -0,0 +1,67 @@
+package tag
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+var ErrTagNotFound = errors.New("tag not found")
+
+// MaxNameLength is the longest tag name accepted.
+const MaxNameLength = 100
+
+type Tag struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	// TransactionCount is the number of transactions carrying the tag.
+	TransactionCount int64 `json:"transaction_count"`
+}
+
+// Spending is the expense total of one tag in one currency.
+type Spending struct {
+	TagID    int64        `json:"tag_id"`
+	TagName  string       `json:"tag_name"`
+	Currency string       `json:"currency"`
+	Amount   money.Amount `json:"amount"`
+}
+
+// NormalizeName makes tag names case-insensitive: "Trip Bali" and
+// "trip bali " are the same tag.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// NormalizeNames normalizes names and drops blanks and duplicates, keeping
+// the first occurrence of each.
+func NormalizeNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		n = NormalizeName(n)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	return out
+}
+
+type Repository interface {
+	FindAllByUserID(userID int64) ([]Tag, error)
+	FindByID(id int64) (Tag, error)
+	FindByName(userID int64, name string) (Tag, error)
+	Rename(id int64, name string) error
+	// Merge moves every transaction of the source tags to target and
+	// deletes the source tags, in a single database transaction.
+	Merge(sourceIDs []int64, targetID int64) error
+	Delete(id int64) error
+	// GetSpending sums the expenses of each tag between from and to
+	// (inclusive) per currency. A transaction with several tags counts
+	// towards each of them.
+	GetSpending(userID int64, from, to time.Time) ([]Spending, error)
+}
@@ -1,55 +1,227 @@
 package transaction
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+var (
+	ErrNotFound = errors.New("transaction not found")
+	// ErrDuplicateExternalID is returned by Save when the account already
+	// has a transaction with the same ExternalID.
+	ErrDuplicateExternalID = errors.New("transaction already imported")
+)
+
+const (
+	TypeIncome      = "income"
+	TypeExpense     = "expense"
+	TypeTransferIn  = "transfer_in"
+	TypeTransferOut = "transfer_out"
+)
+
+// IsTransfer reports whether a transaction type is one leg of an
+// inter-account transfer. Transfer legs move money between accounts and are
+// never counted as income or expense.
+func IsTransfer(txType string) bool {
+	return txType == TypeTransferIn || txType == TypeTransferOut
+}
 
 type Transaction struct {
-	ID         int64     `json:"id"`
-	UserID     int64     `json:"user_id"`
-	CategoryID int64     `json:"category_id"`
-	Amount     float64   `json:"amount"`
-	Note       string    `json:"note"`
-	Date       time.Time `json:"date"`
-	Type       string    `json:"type"` // "income" or "expense"
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	AccountID  int64        `json:"account_id"`
+	CategoryID int64        `json:"category_id"`
+	TransferID int64        `json:"transfer_id,omitempty"`
+	Amount     money.Amount `json:"amount"`
+	Currency   string       `json:"currency"` // always the currency of the account
+	Note       string       `json:"note"`
+	Date       time.Time    `json:"date"`
+	Type       string       `json:"type"` // "income", "expense", "transfer_in" or "transfer_out"
+	// ExternalID is the bank's identifier for imported transactions (OFX
+	// FITID). It is unique per account so re-importing a statement is a no-op.
+	ExternalID string `json:"external_id,omitempty"`
+	// RecurringID is the recurring template the transaction was generated
+	// from. Each of its occurrences, identified by the UTC day of Date,
+	// generates at most one transaction.
+	RecurringID int64 `json:"recurring_id,omitempty"`
+	// Splits divide the transaction across several categories. When present
+	// they add up to Amount and CategoryID is the category of the first one.
+	Splits []Split `json:"splits,omitempty"`
+	// Tags are free-form labels such as "trip-bali-2026", stored
+	// normalized (see tag.NormalizeName).
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Split is one line of a transaction that is divided across categories.
+type Split struct {
+	ID            int64        `json:"id"`
+	TransactionID int64        `json:"transaction_id"`
+	CategoryID    int64        `json:"category_id"`
+	Amount        money.Amount `json:"amount"`
+	Note          string       `json:"note"`
 }
 
 type PaginatedTransactions struct {
 	Transactions []Transaction `json:"transactions"`
 	Total        int64         `json:"total"`
-	TotalAmount  float64       `json:"total_amount"`
+	// Totals sums the filtered income and expense per currency; transfers
+	// are left out.
+	Totals []CurrencySummary `json:"totals"`
 }
 
 type ReportTransaction struct {
-	ID           int64     `json:"id"`
-	UserID       int64     `json:"user_id"`
-	CategoryID   int64     `json:"category_id"`
-	CategoryName string    `json:"category_name"`
-	Color        string    `json:"color"`
-	Amount       float64   `json:"amount"`
-	Note         string    `json:"note"`
-	Date         time.Time `json:"date"`
-	Type         string    `json:"type"` // "income" or "expense"
+	ID           int64        `json:"id"`
+	UserID       int64        `json:"user_id"`
+	AccountID    int64        `json:"account_id"`
+	AccountName  string       `json:"account_name,omitempty"`
+	CategoryID   int64        `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	Color        string       `json:"color"`
+	Amount       money.Amount `json:"amount"`
+	Currency     string       `json:"currency"`
+	Note         string       `json:"note"`
+	Date         time.Time    `json:"date"`
+	Type         string       `json:"type"` // "income" or "expense"
 }
 
 type Filter struct {
+	AccountID  int64     `json:"account_id"`
 	CategoryID int64     `json:"category_id"`
 	Type       string    `json:"type"`
 	StartDate  time.Time `json:"start_date"`
 	EndDate    time.Time `json:"end_date"`
 	Search     string    `json:"search"`
+	// Tags restricts the result to transactions carrying any (the default)
+	// or, with TagMatch "all", every one of the tags.
+	Tags     []string `json:"tags"`
+	TagMatch string   `json:"tag_match"`
 }
 
+// Tag match modes for Filter.TagMatch.
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// DashboardSummary totals income and expense in the user's base currency.
+// ByCurrency keeps the unconverted totals per currency together with the
+// rate used to convert each of them.
 type DashboardSummary struct {
-	TotalIncome  float64 `json:"total_income"`
-	TotalExpense float64 `json:"total_expense"`
-	Balance      float64 `json:"balance"`
+	Currency     string            `json:"currency"`
+	TotalIncome  money.Amount      `json:"total_income"`
+	TotalExpense money.Amount      `json:"total_expense"`
+	Balance      money.Amount      `json:"balance"`
+	ByCurrency   []CurrencySummary `json:"by_currency"`
+}
+
+type CurrencySummary struct {
+	Currency     string       `json:"currency"`
+	TotalIncome  money.Amount `json:"total_income"`
+	TotalExpense money.Amount `json:"total_expense"`
+	Conversion   fx.Quote     `json:"conversion"`
+}
+
+// CategoryTotal is the sum of a category's amounts in one currency.
+type CategoryTotal struct {
+	CategoryID   int64        `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	Color        string       `json:"color"`
+	Currency     string       `json:"currency"`
+	Amount       money.Amount `json:"amount"`
+}
+
+// Trend intervals, matching PostgreSQL's date_trunc fields. Weeks start on
+// Monday.
+const (
+	IntervalDay     = "day"
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalQuarter = "quarter"
+	IntervalYear    = "year"
+)
+
+// Trend groupings. An empty GroupBy totals all transactions together.
+const (
+	GroupByCategory = "category"
+	GroupByType     = "type"
+	GroupByAccount  = "account"
+)
+
+// TrendQuery selects the income and expense between the days From and To,
+// both inclusive, in periods of Interval.
+type TrendQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	GroupBy  string
+}
+
+// TrendRow is the income and expense of one group in one currency during
+// the period starting at Period. GroupID is zero for groupings without an
+// ID, such as the transaction type.
+type TrendRow struct {
+	Period    time.Time
+	GroupID   int64
+	GroupName string
+	Currency  string
+	Income    money.Amount
+	Expense   money.Amount
 }
 
 type Repository interface {
 	FindAllByUserID(userID int64, limit, offset int, filter Filter) ([]Transaction, error)
-	GetTotalAndSum(userID int64, filter Filter) (int64, float64, error)
-	GetCategorySpending(userID int64, limit, offset int, filter Filter) ([]ReportTransaction, error)
-	FindByID(id int64) (Transaction, error)
+	Count(userID int64, filter Filter) (int64, error)
+	// SumByCurrency totals income and expense per currency, ordered by
+	// currency. Transfers are not counted and Conversion is left empty.
+	SumByCurrency(userID int64, filter Filter) ([]CurrencySummary, error)
+	// SumByCategory totals amounts per category and currency, largest
+	// first. Splits count towards their own category, which is also the
+	// one filter.CategoryID is matched against.
+	SumByCategory(userID int64, filter Filter) ([]CategoryTotal, error)
+	// Trend returns a row for every period between query.From and query.To
+	// and every group and currency seen in that range, with zeros where
+	// nothing was recorded, ordered by period. Periods are in UTC. When
+	// there are no transactions at all, each period has a single row with
+	// an empty group and currency.
+	Trend(userID int64, query TrendQuery) ([]TrendRow, error)
+	// StreamReport calls fn for every transaction matching filter, newest
+	// first, without loading them all into memory. Iteration stops at the
+	// first error returned by fn.
+	StreamReport(userID int64, filter Filter, fn func(ReportTransaction) error) error
+	// FindByIDForUser returns ErrNotFound when the transaction does not
+	// exist or belongs to another user.
+	FindByIDForUser(userID, id int64) (Transaction, error)
+	// Save returns ErrDuplicateExternalID, without failing an enclosing
+	// InTx, when transaction.ExternalID is already on the account.
 	Save(transaction *Transaction) error
+	// SaveOccurrence saves a transaction generated for the occurrence of
+	// the recurring template transaction.RecurringID on the day of
+	// transaction.Date, unless that occurrence already has one, and
+	// reports whether it did.
+	SaveOccurrence(transaction *Transaction) (bool, error)
+	// Update only changes the row when it belongs to transaction.UserID.
 	Update(transaction *Transaction) error
-	Delete(id int64) error
+	Delete(userID, id int64) error
+	// FindSplits returns the splits of the given transactions keyed by
+	// transaction ID. Transactions without splits are absent.
+	FindSplits(transactionIDs []int64) (map[int64][]Split, error)
+	// SaveSplits replaces the splits of a transaction. An empty slice
+	// removes them.
+	SaveSplits(transactionID int64, splits []Split) error
+	// FindTags returns the tag names of the given transactions keyed by
+	// transaction ID.
+	FindTags(transactionIDs []int64) (map[int64][]string, error)
+	// SetTags replaces the tags of a transaction, creating tags the user
+	// does not have yet. An empty slice removes them.
+	SetTags(transactionID, userID int64, names []string) error
+	// FindByExternalIDs returns the IDs of the account's transactions whose
+	// ExternalID is one of ids, keyed by ExternalID.
+	FindByExternalIDs(accountID int64, ids []string) (map[string]int64, error)
+	// InTx runs fn with a repository bound to a single database transaction.
+	// The transaction is committed when fn returns nil and rolled back
+	// otherwise.
+	InTx(fn func(repo Repository) error) error
 }
@@ -1,6 +1,29 @@
 package user
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrTokenNotFound   = errors.New("token not found")
+)
+
+// Purposes of the single-use tokens handed to users.
+const (
+	// TokenVerifyEmail confirms that an address belongs to the user.
+	TokenVerifyEmail = "verify_email"
+	// TokenResetPassword lets a user who forgot their password choose a
+	// new one.
+	TokenResetPassword = "reset_password"
+	// TokenChangeEmail confirms the address a user asked to change their
+	// email to.
+	TokenChangeEmail = "change_email"
+	// TokenOAuthLogin is exchanged by the frontend for the session of a
+	// Google login, so no token travels in the redirect URL.
+	TokenOAuthLogin = "oauth_login"
+)
 
 type User struct {
 	ID          int64    `json:"id"`
@@ -12,6 +35,38 @@ type User struct {
 	IsActive    bool     `json:"is_active"`
 	TOTPSecret  string   `json:"-"`
 	TOTPEnabled bool     `json:"totp_enabled"`
+	// EmailVerified is false for self-registered accounts until the link
+	// emailed to them is followed; they cannot log in before that.
+	EmailVerified bool `json:"email_verified"`
+	// BaseCurrency is the currency dashboards, reports and budgets are
+	// converted into.
+	BaseCurrency string `json:"base_currency"`
+	// Locale is a BCP 47 language tag such as id-ID, and Timezone an IANA
+	// time zone name such as Asia/Jakarta.
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+	// PendingEmail is the address the user asked to change Email to. It
+	// replaces Email once the link emailed to it is followed.
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+// ProfileUpdateRequest changes the fields of the caller's own account that
+// are set.
+type ProfileUpdateRequest struct {
+	Name         *string `json:"name"`
+	Email        *string `json:"email" binding:"omitempty,email"`
+	Locale       *string `json:"locale"`
+	Timezone     *string `json:"timezone"`
+	BaseCurrency *string `json:"base_currency"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type RolesRequest struct {
+	Roles []string `json:"roles" binding:"required,min=1"`
 }
 
 type LoginRequest struct {
@@ -19,16 +74,74 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RegisterRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 type LoginResponse struct {
-	Token       string `json:"token,omitempty"`
-	Requires2FA bool   `json:"requires_2fa,omitempty"`
-	TempToken   string `json:"temp_token,omitempty"`
+	Token string `json:"token,omitempty"`
+	// RefreshToken gets a new Token once it expires, ExpiresIn seconds
+	// from now, through POST /auth/refresh.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	Requires2FA  bool   `json:"requires_2fa,omitempty"`
+	TempToken    string `json:"temp_token,omitempty"`
+}
+
+type OAuthExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Device is where a session is used from.
+type Device struct {
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+}
+
+// Session is a login on a device. It lasts as long as its refresh token is
+// used before ExpiresAt. Each refresh rotates the token, and presenting a
+// token that was already rotated revokes the session, since it means the
+// token leaked.
+type Session struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+	Device
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// Current marks the session of the request listing them.
+	Current bool `json:"current"`
+}
+
+// Active reports whether the session can still be refreshed at now.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
 }
 
 type PasswordResetRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+type PasswordResetTokenRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 type TwoFASetupResponse struct {
 	Secret string `json:"secret"`
 	QRCode string `json:"qr_code"`
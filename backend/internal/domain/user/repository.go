@@ -1,12 +1,16 @@
 package user
 
+import "time"
+
 type UserRepository interface {
 	FindAll(limit, offset int) ([]User, error)
 	FindByID(id int64) (User, error)
 	FindByEmail(email string) (User, error)
 	FindByGoogleID(googleID string) (User, error)
 	Save(user User) error
+	// Update saves every field of the user but Roles.
 	Update(user User) error
+	UpdateRoles(id int64, roles []string) error
 	Delete(id int64) error
 	DisableTOTP(user User) error
 	UpdateTOTPSecret(user User) error
@@ -17,6 +21,23 @@ type AuthService interface {
 	Login(email, password string) (bool, error)
 }
 
+// Mailer sends plain text email.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// TokenRepository keeps the single-use tokens handed to users by their
+// SHA-256 hash.
+type TokenRepository interface {
+	// Create issues tokenHash to the user for purpose, invalidating the
+	// user's earlier unused tokens for it.
+	Create(userID int64, purpose, tokenHash string, expiresAt time.Time) error
+	// Consume marks the token used and returns its user. It returns
+	// ErrTokenNotFound when no unused token for purpose has that hash or
+	// the token expired before now.
+	Consume(purpose, tokenHash string, now time.Time) (int64, error)
+}
+
 type OauthStateRepository interface {
 	Save(state OauthState) error
 	FindByState(state string) (*OauthState, error)
@@ -34,3 +55,24 @@ type MFABackupCodeRepository interface {
 	MarkUsed(id int64) error
 	DeleteByUserID(userID int64) error
 }
+
+type SessionRepository interface {
+	// Create saves the session and tokenHash as its first refresh token.
+	Create(s *Session, tokenHash string) error
+	// FindByTokenHash returns the session a refresh token was issued for
+	// and whether the token was already used. It returns ErrSessionNotFound
+	// when no such token was issued.
+	FindByTokenHash(tokenHash string) (Session, bool, error)
+	// Rotate marks the refresh token oldHash used and issues newHash for
+	// session id instead, recording the device and the new expiry. It
+	// reports false, changing nothing, when oldHash was already used.
+	Rotate(id int64, oldHash, newHash string, device Device, expiresAt time.Time) (bool, error)
+	// FindActiveByUserID returns the user's sessions that are neither
+	// revoked nor expired at now, most recently used first.
+	FindActiveByUserID(userID int64, now time.Time) ([]Session, error)
+	// Revoke returns ErrSessionNotFound when the session does not exist,
+	// belongs to another user or is already revoked.
+	Revoke(userID, id int64) error
+	// RevokeAllByUserID revokes the user's sessions but exceptID.
+	RevokeAllByUserID(userID, exceptID int64) error
+}
@@ -0,0 +1,27 @@
+package mail
+
+import (
+	"log"
+
+	"github.com/afandimsr/cashbook-backend/internal/config"
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+)
+
+// New returns a mailer sending through the configured SMTP server, or one
+// that only logs emails when no server is configured.
+func New(cfg config.SMTPConfig) (user.Mailer, error) {
+	if cfg.Host == "" {
+		log.Println("SMTP_HOST is not set, emails will only be logged")
+		return logMailer{}, nil
+	}
+	return NewSMTP(cfg)
+}
+
+// logMailer writes emails to the log, which is enough to follow their links
+// in development.
+type logMailer struct{}
+
+func (logMailer) Send(to, subject, body string) error {
+	log.Printf("mail to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/config"
+)
+
+// SMTP sends email through an SMTP server, upgrading to TLS when the server
+// offers STARTTLS. net/smtp refuses to send credentials over a plain
+// connection to anything but localhost.
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from *mail.Address
+}
+
+func NewSMTP(cfg config.SMTPConfig) (*SMTP, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+
+	s := &SMTP{addr: net.JoinHostPort(cfg.Host, cfg.Port), from: from}
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return s, nil
+}
+
+func (s *SMTP) Send(to, subject, body string) error {
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		return errors.New("subject must be a single line")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", rcpt)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return smtp.SendMail(s.addr, s.auth, s.from.Address, []string{rcpt.Address}, msg.Bytes())
+}
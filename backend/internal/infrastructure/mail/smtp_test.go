@@ -0,0 +1,158 @@
+package mail_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/config"
+	"github.com/afandimsr/cashbook-backend/internal/infrastructure/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// message is an email as the catcher received it.
+type message struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// catcher is a local SMTP server that accepts every email and keeps it.
+type catcher struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []message
+}
+
+func newCatcher(t *testing.T) *catcher {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	c := &catcher{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go c.serve(conn)
+		}
+	}()
+	return c
+}
+
+func (c *catcher) config() config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(c.ln.Addr().String())
+	return config.SMTPConfig{Host: host, Port: port, From: "Cashbook <no-reply@cashbook.test>"}
+}
+
+func (c *catcher) serve(conn net.Conn) {
+	defer conn.Close()
+	r := textproto.NewReader(bufio.NewReader(conn))
+	w := textproto.NewWriter(bufio.NewWriter(conn))
+	var msg message
+
+	w.PrintfLine("220 catcher ready")
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			w.PrintfLine("250-catcher")
+			w.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			_, credentials, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			msg.auth = string(decoded)
+			w.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			w.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			w.PrintfLine("250 ok")
+		case "DATA":
+			w.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(r.DotReader())
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			c.mu.Lock()
+			c.messages = append(c.messages, msg)
+			c.mu.Unlock()
+			msg = message{}
+			w.PrintfLine("250 queued")
+		case "QUIT":
+			w.PrintfLine("221 bye")
+			return
+		default:
+			w.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (c *catcher) received() []message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]message(nil), c.messages...)
+}
+
+func TestSMTPSend(t *testing.T) {
+	c := newCatcher(t)
+	mailer, err := mail.NewSMTP(c.config())
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send("Alice <alice@example.com>", "Verify your email", "Hello Alice,\nfollow the link.\n"))
+
+	messages := c.received()
+	require.Len(t, messages, 1)
+	msg := messages[0]
+	assert.Empty(t, msg.auth)
+	assert.Equal(t, "no-reply@cashbook.test", msg.from)
+	assert.Equal(t, []string{"alice@example.com"}, msg.to)
+	assert.Contains(t, msg.data, "From: \"Cashbook\" <no-reply@cashbook.test>\n")
+	assert.Contains(t, msg.data, "To: \"Alice\" <alice@example.com>\n")
+	assert.Contains(t, msg.data, "Subject: Verify your email\n")
+	assert.Contains(t, msg.data, "Content-Type: text/plain; charset=utf-8\n")
+	assert.True(t, strings.HasSuffix(msg.data, "\n\nHello Alice,\nfollow the link.\n"), msg.data)
+}
+
+func TestSMTPSendAuthenticates(t *testing.T) {
+	c := newCatcher(t)
+	cfg := c.config()
+	cfg.Username, cfg.Password = "cashbook", "s3cret"
+	mailer, err := mail.NewSMTP(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send("alice@example.com", "Hi", "body"))
+
+	messages := c.received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "\x00cashbook\x00s3cret", messages[0].auth)
+}
+
+func TestSMTPSendRejectsHeaderInjection(t *testing.T) {
+	c := newCatcher(t)
+	mailer, err := mail.NewSMTP(c.config())
+	require.NoError(t, err)
+
+	assert.Error(t, mailer.Send("alice@example.com", "Hi\r\nBcc: eve@example.com", "body"))
+	assert.Error(t, mailer.Send("alice@example.com\r\nBcc: eve@example.com", "Hi", "body"))
+	assert.Empty(t, c.received())
+}
+
+func TestNewSMTPRejectsInvalidFrom(t *testing.T) {
+	_, err := mail.NewSMTP(config.SMTPConfig{Host: "localhost", Port: "25", From: "not an address"})
+	assert.Error(t, err)
+}
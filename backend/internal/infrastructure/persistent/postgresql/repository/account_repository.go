@@ -0,0 +1,150 @@
+package postgresql
+
+import (
+	"database/sql"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+)
+
+type accountRepo struct {
+	db *sql.DB
+}
+
+func NewAccountRepo(db *sql.DB) account.Repository {
+	return &accountRepo{db: db}
+}
+
+// accountSelect computes the running balance from the opening balance and
+// every posted transaction leg on the account.
+const accountSelect = `
+	SELECT a.id, a.user_id, a.name, a.type, a.currency, a.opening_balance,
+		a.opening_balance + COALESCE((
+			SELECT SUM(CASE WHEN t.type IN ('income', 'transfer_in') THEN t.amount ELSE -t.amount END)
+			FROM transactions t WHERE t.account_id = a.id
+		), 0) AS balance,
+		a.created_at
+	FROM accounts a`
+
+func (r *accountRepo) FindAllByUserID(userID int64) ([]account.Account, error) {
+	rows, err := r.db.Query(accountSelect+" WHERE a.user_id = $1 ORDER BY a.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []account.Account
+	for rows.Next() {
+		var a account.Account
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Name, &a.Type, &a.Currency, &a.OpeningBalance, &a.Balance, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+func (r *accountRepo) FindByID(id int64) (account.Account, error) {
+	var a account.Account
+	err := r.db.QueryRow(accountSelect+" WHERE a.id = $1", id).
+		Scan(&a.ID, &a.UserID, &a.Name, &a.Type, &a.Currency, &a.OpeningBalance, &a.Balance, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, account.ErrNotFound
+	}
+	return a, err
+}
+
+func (r *accountRepo) FindDefault(userID int64) (account.Account, error) {
+	var a account.Account
+	err := r.db.QueryRow(accountSelect+" WHERE a.user_id = $1 ORDER BY a.id LIMIT 1", userID).
+		Scan(&a.ID, &a.UserID, &a.Name, &a.Type, &a.Currency, &a.OpeningBalance, &a.Balance, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, account.ErrNotFound
+	}
+	return a, err
+}
+
+func (r *accountRepo) Save(a *account.Account) error {
+	return r.db.QueryRow(
+		"INSERT INTO accounts(user_id, name, type, currency, opening_balance) VALUES($1, $2, $3, $4, $5) RETURNING id, created_at",
+		a.UserID, a.Name, a.Type, a.Currency, a.OpeningBalance,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *accountRepo) Update(a *account.Account) error {
+	_, err := r.db.Exec(
+		"UPDATE accounts SET name = $1, type = $2, opening_balance = $3 WHERE id = $4",
+		a.Name, a.Type, a.OpeningBalance, a.ID,
+	)
+	return err
+}
+
+// Delete removes the account. Accounts that still have transactions,
+// transfers or recurring transactions are refused by their foreign keys and
+// reported as ErrInUse.
+func (r *accountRepo) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM accounts WHERE id = $1", id)
+	if isForeignKeyViolation(err) {
+		return account.ErrInUse
+	}
+	return err
+}
+
+func (r *accountRepo) FindTransferByID(id int64) (account.Transfer, error) {
+	var tr account.Transfer
+	var note sql.NullString
+	err := r.db.QueryRow(
+		"SELECT id, user_id, from_account_id, to_account_id, amount, note, date FROM transfers WHERE id = $1",
+		id,
+	).Scan(&tr.ID, &tr.UserID, &tr.FromAccountID, &tr.ToAccountID, &tr.Amount, &note, &tr.Date)
+	if err == sql.ErrNoRows {
+		return tr, account.ErrTransferNotFound
+	}
+	tr.Note = note.String
+	return tr, err
+}
+
+// SaveTransfer writes the transfer row and both transaction legs in a single
+// database transaction so a transfer is never half-applied.
+func (r *accountRepo) SaveTransfer(tr *account.Transfer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO transfers(user_id, from_account_id, to_account_id, amount, note, date) VALUES($1, $2, $3, $4, $5, $6) RETURNING id",
+		tr.UserID, tr.FromAccountID, tr.ToAccountID, tr.Amount, tr.Note, tr.Date,
+	).Scan(&tr.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	legs := []struct {
+		accountID int64
+		txType    string
+	}{
+		{tr.FromAccountID, transaction.TypeTransferOut},
+		{tr.ToAccountID, transaction.TypeTransferIn},
+	}
+	for _, leg := range legs {
+		_, err = tx.Exec(
+			"INSERT INTO transactions(user_id, account_id, transfer_id, amount, currency, note, date, type) VALUES($1, $2, $3, $4, (SELECT currency FROM accounts WHERE id = $2), $5, $6, $7)",
+			tr.UserID, leg.accountID, tr.ID, tr.Amount, tr.Note, tr.Date, leg.txType,
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteTransfer removes the transfer; both legs go with it through the
+// ON DELETE CASCADE on transactions.transfer_id.
+func (r *accountRepo) DeleteTransfer(id int64) error {
+	_, err := r.db.Exec("DELETE FROM transfers WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,45 @@
+package postgresql_test
+
+import (
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	postgresql "github.com/afandimsr/cashbook-backend/internal/infrastructure/persistent/postgresql/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteAccountInUse(t *testing.T) {
+	db := openTestDB(t)
+	s := seedTransactions(t, db, 10)
+	repo := postgresql.NewAccountRepo(db)
+
+	require.ErrorIs(t, repo.Delete(s.accounts[0]), account.ErrInUse)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM transactions WHERE account_id = $1", s.accounts[0]).Scan(&count))
+	require.NotZero(t, count, "history survives the refused delete")
+
+	var empty int64
+	require.NoError(t, db.QueryRow("INSERT INTO accounts (user_id, name, currency) VALUES ($1, 'empty', 'IDR') RETURNING id", s.userID).Scan(&empty))
+	require.NoError(t, repo.Delete(empty))
+
+	// deleting the user still takes the accounts and their history with it
+	_, err := db.Exec("DELETE FROM users WHERE id = $1", s.userID)
+	require.NoError(t, err)
+}
+
+func TestDeleteAccountWithRecurringTransactions(t *testing.T) {
+	db := openTestDB(t)
+	s := seedTransactions(t, db, 0)
+	repo := postgresql.NewAccountRepo(db)
+
+	var accountID, recurringID int64
+	require.NoError(t, db.QueryRow("INSERT INTO accounts (user_id, name, currency) VALUES ($1, 'rent', 'IDR') RETURNING id", s.userID).Scan(&accountID))
+	require.NoError(t, db.QueryRow("INSERT INTO recurring_transactions (user_id, account_id, category_id, amount, type, note, frequency, start_date) VALUES ($1, $2, $3, 10, 'expense', 'rent', 'monthly', '2026-01-01T09:00:00Z') RETURNING id", s.userID, accountID, s.categories[0]).Scan(&recurringID))
+
+	require.ErrorIs(t, repo.Delete(accountID), account.ErrInUse)
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM recurring_transactions WHERE id = $1", recurringID).Scan(&n))
+	require.Equal(t, 1, n, "the template survives the refused delete")
+}
@@ -0,0 +1,72 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
+)
+
+type attachmentRepo struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepo(db *sql.DB) attachment.Repository {
+	return &attachmentRepo{db: db}
+}
+
+const attachmentSelect = "SELECT id, user_id, transaction_id, file_name, content_type, size, storage_key, created_at FROM attachments"
+
+func (r *attachmentRepo) FindAllByTransactionID(transactionID int64) ([]attachment.Attachment, error) {
+	return r.query(attachmentSelect+" WHERE transaction_id = $1 ORDER BY created_at, id", transactionID)
+}
+
+func (r *attachmentRepo) FindByID(id int64) (attachment.Attachment, error) {
+	a, err := scanAttachment(r.db.QueryRow(attachmentSelect+" WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, attachment.ErrAttachmentNotFound
+	}
+	return a, err
+}
+
+func (r *attachmentRepo) Save(a *attachment.Attachment) error {
+	return r.db.QueryRow(
+		"INSERT INTO attachments(user_id, transaction_id, file_name, content_type, size, storage_key) VALUES($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		a.UserID, a.TransactionID, a.FileName, a.ContentType, a.Size, a.StorageKey,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *attachmentRepo) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM attachments WHERE id = $1", id)
+	return err
+}
+
+func (r *attachmentRepo) FindOrphaned(limit int) ([]attachment.Attachment, error) {
+	return r.query(attachmentSelect+" WHERE transaction_id IS NULL ORDER BY id LIMIT $1", limit)
+}
+
+func (r *attachmentRepo) query(q string, args ...interface{}) ([]attachment.Attachment, error) {
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []attachment.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func scanAttachment(row rowScanner) (attachment.Attachment, error) {
+	var a attachment.Attachment
+	var transactionID sql.NullInt64
+	err := row.Scan(&a.ID, &a.UserID, &transactionID, &a.FileName, &a.ContentType, &a.Size, &a.StorageKey, &a.CreatedAt)
+	a.TransactionID = transactionID.Int64
+	return a, err
+}
@@ -3,6 +3,9 @@ package postgresql
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/afandimsr/cashbook-backend/internal/domain/budget"
 )
@@ -15,11 +18,60 @@ func NewBudgetRepo(db *sql.DB) budget.Repository {
 	return &budgetRepo{db: db}
 }
 
-func (r *budgetRepo) FindAllByUserID(userID int64, month, year int) ([]budget.Budget, error) {
-	rows, err := r.db.Query(
-		"SELECT id, user_id, category_id, amount, month, year FROM budgets WHERE user_id = $1 AND month = $2 AND year = $3",
-		userID, month, year,
+const budgetColumns = "b.id, b.user_id, b.category_id, COALESCE(c.name, ''), COALESCE(c.color, ''), b.amount, b.currency, b.period, b.start_date, b.rollover, b.carried_over, b.auto_copy"
+
+const budgetFrom = " FROM budgets b LEFT JOIN categories c ON c.id = b.category_id"
+
+// dates are passed as text so the session time zone cannot shift them
+const dateLayout = "2006-01-02"
+
+func (r *budgetRepo) FindAllByUserID(userID int64, on time.Time, periods ...string) ([]budget.Budget, error) {
+	if len(periods) == 0 {
+		periods = budget.Periods
+	}
+	args := []interface{}{userID}
+	var spans []string
+	for _, period := range periods {
+		args = append(args, period, budget.PeriodStart(period, on).Format(dateLayout))
+		spans = append(spans, fmt.Sprintf("($%d, $%d::date)", len(args)-1, len(args)))
+	}
+	return r.query(
+		"SELECT "+budgetColumns+budgetFrom+" WHERE b.user_id = $1 AND (b.period, b.start_date) IN ("+strings.Join(spans, ", ")+") ORDER BY b.start_date DESC, b.category_id NULLS FIRST, b.id",
+		args...,
 	)
+}
+
+func (r *budgetRepo) FindByIDForUser(userID, id int64) (budget.Budget, error) {
+	return r.queryRow(
+		"SELECT "+budgetColumns+budgetFrom+" WHERE b.id = $1 AND b.user_id = $2",
+		id, userID,
+	)
+}
+
+func (r *budgetRepo) FindByCategory(userID int64, categoryID int64, period string, start time.Time) (budget.Budget, error) {
+	return r.queryRow(
+		"SELECT "+budgetColumns+budgetFrom+" WHERE b.user_id = $1 AND COALESCE(b.category_id, 0) = $2 AND b.period = $3 AND b.start_date = $4",
+		userID, categoryID, period, start.Format(dateLayout),
+	)
+}
+
+func (r *budgetRepo) FindUncopied(period string, start time.Time) ([]budget.Budget, error) {
+	return r.query(
+		"SELECT "+budgetColumns+budgetFrom+" WHERE b.auto_copy AND b.period = $1 AND b.start_date = $2 AND NOT EXISTS (SELECT 1 FROM budgets n WHERE n.user_id = b.user_id AND COALESCE(n.category_id, 0) = COALESCE(b.category_id, 0) AND n.period = b.period AND n.start_date = $3) ORDER BY b.user_id, b.id",
+		period, start.Format(dateLayout), budget.NextPeriod(period, start).Format(dateLayout),
+	)
+}
+
+func (r *budgetRepo) queryRow(query string, args ...interface{}) (budget.Budget, error) {
+	b, err := scanBudget(r.db.QueryRow(query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return b, budget.ErrNotFound
+	}
+	return b, err
+}
+
+func (r *budgetRepo) query(query string, args ...interface{}) ([]budget.Budget, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -27,46 +79,52 @@ func (r *budgetRepo) FindAllByUserID(userID int64, month, year int) ([]budget.Bu
 
 	var budgets []budget.Budget
 	for rows.Next() {
-		var b budget.Budget
-		if err := rows.Scan(&b.ID, &b.UserID, &b.CategoryID, &b.Amount, &b.Month, &b.Year); err != nil {
+		b, err := scanBudget(rows)
+		if err != nil {
 			return nil, err
 		}
 		budgets = append(budgets, b)
 	}
-	return budgets, nil
-}
-
-func (r *budgetRepo) FindByCategory(userID int64, categoryID int64, month, year int) (budget.Budget, error) {
-	var b budget.Budget
-	err := r.db.QueryRow(
-		"SELECT id, user_id, category_id, amount, month, year FROM budgets WHERE user_id = $1 AND category_id = $2 AND month = $3 AND year = $4",
-		userID, categoryID, month, year,
-	).Scan(&b.ID, &b.UserID, &b.CategoryID, &b.Amount, &b.Month, &b.Year)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return b, errors.New("budget not found")
-		}
-		return b, err
-	}
-	return b, nil
+	return budgets, rows.Err()
 }
 
 func (r *budgetRepo) Save(b *budget.Budget) error {
 	return r.db.QueryRow(
-		"INSERT INTO budgets(user_id, category_id, amount, month, year) VALUES($1, $2, $3, $4, $5) RETURNING id",
-		b.UserID, b.CategoryID, b.Amount, b.Month, b.Year,
+		"INSERT INTO budgets(user_id, category_id, amount, currency, period, start_date, rollover, carried_over, auto_copy) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+		b.UserID, nullInt64(b.CategoryID), b.Amount, b.Currency, b.Period, b.StartDate.Format(dateLayout), b.Rollover, b.CarriedOver, b.AutoCopy,
 	).Scan(&b.ID)
 }
 
+func (r *budgetRepo) SaveIfAbsent(b *budget.Budget) (bool, error) {
+	err := r.db.QueryRow(
+		"INSERT INTO budgets(user_id, category_id, amount, currency, period, start_date, rollover, carried_over, auto_copy) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (user_id, period, start_date, COALESCE(category_id, 0)) DO NOTHING RETURNING id",
+		b.UserID, nullInt64(b.CategoryID), b.Amount, b.Currency, b.Period, b.StartDate.Format(dateLayout), b.Rollover, b.CarriedOver, b.AutoCopy,
+	).Scan(&b.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (r *budgetRepo) Update(b *budget.Budget) error {
 	_, err := r.db.Exec(
-		"UPDATE budgets SET amount = $1 WHERE id = $2",
-		b.Amount, b.ID,
+		"UPDATE budgets SET amount = $1, currency = $2, rollover = $3, carried_over = $4, auto_copy = $5 WHERE id = $6 AND user_id = $7",
+		b.Amount, b.Currency, b.Rollover, b.CarriedOver, b.AutoCopy, b.ID, b.UserID,
 	)
 	return err
 }
 
-func (r *budgetRepo) Delete(id int64) error {
-	_, err := r.db.Exec("DELETE FROM budgets WHERE id = $1", id)
+func (r *budgetRepo) Delete(userID, id int64) error {
+	_, err := r.db.Exec("DELETE FROM budgets WHERE id = $1 AND user_id = $2", id, userID)
 	return err
 }
+
+func scanBudget(row rowScanner) (budget.Budget, error) {
+	var b budget.Budget
+	var categoryID sql.NullInt64
+	var start time.Time
+	err := row.Scan(&b.ID, &b.UserID, &categoryID, &b.CategoryName, &b.CategoryColor, &b.Amount, &b.Currency, &b.Period, &start, &b.Rollover, &b.CarriedOver, &b.AutoCopy)
+	b.CategoryID = categoryID.Int64
+	b.SetStart(time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC))
+	return b, err
+}
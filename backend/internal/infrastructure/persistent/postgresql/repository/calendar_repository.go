@@ -0,0 +1,46 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/calendar"
+)
+
+type calendarRepo struct {
+	db *sql.DB
+}
+
+func NewCalendarRepo(db *sql.DB) calendar.Repository {
+	return &calendarRepo{db: db}
+}
+
+func (r *calendarRepo) FindByUserID(userID int64) (calendar.Feed, error) {
+	return r.queryRow("SELECT user_id, token_hash, created_at FROM calendar_feeds WHERE user_id = $1", userID)
+}
+
+func (r *calendarRepo) FindByTokenHash(hash string) (calendar.Feed, error) {
+	return r.queryRow("SELECT user_id, token_hash, created_at FROM calendar_feeds WHERE token_hash = $1", hash)
+}
+
+func (r *calendarRepo) queryRow(query string, args ...interface{}) (calendar.Feed, error) {
+	var f calendar.Feed
+	err := r.db.QueryRow(query, args...).Scan(&f.UserID, &f.TokenHash, &f.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, calendar.ErrNotFound
+	}
+	return f, err
+}
+
+func (r *calendarRepo) Save(f *calendar.Feed) error {
+	return r.db.QueryRow(`
+		INSERT INTO calendar_feeds(user_id, token_hash) VALUES($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW()
+		RETURNING created_at
+	`, f.UserID, f.TokenHash).Scan(&f.CreatedAt)
+}
+
+func (r *calendarRepo) Delete(userID int64) error {
+	_, err := r.db.Exec("DELETE FROM calendar_feeds WHERE user_id = $1", userID)
+	return err
+}
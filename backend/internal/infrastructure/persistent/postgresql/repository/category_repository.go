@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"database/sql"
+	"errors"
 
 	"github.com/afandimsr/cashbook-backend/internal/domain/category"
 )
@@ -33,9 +34,12 @@ func (r *categoryRepo) FindAllByUserID(userID int64) ([]category.Category, error
 	return categories, nil
 }
 
-func (r *categoryRepo) FindByID(id int64) (category.Category, error) {
+func (r *categoryRepo) FindByIDForUser(userID, id int64) (category.Category, error) {
 	var c category.Category
-	err := r.db.QueryRow("SELECT id, user_id, name, type, color, icon FROM categories WHERE id = $1", id).Scan(&c.ID, &c.UserID, &c.Name, &c.Type, &c.Color, &c.Icon)
+	err := r.db.QueryRow("SELECT id, user_id, name, type, color, icon FROM categories WHERE id = $1 AND user_id = $2", id, userID).Scan(&c.ID, &c.UserID, &c.Name, &c.Type, &c.Color, &c.Icon)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, category.ErrNotFound
+	}
 	return c, err
 }
 
@@ -48,13 +52,19 @@ func (r *categoryRepo) Save(c *category.Category) error {
 
 func (r *categoryRepo) Update(c *category.Category) error {
 	_, err := r.db.Exec(
-		"UPDATE categories SET name = $1, type = $2, color = $3, icon = $4 WHERE id = $5",
-		c.Name, c.Type, c.Color, c.Icon, c.ID,
+		"UPDATE categories SET name = $1, type = $2, color = $3, icon = $4 WHERE id = $5 AND user_id = $6",
+		c.Name, c.Type, c.Color, c.Icon, c.ID, c.UserID,
 	)
 	return err
 }
 
-func (r *categoryRepo) Delete(id int64) error {
-	_, err := r.db.Exec("DELETE FROM categories WHERE id = $1", id)
+// Delete removes the category together with the transactions filed under
+// it. Splits keep their category through a foreign key, so a category still
+// used by a split of another transaction is reported as ErrInUse.
+func (r *categoryRepo) Delete(userID, id int64) error {
+	_, err := r.db.Exec("DELETE FROM categories WHERE id = $1 AND user_id = $2", id, userID)
+	if isForeignKeyViolation(err) {
+		return category.ErrInUse
+	}
 	return err
 }
@@ -0,0 +1,27 @@
+package postgresql_test
+
+import (
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	postgresql "github.com/afandimsr/cashbook-backend/internal/infrastructure/persistent/postgresql/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteCategoryUsedBySplit(t *testing.T) {
+	db := openTestDB(t)
+	s := seedTransactions(t, db, 10)
+	repo := postgresql.NewCategoryRepo(db)
+
+	// the seeded split parent is filed under categories[0] with a split in
+	// categories[1]
+	require.ErrorIs(t, repo.Delete(s.userID, s.categories[1]), category.ErrInUse)
+
+	var sum string
+	require.NoError(t, db.QueryRow("SELECT SUM(sp.amount)::text FROM transaction_splits sp INNER JOIN transactions t ON t.id = sp.transaction_id WHERE t.user_id = $1", s.userID).Scan(&sum))
+	require.Equal(t, "100.00", sum, "splits still add up to the parent")
+
+	// deleting the parent's own category takes the parent and its splits along
+	require.NoError(t, repo.Delete(s.userID, s.categories[0]))
+	require.NoError(t, repo.Delete(s.userID, s.categories[1]))
+}
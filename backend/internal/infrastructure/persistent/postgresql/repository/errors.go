@@ -0,0 +1,14 @@
+package postgresql
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// isForeignKeyViolation reports whether err is Postgres refusing a write
+// because other rows still reference the target.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
@@ -0,0 +1,55 @@
+package postgresql
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+)
+
+type fxRateRepo struct {
+	db *sql.DB
+}
+
+func NewFxRateRepo(db *sql.DB) fx.Repository {
+	return &fxRateRepo{db: db}
+}
+
+func (r *fxRateRepo) FindLatest(base, quote string, on time.Time) (fx.Rate, error) {
+	var rate fx.Rate
+	err := r.db.QueryRow(
+		"SELECT rate_date, base, quote, rate::TEXT FROM fx_rates WHERE base = $1 AND quote = $2 AND rate_date <= $3 ORDER BY rate_date DESC LIMIT 1",
+		base, quote, on.Format(fx.DateLayout),
+	).Scan(&rate.Date, &rate.Base, &rate.Quote, &rate.Rate)
+	if err == sql.ErrNoRows {
+		return rate, fx.ErrRateNotFound
+	}
+	return rate, err
+}
+
+// SaveBatch upserts all rates in one database transaction so a bad file never
+// leaves a partial import behind.
+func (r *fxRateRepo) SaveBatch(rates []fx.Rate) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(
+		"INSERT INTO fx_rates(rate_date, base, quote, rate) VALUES($1, $2, $3, $4) ON CONFLICT (base, quote, rate_date) DO UPDATE SET rate = EXCLUDED.rate",
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.Exec(rate.Date.Format(fx.DateLayout), rate.Base, rate.Quote, rate.Rate); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
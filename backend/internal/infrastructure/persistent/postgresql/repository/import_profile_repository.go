@@ -0,0 +1,77 @@
+package postgresql
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+)
+
+type importProfileRepo struct {
+	db *sql.DB
+}
+
+func NewImportProfileRepo(db *sql.DB) importer.ProfileRepository {
+	return &importProfileRepo{db: db}
+}
+
+func (r *importProfileRepo) FindAllByUserID(userID int64) ([]importer.Profile, error) {
+	rows, err := r.db.Query("SELECT id, user_id, name, mapping, created_at FROM import_profiles WHERE user_id = $1 ORDER BY name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []importer.Profile
+	for rows.Next() {
+		p, err := scanImportProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func (r *importProfileRepo) FindByID(id int64) (importer.Profile, error) {
+	p, err := scanImportProfile(r.db.QueryRow("SELECT id, user_id, name, mapping, created_at FROM import_profiles WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return p, importer.ErrProfileNotFound
+	}
+	return p, err
+}
+
+func (r *importProfileRepo) Save(p *importer.Profile) error {
+	mapping, err := json.Marshal(p.Mapping)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRow(
+		"INSERT INTO import_profiles(user_id, name, mapping) VALUES($1, $2, $3) RETURNING id, created_at",
+		p.UserID, p.Name, mapping,
+	).Scan(&p.ID, &p.CreatedAt)
+}
+
+func (r *importProfileRepo) Update(p *importer.Profile) error {
+	mapping, err := json.Marshal(p.Mapping)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec("UPDATE import_profiles SET name = $1, mapping = $2 WHERE id = $3", p.Name, mapping, p.ID)
+	return err
+}
+
+func (r *importProfileRepo) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM import_profiles WHERE id = $1", id)
+	return err
+}
+
+func scanImportProfile(row rowScanner) (importer.Profile, error) {
+	var p importer.Profile
+	var mapping []byte
+	if err := row.Scan(&p.ID, &p.UserID, &p.Name, &mapping, &p.CreatedAt); err != nil {
+		return p, err
+	}
+	err := json.Unmarshal(mapping, &p.Mapping)
+	return p, err
+}
@@ -2,22 +2,43 @@ package postgresql
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
 )
 
 type recurringRepo struct {
-	db *sql.DB
+	db   queryer
+	conn *sql.DB // nil when the repo is bound to a transaction
 }
 
 func NewRecurringRepo(db *sql.DB) recurring_transaction.Repository {
-	return &recurringRepo{db: db}
+	return &recurringRepo{db: db, conn: db}
 }
 
+func (r *recurringRepo) InTx(fn func(repo recurring_transaction.Repository, txRepo transaction.Repository) error) error {
+	if r.conn == nil {
+		return fn(r, &transactionRepo{db: r.db})
+	}
+
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&recurringRepo{db: tx}, &transactionRepo{db: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+const recurringColumns = "id, user_id, account_id, category_id, amount, type, note, frequency, rule, start_date, last_processed, next_occurrence, paused"
+
 func (r *recurringRepo) FindAllByUserID(userID int64) ([]recurring_transaction.RecurringTransaction, error) {
 	rows, err := r.db.Query(
-		"SELECT id, user_id, category_id, amount, type, note, frequency, start_date, last_processed FROM recurring_transactions WHERE user_id = $1",
+		"SELECT "+recurringColumns+" FROM recurring_transactions WHERE user_id = $1",
 		userID,
 	)
 	if err != nil {
@@ -28,18 +49,44 @@ func (r *recurringRepo) FindAllByUserID(userID int64) ([]recurring_transaction.R
 	return r.scanRows(rows)
 }
 
+func (r *recurringRepo) FindByIDForUser(userID, id int64) (recurring_transaction.RecurringTransaction, error) {
+	rows, err := r.db.Query(
+		"SELECT "+recurringColumns+" FROM recurring_transactions WHERE id = $1 AND user_id = $2",
+		id, userID,
+	)
+	if err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	defer rows.Close()
+
+	rts, err := r.scanRows(rows)
+	if err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	if len(rts) == 0 {
+		return recurring_transaction.RecurringTransaction{}, recurring_transaction.ErrNotFound
+	}
+	return rts[0], nil
+}
+
 func (r *recurringRepo) FindDue(now time.Time) ([]recurring_transaction.RecurringTransaction, error) {
-	// Simple logic: if last_processed is nil, it's due if now >= start_date.
-	// If not nil, it depends on frequency.
-	// This query identifies records needing processed today/due.
-	rows, err := r.db.Query(`
-		SELECT id, user_id, category_id, amount, type, note, frequency, start_date, last_processed 
-		FROM recurring_transactions 
-		WHERE (last_processed IS NULL AND start_date <= $1)
-		OR (frequency = 'daily' AND last_processed <= $1 - INTERVAL '1 day')
-		OR (frequency = 'weekly' AND last_processed <= $1 - INTERVAL '1 week')
-		OR (frequency = 'monthly' AND last_processed <= $1 - INTERVAL '1 month')
-	`, now)
+	rows, err := r.db.Query(
+		"SELECT "+recurringColumns+" FROM recurring_transactions WHERE next_occurrence <= $1 AND NOT paused ORDER BY next_occurrence, id",
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRows(rows)
+}
+
+func (r *recurringRepo) FindDueByUserID(userID int64, now time.Time) ([]recurring_transaction.RecurringTransaction, error) {
+	rows, err := r.db.Query(
+		"SELECT "+recurringColumns+" FROM recurring_transactions WHERE user_id = $1 AND next_occurrence <= $2 AND NOT paused ORDER BY next_occurrence, id",
+		userID, now,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -50,43 +97,146 @@ func (r *recurringRepo) FindDue(now time.Time) ([]recurring_transaction.Recurrin
 
 func (r *recurringRepo) Save(rt *recurring_transaction.RecurringTransaction) error {
 	return r.db.QueryRow(`
-		INSERT INTO recurring_transactions(user_id, category_id, amount, type, note, frequency, start_date, last_processed) 
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
-	`, rt.UserID, rt.CategoryID, rt.Amount, rt.Type, rt.Note, rt.Frequency, rt.StartDate, rt.LastProcessed).Scan(&rt.ID)
+		INSERT INTO recurring_transactions(user_id, account_id, category_id, amount, type, note, frequency, rule, start_date, last_processed, next_occurrence, paused) 
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id
+	`, rt.UserID, rt.AccountID, rt.CategoryID, rt.Amount, rt.Type, rt.Note, rt.Frequency, rt.Rule, rt.StartDate, nullTime(rt.LastProcessed), nullTime(rt.NextOccurrence), rt.Paused).Scan(&rt.ID)
 }
 
 func (r *recurringRepo) Update(rt *recurring_transaction.RecurringTransaction) error {
 	_, err := r.db.Exec(`
 		UPDATE recurring_transactions 
-		SET category_id = $1, amount = $2, type = $3, note = $4, frequency = $5, start_date = $6
-		WHERE id = $7
-	`, rt.CategoryID, rt.Amount, rt.Type, rt.Note, rt.Frequency, rt.StartDate, rt.ID)
+		SET account_id = $1, category_id = $2, amount = $3, type = $4, note = $5, frequency = $6, rule = $7, start_date = $8, last_processed = $9, next_occurrence = $10, paused = $11
+		WHERE id = $12 AND user_id = $13
+	`, rt.AccountID, rt.CategoryID, rt.Amount, rt.Type, rt.Note, rt.Frequency, rt.Rule, rt.StartDate, nullTime(rt.LastProcessed), nullTime(rt.NextOccurrence), rt.Paused, rt.ID, rt.UserID)
+	return err
+}
+
+func (r *recurringRepo) Delete(userID, id int64) error {
+	_, err := r.db.Exec("DELETE FROM recurring_transactions WHERE id = $1 AND user_id = $2", id, userID)
+	return err
+}
+
+func (r *recurringRepo) UpdateSchedule(id int64, lastProcessed, nextOccurrence time.Time) error {
+	_, err := r.db.Exec("UPDATE recurring_transactions SET last_processed = $1, next_occurrence = $2 WHERE id = $3", nullTime(lastProcessed), nullTime(nextOccurrence), id)
 	return err
 }
 
-func (r *recurringRepo) Delete(id int64) error {
-	_, err := r.db.Exec("DELETE FROM recurring_transactions WHERE id = $1", id)
+func (r *recurringRepo) FindExceptions(id int64, from time.Time) ([]recurring_transaction.Exception, error) {
+	rows, err := r.db.Query(
+		"SELECT recurring_id, occurrence_date, skip, amount FROM recurring_exceptions WHERE recurring_id = $1 AND occurrence_date >= $2 ORDER BY occurrence_date",
+		id, from.Format(dateLayout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []recurring_transaction.Exception
+	for rows.Next() {
+		var e recurring_transaction.Exception
+		if err := rows.Scan(&e.RecurringID, &e.Date, &e.Skip, &e.Amount); err != nil {
+			return nil, err
+		}
+		e.Date = recurring_transaction.Day(e.Date)
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, rows.Err()
+}
+
+func (r *recurringRepo) SaveException(e *recurring_transaction.Exception) error {
+	_, err := r.db.Exec(`
+		INSERT INTO recurring_exceptions(recurring_id, occurrence_date, skip, amount) VALUES($1, $2, $3, $4)
+		ON CONFLICT (recurring_id, occurrence_date) DO UPDATE SET skip = EXCLUDED.skip, amount = EXCLUDED.amount
+	`, e.RecurringID, e.Date.Format(dateLayout), e.Skip, e.Amount)
 	return err
 }
 
-func (r *recurringRepo) UpdateLastProcessed(id int64, lastProcessed time.Time) error {
-	_, err := r.db.Exec("UPDATE recurring_transactions SET last_processed = $1 WHERE id = $2", lastProcessed, id)
+func (r *recurringRepo) DeleteException(id int64, day time.Time) error {
+	_, err := r.db.Exec("DELETE FROM recurring_exceptions WHERE recurring_id = $1 AND occurrence_date = $2", id, day.Format(dateLayout))
 	return err
 }
 
+func (r *recurringRepo) SaveEvent(e *recurring_transaction.Event) error {
+	return r.db.QueryRow(
+		"INSERT INTO recurring_events(recurring_id, action, occurrence, detail) VALUES($1, $2, $3, $4) RETURNING id, created_at",
+		e.RecurringID, e.Action, e.Occurrence, e.Detail,
+	).Scan(&e.ID, &e.CreatedAt)
+}
+
+func (r *recurringRepo) FindEvents(id int64) ([]recurring_transaction.Event, error) {
+	rows, err := r.db.Query(
+		"SELECT id, recurring_id, action, occurrence, detail, created_at FROM recurring_events WHERE recurring_id = $1 ORDER BY id DESC",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []recurring_transaction.Event
+	for rows.Next() {
+		var e recurring_transaction.Event
+		if err := rows.Scan(&e.ID, &e.RecurringID, &e.Action, &e.Occurrence, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *recurringRepo) SaveRun(run *recurring_transaction.Run) error {
+	items, err := json.Marshal(run.Items)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRow(
+		"INSERT INTO recurring_runs(user_id, started_at, finished_at, generated, skipped, failed, items) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		run.UserID, run.StartedAt, run.FinishedAt, run.Generated, run.Skipped, run.Failed, items,
+	).Scan(&run.ID)
+}
+
+func (r *recurringRepo) FindRuns(limit int) ([]recurring_transaction.Run, error) {
+	rows, err := r.db.Query(
+		"SELECT id, user_id, started_at, finished_at, generated, skipped, failed, items FROM recurring_runs ORDER BY started_at DESC, id DESC LIMIT $1",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []recurring_transaction.Run
+	for rows.Next() {
+		var run recurring_transaction.Run
+		var items []byte
+		if err := rows.Scan(&run.ID, &run.UserID, &run.StartedAt, &run.FinishedAt, &run.Generated, &run.Skipped, &run.Failed, &items); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(items, &run.Items); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
 func (r *recurringRepo) scanRows(rows *sql.Rows) ([]recurring_transaction.RecurringTransaction, error) {
 	var rts []recurring_transaction.RecurringTransaction
 	for rows.Next() {
 		var rt recurring_transaction.RecurringTransaction
-		var lastProcessed sql.NullTime
-		err := rows.Scan(&rt.ID, &rt.UserID, &rt.CategoryID, &rt.Amount, &rt.Type, &rt.Note, &rt.Frequency, &rt.StartDate, &lastProcessed)
+		var lastProcessed, nextOccurrence sql.NullTime
+		err := rows.Scan(&rt.ID, &rt.UserID, &rt.AccountID, &rt.CategoryID, &rt.Amount, &rt.Type, &rt.Note, &rt.Frequency, &rt.Rule, &rt.StartDate, &lastProcessed, &nextOccurrence, &rt.Paused)
 		if err != nil {
 			return nil, err
 		}
-		if lastProcessed.Valid {
-			rt.LastProcessed = lastProcessed.Time
-		}
+		rt.LastProcessed = lastProcessed.Time
+		rt.NextOccurrence = nextOccurrence.Time
 		rts = append(rts, rt)
 	}
-	return rts, nil
+	return rts, rows.Err()
+}
+
+// nullTime maps the zero time to SQL NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
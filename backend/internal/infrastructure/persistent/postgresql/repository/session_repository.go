@@ -0,0 +1,131 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+)
+
+type sessionRepo struct {
+	db *sql.DB
+}
+
+func NewSessionRepo(db *sql.DB) user.SessionRepository {
+	return &sessionRepo{db: db}
+}
+
+const sessionColumns = "s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_used_at, s.expires_at, s.revoked_at"
+
+func scanSession(row interface{ Scan(...interface{}) error }, extra ...interface{}) (user.Session, error) {
+	var s user.Session
+	dest := append([]interface{}{&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt}, extra...)
+	err := row.Scan(dest...)
+	return s, err
+}
+
+func (r *sessionRepo) Create(s *user.Session, tokenHash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO sessions(user_id, user_agent, ip, expires_at) VALUES($1, $2, $3, $4) RETURNING id, created_at, last_used_at",
+		s.UserID, s.UserAgent, s.IP, s.ExpiresAt,
+	).Scan(&s.ID, &s.CreatedAt, &s.LastUsedAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO session_tokens(token_hash, session_id) VALUES($1, $2)", tokenHash, s.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sessionRepo) FindByTokenHash(tokenHash string) (user.Session, bool, error) {
+	var usedAt sql.NullTime
+	s, err := scanSession(r.db.QueryRow(
+		"SELECT "+sessionColumns+", t.used_at FROM session_tokens t JOIN sessions s ON s.id = t.session_id WHERE t.token_hash = $1",
+		tokenHash,
+	), &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, false, user.ErrSessionNotFound
+	}
+	return s, usedAt.Valid, err
+}
+
+func (r *sessionRepo) Rotate(id int64, oldHash, newHash string, device user.Device, expiresAt time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	// claiming the old token first makes concurrent refreshes with it
+	// fail instead of both succeeding
+	res, err := tx.Exec("UPDATE session_tokens SET used_at = NOW() WHERE token_hash = $1 AND session_id = $2 AND used_at IS NULL", oldHash, id)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return false, err
+	}
+	if _, err := tx.Exec("INSERT INTO session_tokens(token_hash, session_id) VALUES($1, $2)", newHash, id); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if _, err := tx.Exec(
+		"UPDATE sessions SET user_agent = $1, ip = $2, last_used_at = NOW(), expires_at = $3 WHERE id = $4",
+		device.UserAgent, device.IP, expiresAt, id,
+	); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *sessionRepo) FindActiveByUserID(userID int64, now time.Time) ([]user.Session, error) {
+	rows, err := r.db.Query(
+		"SELECT "+sessionColumns+" FROM sessions s WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > $2 ORDER BY s.last_used_at DESC, s.id DESC",
+		userID, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []user.Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *sessionRepo) Revoke(userID, id int64) error {
+	res, err := r.db.Exec("UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return user.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepo) RevokeAllByUserID(userID, exceptID int64) error {
+	_, err := r.db.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL", userID, exceptID)
+	return err
+}
@@ -0,0 +1,109 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/lib/pq"
+)
+
+type tagRepo struct {
+	db *sql.DB
+}
+
+func NewTagRepo(db *sql.DB) tag.Repository {
+	return &tagRepo{db: db}
+}
+
+const tagSelect = "SELECT g.id, g.user_id, g.name, (SELECT COUNT(*) FROM transaction_tags tt WHERE tt.tag_id = g.id) FROM tags g"
+
+func (r *tagRepo) FindAllByUserID(userID int64) ([]tag.Tag, error) {
+	rows, err := r.db.Query(tagSelect+" WHERE g.user_id = $1 ORDER BY g.name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []tag.Tag{}
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (r *tagRepo) FindByID(id int64) (tag.Tag, error) {
+	return scanTag(r.db.QueryRow(tagSelect+" WHERE g.id = $1", id))
+}
+
+func (r *tagRepo) FindByName(userID int64, name string) (tag.Tag, error) {
+	return scanTag(r.db.QueryRow(tagSelect+" WHERE g.user_id = $1 AND g.name = $2", userID, name))
+}
+
+func (r *tagRepo) Rename(id int64, name string) error {
+	_, err := r.db.Exec("UPDATE tags SET name = $1 WHERE id = $2", name, id)
+	return err
+}
+
+func (r *tagRepo) Merge(sourceIDs []int64, targetID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// transactions carrying both a source and the target keep a single link
+	if _, err := tx.Exec(
+		"INSERT INTO transaction_tags(transaction_id, tag_id) SELECT DISTINCT transaction_id, $1 FROM transaction_tags WHERE tag_id = ANY($2) ON CONFLICT DO NOTHING",
+		targetID, pq.Array(sourceIDs),
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM tags WHERE id = ANY($1)", pq.Array(sourceIDs)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *tagRepo) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM tags WHERE id = $1", id)
+	return err
+}
+
+// GetSpending uses the parent amount of split transactions, since tags are
+// attached to the transaction rather than to its splits.
+func (r *tagRepo) GetSpending(userID int64, from, to time.Time) ([]tag.Spending, error) {
+	rows, err := r.db.Query(
+		"SELECT g.id, g.name, t.currency, SUM(t.amount) FROM tags g INNER JOIN transaction_tags tt ON tt.tag_id = g.id INNER JOIN transactions t ON t.id = tt.transaction_id WHERE g.user_id = $1 AND t.type = $2 AND t.date >= $3 AND t.date <= $4 GROUP BY g.id, g.name, t.currency ORDER BY SUM(t.amount) DESC, g.name",
+		userID, transaction.TypeExpense, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spending []tag.Spending
+	for rows.Next() {
+		var s tag.Spending
+		if err := rows.Scan(&s.TagID, &s.TagName, &s.Currency, &s.Amount); err != nil {
+			return nil, err
+		}
+		spending = append(spending, s)
+	}
+	return spending, rows.Err()
+}
+
+func scanTag(row rowScanner) (tag.Tag, error) {
+	var t tag.Tag
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.TransactionCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, tag.ErrTagNotFound
+	}
+	return t, err
+}
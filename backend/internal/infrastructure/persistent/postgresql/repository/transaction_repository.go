@@ -2,55 +2,103 @@ package postgresql
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/lib/pq"
 )
 
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type transactionRepo struct {
-	db *sql.DB
+	db   queryer
+	conn *sql.DB // nil when the repo is bound to a transaction
 }
 
 func NewTransactionRepo(db *sql.DB) transaction.Repository {
-	return &transactionRepo{db: db}
+	return &transactionRepo{db: db, conn: db}
 }
 
-func (r *transactionRepo) FindAllByUserID(userID int64, limit, offset int, filter transaction.Filter) ([]transaction.Transaction, error) {
-	query := "SELECT id, user_id, category_id, amount, note, date, type FROM transactions WHERE user_id = $1"
-	args := []interface{}{userID}
-	placeholderCount := 1
+func (r *transactionRepo) InTx(fn func(repo transaction.Repository) error) error {
+	if r.conn == nil {
+		return fn(r)
+	}
+
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&transactionRepo{db: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+const transactionColumns = "id, user_id, account_id, category_id, transfer_id, amount, currency, note, date, type, external_id, recurring_id"
 
+// applyTransactionFilter appends the WHERE conditions for filter to query.
+// prefix is the table alias including the trailing dot (e.g. "t.") or empty.
+func applyTransactionFilter(query string, args []interface{}, filter transaction.Filter, prefix string) (string, []interface{}) {
 	if filter.Search != "" {
-		placeholderCount++
-		query += " AND note ILIKE $" + strconv.Itoa(placeholderCount)
 		args = append(args, "%"+filter.Search+"%")
+		query += " AND " + prefix + "note ILIKE $" + strconv.Itoa(len(args))
+	}
+
+	if filter.AccountID != 0 {
+		args = append(args, filter.AccountID)
+		query += " AND " + prefix + "account_id = $" + strconv.Itoa(len(args))
 	}
 
 	if filter.CategoryID != 0 {
-		placeholderCount++
-		query += " AND category_id = $" + strconv.Itoa(placeholderCount)
 		args = append(args, filter.CategoryID)
+		query += " AND " + prefix + "category_id = $" + strconv.Itoa(len(args))
 	}
 
 	if filter.Type != "" {
-		placeholderCount++
-		query += " AND type = $" + strconv.Itoa(placeholderCount)
 		args = append(args, filter.Type)
+		query += " AND " + prefix + "type = $" + strconv.Itoa(len(args))
 	}
 
 	if !filter.StartDate.IsZero() {
-		placeholderCount++
-		query += " AND date >= $" + strconv.Itoa(placeholderCount)
 		args = append(args, filter.StartDate)
+		query += " AND " + prefix + "date >= $" + strconv.Itoa(len(args))
 	}
 
 	if !filter.EndDate.IsZero() {
-		placeholderCount++
-		query += " AND date <= $" + strconv.Itoa(placeholderCount)
 		args = append(args, filter.EndDate)
+		query += " AND " + prefix + "date <= $" + strconv.Itoa(len(args))
+	}
+
+	if len(filter.Tags) > 0 {
+		args = append(args, pq.Array(filter.Tags))
+		tagged := "SELECT tt.transaction_id FROM transaction_tags tt INNER JOIN tags g ON g.id = tt.tag_id WHERE g.name = ANY($" + strconv.Itoa(len(args)) + ")"
+		if filter.TagMatch == transaction.TagMatchAll {
+			// the tag names are distinct, so a transaction has all of them
+			// when it matches as many as were asked for
+			args = append(args, len(filter.Tags))
+			tagged += " GROUP BY tt.transaction_id HAVING COUNT(*) = $" + strconv.Itoa(len(args))
+		}
+		query += " AND " + prefix + "id IN (" + tagged + ")"
 	}
 
-	query += " ORDER BY date DESC LIMIT $" + strconv.Itoa(placeholderCount+1) + " OFFSET $" + strconv.Itoa(placeholderCount+2)
+	return query, args
+}
+
+func (r *transactionRepo) FindAllByUserID(userID int64, limit, offset int, filter transaction.Filter) ([]transaction.Transaction, error) {
+	query, args := applyTransactionFilter(
+		"SELECT "+transactionColumns+" FROM transactions WHERE user_id = $1",
+		[]interface{}{userID}, filter, "",
+	)
+
+	query += " ORDER BY date DESC LIMIT $" + strconv.Itoa(len(args)+1) + " OFFSET $" + strconv.Itoa(len(args)+2)
 	args = append(args, limit, offset)
 
 	rows, err := r.db.Query(query, args...)
@@ -61,8 +109,7 @@ func (r *transactionRepo) FindAllByUserID(userID int64, limit, offset int, filte
 
 	var transactions []transaction.Transaction
 	for rows.Next() {
-		var t transaction.Transaction
-		err := rows.Scan(&t.ID, &t.UserID, &t.CategoryID, &t.Amount, &t.Note, &t.Date, &t.Type)
+		t, err := scanTransaction(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -71,128 +118,333 @@ func (r *transactionRepo) FindAllByUserID(userID int64, limit, offset int, filte
 	return transactions, nil
 }
 
-func (r *transactionRepo) GetTotalAndSum(userID int64, filter transaction.Filter) (int64, float64, error) {
-	query := "SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM transactions WHERE user_id = $1"
-	args := []interface{}{userID}
-	placeholderCount := 1
+func (r *transactionRepo) Count(userID int64, filter transaction.Filter) (int64, error) {
+	query, args := applyTransactionFilter(
+		"SELECT COUNT(*) FROM transactions WHERE user_id = $1",
+		[]interface{}{userID}, filter, "",
+	)
 
-	if filter.Search != "" {
-		placeholderCount++
-		query += " AND note ILIKE $" + strconv.Itoa(placeholderCount)
-		args = append(args, "%"+filter.Search+"%")
+	var count int64
+	err := r.db.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
+
+func (r *transactionRepo) SumByCurrency(userID int64, filter transaction.Filter) ([]transaction.CurrencySummary, error) {
+	query, args := applyTransactionFilter(
+		"SELECT currency, COALESCE(SUM(amount) FILTER (WHERE type = $2), 0), COALESCE(SUM(amount) FILTER (WHERE type = $3), 0) FROM transactions WHERE user_id = $1 AND type IN ($2, $3)",
+		[]interface{}{userID, transaction.TypeIncome, transaction.TypeExpense}, filter, "",
+	)
+	query += " GROUP BY currency ORDER BY currency"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if filter.CategoryID != 0 {
-		placeholderCount++
-		query += " AND category_id = $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.CategoryID)
+	var totals []transaction.CurrencySummary
+	for rows.Next() {
+		var cs transaction.CurrencySummary
+		if err := rows.Scan(&cs.Currency, &cs.TotalIncome, &cs.TotalExpense); err != nil {
+			return nil, err
+		}
+		totals = append(totals, cs)
 	}
+	return totals, rows.Err()
+}
 
-	if filter.Type != "" {
-		placeholderCount++
-		query += " AND type = $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.Type)
+func (r *transactionRepo) SumByCategory(userID int64, filter transaction.Filter) ([]transaction.CategoryTotal, error) {
+	// a split transaction joins to one row per split; the category filter
+	// applies to the split's category, so it is added separately
+	categoryID := filter.CategoryID
+	filter.CategoryID = 0
+	query, args := applyTransactionFilter(
+		"SELECT c.id, c.name, COALESCE(c.color, ''), t.currency, SUM(COALESCE(s.amount, t.amount)) FROM transactions t LEFT JOIN transaction_splits s ON s.transaction_id = t.id INNER JOIN categories c ON c.id = COALESCE(s.category_id, t.category_id) WHERE t.user_id = $1",
+		[]interface{}{userID}, filter, "t.",
+	)
+	if categoryID != 0 {
+		args = append(args, categoryID)
+		query += " AND c.id = $" + strconv.Itoa(len(args))
 	}
+	query += " GROUP BY c.id, c.name, c.color, t.currency ORDER BY SUM(COALESCE(s.amount, t.amount)) DESC, c.name"
 
-	if !filter.StartDate.IsZero() {
-		placeholderCount++
-		query += " AND date >= $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.StartDate)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if !filter.EndDate.IsZero() {
-		placeholderCount++
-		query += " AND date <= $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.EndDate)
+	var totals []transaction.CategoryTotal
+	for rows.Next() {
+		var ct transaction.CategoryTotal
+		if err := rows.Scan(&ct.CategoryID, &ct.CategoryName, &ct.Color, &ct.Currency, &ct.Amount); err != nil {
+			return nil, err
+		}
+		totals = append(totals, ct)
 	}
+	return totals, rows.Err()
+}
 
-	var count int64
-	var sum float64
-	err := r.db.QueryRow(query, args...).Scan(&count, &sum)
-	return count, sum, err
+// trendGroup is the part of the trend query that depends on the grouping.
+type trendGroup struct {
+	from   string
+	id     string
+	name   string
+	amount string
 }
 
-func (r *transactionRepo) GetCategorySpending(userID int64, limit, offset int, filter transaction.Filter) ([]transaction.ReportTransaction, error) {
-	query := "SELECT t.id, t.user_id, t.category_id, c.name as category_name, c.color as color, t.amount, t.note, t.date, t.type FROM transactions as t INNER JOIN categories c ON t.category_id = c.id WHERE t.user_id = $1"
-	args := []interface{}{userID}
-	placeholderCount := 1
+var trendGroups = map[string]trendGroup{
+	"": {"transactions t", "0", "''", "t.amount"},
+	// splits count towards their own category
+	transaction.GroupByCategory: {"transactions t LEFT JOIN transaction_splits s ON s.transaction_id = t.id INNER JOIN categories g ON g.id = COALESCE(s.category_id, t.category_id)", "g.id", "g.name", "COALESCE(s.amount, t.amount)"},
+	transaction.GroupByType:     {"transactions t", "0", "t.type", "t.amount"},
+	transaction.GroupByAccount:  {"transactions t INNER JOIN accounts g ON g.id = t.account_id", "g.id", "g.name", "t.amount"},
+}
 
-	if filter.Search != "" {
-		placeholderCount++
-		query += " AND note ILIKE $" + strconv.Itoa(placeholderCount)
-		args = append(args, "%"+filter.Search+"%")
-	}
+// trendSteps are the generate_series steps of the trend intervals.
+var trendSteps = map[string]string{
+	transaction.IntervalDay:     "1 day",
+	transaction.IntervalWeek:    "1 week",
+	transaction.IntervalMonth:   "1 month",
+	transaction.IntervalQuarter: "3 months",
+	transaction.IntervalYear:    "1 year",
+}
 
-	if filter.CategoryID != 0 {
-		placeholderCount++
-		query += " AND t.category_id = $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.CategoryID)
+// Trend cross joins every period with every group and currency that has
+// totals, so each series comes back gap-filled.
+func (r *transactionRepo) Trend(userID int64, query transaction.TrendQuery) ([]transaction.TrendRow, error) {
+	group, ok := trendGroups[query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown trend grouping %q", query.GroupBy)
 	}
-
-	if filter.Type != "" {
-		placeholderCount++
-		query += " AND t.type = $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.Type)
+	step, ok := trendSteps[query.Interval]
+	if !ok {
+		return nil, fmt.Errorf("unknown trend interval %q", query.Interval)
 	}
 
-	if !filter.StartDate.IsZero() {
-		placeholderCount++
-		query += " AND t.date >= $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.StartDate)
+	rows, err := r.db.Query(
+		"WITH periods AS (SELECT generate_series(date_trunc($2, $4::timestamp), date_trunc($2, $5::timestamp), $3::interval) AS period), "+
+			"totals AS (SELECT date_trunc($2, t.date AT TIME ZONE 'UTC') AS period, "+group.id+" AS group_id, "+group.name+" AS group_name, t.currency, SUM("+group.amount+") FILTER (WHERE t.type = $6) AS income, SUM("+group.amount+") FILTER (WHERE t.type = $7) AS expense FROM "+group.from+" WHERE t.user_id = $1 AND t.type IN ($6, $7) AND t.date >= $4::timestamp AT TIME ZONE 'UTC' AND t.date < ($5::timestamp + INTERVAL '1 day') AT TIME ZONE 'UTC' GROUP BY 1, 2, 3, 4), "+
+			"groups AS (SELECT DISTINCT group_id, group_name, currency FROM totals) "+
+			"SELECT p.period, COALESCE(g.group_id, 0), COALESCE(g.group_name, ''), COALESCE(g.currency, ''), COALESCE(t.income, 0), COALESCE(t.expense, 0) FROM periods p LEFT JOIN groups g ON true LEFT JOIN totals t ON t.period = p.period AND t.group_id = g.group_id AND t.group_name = g.group_name AND t.currency = g.currency ORDER BY p.period, g.group_name, g.group_id, g.currency",
+		userID, query.Interval, step, query.From.Format("2006-01-02"), query.To.Format("2006-01-02"), transaction.TypeIncome, transaction.TypeExpense,
+	)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if !filter.EndDate.IsZero() {
-		placeholderCount++
-		query += " AND t.date <= $" + strconv.Itoa(placeholderCount)
-		args = append(args, filter.EndDate)
+	var trend []transaction.TrendRow
+	for rows.Next() {
+		var row transaction.TrendRow
+		if err := rows.Scan(&row.Period, &row.GroupID, &row.GroupName, &row.Currency, &row.Income, &row.Expense); err != nil {
+			return nil, err
+		}
+		row.Period = row.Period.UTC()
+		trend = append(trend, row)
 	}
+	return trend, rows.Err()
+}
 
-	query += " ORDER BY date DESC LIMIT $" + strconv.Itoa(placeholderCount+1) + " OFFSET $" + strconv.Itoa(placeholderCount+2)
-	args = append(args, limit, offset)
+// StreamReport left joins categories so transfers, which have none, are
+// included with an empty category.
+func (r *transactionRepo) StreamReport(userID int64, filter transaction.Filter, fn func(transaction.ReportTransaction) error) error {
+	query, args := applyTransactionFilter(
+		"SELECT t.id, t.user_id, t.account_id, a.name, COALESCE(t.category_id, 0), COALESCE(c.name, ''), COALESCE(c.color, ''), t.amount, t.currency, COALESCE(t.note, ''), t.date, t.type FROM transactions t INNER JOIN accounts a ON a.id = t.account_id LEFT JOIN categories c ON c.id = t.category_id WHERE t.user_id = $1",
+		[]interface{}{userID}, filter, "t.",
+	)
+	query += " ORDER BY t.date DESC, t.id DESC"
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var transactions []transaction.ReportTransaction
 	for rows.Next() {
 		var t transaction.ReportTransaction
-		err := rows.Scan(&t.ID, &t.UserID, &t.CategoryID, &t.CategoryName, &t.Color, &t.Amount, &t.Note, &t.Date, &t.Type)
+		err := rows.Scan(&t.ID, &t.UserID, &t.AccountID, &t.AccountName, &t.CategoryID, &t.CategoryName, &t.Color, &t.Amount, &t.Currency, &t.Note, &t.Date, &t.Type)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
 		}
-		transactions = append(transactions, t)
 	}
-	return transactions, nil
+	return rows.Err()
 }
 
-func (r *transactionRepo) FindByID(id int64) (transaction.Transaction, error) {
-	var t transaction.Transaction
-	err := r.db.QueryRow(
-		"SELECT id, user_id, category_id, amount, note, date, type FROM transactions WHERE id = $1",
-		id,
-	).Scan(&t.ID, &t.UserID, &t.CategoryID, &t.Amount, &t.Note, &t.Date, &t.Type)
+func (r *transactionRepo) FindByIDForUser(userID, id int64) (transaction.Transaction, error) {
+	row := r.db.QueryRow("SELECT "+transactionColumns+" FROM transactions WHERE id = $1 AND user_id = $2", id, userID)
+	t, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, transaction.ErrNotFound
+	}
 	return t, err
 }
 
+// Save inserts the transaction. When no currency is set it is taken from the
+// account, so callers that only know the account still store a consistent row.
+// Save skips a row whose external_id conflicts rather than raising the unique
+// violation, which would abort the transaction of a whole import.
 func (r *transactionRepo) Save(t *transaction.Transaction) error {
-	return r.db.QueryRow(
-		"INSERT INTO transactions(user_id, category_id, amount, note, date, type) VALUES($1, $2, $3, $4, $5, $6) RETURNING id",
-		t.UserID, t.CategoryID, t.Amount, t.Note, t.Date, t.Type,
-	).Scan(&t.ID)
+	err := r.db.QueryRow(
+		"INSERT INTO transactions(user_id, account_id, category_id, transfer_id, amount, currency, note, date, type, external_id) VALUES($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), (SELECT currency FROM accounts WHERE id = $2)), $7, $8, $9, NULLIF($10, '')) ON CONFLICT (account_id, external_id) WHERE external_id IS NOT NULL DO NOTHING RETURNING id, currency",
+		t.UserID, t.AccountID, nullInt64(t.CategoryID), nullInt64(t.TransferID), t.Amount, t.Currency, t.Note, t.Date, t.Type, t.ExternalID,
+	).Scan(&t.ID, &t.Currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return transaction.ErrDuplicateExternalID
+	}
+	return err
+}
+
+func (r *transactionRepo) SaveOccurrence(t *transaction.Transaction) (bool, error) {
+	err := r.db.QueryRow(
+		"INSERT INTO transactions(user_id, account_id, category_id, amount, currency, note, date, type, recurring_id, occurrence_date) VALUES($1, $2, $3, $4, COALESCE(NULLIF($5, ''), (SELECT currency FROM accounts WHERE id = $2)), $6, $7, $8, $9, $10) ON CONFLICT (recurring_id, occurrence_date) DO NOTHING RETURNING id, currency",
+		t.UserID, t.AccountID, nullInt64(t.CategoryID), t.Amount, t.Currency, t.Note, t.Date, t.Type, t.RecurringID, t.Date.UTC().Format("2006-01-02"),
+	).Scan(&t.ID, &t.Currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 func (r *transactionRepo) Update(t *transaction.Transaction) error {
 	_, err := r.db.Exec(
-		"UPDATE transactions SET category_id = $1, amount = $2, note = $3, date = $4, type = $5 WHERE id = $6",
-		t.CategoryID, t.Amount, t.Note, t.Date, t.Type, t.ID,
+		"UPDATE transactions SET account_id = $1, category_id = $2, amount = $3, currency = COALESCE(NULLIF($4, ''), (SELECT currency FROM accounts WHERE id = $1)), note = $5, date = $6, type = $7 WHERE id = $8 AND user_id = $9",
+		t.AccountID, nullInt64(t.CategoryID), t.Amount, t.Currency, t.Note, t.Date, t.Type, t.ID, t.UserID,
 	)
 	return err
 }
 
-func (r *transactionRepo) Delete(id int64) error {
-	_, err := r.db.Exec("DELETE FROM transactions WHERE id = $1", id)
+func (r *transactionRepo) Delete(userID, id int64) error {
+	_, err := r.db.Exec("DELETE FROM transactions WHERE id = $1 AND user_id = $2", id, userID)
 	return err
 }
+
+func (r *transactionRepo) FindSplits(transactionIDs []int64) (map[int64][]transaction.Split, error) {
+	splits := make(map[int64][]transaction.Split)
+	if len(transactionIDs) == 0 {
+		return splits, nil
+	}
+	rows, err := r.db.Query("SELECT id, transaction_id, category_id, amount, COALESCE(note, '') FROM transaction_splits WHERE transaction_id = ANY($1) ORDER BY id", pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s transaction.Split
+		if err := rows.Scan(&s.ID, &s.TransactionID, &s.CategoryID, &s.Amount, &s.Note); err != nil {
+			return nil, err
+		}
+		splits[s.TransactionID] = append(splits[s.TransactionID], s)
+	}
+	return splits, rows.Err()
+}
+
+// SaveSplits deletes and re-inserts the splits, so callers should run it in
+// the same database transaction as the parent's Save or Update.
+func (r *transactionRepo) SaveSplits(transactionID int64, splits []transaction.Split) error {
+	if _, err := r.db.Exec("DELETE FROM transaction_splits WHERE transaction_id = $1", transactionID); err != nil {
+		return err
+	}
+	for i := range splits {
+		s := &splits[i]
+		s.TransactionID = transactionID
+		err := r.db.QueryRow(
+			"INSERT INTO transaction_splits(transaction_id, category_id, amount, note) VALUES($1, $2, $3, $4) RETURNING id",
+			transactionID, s.CategoryID, s.Amount, s.Note,
+		).Scan(&s.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *transactionRepo) FindTags(transactionIDs []int64) (map[int64][]string, error) {
+	tags := make(map[int64][]string)
+	if len(transactionIDs) == 0 {
+		return tags, nil
+	}
+	rows, err := r.db.Query("SELECT tt.transaction_id, g.name FROM transaction_tags tt INNER JOIN tags g ON g.id = tt.tag_id WHERE tt.transaction_id = ANY($1) ORDER BY g.name", pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], name)
+	}
+	return tags, rows.Err()
+}
+
+func (r *transactionRepo) SetTags(transactionID, userID int64, names []string) error {
+	if _, err := r.db.Exec("DELETE FROM transaction_tags WHERE transaction_id = $1", transactionID); err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if _, err := r.db.Exec(
+		"INSERT INTO tags(user_id, name) SELECT $1, unnest($2::text[]) ON CONFLICT (user_id, name) DO NOTHING",
+		userID, pq.Array(names),
+	); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(
+		"INSERT INTO transaction_tags(transaction_id, tag_id) SELECT $1, id FROM tags WHERE user_id = $2 AND name = ANY($3)",
+		transactionID, userID, pq.Array(names),
+	)
+	return err
+}
+
+func (r *transactionRepo) FindByExternalIDs(accountID int64, ids []string) (map[string]int64, error) {
+	found := make(map[string]int64)
+	if len(ids) == 0 {
+		return found, nil
+	}
+	rows, err := r.db.Query("SELECT external_id, id FROM transactions WHERE account_id = $1 AND external_id = ANY($2)", accountID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var externalID string
+		var id int64
+		if err := rows.Scan(&externalID, &id); err != nil {
+			return nil, err
+		}
+		found[externalID] = id
+	}
+	return found, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (transaction.Transaction, error) {
+	var t transaction.Transaction
+	var categoryID, transferID, recurringID sql.NullInt64
+	var note, externalID sql.NullString
+	err := row.Scan(&t.ID, &t.UserID, &t.AccountID, &categoryID, &transferID, &t.Amount, &t.Currency, &note, &t.Date, &t.Type, &externalID, &recurringID)
+	t.CategoryID = categoryID.Int64
+	t.TransferID = transferID.Int64
+	t.RecurringID = recurringID.Int64
+	t.Note = note.String
+	t.ExternalID = externalID.String
+	return t, err
+}
+
+// nullInt64 maps the zero ID to SQL NULL for optional foreign keys.
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
@@ -16,7 +16,7 @@ func NewUserRepo(db *sql.DB) user.UserRepository {
 }
 
 func (r *userRepo) FindAll(limit, offset int) ([]user.User, error) {
-	rows, err := r.db.Query("SELECT id, name, email, is_active, totp_enabled FROM users LIMIT $1 OFFSET $2", limit, offset)
+	rows, err := r.db.Query("SELECT id, name, email, is_active, totp_enabled, email_verified_at IS NOT NULL FROM users LIMIT $1 OFFSET $2", limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -25,7 +25,7 @@ func (r *userRepo) FindAll(limit, offset int) ([]user.User, error) {
 	var users []user.User
 	for rows.Next() {
 		var u user.User
-		rows.Scan(&u.ID, &u.Name, &u.Email, &u.IsActive, &u.TOTPEnabled)
+		rows.Scan(&u.ID, &u.Name, &u.Email, &u.IsActive, &u.TOTPEnabled, &u.EmailVerified)
 		// fetch roles for user
 		rRows, _ := r.db.Query("SELECT r.name FROM roles r JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = $1", u.ID)
 		var roles []string
@@ -47,7 +47,8 @@ func (r *userRepo) FindByID(id int64) (user.User, error) {
 	var u user.User
 	var googleID sql.NullString
 	var totpSecret sql.NullString
-	err := r.db.QueryRow("SELECT id, name, email, password, google_id, is_active, totp_secret, totp_enabled FROM users WHERE id = $1", id).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &googleID, &u.IsActive, &totpSecret, &u.TOTPEnabled)
+	var pendingEmail sql.NullString
+	err := r.db.QueryRow("SELECT id, name, email, password, google_id, is_active, totp_secret, totp_enabled, base_currency, email_verified_at IS NOT NULL, locale, timezone, pending_email FROM users WHERE id = $1", id).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &googleID, &u.IsActive, &totpSecret, &u.TOTPEnabled, &u.BaseCurrency, &u.EmailVerified, &u.Locale, &u.Timezone, &pendingEmail)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return u, errors.New("user not found")
@@ -62,6 +63,7 @@ func (r *userRepo) FindByID(id int64) (user.User, error) {
 	if totpSecret.Valid {
 		u.TOTPSecret = totpSecret.String
 	}
+	u.PendingEmail = pendingEmail.String
 	// populate roles
 	rows, _ := r.db.Query("SELECT r.name FROM roles r JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = $1", u.ID)
 	var roles []string
@@ -83,7 +85,7 @@ func (r *userRepo) Save(u user.User) error {
 		return err
 	}
 	var userID int64
-	err = tx.QueryRow("INSERT INTO users(name, email, password, google_id, is_active, totp_secret, totp_enabled) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id", u.Name, u.Email, u.Password, u.GoogleID, u.IsActive, u.TOTPSecret, u.TOTPEnabled).Scan(&userID)
+	err = tx.QueryRow("INSERT INTO users(name, email, password, google_id, is_active, totp_secret, totp_enabled, email_verified_at) VALUES($1, $2, $3, $4, $5, $6, $7, CASE WHEN $8::boolean THEN NOW() END) RETURNING id", u.Name, u.Email, u.Password, u.GoogleID, u.IsActive, u.TOTPSecret, u.TOTPEnabled, u.EmailVerified).Scan(&userID)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -106,29 +108,36 @@ func (r *userRepo) Save(u user.User) error {
 }
 
 func (r *userRepo) Update(u user.User) error {
+	_, err := r.db.Exec(`
+		UPDATE users
+		SET name = $1, email = $2, password = $3, google_id = $4, is_active = $5, totp_secret = $6, totp_enabled = $7,
+			base_currency = COALESCE(NULLIF($8, ''), base_currency),
+			email_verified_at = CASE WHEN $9::boolean THEN COALESCE(email_verified_at, NOW()) END,
+			locale = COALESCE(NULLIF($10, ''), locale), timezone = COALESCE(NULLIF($11, ''), timezone), pending_email = NULLIF($12, '')
+		WHERE id = $13
+	`, u.Name, u.Email, u.Password, u.GoogleID, u.IsActive, u.TOTPSecret, u.TOTPEnabled, u.BaseCurrency, u.EmailVerified, u.Locale, u.Timezone, u.PendingEmail, u.ID)
+	return err
+}
+
+func (r *userRepo) UpdateRoles(id int64, roles []string) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec("UPDATE users SET name = $1, email = $2, password = $3, google_id = $4, is_active = $5, totp_secret = $6, totp_enabled = $7 WHERE id = $8", u.Name, u.Email, u.Password, u.GoogleID, u.IsActive, u.TOTPSecret, u.TOTPEnabled, u.ID)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
 	// reset roles
-	_, err = tx.Exec("DELETE FROM user_roles WHERE user_id = $1", u.ID)
+	_, err = tx.Exec("DELETE FROM user_roles WHERE user_id = $1", id)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	for _, roleName := range u.Roles {
+	for _, roleName := range roles {
 		var roleID int64
 		err = tx.QueryRow("SELECT id FROM roles WHERE name = $1", roleName).Scan(&roleID)
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
-		_, err = tx.Exec("INSERT INTO user_roles(user_id, role_id) VALUES($1, $2) ON CONFLICT DO NOTHING", u.ID, roleID)
+		_, err = tx.Exec("INSERT INTO user_roles(user_id, role_id) VALUES($1, $2) ON CONFLICT DO NOTHING", id, roleID)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -185,7 +194,8 @@ func (r *userRepo) FindByEmail(email string) (user.User, error) {
 	var u user.User
 	var googleID sql.NullString
 	var totpSecret sql.NullString
-	err := r.db.QueryRow("SELECT id, name, email, password, google_id, is_active, totp_secret, totp_enabled FROM users WHERE email = $1", email).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &googleID, &u.IsActive, &totpSecret, &u.TOTPEnabled)
+	var pendingEmail sql.NullString
+	err := r.db.QueryRow("SELECT id, name, email, password, google_id, is_active, totp_secret, totp_enabled, base_currency, email_verified_at IS NOT NULL, locale, timezone, pending_email FROM users WHERE email = $1", email).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &googleID, &u.IsActive, &totpSecret, &u.TOTPEnabled, &u.BaseCurrency, &u.EmailVerified, &u.Locale, &u.Timezone, &pendingEmail)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return u, errors.New("user not found")
@@ -200,6 +210,7 @@ func (r *userRepo) FindByEmail(email string) (user.User, error) {
 	if totpSecret.Valid {
 		u.TOTPSecret = totpSecret.String
 	}
+	u.PendingEmail = pendingEmail.String
 	rows, _ := r.db.Query("SELECT r.name FROM roles r JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = $1", u.ID)
 	var roles []string
 	for rows.Next() {
@@ -218,7 +229,8 @@ func (r *userRepo) FindByGoogleID(googleID string) (user.User, error) {
 	var u user.User
 	var gID sql.NullString
 	var totpSecret sql.NullString
-	err := r.db.QueryRow("SELECT id, name, email, password, google_id, is_active, totp_secret, totp_enabled FROM users WHERE google_id = $1", googleID).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &gID, &u.IsActive, &totpSecret, &u.TOTPEnabled)
+	var pendingEmail sql.NullString
+	err := r.db.QueryRow("SELECT id, name, email, password, google_id, is_active, totp_secret, totp_enabled, base_currency, email_verified_at IS NOT NULL, locale, timezone, pending_email FROM users WHERE google_id = $1", googleID).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &gID, &u.IsActive, &totpSecret, &u.TOTPEnabled, &u.BaseCurrency, &u.EmailVerified, &u.Locale, &u.Timezone, &pendingEmail)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return u, errors.New("user not found")
@@ -233,6 +245,7 @@ func (r *userRepo) FindByGoogleID(googleID string) (user.User, error) {
 	if totpSecret.Valid {
 		u.TOTPSecret = totpSecret.String
 	}
+	u.PendingEmail = pendingEmail.String
 	rows, _ := r.db.Query("SELECT r.name FROM roles r JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = $1", u.ID)
 	var roles []string
 	for rows.Next() {
@@ -0,0 +1,48 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+)
+
+type userTokenRepo struct {
+	db *sql.DB
+}
+
+func NewUserTokenRepo(db *sql.DB) user.TokenRepository {
+	return &userTokenRepo{db: db}
+}
+
+func (r *userTokenRepo) Create(userID int64, purpose, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL", userID, purpose); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO user_tokens(token_hash, user_id, purpose, expires_at) VALUES($1, $2, $3, $4)",
+		tokenHash, userID, purpose, expiresAt,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *userTokenRepo) Consume(purpose, tokenHash string, now time.Time) (int64, error) {
+	var userID int64
+	err := r.db.QueryRow(
+		"UPDATE user_tokens SET used_at = $1 WHERE token_hash = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1 RETURNING user_id",
+		now, tokenHash, purpose,
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, user.ErrTokenNotFound
+	}
+	return userID, err
+}
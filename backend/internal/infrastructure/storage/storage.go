@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/afandimsr/cashbook-backend/internal/config"
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
+)
+
+// New returns the attachment storage selected by cfg.Driver.
+func New(cfg config.StorageConfig) (attachment.Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocal(cfg.LocalPath)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,97 @@
+// Package ical writes iCalendar (RFC 5545) files of all-day events, such as
+// the feeds calendar apps subscribe to.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event is an all-day event.
+type Event struct {
+	// UID identifies the event across updates of the feed, so it must be
+	// stable and globally unique.
+	UID         string
+	Day         time.Time
+	Summary     string
+	Description string
+	Categories  []string
+	// Cancelled events are kept in the feed so that calendars remove them.
+	Cancelled bool
+}
+
+// maxLine is the longest content line allowed, in octets, before folding.
+const maxLine = 75
+
+// Write writes a calendar called name holding events. stamp is when the
+// feed was generated.
+func Write(w io.Writer, name string, events []Event, stamp time.Time) error {
+	b := bufio.NewWriter(w)
+	line := func(s string) {
+		b.WriteString(fold(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Cashbook//Bills//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escape(name))
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escape(e.UID))
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:" + e.Day.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + e.Day.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escape(e.Description))
+		}
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				categories[i] = escape(c)
+			}
+			line("CATEGORIES:" + strings.Join(categories, ","))
+		}
+		line("TRANSP:TRANSPARENT")
+		if e.Cancelled {
+			line("STATUS:CANCELLED")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.Flush()
+}
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// fold splits s into lines of at most maxLine octets, continued with a
+// leading space, without splitting UTF-8 sequences.
+func fold(s string) string {
+	if len(s) <= maxLine {
+		return s
+	}
+	var b strings.Builder
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// the leading space counts towards the next line
+		limit = maxLine - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
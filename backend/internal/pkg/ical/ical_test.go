@@ -0,0 +1,54 @@
+package ical_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/afandimsr/cashbook-backend/internal/pkg/ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	stamp := time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC)
+	err := ical.Write(&buf, "Bills", []ical.Event{
+		{UID: "1@test", Day: time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), Summary: "Rent; flat, 2", Description: "Amount: 500.00\nNote: a\\b", Categories: []string{"Housing, etc"}},
+		{UID: "2@test", Day: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), Summary: "Skipped", Cancelled: true},
+	}, stamp)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, out, "X-WR-CALNAME:Bills\r\n")
+	assert.Contains(t, out, "DTSTAMP:20261018T103000Z\r\n")
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20261025\r\nDTEND;VALUE=DATE:20261026\r\n")
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20261231\r\nDTEND;VALUE=DATE:20270101\r\n")
+	assert.Contains(t, out, `SUMMARY:Rent\; flat\, 2`+"\r\n")
+	assert.Contains(t, out, `DESCRIPTION:Amount: 500.00\nNote: a\\b`+"\r\n")
+	assert.Contains(t, out, `CATEGORIES:Housing\, etc`+"\r\n")
+	assert.Equal(t, 1, strings.Count(out, "STATUS:CANCELLED"))
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VEVENT"))
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	var buf bytes.Buffer
+	summary := strings.Repeat("é", 100)
+	require.NoError(t, ical.Write(&buf, "Bills", []ical.Event{{UID: "1@test", Day: time.Now(), Summary: summary}}, time.Now()))
+
+	var unfolded []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+		assert.True(t, utf8.ValidString(line), "folded inside a character")
+		if strings.HasPrefix(line, " ") {
+			unfolded[len(unfolded)-1] += line[1:]
+			continue
+		}
+		unfolded = append(unfolded, line)
+	}
+	assert.Contains(t, unfolded, "SUMMARY:"+summary)
+}
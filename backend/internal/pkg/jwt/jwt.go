@@ -7,6 +7,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// AccessTokenTTL is how long a token from GenerateToken is valid. Sessions
+// outlive it by refreshing it.
+const AccessTokenTTL = 15 * time.Minute
+
 var secretKey []byte
 
 func SetSecret(secret string) {
@@ -18,6 +22,8 @@ type Claims struct {
 	Email  string   `json:"email"`
 	Name   string   `json:"name,omitempty"`
 	Roles  []string `json:"roles,omitempty"`
+	// SessionID is the session the token was issued for.
+	SessionID int64 `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -28,14 +34,16 @@ type TempClaims struct {
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID int64, email string, name string, roles []string) (string, error) {
+// GenerateToken creates a short-lived access token for session sessionID.
+func GenerateToken(userID, sessionID int64, email string, name string, roles []string) (string, error) {
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
-		Roles:  roles,
+		UserID:    userID,
+		SessionID: sessionID,
+		Email:     email,
+		Name:      name,
+		Roles:     roles,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
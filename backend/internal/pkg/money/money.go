@@ -0,0 +1,283 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places kept for every amount. It matches the
+// DECIMAL(15, 2) columns used for money in the database.
+const Scale = 2
+
+const unit = 100 // 10^Scale
+
+// DefaultCurrency is used wherever a currency has not been chosen explicitly.
+const DefaultCurrency = "IDR"
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooManyDecimals  = errors.New("amount has more than 2 decimal places")
+	ErrOutOfRange       = errors.New("amount is out of range")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// Amount is an exact monetary value stored as an integer number of minor
+// units (1/100 of the currency unit). Use it instead of float64 so sums never
+// drift.
+type Amount int64
+
+// FromMinor builds an Amount from a number of minor units.
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// FromMajor builds an Amount from a whole number of currency units.
+func FromMajor(major int64) Amount {
+	return Amount(major * unit)
+}
+
+// Parse reads a plain decimal string such as "1500", "-12.5" or "0.05".
+// Exponents, thousands separators and more than Scale decimals are rejected.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, ErrInvalidAmount
+	}
+	if hasDot && fracPart == "" {
+		return 0, ErrInvalidAmount
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidAmount
+	}
+	if len(fracPart) > Scale {
+		return 0, ErrTooManyDecimals
+	}
+	fracPart += strings.Repeat("0", Scale-len(fracPart))
+
+	var major int64
+	if intPart != "" {
+		var err error
+		major, err = strconv.ParseInt(intPart, 10, 64)
+		if err != nil || major > math.MaxInt64/unit {
+			return 0, ErrOutOfRange
+		}
+	}
+	minor, _ := strconv.ParseInt(fracPart, 10, 64)
+
+	v := major*unit + minor
+	if v < 0 {
+		return 0, ErrOutOfRange
+	}
+	if neg {
+		v = -v
+	}
+	return Amount(v), nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units.
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Float64 returns an approximate value for ratios and display only. Never feed
+// the result back into an Amount.
+func (a Amount) Float64() float64 {
+	return float64(a) / unit
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+// Abs returns the absolute value of a.
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// MulRatio returns a*num/den rounded half away from zero.
+func (a Amount) MulRatio(num, den int64) Amount {
+	if den == 0 {
+		return 0
+	}
+	// with a positive denominator the remainder has the sign of the result
+	if den < 0 {
+		num, den = -num, -den
+	}
+	p := int64(a) * num
+	q, r := p/den, p%den
+	switch {
+	case r*2 >= den:
+		q++
+	case -r*2 >= den:
+		q--
+	}
+	return Amount(q)
+}
+
+// MulRat returns a*r rounded half away from zero. It is used to apply exchange
+// rates, which carry more precision than an Amount.
+func (a Amount) MulRat(r *big.Rat) Amount {
+	p := new(big.Rat).Mul(big.NewRat(int64(a), 1), r)
+	num, den := p.Num(), p.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Amount(q.Int64())
+}
+
+// String formats the amount with exactly Scale decimals, e.g. "-12.30".
+func (a Amount) String() string {
+	v := int64(a)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/unit, v%unit)
+}
+
+// MarshalJSON writes the amount as an exact JSON number.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string and applies
+// the same strict rules as Parse.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = FromMajor(v)
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+}
+
+func (a *Amount) scanString(s string) error {
+	// NUMERIC results such as SUM() may carry trailing zeros beyond Scale.
+	if intPart, fracPart, ok := strings.Cut(s, "."); ok && len(fracPart) > Scale {
+		if strings.Trim(fracPart[Scale:], "0") != "" {
+			return ErrTooManyDecimals
+		}
+		s = intPart + "." + fracPart[:Scale]
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value implements driver.Valuer so amounts are written as exact decimals.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Money is an Amount in a specific currency.
+type Money struct {
+	Amount   Amount `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New returns a Money value, defaulting the currency when it is empty.
+func New(amount Amount, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add sums two values of the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub subtracts two values of the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
@@ -0,0 +1,143 @@
+package money_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	valid := map[string]int64{
+		"0":          0,
+		"15000":      1500000,
+		"12.5":       1250,
+		"12.50":      1250,
+		"-0.05":      -5,
+		"+3.10":      310,
+		".75":        75,
+		"1234567.89": 123456789,
+	}
+	for in, want := range valid {
+		got, err := money.Parse(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got.Minor(), in)
+	}
+
+	invalid := map[string]error{
+		"":                     money.ErrInvalidAmount,
+		"abc":                  money.ErrInvalidAmount,
+		"1e3":                  money.ErrInvalidAmount,
+		"1,000":                money.ErrInvalidAmount,
+		"12.":                  money.ErrInvalidAmount,
+		"-":                    money.ErrInvalidAmount,
+		"1.005":                money.ErrTooManyDecimals,
+		"0.001":                money.ErrTooManyDecimals,
+		"99999999999999999999": money.ErrOutOfRange,
+	}
+	for in, want := range invalid {
+		_, err := money.Parse(in)
+		assert.ErrorIs(t, err, want, in)
+	}
+}
+
+func TestSumHasNoDrift(t *testing.T) {
+	var total money.Amount
+	var f float64
+	for i := 0; i < 1000; i++ {
+		a, _ := money.Parse("0.10")
+		total += a
+		f += 0.10
+	}
+	assert.Equal(t, "100.00", total.String())
+	assert.NotEqual(t, 100.0, f)
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		Amount money.Amount `json:"amount"`
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 1500.25}`), &v))
+	assert.Equal(t, int64(150025), v.Amount.Minor())
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "7.5"}`), &v))
+	assert.Equal(t, int64(750), v.Amount.Minor())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 1.234}`), &v))
+
+	out, err := json.Marshal(v)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 7.50}`, string(out))
+}
+
+func TestScan(t *testing.T) {
+	var a money.Amount
+
+	assert.NoError(t, a.Scan([]byte("1250.40")))
+	assert.Equal(t, int64(125040), a.Minor())
+
+	// SUM() over NUMERIC can widen the scale with trailing zeros
+	assert.NoError(t, a.Scan([]byte("10.5000")))
+	assert.Equal(t, int64(1050), a.Minor())
+
+	assert.ErrorIs(t, a.Scan([]byte("10.5001")), money.ErrTooManyDecimals)
+
+	assert.NoError(t, a.Scan(nil))
+	assert.True(t, a.IsZero())
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		amount   string
+		num, den int64
+		want     string
+	}{
+		{"100.00", 110, 100, "110.00"},
+		{"0.05", 1, 2, "0.03"},
+		{"-0.05", 1, 2, "-0.03"},
+		{"0.05", -1, 2, "-0.03"},
+		{"0.05", 1, -2, "-0.03"},
+		{"-0.05", 1, -2, "0.03"},
+		{"0.10", 1, -3, "-0.03"},
+		{"0.10", -2, -3, "0.07"},
+		{"1.00", 1, 0, "0.00"},
+	}
+	for _, tt := range tests {
+		a, err := money.Parse(tt.amount)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, a.MulRatio(tt.num, tt.den).String(), "%s * %d/%d", tt.amount, tt.num, tt.den)
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	idr := money.New(money.FromMajor(10), "")
+	usd := money.New(money.FromMajor(1), "USD")
+
+	sum, err := idr.Add(idr)
+	assert.NoError(t, err)
+	assert.Equal(t, "20.00 IDR", sum.String())
+
+	_, err = idr.Add(usd)
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
+
+func TestMulRat(t *testing.T) {
+	usd, _ := money.Parse("12.34")
+	rate, _ := new(big.Rat).SetString("16250.5")
+	assert.Equal(t, "200531.17", usd.MulRat(rate).String())
+
+	inv := new(big.Rat).Inv(rate)
+	idr, _ := money.Parse("16250.50")
+	assert.Equal(t, "1.00", idr.MulRat(inv).String())
+	assert.Equal(t, "-1.00", (-idr).MulRat(inv).String())
+}
+
+func TestValidCurrency(t *testing.T) {
+	assert.True(t, money.ValidCurrency("IDR"))
+	assert.False(t, money.ValidCurrency("idr"))
+	assert.False(t, money.ValidCurrency("RUPIAH"))
+}
@@ -0,0 +1,44 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/pkg/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, "Transactions & more")
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteRow("date", "note", "amount"))
+	require.NoError(t, w.WriteRow("2026-10-01", "Fish <& chips>", xlsx.Number("-12.50")))
+	assert.Error(t, w.WriteRow(1.5))
+	require.NoError(t, w.Close())
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(body)
+	}
+
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files["xl/workbook.xml"], `name="Transactions &amp; more"`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row r="2"><c t="inlineStr"><is><t xml:space="preserve">2026-10-01</t></is></c>`)
+	assert.Contains(t, sheet, `Fish &lt;&amp; chips&gt;`)
+	assert.Contains(t, sheet, `<c t="n"><v>-12.50</v></c>`)
+	assert.Contains(t, sheet, `</sheetData></worksheet>`)
+}
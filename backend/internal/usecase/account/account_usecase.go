@@ -0,0 +1,192 @@
+package account
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+type Usecase interface {
+	GetAllByUserID(userID int64) ([]account.Account, error)
+	GetByID(userID, id int64) (account.Account, error)
+	Create(userID int64, a account.Account) (account.Account, error)
+	Update(userID, id int64, a account.Account) error
+	Delete(userID, id int64) error
+	Transfer(userID int64, tr account.Transfer) (account.Transfer, error)
+	DeleteTransfer(userID, id int64) error
+}
+
+type usecase struct {
+	repo account.Repository
+}
+
+func New(repo account.Repository) Usecase {
+	return &usecase{repo: repo}
+}
+
+func (u *usecase) GetAllByUserID(userID int64) ([]account.Account, error) {
+	return u.repo.FindAllByUserID(userID)
+}
+
+func (u *usecase) GetByID(userID, id int64) (account.Account, error) {
+	a, err := u.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, account.ErrNotFound) {
+			return account.Account{}, apperror.NotFound("account not found", err)
+		}
+		return account.Account{}, err
+	}
+	if a.UserID != userID {
+		return account.Account{}, apperror.NotFound("account not found", account.ErrNotFound)
+	}
+	return a, nil
+}
+
+func (u *usecase) Create(userID int64, a account.Account) (account.Account, error) {
+	if err := validate(&a); err != nil {
+		return account.Account{}, err
+	}
+	a.UserID = userID
+	if err := u.repo.Save(&a); err != nil {
+		return account.Account{}, err
+	}
+	a.Balance = a.OpeningBalance
+	return a, nil
+}
+
+func (u *usecase) Update(userID, id int64, a account.Account) error {
+	existing, err := u.GetByID(userID, id)
+	if err != nil {
+		return err
+	}
+	if a.Currency == "" {
+		a.Currency = existing.Currency
+	}
+	if err := validate(&a); err != nil {
+		return err
+	}
+	if a.Currency != existing.Currency {
+		return apperror.BadRequest("the currency of an account cannot be changed", nil)
+	}
+
+	existing.Name = a.Name
+	existing.Type = a.Type
+	existing.OpeningBalance = a.OpeningBalance
+
+	return u.repo.Update(&existing)
+}
+
+func (u *usecase) Delete(userID, id int64) error {
+	if _, err := u.GetByID(userID, id); err != nil {
+		return err
+	}
+	if err := u.repo.Delete(id); err != nil {
+		if errors.Is(err, account.ErrInUse) {
+			return apperror.New(http.StatusConflict, "account still has transactions or recurring transactions; move or delete them first", err).WithCode(apperror.ConflictError)
+		}
+		return err
+	}
+	return nil
+}
+
+func (u *usecase) Transfer(userID int64, tr account.Transfer) (account.Transfer, error) {
+	if tr.Amount <= 0 {
+		return account.Transfer{}, apperror.BadRequest("amount must be greater than zero", nil)
+	}
+	if tr.FromAccountID == tr.ToAccountID {
+		return account.Transfer{}, apperror.BadRequest("cannot transfer to the same account", nil)
+	}
+	from, err := u.GetByID(userID, tr.FromAccountID)
+	if err != nil {
+		return account.Transfer{}, err
+	}
+	to, err := u.GetByID(userID, tr.ToAccountID)
+	if err != nil {
+		return account.Transfer{}, err
+	}
+	if from.Currency != to.Currency {
+		return account.Transfer{}, apperror.BadRequest("cannot transfer between accounts in different currencies", nil)
+	}
+
+	tr.UserID = userID
+	if tr.Date.IsZero() {
+		tr.Date = time.Now()
+	}
+	if err := u.repo.SaveTransfer(&tr); err != nil {
+		return account.Transfer{}, err
+	}
+	return tr, nil
+}
+
+func (u *usecase) DeleteTransfer(userID, id int64) error {
+	tr, err := u.repo.FindTransferByID(id)
+	if err != nil {
+		if errors.Is(err, account.ErrTransferNotFound) {
+			return apperror.NotFound("transfer not found", err)
+		}
+		return err
+	}
+	if tr.UserID != userID {
+		return apperror.NotFound("transfer not found", account.ErrTransferNotFound)
+	}
+	return u.repo.DeleteTransfer(id)
+}
+
+func validate(a *account.Account) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return apperror.BadRequest("name is required", nil)
+	}
+	if a.Type == "" {
+		a.Type = account.Cash
+	}
+	if !a.Type.Valid() {
+		return apperror.BadRequest("invalid account type", nil)
+	}
+	a.Currency = strings.ToUpper(strings.TrimSpace(a.Currency))
+	if a.Currency == "" {
+		a.Currency = money.DefaultCurrency
+	}
+	if !money.ValidCurrency(a.Currency) {
+		return apperror.BadRequest("invalid currency", nil)
+	}
+	return nil
+}
+
+// Resolve checks that accountID belongs to userID and returns the account.
+// When no account is given the user's default account is used, creating a
+// "Cash" account for users who have none yet.
+func Resolve(repo account.Repository, userID, accountID int64) (account.Account, error) {
+	if accountID != 0 {
+		a, err := repo.FindByID(accountID)
+		if errors.Is(err, account.ErrNotFound) {
+			return account.Account{}, apperror.BadRequest("invalid account_id", err)
+		}
+		if err != nil {
+			return account.Account{}, err
+		}
+		if a.UserID != userID {
+			return account.Account{}, apperror.BadRequest("invalid account_id", account.ErrNotFound)
+		}
+		return a, nil
+	}
+
+	a, err := repo.FindDefault(userID)
+	if err == nil {
+		return a, nil
+	}
+	if !errors.Is(err, account.ErrNotFound) {
+		return account.Account{}, err
+	}
+
+	a = account.Account{UserID: userID, Name: "Cash", Type: account.Cash, Currency: money.DefaultCurrency}
+	if err := repo.Save(&a); err != nil {
+		return account.Account{}, err
+	}
+	return a, nil
+}
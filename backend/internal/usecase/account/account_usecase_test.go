@@ -0,0 +1,224 @@
+package account_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) FindAllByUserID(userID int64) ([]account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) FindByID(id int64) (account.Account, error) {
+	args := m.Called(id)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) FindDefault(userID int64) (account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) Save(a *account.Account) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Update(a *account.Account) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) FindTransferByID(id int64) (account.Transfer, error) {
+	args := m.Called(id)
+	return args.Get(0).(account.Transfer), args.Error(1)
+}
+
+func (m *MockAccountRepository) SaveTransfer(tr *account.Transfer) error {
+	args := m.Called(tr)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) DeleteTransfer(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestTransfer(t *testing.T) {
+	userID := int64(1)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		mockRepo.On("FindByID", int64(10)).Return(account.Account{ID: 10, UserID: userID}, nil).Once()
+		mockRepo.On("FindByID", int64(11)).Return(account.Account{ID: 11, UserID: userID}, nil).Once()
+		mockRepo.On("SaveTransfer", mock.MatchedBy(func(tr *account.Transfer) bool {
+			return tr.UserID == userID && tr.Amount == money.FromMajor(50) && !tr.Date.IsZero()
+		})).Return(nil).Once()
+
+		_, err := usecase.Transfer(userID, account.Transfer{FromAccountID: 10, ToAccountID: 11, Amount: money.FromMajor(50)})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("SameAccount", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		_, err := usecase.Transfer(userID, account.Transfer{FromAccountID: 10, ToAccountID: 10, Amount: money.FromMajor(50)})
+
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "SaveTransfer", mock.Anything)
+	})
+
+	t.Run("NonPositiveAmount", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		_, err := usecase.Transfer(userID, account.Transfer{FromAccountID: 10, ToAccountID: 11, Amount: 0})
+
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "SaveTransfer", mock.Anything)
+	})
+
+	t.Run("CurrencyMismatch", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		mockRepo.On("FindByID", int64(10)).Return(account.Account{ID: 10, UserID: userID, Currency: "IDR"}, nil).Once()
+		mockRepo.On("FindByID", int64(11)).Return(account.Account{ID: 11, UserID: userID, Currency: "USD"}, nil).Once()
+
+		_, err := usecase.Transfer(userID, account.Transfer{FromAccountID: 10, ToAccountID: 11, Amount: money.FromMajor(50)})
+
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "SaveTransfer", mock.Anything)
+	})
+
+	t.Run("ForeignAccount", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		mockRepo.On("FindByID", int64(10)).Return(account.Account{ID: 10, UserID: userID}, nil).Once()
+		mockRepo.On("FindByID", int64(20)).Return(account.Account{ID: 20, UserID: 2}, nil).Once()
+
+		_, err := usecase.Transfer(userID, account.Transfer{FromAccountID: 10, ToAccountID: 20, Amount: money.FromMajor(50)})
+
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "SaveTransfer", mock.Anything)
+	})
+}
+
+func TestResolveCreatesDefaultAccount(t *testing.T) {
+	mockRepo := new(MockAccountRepository)
+	userID := int64(1)
+
+	mockRepo.On("FindDefault", userID).Return(account.Account{}, account.ErrNotFound).Once()
+	mockRepo.On("Save", mock.MatchedBy(func(a *account.Account) bool {
+		return a.UserID == userID && a.Type == account.Cash
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*account.Account).ID = 7
+	}).Return(nil).Once()
+
+	a, err := uc.Resolve(mockRepo, userID, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), a.ID)
+	assert.Equal(t, money.DefaultCurrency, a.Currency)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDelete(t *testing.T) {
+	userID := int64(1)
+
+	t.Run("Empty", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		mockRepo.On("FindByID", int64(10)).Return(account.Account{ID: 10, UserID: userID}, nil).Once()
+		mockRepo.On("Delete", int64(10)).Return(nil).Once()
+
+		assert.NoError(t, usecase.Delete(userID, 10))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("HasTransactions", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		mockRepo.On("FindByID", int64(10)).Return(account.Account{ID: 10, UserID: userID}, nil).Once()
+		mockRepo.On("Delete", int64(10)).Return(account.ErrInUse).Once()
+
+		err := usecase.Delete(userID, 10)
+
+		var appErr *apperror.AppError
+		if assert.ErrorAs(t, err, &appErr) {
+			assert.Equal(t, http.StatusConflict, appErr.Code)
+		}
+	})
+
+	t.Run("ForeignAccount", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		usecase := uc.New(mockRepo)
+
+		mockRepo.On("FindByID", int64(20)).Return(account.Account{ID: 20, UserID: 2}, nil).Once()
+
+		assert.Error(t, usecase.Delete(userID, 20))
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+	})
+}
+
+func TestResolveGivenAccount(t *testing.T) {
+	userID := int64(1)
+
+	t.Run("Unknown", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		mockRepo.On("FindByID", int64(10)).Return(account.Account{}, account.ErrNotFound).Once()
+
+		_, err := uc.Resolve(mockRepo, userID, 10)
+
+		var appErr *apperror.AppError
+		if assert.ErrorAs(t, err, &appErr) {
+			assert.Equal(t, http.StatusBadRequest, appErr.Code)
+		}
+	})
+
+	t.Run("ForeignAccount", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		mockRepo.On("FindByID", int64(20)).Return(account.Account{ID: 20, UserID: 2}, nil).Once()
+
+		_, err := uc.Resolve(mockRepo, userID, 20)
+
+		var appErr *apperror.AppError
+		if assert.ErrorAs(t, err, &appErr) {
+			assert.Equal(t, http.StatusBadRequest, appErr.Code)
+		}
+	})
+
+	t.Run("DatabaseError", func(t *testing.T) {
+		mockRepo := new(MockAccountRepository)
+		mockRepo.On("FindByID", int64(10)).Return(account.Account{}, assert.AnError).Once()
+
+		_, err := uc.Resolve(mockRepo, userID, 10)
+
+		assert.Equal(t, assert.AnError, err, "left for the error middleware to render as a 500")
+	})
+}
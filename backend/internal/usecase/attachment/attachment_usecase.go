@@ -0,0 +1,249 @@
+package attachment
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+)
+
+// DefaultMaxSize is the upload limit used when New is given none.
+const DefaultMaxSize = 10 << 20
+
+// maxFileNameLength matches the file_name column.
+const maxFileNameLength = 255
+
+// purgeBatchSize is the number of orphaned attachments removed per query.
+const purgeBatchSize = 100
+
+// allowedTypes maps the sniffed MIME types accepted for upload to the file
+// extension used in storage keys.
+var allowedTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type Usecase interface {
+	GetAll(userID, transactionID int64) ([]attachment.Attachment, error)
+	// Upload stores the contents of r as an attachment of the transaction.
+	// The type is sniffed from the contents; the file name is only kept
+	// for display.
+	Upload(userID, transactionID int64, fileName string, r io.Reader) (attachment.Attachment, error)
+	// Open returns the attachment with its contents, which the caller must
+	// close.
+	Open(userID, transactionID, id int64) (attachment.Attachment, io.ReadCloser, error)
+	Delete(userID, transactionID, id int64) error
+	// Purge removes the given attachments with their stored files and
+	// returns how many were removed before any failure.
+	Purge(attachments []attachment.Attachment) (int, error)
+	// PurgeOrphaned removes the attachments left behind by deleted
+	// transactions and returns how many were removed.
+	PurgeOrphaned() (int, error)
+}
+
+type usecase struct {
+	repo    attachment.Repository
+	txRepo  transaction.Repository
+	storage attachment.Storage
+	maxSize int64
+}
+
+func New(repo attachment.Repository, txRepo transaction.Repository, storage attachment.Storage, maxSize int64) Usecase {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &usecase{
+		repo:    repo,
+		txRepo:  txRepo,
+		storage: storage,
+		maxSize: maxSize,
+	}
+}
+
+// checkTransaction returns 404 when the transaction does not exist or
+// belongs to someone else.
+func (u *usecase) checkTransaction(userID, transactionID int64) error {
+	if _, err := u.txRepo.FindByIDForUser(userID, transactionID); err != nil {
+		if errors.Is(err, transaction.ErrNotFound) {
+			return apperror.NotFound("transaction not found", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (u *usecase) find(userID, transactionID, id int64) (attachment.Attachment, error) {
+	if err := u.checkTransaction(userID, transactionID); err != nil {
+		return attachment.Attachment{}, err
+	}
+	a, err := u.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, attachment.ErrAttachmentNotFound) {
+			return attachment.Attachment{}, apperror.NotFound("attachment not found", err)
+		}
+		return attachment.Attachment{}, err
+	}
+	if a.UserID != userID || a.TransactionID != transactionID {
+		return attachment.Attachment{}, apperror.NotFound("attachment not found", attachment.ErrAttachmentNotFound)
+	}
+	return a, nil
+}
+
+func (u *usecase) GetAll(userID, transactionID int64) ([]attachment.Attachment, error) {
+	if err := u.checkTransaction(userID, transactionID); err != nil {
+		return nil, err
+	}
+	return u.repo.FindAllByTransactionID(transactionID)
+}
+
+func (u *usecase) Upload(userID, transactionID int64, fileName string, r io.Reader) (attachment.Attachment, error) {
+	if err := u.checkTransaction(userID, transactionID); err != nil {
+		return attachment.Attachment{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, u.maxSize+1))
+	if err != nil {
+		return attachment.Attachment{}, apperror.BadRequest("cannot read file", err)
+	}
+	if len(data) == 0 {
+		return attachment.Attachment{}, apperror.BadRequest("file is empty", nil)
+	}
+	if int64(len(data)) > u.maxSize {
+		msg := fmt.Sprintf("file is larger than %d MB", u.maxSize>>20)
+		return attachment.Attachment{}, apperror.New(http.StatusRequestEntityTooLarge, msg, nil).WithCode(apperror.BadRequestError)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		msg := fmt.Sprintf("files of type %s cannot be attached; use a JPEG, PNG, GIF, WebP or PDF file", contentType)
+		return attachment.Attachment{}, apperror.New(http.StatusUnsupportedMediaType, msg, nil).WithCode(apperror.BadRequestError)
+	}
+
+	key, err := storageKey(userID, transactionID, ext)
+	if err != nil {
+		return attachment.Attachment{}, apperror.Internal(err)
+	}
+
+	a := attachment.Attachment{
+		UserID:        userID,
+		TransactionID: transactionID,
+		FileName:      cleanFileName(fileName, ext),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		StorageKey:    key,
+	}
+	if err := u.storage.Put(key, bytes.NewReader(data), a.Size, contentType); err != nil {
+		return attachment.Attachment{}, apperror.Internal(err)
+	}
+	if err := u.repo.Save(&a); err != nil {
+		_ = u.storage.Delete(key)
+		return attachment.Attachment{}, err
+	}
+	return a, nil
+}
+
+func (u *usecase) Open(userID, transactionID, id int64) (attachment.Attachment, io.ReadCloser, error) {
+	a, err := u.find(userID, transactionID, id)
+	if err != nil {
+		return attachment.Attachment{}, nil, err
+	}
+	rc, err := u.storage.Get(a.StorageKey)
+	if err != nil {
+		if errors.Is(err, attachment.ErrObjectNotFound) {
+			return attachment.Attachment{}, nil, apperror.NotFound("attachment file is missing", err)
+		}
+		return attachment.Attachment{}, nil, apperror.Internal(err)
+	}
+	return a, rc, nil
+}
+
+// Delete removes the stored file before the row, so a failure never leaves a
+// file that nothing points to.
+func (u *usecase) Delete(userID, transactionID, id int64) error {
+	a, err := u.find(userID, transactionID, id)
+	if err != nil {
+		return err
+	}
+	if err := u.storage.Delete(a.StorageKey); err != nil {
+		return apperror.Internal(err)
+	}
+	return u.repo.Delete(a.ID)
+}
+
+// Purge removes each file before its row, like Delete.
+func (u *usecase) Purge(attachments []attachment.Attachment) (int, error) {
+	for i, a := range attachments {
+		if err := u.storage.Delete(a.StorageKey); err != nil {
+			return i, err
+		}
+		if err := u.repo.Delete(a.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(attachments), nil
+}
+
+func (u *usecase) PurgeOrphaned() (int, error) {
+	purged := 0
+	for {
+		orphans, err := u.repo.FindOrphaned(purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		n, err := u.Purge(orphans)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		if len(orphans) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// storageKey returns a new unguessable key grouping the files of one
+// transaction.
+func storageKey(userID, transactionID int64, ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d/%s%s", userID, transactionID, hex.EncodeToString(b), ext), nil
+}
+
+// cleanFileName drops any directory and control characters from the name
+// given by the client, falling back to "attachment" plus ext.
+func cleanFileName(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment" + ext
+	}
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,261 @@
+package attachment_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/attachment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAttachmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentRepository) FindAllByTransactionID(transactionID int64) ([]attachment.Attachment, error) {
+	args := m.Called(transactionID)
+	return args.Get(0).([]attachment.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) FindByID(id int64) (attachment.Attachment, error) {
+	args := m.Called(id)
+	return args.Get(0).(attachment.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) Save(a *attachment.Attachment) error {
+	args := m.Called(a)
+	a.ID = 10
+	return args.Error(0)
+}
+
+func (m *MockAttachmentRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAttachmentRepository) FindOrphaned(limit int) ([]attachment.Attachment, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]attachment.Attachment), args.Error(1)
+}
+
+// MockTransactionRepository only implements the lookup the attachment
+// usecase needs.
+type MockTransactionRepository struct {
+	transaction.Repository
+	mock.Mock
+}
+
+func (m *MockTransactionRepository) FindByIDForUser(userID, id int64) (transaction.Transaction, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(transaction.Transaction), args.Error(1)
+}
+
+type memoryStorage struct {
+	objects map[string][]byte
+}
+
+func (s *memoryStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryStorage) Get(key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, attachment.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStorage) Delete(key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+var (
+	pngData = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	pdfData = []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\n")
+)
+
+func setup(maxSize int64) (uc.Usecase, *MockAttachmentRepository, *memoryStorage) {
+	repo := new(MockAttachmentRepository)
+	txRepo := new(MockTransactionRepository)
+	txRepo.On("FindByIDForUser", int64(7), int64(1)).Return(transaction.Transaction{ID: 1, UserID: 7}, nil).Maybe()
+	txRepo.On("FindByIDForUser", int64(7), int64(2)).Return(transaction.Transaction{}, transaction.ErrNotFound).Maybe()
+	store := &memoryStorage{objects: map[string][]byte{}}
+	return uc.New(repo, txRepo, store, maxSize), repo, store
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, status, appErr.Code)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	usecase, repo, store := setup(0)
+	repo.On("Save", mock.AnythingOfType("*attachment.Attachment")).Return(nil).Once()
+
+	a, err := usecase.Upload(7, 1, `C:\Users\me\receipt "lunch".png`, bytes.NewReader(pngData))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), a.ID)
+	assert.Equal(t, "image/png", a.ContentType)
+	assert.Equal(t, "receipt lunch.png", a.FileName)
+	assert.Equal(t, int64(len(pngData)), a.Size)
+	assert.True(t, strings.HasPrefix(a.StorageKey, "7/1/"))
+	assert.True(t, strings.HasSuffix(a.StorageKey, ".png"))
+	assert.Equal(t, pngData, store.objects[a.StorageKey])
+}
+
+func TestUploadSniffsTypeFromContents(t *testing.T) {
+	usecase, repo, _ := setup(0)
+	repo.On("Save", mock.AnythingOfType("*attachment.Attachment")).Return(nil).Once()
+
+	// the name says image, the contents say PDF
+	a, err := usecase.Upload(7, 1, "receipt.jpg", bytes.NewReader(pdfData))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/pdf", a.ContentType)
+	assert.True(t, strings.HasSuffix(a.StorageKey, ".pdf"))
+}
+
+func TestUploadRejectsUnsupportedType(t *testing.T) {
+	usecase, repo, store := setup(0)
+
+	_, err := usecase.Upload(7, 1, "receipt.png", strings.NewReader("<html><script>alert(1)</script></html>"))
+
+	assertStatus(t, err, http.StatusUnsupportedMediaType)
+	assert.Empty(t, store.objects)
+	repo.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestUploadRejectsLargeFiles(t *testing.T) {
+	usecase, _, store := setup(16)
+
+	_, err := usecase.Upload(7, 1, "receipt.png", bytes.NewReader(pngData))
+
+	assertStatus(t, err, http.StatusRequestEntityTooLarge)
+	assert.Empty(t, store.objects)
+}
+
+func TestUploadRejectsEmptyFiles(t *testing.T) {
+	usecase, _, _ := setup(0)
+
+	_, err := usecase.Upload(7, 1, "receipt.png", strings.NewReader(""))
+
+	assertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestUploadToForeignTransaction(t *testing.T) {
+	usecase, _, store := setup(0)
+
+	_, err := usecase.Upload(7, 2, "receipt.png", bytes.NewReader(pngData))
+
+	assertStatus(t, err, http.StatusNotFound)
+	assert.Empty(t, store.objects)
+}
+
+func TestUploadRemovesFileWhenSaveFails(t *testing.T) {
+	usecase, repo, store := setup(0)
+	repo.On("Save", mock.AnythingOfType("*attachment.Attachment")).Return(assert.AnError).Once()
+
+	_, err := usecase.Upload(7, 1, "receipt.png", bytes.NewReader(pngData))
+
+	assert.Error(t, err)
+	assert.Empty(t, store.objects)
+}
+
+func TestOpen(t *testing.T) {
+	usecase, repo, store := setup(0)
+	store.objects["7/1/abc.pdf"] = pdfData
+	repo.On("FindByID", int64(3)).Return(attachment.Attachment{ID: 3, UserID: 7, TransactionID: 1, StorageKey: "7/1/abc.pdf"}, nil)
+
+	a, rc, err := usecase.Open(7, 1, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), a.ID)
+	data, _ := io.ReadAll(rc)
+	assert.Equal(t, pdfData, data)
+}
+
+func TestOpenThroughAnotherTransaction(t *testing.T) {
+	usecase, repo, _ := setup(0)
+	repo.On("FindByID", int64(3)).Return(attachment.Attachment{ID: 3, UserID: 7, TransactionID: 5}, nil)
+
+	_, _, err := usecase.Open(7, 1, 3)
+
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestDelete(t *testing.T) {
+	usecase, repo, store := setup(0)
+	store.objects["7/1/abc.pdf"] = pdfData
+	repo.On("FindByID", int64(3)).Return(attachment.Attachment{ID: 3, UserID: 7, TransactionID: 1, StorageKey: "7/1/abc.pdf"}, nil)
+	repo.On("Delete", int64(3)).Return(nil).Once()
+
+	err := usecase.Delete(7, 1, 3)
+
+	assert.NoError(t, err)
+	assert.Empty(t, store.objects)
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteOnForeignTransaction(t *testing.T) {
+	usecase, repo, _ := setup(0)
+
+	err := usecase.Delete(7, 2, 3)
+
+	assertStatus(t, err, http.StatusNotFound)
+	repo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestPurgeStopsAtFirstFailure(t *testing.T) {
+	usecase, repo, store := setup(0)
+	store.objects["7/1/a.png"] = pngData
+	store.objects["7/1/b.pdf"] = pdfData
+	repo.On("Delete", int64(1)).Return(assert.AnError).Once()
+
+	purged, err := usecase.Purge([]attachment.Attachment{
+		{ID: 1, StorageKey: "7/1/a.png"},
+		{ID: 2, StorageKey: "7/1/b.pdf"},
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, purged)
+	assert.Contains(t, store.objects, "7/1/b.pdf")
+	repo.AssertNotCalled(t, "Delete", int64(2))
+}
+
+func TestPurgeOrphaned(t *testing.T) {
+	usecase, repo, store := setup(0)
+	store.objects["7/1/a.png"] = pngData
+	store.objects["7/1/b.pdf"] = pdfData
+	store.objects["7/4/c.pdf"] = pdfData
+	repo.On("FindOrphaned", 100).Return([]attachment.Attachment{
+		{ID: 1, StorageKey: "7/1/a.png"},
+		{ID: 2, StorageKey: "7/1/b.pdf"},
+	}, nil).Once()
+	repo.On("Delete", int64(1)).Return(nil).Once()
+	repo.On("Delete", int64(2)).Return(nil).Once()
+
+	purged, err := usecase.PurgeOrphaned()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, map[string][]byte{"7/4/c.pdf": pdfData}, store.objects)
+	repo.AssertExpectations(t)
+}
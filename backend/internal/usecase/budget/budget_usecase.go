@@ -1,41 +1,526 @@
 package budget
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
 	"github.com/afandimsr/cashbook-backend/internal/domain/budget"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	categoryUC "github.com/afandimsr/cashbook-backend/internal/usecase/category"
+	fxUC "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
 )
 
 type Usecase interface {
-	GetBudgets(userID int64, month, year int) ([]budget.Budget, error)
-	SetBudget(userID int64, b budget.Budget) error
-	GetBudgetByCategory(userID int64, categoryID int64, month, year int) (budget.Budget, error)
+	// GetBudgets returns the budgets whose period contains on, of one
+	// period or of all when period is empty.
+	GetBudgets(userID int64, on time.Time, period string) ([]budget.Budget, error)
+	// GetProgress returns the budgets GetBudgets returns with what has been
+	// spent against them so far, in the user's base currency.
+	GetProgress(userID int64, on time.Time, period string) ([]budget.Progress, error)
+	// SetBudget creates the budget of a category, or of all categories
+	// when CategoryID is 0, or updates the one the period already has.
+	SetBudget(userID int64, b budget.Budget) (budget.Budget, error)
+	// UpdateBudget changes the amount, currency, rollover and auto-copy of
+	// a budget. Its category and period stay.
+	UpdateBudget(userID, id int64, b budget.Budget) (budget.Budget, error)
+	DeleteBudget(userID, id int64) error
+	GetBudgetByCategory(userID int64, categoryID int64, period string, start time.Time) (budget.Budget, error)
+	// CopyBudgets copies the budgets of one period into another, adjusted
+	// by req.AdjustPercent. Copying into the following period also carries
+	// over what the rollover budgets have left.
+	CopyBudgets(userID int64, req budget.CopyRequest) (budget.CopyResult, error)
+	// CopyDue copies the auto-copy budgets of every user from the previous
+	// period into the one containing now, for each period length, skipping
+	// categories that already have a budget. It returns the number of
+	// budgets created.
+	CopyDue(now time.Time) (int, error)
 }
 
 type usecase struct {
-	repo budget.Repository
+	repo         budget.Repository
+	categoryRepo category.Repository
+	txRepo       transaction.Repository
+	fx           fxUC.Usecase
+}
+
+func New(repo budget.Repository, categoryRepo category.Repository, txRepo transaction.Repository, fx fxUC.Usecase) Usecase {
+	return &usecase{repo: repo, categoryRepo: categoryRepo, txRepo: txRepo, fx: fx}
+}
+
+// GetBudgets returns the budgets with each limit also converted into the
+// user's base currency at the last rate of its period.
+func (u *usecase) GetBudgets(userID int64, on time.Time, period string) ([]budget.Budget, error) {
+	var periods []string
+	if period != "" {
+		if !budget.ValidPeriod(period) {
+			return nil, apperror.BadRequest("period must be week, month, quarter or year", nil)
+		}
+		periods = append(periods, period)
+	}
+	budgets, err := u.repo.FindAllByUserID(userID, on, periods...)
+	if err != nil {
+		return nil, err
+	}
+	if budgets == nil {
+		budgets = []budget.Budget{}
+	}
+
+	base, err := u.fx.BaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range budgets {
+		b := &budgets[i]
+		if b.Currency == "" {
+			b.Currency = base
+		}
+		quote, err := u.fx.Quote(b.Currency, base, rateDay(b.End()))
+		if err != nil {
+			return nil, err
+		}
+		b.BaseAmount = quote.Apply(b.Limit())
+		b.Conversion = &quote
+	}
+	return budgets, nil
+}
+
+func (u *usecase) GetProgress(userID int64, on time.Time, period string) ([]budget.Progress, error) {
+	budgets, err := u.GetBudgets(userID, on, period)
+	if err != nil {
+		return nil, err
+	}
+	progress := []budget.Progress{}
+	if len(budgets) == 0 {
+		return progress, nil
+	}
+
+	base, err := u.fx.BaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// budgets of the same length share their period
+	byPeriod := make(map[string][]budget.Budget)
+	for _, b := range budgets {
+		byPeriod[b.Period] = append(byPeriod[b.Period], b)
+	}
+	spent := make(map[string]map[int64]money.Amount)
+	for period, group := range byPeriod {
+		if spent[period], err = u.spending(userID, group, func(budget.Budget) string { return base }); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	for _, b := range budgets {
+		progress = append(progress, b.Progress(spent[b.Period][b.CategoryID], now))
+	}
+	return progress, nil
+}
+
+// spending adds up the expenses against budgets that share one period,
+// keyed by category with 0 for the all-categories budget, each in the
+// currency that currency returns for its budget.
+func (u *usecase) spending(userID int64, budgets []budget.Budget, currency func(budget.Budget) string) (map[int64]money.Amount, error) {
+	spent := make(map[int64]money.Amount)
+	if len(budgets) == 0 {
+		return spent, nil
+	}
+	start, end := budgets[0].StartDate, budgets[0].End()
+	filter := transaction.Filter{StartDate: start, EndDate: end.Add(-time.Nanosecond)}
+	quotes := u.quoter(end)
+
+	byCategory := make(map[int64]budget.Budget)
+	for _, b := range budgets {
+		byCategory[b.CategoryID] = b
+	}
+
+	if overall, ok := byCategory[0]; ok {
+		totals, err := u.txRepo.SumByCurrency(userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range totals {
+			amount, err := quotes.convert(t.TotalExpense, t.Currency, currency(overall))
+			if err != nil {
+				return nil, err
+			}
+			spent[0] += amount
+		}
+		if len(byCategory) == 1 {
+			return spent, nil
+		}
+	}
+
+	filter.Type = transaction.TypeExpense
+	totals, err := u.txRepo.SumByCategory(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range totals {
+		b, ok := byCategory[t.CategoryID]
+		if !ok {
+			continue
+		}
+		amount, err := quotes.convert(t.Amount, t.Currency, currency(b))
+		if err != nil {
+			return nil, err
+		}
+		spent[t.CategoryID] += amount
+	}
+	return spent, nil
+}
+
+// quoter converts amounts at the rates that value a period, fetching each
+// rate once.
+type quoter struct {
+	fx     fxUC.Usecase
+	on     time.Time
+	quotes map[[2]string]fx.Quote
+}
+
+// quoter returns a quoter for the period ending at end.
+func (u *usecase) quoter(end time.Time) *quoter {
+	return &quoter{fx: u.fx, on: rateDay(end), quotes: make(map[[2]string]fx.Quote)}
+}
+
+func (q *quoter) convert(amount money.Amount, from, to string) (money.Amount, error) {
+	pair := [2]string{from, to}
+	quote, ok := q.quotes[pair]
+	if !ok {
+		var err error
+		if quote, err = q.fx.Quote(from, to, q.on); err != nil {
+			return 0, err
+		}
+		q.quotes[pair] = quote
+	}
+	return quote.Apply(amount), nil
+}
+
+// carries returns what each of the rollover budgets of one period passes
+// on to the next, keyed by category and in the budget's currency.
+func (u *usecase) carries(userID int64, budgets []budget.Budget) (map[int64]money.Amount, error) {
+	carries := make(map[int64]money.Amount)
+	var rollover []budget.Budget
+	for _, b := range budgets {
+		if b.Rollover != "" && b.Rollover != budget.RolloverNone {
+			rollover = append(rollover, b)
+		}
+	}
+	if len(rollover) == 0 {
+		return carries, nil
+	}
+
+	spent, err := u.spending(userID, rollover, func(b budget.Budget) string { return b.Currency })
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range rollover {
+		carries[b.CategoryID] = b.Carry(spent[b.CategoryID])
+	}
+	return carries, nil
+}
+
+func (u *usecase) CopyBudgets(userID int64, req budget.CopyRequest) (budget.CopyResult, error) {
+	if req.Period == "" {
+		req.Period = budget.PeriodMonth
+	}
+	if !budget.ValidPeriod(req.Period) {
+		return budget.CopyResult{}, apperror.BadRequest("period must be week, month, quarter or year", nil)
+	}
+	var err error
+	if req.From, err = copyDay(req.From, req.FromMonth, req.FromYear); err != nil {
+		return budget.CopyResult{}, err
+	}
+	if req.To, err = copyDay(req.To, req.ToMonth, req.ToYear); err != nil {
+		return budget.CopyResult{}, err
+	}
+	req.From = budget.PeriodStart(req.Period, req.From)
+	req.To = budget.PeriodStart(req.Period, req.To)
+	if req.From.Equal(req.To) {
+		return budget.CopyResult{}, apperror.BadRequest("cannot copy a period onto itself", nil)
+	}
+	if req.AdjustPercent <= -100 {
+		return budget.CopyResult{}, apperror.BadRequest("adjust_percent must be greater than -100", nil)
+	}
+
+	sources, err := u.repo.FindAllByUserID(userID, req.From, req.Period)
+	if err != nil {
+		return budget.CopyResult{}, err
+	}
+	return u.copy(userID, sources, req)
 }
 
-func New(repo budget.Repository) Usecase {
-	return &usecase{repo: repo}
+// copyDay returns day, or the first day of the month when the month
+// fields are used instead.
+func copyDay(day time.Time, month, year int) (time.Time, error) {
+	if !day.IsZero() {
+		return day, nil
+	}
+	if month < 1 || month > 12 {
+		return time.Time{}, apperror.BadRequest("month must be between 1 and 12", nil)
+	}
+	if year < 1 {
+		return time.Time{}, apperror.BadRequest("invalid year", nil)
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
 }
 
-func (u *usecase) GetBudgets(userID int64, month, year int) ([]budget.Budget, error) {
-	return u.repo.FindAllByUserID(userID, month, year)
+// copy copies sources, all of req.Period starting at req.From, into the
+// period starting at req.To.
+func (u *usecase) copy(userID int64, sources []budget.Budget, req budget.CopyRequest) (budget.CopyResult, error) {
+	result := budget.CopyResult{Copied: []budget.Budget{}, SkippedCategoryIDs: []int64{}}
+	if len(sources) == 0 {
+		return result, nil
+	}
+
+	carries := map[int64]money.Amount{}
+	if budget.NextPeriod(req.Period, req.From).Equal(req.To) {
+		var err error
+		if carries, err = u.carries(userID, sources); err != nil {
+			return budget.CopyResult{}, err
+		}
+	}
+
+	targets, err := u.repo.FindAllByUserID(userID, req.To, req.Period)
+	if err != nil {
+		return budget.CopyResult{}, err
+	}
+	existing := make(map[int64]budget.Budget)
+	for _, b := range targets {
+		existing[b.CategoryID] = b
+	}
+
+	// percentages are kept to two decimals, like amounts
+	factor := int64(math.Round((100 + req.AdjustPercent) * 100))
+	for _, src := range sources {
+		b := budget.Budget{
+			UserID:        userID,
+			CategoryID:    src.CategoryID,
+			CategoryName:  src.CategoryName,
+			CategoryColor: src.CategoryColor,
+			Amount:        src.Amount.MulRatio(factor, 10000),
+			Currency:      src.Currency,
+			Period:        req.Period,
+			Rollover:      src.Rollover,
+			CarriedOver:   carries[src.CategoryID],
+			AutoCopy:      src.AutoCopy,
+		}
+		b.SetStart(req.To)
+		if old, ok := existing[src.CategoryID]; ok {
+			if !req.Overwrite {
+				result.SkippedCategoryIDs = append(result.SkippedCategoryIDs, src.CategoryID)
+				continue
+			}
+			b.ID = old.ID
+			if err := u.repo.Update(&b); err != nil {
+				return budget.CopyResult{}, err
+			}
+		} else {
+			saved, err := u.repo.SaveIfAbsent(&b)
+			if err != nil {
+				return budget.CopyResult{}, err
+			}
+			if !saved {
+				// created in the meantime, e.g. by the period-start job
+				result.SkippedCategoryIDs = append(result.SkippedCategoryIDs, src.CategoryID)
+				continue
+			}
+		}
+		result.Copied = append(result.Copied, b)
+	}
+	return result, nil
 }
 
-func (u *usecase) SetBudget(userID int64, b budget.Budget) error {
+func (u *usecase) CopyDue(now time.Time) (int, error) {
+	copied := 0
+	var errs []error
+	for _, period := range budget.Periods {
+		to := budget.PeriodStart(period, now)
+		from := budget.PreviousPeriod(period, to)
+		sources, err := u.repo.FindUncopied(period, from)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s budgets: %w", period, err))
+			continue
+		}
+
+		// sources come ordered by user
+		for start := 0; start < len(sources); {
+			end := start
+			for end < len(sources) && sources[end].UserID == sources[start].UserID {
+				end++
+			}
+			userID := sources[start].UserID
+			result, err := u.copy(userID, sources[start:end], budget.CopyRequest{Period: period, From: from, To: to})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("user %d: %w", userID, err))
+			}
+			copied += len(result.Copied)
+			start = end
+		}
+	}
+	return copied, errors.Join(errs...)
+}
+
+// rateDay is the day whose exchange rates value the period ending at end:
+// its last day, or today for the running period.
+func rateDay(end time.Time) time.Time {
+	day := end.AddDate(0, 0, -1)
+	if now := time.Now(); day.After(now) {
+		return now
+	}
+	return day
+}
+
+func (u *usecase) SetBudget(userID int64, b budget.Budget) (budget.Budget, error) {
 	b.UserID = userID
+	if b.CategoryID != 0 {
+		if err := categoryUC.Check(u.categoryRepo, userID, b.CategoryID); err != nil {
+			return budget.Budget{}, err
+		}
+	}
+
+	if b.Period == "" {
+		b.Period = budget.PeriodMonth
+	}
+	if !budget.ValidPeriod(b.Period) {
+		return budget.Budget{}, apperror.BadRequest("period must be week, month, quarter or year", nil)
+	}
+	if b.StartDate.IsZero() {
+		if b.Period != budget.PeriodMonth {
+			return budget.Budget{}, apperror.BadRequest("start_date is required", nil)
+		}
+		day, err := copyDay(time.Time{}, b.Month, b.Year)
+		if err != nil {
+			return budget.Budget{}, err
+		}
+		b.StartDate = day
+	}
+	b.SetStart(b.StartDate)
+
+	if err := u.validate(userID, &b); err != nil {
+		return budget.Budget{}, err
+	}
 
-	existing, err := u.repo.FindByCategory(userID, b.CategoryID, b.Month, b.Year)
+	existing, err := u.repo.FindByCategory(userID, b.CategoryID, b.Period, b.StartDate)
 	if err == nil {
 		// Update existing
-		existing.Amount = b.Amount
-		return u.repo.Update(&existing)
+		if err := u.applyUpdate(&existing, b); err != nil {
+			return budget.Budget{}, err
+		}
+		return existing, u.repo.Update(&existing)
+	}
+	if !errors.Is(err, budget.ErrNotFound) {
+		return budget.Budget{}, err
+	}
+
+	// a new budget starts with what the previous period rolls over
+	b.CarriedOver = 0
+	prevStart := budget.PreviousPeriod(b.Period, b.StartDate)
+	if prev, err := u.repo.FindByCategory(userID, b.CategoryID, b.Period, prevStart); err == nil {
+		carries, err := u.carries(userID, []budget.Budget{prev})
+		if err != nil {
+			return budget.Budget{}, err
+		}
+		if carry := carries[prev.CategoryID]; carry != 0 {
+			if b.CarriedOver, err = u.quoter(b.StartDate).convert(carry, prev.Currency, b.Currency); err != nil {
+				return budget.Budget{}, err
+			}
+		}
 	}
 
 	// Create new
-	return u.repo.Save(&b)
+	return b, u.repo.Save(&b)
+}
+
+// validate checks the amount and rollover of b and fills in its currency.
+func (u *usecase) validate(userID int64, b *budget.Budget) error {
+	if b.Amount <= 0 {
+		return apperror.BadRequest("amount must be greater than zero", nil)
+	}
+
+	b.Currency = strings.ToUpper(strings.TrimSpace(b.Currency))
+	if b.Currency == "" {
+		base, err := u.fx.BaseCurrency(userID)
+		if err != nil {
+			return err
+		}
+		b.Currency = base
+	}
+	if !money.ValidCurrency(b.Currency) {
+		return apperror.BadRequest("invalid currency", nil)
+	}
+
+	switch b.Rollover {
+	case "":
+		b.Rollover = budget.RolloverNone
+	case budget.RolloverNone, budget.RolloverUnspent, budget.RolloverAll:
+	default:
+		return apperror.BadRequest("rollover must be none, unspent or all", nil)
+	}
+	return nil
+}
+
+func (u *usecase) UpdateBudget(userID, id int64, b budget.Budget) (budget.Budget, error) {
+	existing, err := u.find(userID, id)
+	if err != nil {
+		return budget.Budget{}, err
+	}
+	if err := u.validate(userID, &b); err != nil {
+		return budget.Budget{}, err
+	}
+
+	if err := u.applyUpdate(&existing, b); err != nil {
+		return budget.Budget{}, err
+	}
+	return existing, u.repo.Update(&existing)
+}
+
+// applyUpdate copies the editable fields of b onto existing. A carry is
+// converted into the new currency at the rate it rolled over at, so the
+// limit never mixes currencies.
+func (u *usecase) applyUpdate(existing *budget.Budget, b budget.Budget) error {
+	if existing.CarriedOver != 0 && existing.Currency != b.Currency {
+		carry, err := u.quoter(existing.StartDate).convert(existing.CarriedOver, existing.Currency, b.Currency)
+		if err != nil {
+			return err
+		}
+		existing.CarriedOver = carry
+	}
+	existing.Amount = b.Amount
+	existing.Currency = b.Currency
+	existing.Rollover = b.Rollover
+	existing.AutoCopy = b.AutoCopy
+	return nil
+}
+
+func (u *usecase) DeleteBudget(userID, id int64) error {
+	if _, err := u.find(userID, id); err != nil {
+		return err
+	}
+	return u.repo.Delete(userID, id)
+}
+
+// find returns the budget unless it belongs to another user.
+func (u *usecase) find(userID, id int64) (budget.Budget, error) {
+	b, err := u.repo.FindByIDForUser(userID, id)
+	if err != nil {
+		if errors.Is(err, budget.ErrNotFound) {
+			return budget.Budget{}, apperror.NotFound("budget not found", err)
+		}
+		return budget.Budget{}, err
+	}
+	return b, nil
 }
 
-func (u *usecase) GetBudgetByCategory(userID int64, categoryID int64, month, year int) (budget.Budget, error) {
-	return u.repo.FindByCategory(userID, categoryID, month, year)
+func (u *usecase) GetBudgetByCategory(userID int64, categoryID int64, period string, start time.Time) (budget.Budget, error) {
+	return u.repo.FindByCategory(userID, categoryID, period, budget.PeriodStart(period, start))
 }
@@ -0,0 +1,600 @@
+package budget_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/budget"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/budget"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBudgetRepository struct {
+	mock.Mock
+}
+
+func (m *MockBudgetRepository) FindAllByUserID(userID int64, on time.Time, periods ...string) ([]budget.Budget, error) {
+	args := m.Called(userID, on, periods)
+	return args.Get(0).([]budget.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepository) FindByIDForUser(userID, id int64) (budget.Budget, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(budget.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepository) FindByCategory(userID int64, categoryID int64, period string, start time.Time) (budget.Budget, error) {
+	args := m.Called(userID, categoryID, period, start)
+	return args.Get(0).(budget.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepository) FindUncopied(period string, start time.Time) ([]budget.Budget, error) {
+	args := m.Called(period, start)
+	return args.Get(0).([]budget.Budget), args.Error(1)
+}
+
+func (m *MockBudgetRepository) Save(b *budget.Budget) error {
+	args := m.Called(b)
+	return args.Error(0)
+}
+
+func (m *MockBudgetRepository) SaveIfAbsent(b *budget.Budget) (bool, error) {
+	args := m.Called(b)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockBudgetRepository) Update(b *budget.Budget) error {
+	args := m.Called(b)
+	return args.Error(0)
+}
+
+func (m *MockBudgetRepository) Delete(userID, id int64) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+// MockTransactionRepository only implements the aggregates the budget
+// usecase needs.
+type MockTransactionRepository struct {
+	transaction.Repository
+	mock.Mock
+}
+
+func (m *MockTransactionRepository) SumByCurrency(userID int64, filter transaction.Filter) ([]transaction.CurrencySummary, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]transaction.CurrencySummary), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SumByCategory(userID int64, filter transaction.Filter) ([]transaction.CategoryTotal, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]transaction.CategoryTotal), args.Error(1)
+}
+
+// MockCategoryRepository only implements the ownership lookup.
+type MockCategoryRepository struct {
+	category.Repository
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) FindByIDForUser(userID, id int64) (category.Category, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(category.Category), args.Error(1)
+}
+
+type MockFxUsecase struct {
+	mock.Mock
+}
+
+func (m *MockFxUsecase) ImportRates(rates []fx.Rate) (int, error) {
+	args := m.Called(rates)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFxUsecase) BaseCurrency(userID int64) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFxUsecase) Quote(from, to string, on time.Time) (fx.Quote, error) {
+	args := m.Called(from, to, on)
+	return args.Get(0).(fx.Quote), args.Error(1)
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// inPeriod places b in the period of the given length containing on.
+func inPeriod(b budget.Budget, period string, on time.Time) budget.Budget {
+	b.Period = period
+	b.SetStart(on)
+	return b
+}
+
+func monthly(b budget.Budget, month time.Month, year int) budget.Budget {
+	return inPeriod(b, budget.PeriodMonth, date(year, month, 1))
+}
+
+func TestPeriodStart(t *testing.T) {
+	// Sunday, 18 October 2026
+	day := time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, date(2026, 10, 12), budget.PeriodStart(budget.PeriodWeek, day))
+	assert.Equal(t, date(2026, 10, 1), budget.PeriodStart(budget.PeriodMonth, day))
+	assert.Equal(t, date(2026, 10, 1), budget.PeriodStart(budget.PeriodQuarter, day))
+	assert.Equal(t, date(2026, 4, 1), budget.PeriodStart(budget.PeriodQuarter, date(2026, 6, 30)))
+	assert.Equal(t, date(2026, 1, 1), budget.PeriodStart(budget.PeriodYear, day))
+	assert.Equal(t, date(2026, 10, 12), budget.PeriodStart(budget.PeriodWeek, date(2026, 10, 12)), "Monday starts the week")
+
+	assert.Equal(t, date(2026, 10, 5), budget.PreviousPeriod(budget.PeriodWeek, date(2026, 10, 12)))
+	assert.Equal(t, date(2026, 7, 1), budget.PreviousPeriod(budget.PeriodQuarter, date(2026, 10, 1)))
+	assert.Equal(t, date(2027, 1, 1), budget.NextPeriod(budget.PeriodQuarter, date(2026, 10, 1)))
+
+	b := inPeriod(budget.Budget{}, budget.PeriodWeek, date(2026, 12, 30))
+	assert.Equal(t, date(2026, 12, 28), b.StartDate)
+	assert.Equal(t, date(2027, 1, 3), b.EndDate)
+	assert.Equal(t, 12, b.Month)
+	assert.Equal(t, 2026, b.Year)
+}
+
+func TestBudgetProgress(t *testing.T) {
+	// October 2026 has 31 days, so 310 allows 10 a day
+	b := monthly(budget.Budget{CategoryID: 4, BaseAmount: money.FromMajor(310)}, 10, 2026)
+	tenth := time.Date(2026, 10, 10, 18, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		spent     int64
+		now       time.Time
+		projected int64
+		daysLeft  int
+		percent   float64
+		status    string
+	}{
+		"on pace":         {100, tenth, 310, 21, 32.3, budget.StatusOnTrack},
+		"ahead of pace":   {120, tenth, 372, 21, 38.7, budget.StatusAtRisk},
+		"overspent":       {320, tenth, 992, 21, 103.2, budget.StatusOver},
+		"month over":      {300, time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC), 300, 0, 96.8, budget.StatusOnTrack},
+		"first day":       {20, time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC), 620, 30, 6.5, budget.StatusAtRisk},
+		"not started yet": {0, time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC), 0, 31, 0, budget.StatusOnTrack},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := b.Progress(money.FromMajor(tc.spent), tc.now)
+
+			assert.Equal(t, money.FromMajor(tc.spent), p.Spent)
+			assert.Equal(t, money.FromMajor(310-tc.spent), p.Remaining)
+			assert.Equal(t, money.FromMajor(tc.projected), p.Projected)
+			assert.Equal(t, tc.daysLeft, p.DaysLeft)
+			assert.Equal(t, tc.percent, p.PercentUsed)
+			assert.Equal(t, tc.status, p.Status)
+		})
+	}
+
+	// a week of 70 allows 10 a day
+	week := inPeriod(budget.Budget{BaseAmount: money.FromMajor(70)}, budget.PeriodWeek, date(2026, 10, 14))
+	p := week.Progress(money.FromMajor(40), date(2026, 10, 15))
+	assert.Equal(t, 3, p.DaysLeft)
+	assert.Equal(t, money.FromMajor(70), p.Projected)
+	assert.Equal(t, budget.StatusOnTrack, p.Status)
+}
+
+func TestGetProgress(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, nil, txRepo, mockFx)
+
+	endOfMarch := date(2025, 3, 31)
+	repo.On("FindAllByUserID", int64(1), date(2025, 3, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{
+		monthly(budget.Budget{ID: 1, UserID: 1, CategoryID: 4, Amount: money.FromMajor(1000), Currency: "IDR"}, 3, 2025),
+		monthly(budget.Budget{ID: 2, UserID: 1, CategoryID: 5, Amount: money.FromMajor(10), Currency: "USD"}, 3, 2025),
+	}, nil).Once()
+	txRepo.On("SumByCategory", int64(1), transaction.Filter{
+		Type:      transaction.TypeExpense,
+		StartDate: date(2025, 3, 1),
+		EndDate:   date(2025, 4, 1).Add(-time.Nanosecond),
+	}).Return([]transaction.CategoryTotal{
+		{CategoryID: 4, Currency: "IDR", Amount: money.FromMajor(600)},
+		{CategoryID: 4, Currency: "USD", Amount: money.FromMajor(10)},
+		{CategoryID: 6, Currency: "IDR", Amount: money.FromMajor(50)},
+	}, nil).Once()
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil)
+	mockFx.On("Quote", "IDR", "IDR", endOfMarch).Return(fx.Quote{From: "IDR", To: "IDR", Rate: "1"}, nil)
+	mockFx.On("Quote", "USD", "IDR", endOfMarch).Return(fx.Quote{From: "USD", To: "IDR", Rate: "100"}, nil)
+
+	progress, err := usecase.GetProgress(1, date(2025, 3, 1), budget.PeriodMonth)
+
+	assert.NoError(t, err)
+	if assert.Len(t, progress, 2) {
+		assert.Equal(t, money.FromMajor(1600), progress[0].Spent)
+		assert.Equal(t, money.FromMajor(-600), progress[0].Remaining)
+		assert.Equal(t, budget.StatusOver, progress[0].Status)
+
+		assert.Equal(t, money.FromMajor(1000), progress[1].BaseAmount)
+		assert.True(t, progress[1].Spent.IsZero())
+		assert.Equal(t, budget.StatusOnTrack, progress[1].Status)
+		assert.Equal(t, 0, progress[1].DaysLeft)
+	}
+	txRepo.AssertExpectations(t)
+}
+
+func TestGetProgressAcrossPeriods(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, nil, txRepo, mockFx)
+
+	on := date(2025, 3, 12)
+	repo.On("FindAllByUserID", int64(1), on, []string(nil)).Return([]budget.Budget{
+		inPeriod(budget.Budget{ID: 1, UserID: 1, Amount: money.FromMajor(500), Currency: "IDR"}, budget.PeriodWeek, on),
+		inPeriod(budget.Budget{ID: 2, UserID: 1, CategoryID: 4, Amount: money.FromMajor(100), Currency: "IDR"}, budget.PeriodWeek, on),
+		inPeriod(budget.Budget{ID: 3, UserID: 1, Amount: money.FromMajor(9000), Currency: "IDR"}, budget.PeriodYear, on),
+	}, nil).Once()
+	week := transaction.Filter{StartDate: date(2025, 3, 10), EndDate: date(2025, 3, 17).Add(-time.Nanosecond)}
+	// the all-categories budget counts every expense, whatever its category
+	txRepo.On("SumByCurrency", int64(1), week).Return([]transaction.CurrencySummary{
+		{Currency: "IDR", TotalIncome: money.FromMajor(1000), TotalExpense: money.FromMajor(300)},
+		{Currency: "USD", TotalExpense: money.FromMajor(1)},
+	}, nil).Once()
+	weekExpenses := week
+	weekExpenses.Type = transaction.TypeExpense
+	txRepo.On("SumByCategory", int64(1), weekExpenses).Return([]transaction.CategoryTotal{
+		{CategoryID: 4, Currency: "IDR", Amount: money.FromMajor(80)},
+	}, nil).Once()
+	txRepo.On("SumByCurrency", int64(1), transaction.Filter{StartDate: date(2025, 1, 1), EndDate: date(2026, 1, 1).Add(-time.Nanosecond)}).Return([]transaction.CurrencySummary{
+		{Currency: "IDR", TotalExpense: money.FromMajor(2000)},
+	}, nil).Once()
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil)
+	mockFx.On("Quote", "IDR", "IDR", mock.Anything).Return(fx.Quote{From: "IDR", To: "IDR", Rate: "1"}, nil)
+	mockFx.On("Quote", "USD", "IDR", date(2025, 3, 16)).Return(fx.Quote{From: "USD", To: "IDR", Rate: "100"}, nil)
+
+	progress, err := usecase.GetProgress(1, on, "")
+
+	assert.NoError(t, err)
+	if assert.Len(t, progress, 3) {
+		assert.Equal(t, money.FromMajor(400), progress[0].Spent)
+		assert.Equal(t, money.FromMajor(80), progress[1].Spent)
+		assert.Equal(t, money.FromMajor(2000), progress[2].Spent)
+		assert.Equal(t, date(2025, 12, 31), progress[2].EndDate)
+	}
+	txRepo.AssertExpectations(t)
+}
+
+func TestGetProgressWithoutBudgets(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, nil, new(MockTransactionRepository), mockFx)
+	repo.On("FindAllByUserID", int64(1), date(2025, 3, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{}, nil).Once()
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil)
+
+	progress, err := usecase.GetProgress(1, date(2025, 3, 1), budget.PeriodMonth)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, progress)
+	assert.Empty(t, progress)
+}
+
+func TestGetBudgetsRejectsUnknownPeriod(t *testing.T) {
+	usecase := uc.New(new(MockBudgetRepository), nil, new(MockTransactionRepository), new(MockFxUsecase))
+
+	_, err := usecase.GetBudgets(1, date(2025, 3, 1), "fortnight")
+
+	var appErr *apperror.AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.Code)
+}
+
+func TestBudgetCarry(t *testing.T) {
+	cases := map[string]struct {
+		rollover string
+		spent    int64
+		carry    int64
+	}{
+		"none":               {budget.RolloverNone, 60, 0},
+		"unspent left":       {budget.RolloverUnspent, 60, 50},
+		"unspent overspent":  {budget.RolloverUnspent, 130, 0},
+		"all left":           {budget.RolloverAll, 60, 50},
+		"all overspent":      {budget.RolloverAll, 130, -20},
+		"unset means none":   {"", 60, 0},
+		"carry is included":  {budget.RolloverAll, 110, 0},
+		"exactly spent none": {budget.RolloverUnspent, 110, 0},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// 100 plus 10 carried over from the month before
+			b := budget.Budget{Amount: money.FromMajor(100), CarriedOver: money.FromMajor(10), Rollover: tc.rollover}
+
+			assert.Equal(t, money.FromMajor(tc.carry), b.Carry(money.FromMajor(tc.spent)))
+		})
+	}
+}
+
+func marchExpenses() transaction.Filter {
+	return transaction.Filter{
+		Type:      transaction.TypeExpense,
+		StartDate: date(2025, 3, 1),
+		EndDate:   date(2025, 4, 1).Add(-time.Nanosecond),
+	}
+}
+
+func TestCopyBudgetsIntoNextMonth(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, nil, txRepo, mockFx)
+
+	repo.On("FindAllByUserID", int64(1), date(2025, 3, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{
+		monthly(budget.Budget{ID: 1, UserID: 1, CategoryID: 4, CategoryName: "food", Amount: money.FromMajor(1000), Currency: "IDR", Rollover: budget.RolloverUnspent, AutoCopy: true}, 3, 2025),
+		monthly(budget.Budget{ID: 2, UserID: 1, CategoryID: 5, Amount: money.FromMajor(200), Currency: "IDR", Rollover: budget.RolloverAll}, 3, 2025),
+		monthly(budget.Budget{ID: 3, UserID: 1, CategoryID: 6, Amount: money.FromMajor(50), Currency: "IDR"}, 3, 2025),
+		monthly(budget.Budget{ID: 4, UserID: 1, CategoryID: 7, Amount: money.FromMajor(70), Currency: "IDR"}, 3, 2025),
+	}, nil).Once()
+	txRepo.On("SumByCategory", int64(1), marchExpenses()).Return([]transaction.CategoryTotal{
+		{CategoryID: 4, Currency: "IDR", Amount: money.FromMajor(600)},
+		{CategoryID: 5, Currency: "IDR", Amount: money.FromMajor(150)},
+		{CategoryID: 5, Currency: "USD", Amount: money.FromMajor(1)},
+		{CategoryID: 6, Currency: "IDR", Amount: money.FromMajor(10)},
+	}, nil).Once()
+	mockFx.On("Quote", "IDR", "IDR", date(2025, 3, 31)).Return(fx.Quote{From: "IDR", To: "IDR", Rate: "1"}, nil)
+	mockFx.On("Quote", "USD", "IDR", date(2025, 3, 31)).Return(fx.Quote{From: "USD", To: "IDR", Rate: "100"}, nil)
+	repo.On("FindAllByUserID", int64(1), date(2025, 4, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{
+		monthly(budget.Budget{ID: 9, UserID: 1, CategoryID: 6, Amount: money.FromMajor(1), Currency: "IDR"}, 4, 2025),
+	}, nil).Once()
+	var saved []budget.Budget
+	repo.On("SaveIfAbsent", mock.Anything).Run(func(args mock.Arguments) {
+		saved = append(saved, *args.Get(0).(*budget.Budget))
+	}).Return(true, nil).Twice()
+	// category 7 got a budget since the target month was read
+	repo.On("SaveIfAbsent", mock.MatchedBy(func(b *budget.Budget) bool { return b.CategoryID == 7 })).Return(false, nil).Once()
+
+	result, err := usecase.CopyBudgets(1, budget.CopyRequest{FromMonth: 3, FromYear: 2025, ToMonth: 4, ToYear: 2025, AdjustPercent: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{6, 7}, result.SkippedCategoryIDs)
+	if assert.Len(t, result.Copied, 2) {
+		food := result.Copied[0]
+		assert.Equal(t, int64(4), food.CategoryID)
+		assert.Equal(t, "food", food.CategoryName)
+		assert.Equal(t, money.FromMajor(1100), food.Amount, "adjusted by 10%")
+		assert.Equal(t, money.FromMajor(400), food.CarriedOver, "the unspent part is not adjusted")
+		assert.Equal(t, budget.PeriodMonth, food.Period)
+		assert.Equal(t, date(2025, 4, 1), food.StartDate)
+		assert.Equal(t, 4, food.Month)
+		assert.True(t, food.AutoCopy)
+		assert.Equal(t, budget.RolloverUnspent, food.Rollover)
+
+		// 150 IDR and 1 USD at 100 make 250 spent of 200
+		assert.Equal(t, money.FromMajor(-50), result.Copied[1].CarriedOver)
+	}
+	assert.Len(t, saved, 2)
+	repo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestCopyBudgetsOverwriteAndLaterMonth(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	usecase := uc.New(repo, nil, new(MockTransactionRepository), new(MockFxUsecase))
+
+	repo.On("FindAllByUserID", int64(1), date(2025, 1, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{
+		monthly(budget.Budget{ID: 1, UserID: 1, CategoryID: 4, Amount: money.FromMajor(100), Currency: "IDR", Rollover: budget.RolloverAll}, 1, 2025),
+	}, nil).Once()
+	repo.On("FindAllByUserID", int64(1), date(2025, 6, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{
+		monthly(budget.Budget{ID: 8, UserID: 1, CategoryID: 4, Amount: money.FromMajor(5), Currency: "IDR"}, 6, 2025),
+	}, nil).Once()
+	repo.On("Update", mock.MatchedBy(func(b *budget.Budget) bool {
+		// no carry into a month that does not follow
+		return b.ID == 8 && b.Amount == money.FromMajor(95) && b.CarriedOver == 0 && b.Month == 6
+	})).Return(nil).Once()
+
+	result, err := usecase.CopyBudgets(1, budget.CopyRequest{FromMonth: 1, FromYear: 2025, ToMonth: 6, ToYear: 2025, AdjustPercent: -5, Overwrite: true})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Copied, 1)
+	assert.Empty(t, result.SkippedCategoryIDs)
+	repo.AssertExpectations(t)
+}
+
+func TestCopyWeeklyBudgets(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, nil, txRepo, mockFx)
+
+	// from and to may be any day of their weeks
+	repo.On("FindAllByUserID", int64(1), date(2025, 3, 3), []string{budget.PeriodWeek}).Return([]budget.Budget{
+		inPeriod(budget.Budget{ID: 1, UserID: 1, Amount: money.FromMajor(700), Currency: "IDR", Rollover: budget.RolloverUnspent}, budget.PeriodWeek, date(2025, 3, 3)),
+	}, nil).Once()
+	txRepo.On("SumByCurrency", int64(1), transaction.Filter{StartDate: date(2025, 3, 3), EndDate: date(2025, 3, 10).Add(-time.Nanosecond)}).Return([]transaction.CurrencySummary{
+		{Currency: "IDR", TotalExpense: money.FromMajor(500)},
+	}, nil).Once()
+	mockFx.On("Quote", "IDR", "IDR", date(2025, 3, 9)).Return(fx.Quote{From: "IDR", To: "IDR", Rate: "1"}, nil)
+	repo.On("FindAllByUserID", int64(1), date(2025, 3, 10), []string{budget.PeriodWeek}).Return([]budget.Budget{}, nil).Once()
+	repo.On("SaveIfAbsent", mock.MatchedBy(func(b *budget.Budget) bool {
+		return b.CategoryID == 0 && b.Period == budget.PeriodWeek && b.StartDate.Equal(date(2025, 3, 10)) && b.CarriedOver == money.FromMajor(200)
+	})).Return(true, nil).Once()
+
+	result, err := usecase.CopyBudgets(1, budget.CopyRequest{Period: budget.PeriodWeek, From: date(2025, 3, 5), To: date(2025, 3, 16)})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Copied, 1)
+	repo.AssertExpectations(t)
+}
+
+func TestCopyBudgetsValidation(t *testing.T) {
+	usecase := uc.New(new(MockBudgetRepository), nil, new(MockTransactionRepository), new(MockFxUsecase))
+
+	cases := map[string]budget.CopyRequest{
+		"month":     {FromMonth: 13, FromYear: 2025, ToMonth: 1, ToYear: 2026},
+		"year":      {FromMonth: 12, ToMonth: 1, ToYear: 2026},
+		"same":      {FromMonth: 3, FromYear: 2025, ToMonth: 3, ToYear: 2025},
+		"same week": {Period: budget.PeriodWeek, From: date(2025, 3, 3), To: date(2025, 3, 9)},
+		"period":    {Period: "fortnight", From: date(2025, 3, 3), To: date(2025, 3, 17)},
+		"no target": {Period: budget.PeriodYear, From: date(2025, 3, 3)},
+		"reduction": {FromMonth: 3, FromYear: 2025, ToMonth: 4, ToYear: 2025, AdjustPercent: -100},
+	}
+	for name, req := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := usecase.CopyBudgets(1, req)
+
+			var appErr *apperror.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.Code)
+		})
+	}
+}
+
+func TestCopyDue(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	usecase := uc.New(repo, nil, new(MockTransactionRepository), new(MockFxUsecase))
+
+	// Thursday, 1 January 2026 starts a month, a quarter and a year
+	repo.On("FindUncopied", budget.PeriodMonth, date(2025, 12, 1)).Return([]budget.Budget{
+		monthly(budget.Budget{ID: 1, UserID: 1, CategoryID: 4, Amount: money.FromMajor(100), Currency: "IDR", AutoCopy: true}, 12, 2025),
+		monthly(budget.Budget{ID: 2, UserID: 1, CategoryID: 5, Amount: money.FromMajor(50), Currency: "IDR", AutoCopy: true}, 12, 2025),
+		monthly(budget.Budget{ID: 3, UserID: 2, CategoryID: 9, Amount: money.FromMajor(70), Currency: "USD", AutoCopy: true}, 12, 2025),
+	}, nil).Once()
+	repo.On("FindUncopied", budget.PeriodWeek, date(2025, 12, 22)).Return([]budget.Budget{}, nil).Once()
+	repo.On("FindUncopied", budget.PeriodQuarter, date(2025, 10, 1)).Return([]budget.Budget{}, nil).Once()
+	repo.On("FindUncopied", budget.PeriodYear, date(2025, 1, 1)).Return([]budget.Budget{
+		inPeriod(budget.Budget{ID: 4, UserID: 1, Amount: money.FromMajor(9000), Currency: "IDR", AutoCopy: true}, budget.PeriodYear, date(2025, 1, 1)),
+	}, nil).Once()
+	repo.On("FindAllByUserID", int64(1), date(2026, 1, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{}, nil).Once()
+	repo.On("FindAllByUserID", int64(2), date(2026, 1, 1), []string{budget.PeriodMonth}).Return([]budget.Budget{}, errors.New("connection reset")).Once()
+	repo.On("FindAllByUserID", int64(1), date(2026, 1, 1), []string{budget.PeriodYear}).Return([]budget.Budget{}, nil).Once()
+	repo.On("SaveIfAbsent", mock.MatchedBy(func(b *budget.Budget) bool {
+		return b.UserID == 1 && b.StartDate.Equal(date(2026, 1, 1)) && b.AutoCopy
+	})).Return(true, nil).Times(3)
+
+	copied, err := usecase.CopyDue(time.Date(2026, 1, 1, 0, 5, 0, 0, time.UTC))
+
+	// one user failing does not hold up the others
+	assert.Equal(t, 3, copied)
+	assert.ErrorContains(t, err, "user 2")
+	repo.AssertExpectations(t)
+}
+
+func TestSetBudgetCarriesFromPreviousMonth(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	categoryRepo := new(MockCategoryRepository)
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, categoryRepo, txRepo, mockFx)
+
+	categoryRepo.On("FindByIDForUser", int64(1), int64(4)).Return(category.Category{ID: 4, UserID: 1}, nil).Once()
+	repo.On("FindByCategory", int64(1), int64(4), budget.PeriodMonth, date(2025, 4, 1)).Return(budget.Budget{}, budget.ErrNotFound).Once()
+	repo.On("FindByCategory", int64(1), int64(4), budget.PeriodMonth, date(2025, 3, 1)).Return(
+		monthly(budget.Budget{ID: 1, UserID: 1, CategoryID: 4, Amount: money.FromMajor(1000), Currency: "IDR", Rollover: budget.RolloverUnspent}, 3, 2025), nil,
+	).Once()
+	txRepo.On("SumByCategory", int64(1), marchExpenses()).Return([]transaction.CategoryTotal{
+		{CategoryID: 4, Currency: "IDR", Amount: money.FromMajor(700)},
+	}, nil).Once()
+	mockFx.On("Quote", "IDR", "IDR", date(2025, 3, 31)).Return(fx.Quote{From: "IDR", To: "IDR", Rate: "1"}, nil)
+	repo.On("Save", mock.MatchedBy(func(b *budget.Budget) bool {
+		return b.StartDate.Equal(date(2025, 4, 1)) && b.CarriedOver == money.FromMajor(300) && b.Rollover == budget.RolloverNone
+	})).Return(nil).Once()
+
+	b, err := usecase.SetBudget(1, budget.Budget{CategoryID: 4, Amount: money.FromMajor(900), Currency: "IDR", Month: 4, Year: 2025})
+
+	assert.NoError(t, err)
+	assert.Equal(t, budget.PeriodMonth, b.Period)
+	assert.Equal(t, date(2025, 4, 30), b.EndDate)
+	repo.AssertExpectations(t)
+}
+
+func TestSetQuarterlyBudgetForAllCategories(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, nil, new(MockTransactionRepository), mockFx)
+
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil).Once()
+	repo.On("FindByCategory", int64(1), int64(0), budget.PeriodQuarter, date(2025, 4, 1)).Return(budget.Budget{}, budget.ErrNotFound).Once()
+	repo.On("FindByCategory", int64(1), int64(0), budget.PeriodQuarter, date(2025, 1, 1)).Return(budget.Budget{}, budget.ErrNotFound).Once()
+	repo.On("Save", mock.MatchedBy(func(b *budget.Budget) bool {
+		return b.CategoryID == 0 && b.Currency == "IDR" && b.StartDate.Equal(date(2025, 4, 1))
+	})).Return(nil).Once()
+
+	b, err := usecase.SetBudget(1, budget.Budget{Amount: money.FromMajor(3000), Period: budget.PeriodQuarter, StartDate: date(2025, 5, 20)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, date(2025, 6, 30), b.EndDate)
+	repo.AssertExpectations(t)
+}
+
+func TestSetBudgetValidation(t *testing.T) {
+	categoryRepo := new(MockCategoryRepository)
+	usecase := uc.New(new(MockBudgetRepository), categoryRepo, new(MockTransactionRepository), new(MockFxUsecase))
+	categoryRepo.On("FindByIDForUser", int64(1), int64(4)).Return(category.Category{ID: 4, UserID: 1}, nil)
+
+	cases := map[string]budget.Budget{
+		"rollover":   {CategoryID: 4, Amount: money.FromMajor(900), Currency: "IDR", Month: 4, Year: 2025, Rollover: "forever"},
+		"amount":     {CategoryID: 4, Currency: "IDR", Month: 4, Year: 2025},
+		"period":     {CategoryID: 4, Amount: money.FromMajor(900), Currency: "IDR", Period: "fortnight", StartDate: date(2025, 4, 1)},
+		"no start":   {CategoryID: 4, Amount: money.FromMajor(900), Currency: "IDR", Period: budget.PeriodWeek},
+		"no month":   {CategoryID: 4, Amount: money.FromMajor(900), Currency: "IDR"},
+		"currency":   {CategoryID: 4, Amount: money.FromMajor(900), Currency: "XX", Month: 4, Year: 2025},
+		"month only": {CategoryID: 4, Amount: money.FromMajor(900), Currency: "IDR", Month: 4},
+	}
+	for name, b := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := usecase.SetBudget(1, b)
+
+			var appErr *apperror.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.Code)
+		})
+	}
+}
+
+func TestUpdateBudget(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(repo, nil, new(MockTransactionRepository), mockFx)
+
+	existing := monthly(budget.Budget{ID: 3, UserID: 1, CategoryID: 4, Amount: money.FromMajor(100), Currency: "IDR", CarriedOver: money.FromMajor(2000)}, 3, 2025)
+	repo.On("FindByIDForUser", int64(1), int64(3)).Return(existing, nil).Once()
+	// the carry is converted at the rate of the day it rolled over
+	mockFx.On("Quote", "IDR", "USD", date(2025, 2, 28)).Return(fx.Quote{From: "IDR", To: "USD", Rate: "0.01"}, nil).Once()
+	repo.On("Update", mock.MatchedBy(func(b *budget.Budget) bool {
+		// the category and period stay
+		return b.ID == 3 && b.CategoryID == 4 && b.StartDate.Equal(date(2025, 3, 1)) && b.Amount == money.FromMajor(150) && b.Currency == "USD" && b.CarriedOver == money.FromMajor(20) && b.AutoCopy
+	})).Return(nil).Once()
+
+	b, err := usecase.UpdateBudget(1, 3, budget.Budget{CategoryID: 9, Amount: money.FromMajor(150), Currency: "usd", Period: budget.PeriodYear, AutoCopy: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, budget.RolloverNone, b.Rollover)
+	repo.AssertExpectations(t)
+}
+
+func TestUpdateAndDeleteBudgetOfAnotherUser(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	usecase := uc.New(repo, nil, new(MockTransactionRepository), new(MockFxUsecase))
+	repo.On("FindByIDForUser", int64(2), int64(3)).Return(budget.Budget{}, budget.ErrNotFound).Twice()
+
+	_, err := usecase.UpdateBudget(2, 3, budget.Budget{Amount: money.FromMajor(150), Currency: "IDR"})
+	var appErr *apperror.AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusNotFound, appErr.Code)
+
+	err = usecase.DeleteBudget(2, 3)
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusNotFound, appErr.Code)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestDeleteBudget(t *testing.T) {
+	repo := new(MockBudgetRepository)
+	usecase := uc.New(repo, nil, new(MockTransactionRepository), new(MockFxUsecase))
+	repo.On("FindByIDForUser", int64(1), int64(3)).Return(budget.Budget{ID: 3, UserID: 1}, nil).Once()
+	repo.On("Delete", int64(1), int64(3)).Return(nil).Once()
+
+	assert.NoError(t, usecase.DeleteBudget(1, 3))
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,165 @@
+package calendar
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/calendar"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/ical"
+	recurringUC "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
+)
+
+// FeedDays is how far ahead the bills feed lists occurrences.
+const FeedDays = 180
+
+type Usecase interface {
+	// GetFeed returns the user's feed. Its token cannot be shown again.
+	GetFeed(userID int64) (calendar.Feed, error)
+	// RegenerateFeed creates the user's feed, or replaces its token, and
+	// returns the new token. The previous token stops working.
+	RegenerateFeed(userID int64) (string, error)
+	// RevokeFeed deletes the user's feed, so its token stops working.
+	RevokeFeed(userID int64) error
+	// Bills renders the upcoming occurrences of the recurring templates of
+	// the user token belongs to as an iCalendar file of all-day events.
+	// Skipped occurrences are listed as cancelled.
+	Bills(token string, now time.Time) ([]byte, error)
+}
+
+type usecase struct {
+	repo         calendar.Repository
+	recurring    recurringUC.Usecase
+	accountRepo  account.Repository
+	categoryRepo category.Repository
+}
+
+func New(repo calendar.Repository, recurring recurringUC.Usecase, accountRepo account.Repository, categoryRepo category.Repository) Usecase {
+	return &usecase{repo: repo, recurring: recurring, accountRepo: accountRepo, categoryRepo: categoryRepo}
+}
+
+func (u *usecase) GetFeed(userID int64) (calendar.Feed, error) {
+	f, err := u.repo.FindByUserID(userID)
+	if errors.Is(err, calendar.ErrNotFound) {
+		return f, apperror.NotFound("calendar feed not found", err)
+	}
+	return f, err
+}
+
+func (u *usecase) RegenerateFeed(userID int64) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := u.repo.Save(&calendar.Feed{UserID: userID, TokenHash: hashToken(token)}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (u *usecase) RevokeFeed(userID int64) error {
+	return u.repo.Delete(userID)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (u *usecase) Bills(token string, now time.Time) ([]byte, error) {
+	if token == "" {
+		return nil, apperror.Unauthorized("calendar token required", nil)
+	}
+	feed, err := u.repo.FindByTokenHash(hashToken(token))
+	if errors.Is(err, calendar.ErrNotFound) {
+		return nil, apperror.Unauthorized("invalid calendar token", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID := feed.UserID
+
+	upcoming, err := u.recurring.GetUpcoming(userID, now.AddDate(0, 0, FeedDays))
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := u.accountRepo.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := u.categoryRepo.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	accountByID := make(map[int64]account.Account, len(accounts))
+	for _, a := range accounts {
+		accountByID[a.ID] = a
+	}
+	categoryNames := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		categoryNames[c.ID] = c.Name
+	}
+
+	events := make([]ical.Event, 0, len(upcoming))
+	for _, o := range upcoming {
+		events = append(events, event(o, accountByID[o.AccountID], categoryNames[o.CategoryID]))
+	}
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, "Cashbook bills", events, now); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// event describes occurrence o, e.g. "Rent: 500.00 IDR" for an expense or
+// "Salary: +5000.00 IDR" for income.
+func event(o recurring_transaction.Upcoming, acc account.Account, categoryName string) ical.Event {
+	title := o.Note
+	if title == "" {
+		title = categoryName
+	}
+	if title == "" {
+		title = "Recurring transaction"
+	}
+	amount := strings.TrimSpace(o.Amount.String() + " " + acc.Currency)
+	if o.Type == transaction.TypeIncome {
+		amount = "+" + amount
+	}
+
+	description := []string{"Amount: " + amount}
+	if categoryName != "" {
+		description = append(description, "Category: "+categoryName)
+	}
+	if acc.Name != "" {
+		description = append(description, "Account: "+acc.Name)
+	}
+	if o.Note != "" {
+		description = append(description, "Note: "+o.Note)
+	}
+	if o.Skip {
+		description = append(description, "Skipped")
+	}
+
+	e := ical.Event{
+		UID:         fmt.Sprintf("recurring-%d-%s@cashbook", o.RecurringID, o.Date.Format("20060102")),
+		Day:         recurring_transaction.Day(o.Date),
+		Summary:     title + ": " + amount,
+		Description: strings.Join(description, "\n"),
+		Cancelled:   o.Skip,
+	}
+	if categoryName != "" {
+		e.Categories = []string{categoryName}
+	}
+	return e
+}
@@ -0,0 +1,155 @@
+package calendar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/calendar"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/calendar"
+	recurringUC "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockCalendarRepository keeps the feeds in memory.
+type MockCalendarRepository struct {
+	feeds map[int64]calendar.Feed
+}
+
+func (m *MockCalendarRepository) FindByUserID(userID int64) (calendar.Feed, error) {
+	f, ok := m.feeds[userID]
+	if !ok {
+		return f, calendar.ErrNotFound
+	}
+	return f, nil
+}
+
+func (m *MockCalendarRepository) FindByTokenHash(hash string) (calendar.Feed, error) {
+	for _, f := range m.feeds {
+		if f.TokenHash == hash {
+			return f, nil
+		}
+	}
+	return calendar.Feed{}, calendar.ErrNotFound
+}
+
+func (m *MockCalendarRepository) Save(f *calendar.Feed) error {
+	if m.feeds == nil {
+		m.feeds = map[int64]calendar.Feed{}
+	}
+	m.feeds[f.UserID] = *f
+	return nil
+}
+
+func (m *MockCalendarRepository) Delete(userID int64) error {
+	delete(m.feeds, userID)
+	return nil
+}
+
+type MockRecurringUsecase struct {
+	recurringUC.Usecase
+	mock.Mock
+}
+
+func (m *MockRecurringUsecase) GetUpcoming(userID int64, until time.Time) ([]recurring_transaction.Upcoming, error) {
+	args := m.Called(userID, until)
+	return args.Get(0).([]recurring_transaction.Upcoming), args.Error(1)
+}
+
+type MockAccountRepository struct {
+	account.Repository
+	mock.Mock
+}
+
+func (m *MockAccountRepository) FindAllByUserID(userID int64) ([]account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]account.Account), args.Error(1)
+}
+
+type MockCategoryRepository struct {
+	category.Repository
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) FindAllByUserID(userID int64) ([]category.Category, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]category.Category), args.Error(1)
+}
+
+func assertStatus(t *testing.T, err error, code int) {
+	t.Helper()
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, code, appErr.Code)
+	}
+}
+
+func TestRegenerateFeed(t *testing.T) {
+	repo := &MockCalendarRepository{}
+	usecase := uc.New(repo, nil, nil, nil)
+
+	_, err := usecase.GetFeed(7)
+	assertStatus(t, err, 404)
+
+	token, err := usecase.RegenerateFeed(7)
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+	assert.NotEqual(t, token, repo.feeds[7].TokenHash, "only the hash is stored")
+
+	again, err := usecase.RegenerateFeed(7)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, again)
+	_, err = usecase.Bills(token, time.Now())
+	assertStatus(t, err, 401)
+
+	require.NoError(t, usecase.RevokeFeed(7))
+	_, err = usecase.Bills(again, time.Now())
+	assertStatus(t, err, 401)
+	_, err = usecase.Bills("", time.Now())
+	assertStatus(t, err, 401)
+}
+
+func TestBills(t *testing.T) {
+	repo := &MockCalendarRepository{}
+	recurring := new(MockRecurringUsecase)
+	accountRepo := new(MockAccountRepository)
+	categoryRepo := new(MockCategoryRepository)
+	usecase := uc.New(repo, recurring, accountRepo, categoryRepo)
+
+	token, err := usecase.RegenerateFeed(7)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	recurring.On("GetUpcoming", int64(7), now.AddDate(0, 0, uc.FeedDays)).Return([]recurring_transaction.Upcoming{
+		{
+			Occurrence:  recurring_transaction.Occurrence{Date: time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC), Amount: money.FromMajor(500)},
+			RecurringID: 3, AccountID: 1, CategoryID: 2, Type: transaction.TypeExpense, Note: "Rent",
+		},
+		{
+			Occurrence:  recurring_transaction.Occurrence{Date: time.Date(2026, 10, 31, 9, 0, 0, 0, time.UTC), Amount: money.FromMajor(5000), Skip: true},
+			RecurringID: 4, AccountID: 1, CategoryID: 5, Type: transaction.TypeIncome,
+		},
+	}, nil).Once()
+	accountRepo.On("FindAllByUserID", int64(7)).Return([]account.Account{{ID: 1, Name: "bank", Currency: "IDR"}}, nil).Once()
+	categoryRepo.On("FindAllByUserID", int64(7)).Return([]category.Category{{ID: 2, Name: "Housing"}, {ID: 5, Name: "Salary"}}, nil).Once()
+
+	b, err := usecase.Bills(token, now)
+
+	require.NoError(t, err)
+	ics := string(b)
+	assert.Contains(t, ics, "UID:recurring-3-20261025@cashbook\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20261025\r\n")
+	assert.Contains(t, ics, "SUMMARY:Rent: 500.00 IDR\r\n")
+	assert.Contains(t, ics, `DESCRIPTION:Amount: 500.00 IDR\nCategory: Housing\nAccount: bank\n`)
+	assert.Contains(t, ics, "CATEGORIES:Housing\r\n")
+	assert.Contains(t, ics, "SUMMARY:Salary: +5000.00 IDR\r\n")
+	assert.Contains(t, ics, "STATUS:CANCELLED\r\n")
+	recurring.AssertExpectations(t)
+}
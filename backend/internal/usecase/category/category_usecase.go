@@ -1,15 +1,19 @@
 package category
 
 import (
+	"errors"
+	"net/http"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
 	"github.com/afandimsr/cashbook-backend/internal/domain/category"
 )
 
 type Usecase interface {
 	GetAllByUserID(userID int64) ([]category.Category, error)
-	GetByID(id int64) (category.Category, error)
+	GetByID(userID, id int64) (category.Category, error)
 	Create(c category.Category) error
-	Update(id int64, c category.Category) error
-	Delete(id int64) error
+	Update(userID, id int64, c category.Category) error
+	Delete(userID, id int64) error
 }
 
 type usecase struct {
@@ -26,16 +30,23 @@ func (u *usecase) GetAllByUserID(userID int64) ([]category.Category, error) {
 	return u.repo.FindAllByUserID(userID)
 }
 
-func (u *usecase) GetByID(id int64) (category.Category, error) {
-	return u.repo.FindByID(id)
+func (u *usecase) GetByID(userID, id int64) (category.Category, error) {
+	c, err := u.repo.FindByIDForUser(userID, id)
+	if err != nil {
+		if errors.Is(err, category.ErrNotFound) {
+			return category.Category{}, apperror.NotFound("category not found", err)
+		}
+		return category.Category{}, err
+	}
+	return c, nil
 }
 
 func (u *usecase) Create(c category.Category) error {
 	return u.repo.Save(&c)
 }
 
-func (u *usecase) Update(id int64, c category.Category) error {
-	existing, err := u.repo.FindByID(id)
+func (u *usecase) Update(userID, id int64, c category.Category) error {
+	existing, err := u.GetByID(userID, id)
 	if err != nil {
 		return err
 	}
@@ -48,6 +59,24 @@ func (u *usecase) Update(id int64, c category.Category) error {
 	return u.repo.Update(&existing)
 }
 
-func (u *usecase) Delete(id int64) error {
-	return u.repo.Delete(id)
+func (u *usecase) Delete(userID, id int64) error {
+	if _, err := u.GetByID(userID, id); err != nil {
+		return err
+	}
+	if err := u.repo.Delete(userID, id); err != nil {
+		if errors.Is(err, category.ErrInUse) {
+			return apperror.New(http.StatusConflict, "category is used by split transactions; edit their splits first", err).WithCode(apperror.ConflictError)
+		}
+		return err
+	}
+	return nil
+}
+
+// Check returns a bad request error unless categoryID names one of the
+// user's categories.
+func Check(repo category.Repository, userID, categoryID int64) error {
+	if _, err := repo.FindByIDForUser(userID, categoryID); err != nil {
+		return apperror.BadRequest("invalid category_id", err)
+	}
+	return nil
 }
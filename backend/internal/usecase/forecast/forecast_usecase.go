@@ -0,0 +1,151 @@
+package forecast
+
+import (
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	recurringUC "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
+)
+
+// DefaultDays is the length of a forecast when none is asked for, and
+// MaxDays the longest one.
+const (
+	DefaultDays = 30
+	MaxDays     = 366
+)
+
+const dateLayout = "2006-01-02"
+
+// Forecast projects the balance of each account of a user over the next
+// Days days, from today's balance and the occurrences of the active
+// recurring templates. Days are UTC days.
+type Forecast struct {
+	From string `json:"from"` // YYYY-MM-DD, today
+	To   string `json:"to"`
+	Days int    `json:"days"`
+	// Threshold is the balance below which an account is low, in the
+	// currency of each account.
+	Threshold money.Amount      `json:"threshold"`
+	Accounts  []AccountForecast `json:"accounts"`
+	// Upcoming lists the occurrences in the forecast, including the
+	// skipped ones, which do not change the balance.
+	Upcoming []recurring_transaction.Upcoming `json:"upcoming"`
+}
+
+// AccountForecast is the projected balance of one account, in its
+// currency, at the end of every day of the forecast.
+type AccountForecast struct {
+	AccountID   int64        `json:"account_id"`
+	AccountName string       `json:"account_name"`
+	Currency    string       `json:"currency"`
+	Balance     money.Amount `json:"balance"` // today, before the occurrences still due today
+	Lowest      money.Amount `json:"lowest_balance"`
+	LowestOn    string       `json:"lowest_on"`
+	// LowOn is the first day the balance is below the threshold, or empty
+	// when it never is.
+	LowOn string       `json:"low_on,omitempty"`
+	Daily []DayBalance `json:"daily"`
+}
+
+// DayBalance is the income and expense expected on Date and the balance at
+// the end of it.
+type DayBalance struct {
+	Date    string       `json:"date"`
+	Income  money.Amount `json:"income"`
+	Expense money.Amount `json:"expense"`
+	Balance money.Amount `json:"balance"`
+	Low     bool         `json:"low"`
+}
+
+type Usecase interface {
+	// GetForecast projects the user's balances over the next days days
+	// from now, including today. Occurrences due earlier that are not
+	// generated yet count towards today.
+	GetForecast(userID int64, now time.Time, days int, threshold money.Amount) (Forecast, error)
+}
+
+type usecase struct {
+	accountRepo account.Repository
+	recurring   recurringUC.Usecase
+}
+
+func New(accountRepo account.Repository, recurring recurringUC.Usecase) Usecase {
+	return &usecase{accountRepo: accountRepo, recurring: recurring}
+}
+
+func (u *usecase) GetForecast(userID int64, now time.Time, days int, threshold money.Amount) (Forecast, error) {
+	if days == 0 {
+		days = DefaultDays
+	}
+	if days < 1 || days > MaxDays {
+		return Forecast{}, apperror.BadRequest("days must be between 1 and 366", nil)
+	}
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := today.AddDate(0, 0, days)
+	accounts, err := u.accountRepo.FindAllByUserID(userID)
+	if err != nil {
+		return Forecast{}, err
+	}
+	upcoming, err := u.recurring.GetUpcoming(userID, end.Add(-time.Nanosecond))
+	if err != nil {
+		return Forecast{}, err
+	}
+
+	f := Forecast{
+		From:      today.Format(dateLayout),
+		To:        end.AddDate(0, 0, -1).Format(dateLayout),
+		Days:      days,
+		Threshold: threshold,
+		Accounts:  []AccountForecast{},
+		Upcoming:  upcoming,
+	}
+	index := make(map[int64]int, len(accounts))
+	for i, a := range accounts {
+		index[a.ID] = i
+		af := AccountForecast{AccountID: a.ID, AccountName: a.Name, Currency: a.Currency, Balance: a.Balance, Daily: make([]DayBalance, days)}
+		for d := range af.Daily {
+			af.Daily[d].Date = today.AddDate(0, 0, d).Format(dateLayout)
+		}
+		f.Accounts = append(f.Accounts, af)
+	}
+
+	for _, o := range upcoming {
+		i, ok := index[o.AccountID]
+		if !ok || o.Skip {
+			continue
+		}
+		// overdue occurrences are generated with the next run
+		d := max(int(o.Date.UTC().Sub(today)/(24*time.Hour)), 0)
+		day := &f.Accounts[i].Daily[d]
+		if o.Type == transaction.TypeIncome {
+			day.Income += o.Amount
+		} else {
+			day.Expense += o.Amount
+		}
+	}
+
+	for i := range f.Accounts {
+		af := &f.Accounts[i]
+		balance := af.Balance
+		af.Lowest, af.LowestOn = balance, f.From
+		for d := range af.Daily {
+			day := &af.Daily[d]
+			balance += day.Income - day.Expense
+			day.Balance = balance
+			day.Low = balance < threshold
+			if day.Low && af.LowOn == "" {
+				af.LowOn = day.Date
+			}
+			if balance < af.Lowest {
+				af.Lowest, af.LowestOn = balance, day.Date
+			}
+		}
+	}
+	return f, nil
+}
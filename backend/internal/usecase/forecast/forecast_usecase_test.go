@@ -0,0 +1,103 @@
+package forecast_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/forecast"
+	recurringUC "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccountRepository struct {
+	account.Repository
+	mock.Mock
+}
+
+func (m *MockAccountRepository) FindAllByUserID(userID int64) ([]account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]account.Account), args.Error(1)
+}
+
+type MockRecurringUsecase struct {
+	recurringUC.Usecase
+	mock.Mock
+}
+
+func (m *MockRecurringUsecase) GetUpcoming(userID int64, until time.Time) ([]recurring_transaction.Upcoming, error) {
+	args := m.Called(userID, until)
+	return args.Get(0).([]recurring_transaction.Upcoming), args.Error(1)
+}
+
+func upcoming(accountID int64, typ string, amount int64, on time.Time, skip bool) recurring_transaction.Upcoming {
+	return recurring_transaction.Upcoming{
+		Occurrence: recurring_transaction.Occurrence{Date: on, Amount: money.FromMajor(amount), Skip: skip},
+		AccountID:  accountID,
+		Type:       typ,
+	}
+}
+
+func TestGetForecast(t *testing.T) {
+	accountRepo := new(MockAccountRepository)
+	recurring := new(MockRecurringUsecase)
+	usecase := uc.New(accountRepo, recurring)
+
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 10, 18+d, 9, 0, 0, 0, time.UTC) }
+	accountRepo.On("FindAllByUserID", int64(7)).Return([]account.Account{
+		{ID: 1, UserID: 7, Name: "bank", Currency: "IDR", Balance: money.FromMajor(100)},
+		{ID: 2, UserID: 7, Name: "wallet", Currency: "IDR", Balance: money.FromMajor(20)},
+	}, nil).Once()
+	recurring.On("GetUpcoming", int64(7), time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)).Return([]recurring_transaction.Upcoming{
+		upcoming(1, transaction.TypeExpense, 10, day(-1), false), // overdue, counts today
+		upcoming(1, transaction.TypeIncome, 30, day(1), false),
+		upcoming(1, transaction.TypeExpense, 100, day(3), false),
+		upcoming(2, transaction.TypeExpense, 50, day(2), true),
+		upcoming(9, transaction.TypeExpense, 50, day(2), false), // an account that is gone
+	}, nil).Once()
+
+	f, err := usecase.GetForecast(7, now, 5, money.FromMajor(50))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-10-18", f.From)
+	assert.Equal(t, "2026-10-22", f.To)
+	assert.Len(t, f.Upcoming, 5)
+	if assert.Len(t, f.Accounts, 2) {
+		bank := f.Accounts[0]
+		var balances []money.Amount
+		for _, d := range bank.Daily {
+			balances = append(balances, d.Balance)
+		}
+		assert.Equal(t, []money.Amount{money.FromMajor(90), money.FromMajor(120), money.FromMajor(120), money.FromMajor(20), money.FromMajor(20)}, balances)
+		assert.Equal(t, money.FromMajor(30), bank.Daily[1].Income)
+		assert.Equal(t, "2026-10-21", bank.LowOn)
+		assert.True(t, bank.Daily[3].Low)
+		assert.False(t, bank.Daily[2].Low)
+		assert.Equal(t, money.FromMajor(20), bank.Lowest)
+		assert.Equal(t, "2026-10-21", bank.LowestOn)
+
+		wallet := f.Accounts[1]
+		assert.Equal(t, money.FromMajor(20), wallet.Daily[4].Balance, "skipped occurrences do not count")
+		assert.Equal(t, "2026-10-18", wallet.LowOn)
+		assert.Equal(t, "2026-10-18", wallet.LowestOn)
+	}
+}
+
+func TestGetForecastValidatesDays(t *testing.T) {
+	usecase := uc.New(nil, nil)
+
+	for _, days := range []int{-1, uc.MaxDays + 1} {
+		_, err := usecase.GetForecast(7, time.Now(), days, 0)
+
+		var appErr *apperror.AppError
+		if assert.ErrorAs(t, err, &appErr, days) {
+			assert.Equal(t, 400, appErr.Code)
+		}
+	}
+}
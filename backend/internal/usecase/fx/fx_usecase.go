@@ -0,0 +1,129 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+type Usecase interface {
+	// ImportRates validates and stores rates, replacing any existing rate for
+	// the same day and pair. It returns the number of rates stored.
+	ImportRates(rates []fx.Rate) (int, error)
+	// BaseCurrency returns the currency userID wants totals reported in.
+	BaseCurrency(userID int64) (string, error)
+	// Quote finds the rate to convert from into to as of the given day, using
+	// the latest stored rate on or before it. An inverse pair is used when
+	// only the opposite direction has been imported.
+	Quote(from, to string, on time.Time) (fx.Quote, error)
+}
+
+type usecase struct {
+	repo     fx.Repository
+	userRepo user.UserRepository
+}
+
+func New(repo fx.Repository, userRepo user.UserRepository) Usecase {
+	return &usecase{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (u *usecase) ImportRates(rates []fx.Rate) (int, error) {
+	if len(rates) == 0 {
+		return 0, apperror.BadRequest("no rates to import", nil)
+	}
+	for i := range rates {
+		if err := validateRate(&rates[i]); err != nil {
+			return 0, apperror.BadRequest(fmt.Sprintf("rate %d: %v", i+1, err), err)
+		}
+	}
+	if err := u.repo.SaveBatch(rates); err != nil {
+		return 0, apperror.Internal(err)
+	}
+	return len(rates), nil
+}
+
+func (u *usecase) BaseCurrency(userID int64) (string, error) {
+	usr, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if usr.BaseCurrency == "" {
+		return money.DefaultCurrency, nil
+	}
+	return usr.BaseCurrency, nil
+}
+
+func (u *usecase) Quote(from, to string, on time.Time) (fx.Quote, error) {
+	if from == to {
+		return fx.Quote{From: from, To: to, Rate: "1"}, nil
+	}
+
+	rate, err := u.repo.FindLatest(from, to, on)
+	if err == nil {
+		return fx.Quote{From: from, To: to, Rate: rate.Rate, RateDate: rate.Date.Format(fx.DateLayout)}, nil
+	}
+	if !errors.Is(err, fx.ErrRateNotFound) {
+		return fx.Quote{}, err
+	}
+
+	rate, err = u.repo.FindLatest(to, from, on)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			msg := fmt.Sprintf("no %s/%s exchange rate on or before %s", from, to, on.Format(fx.DateLayout))
+			return fx.Quote{}, apperror.New(http.StatusUnprocessableEntity, msg, err).WithCode(apperror.FxRateNotFound)
+		}
+		return fx.Quote{}, err
+	}
+	r, ok := new(big.Rat).SetString(rate.Rate)
+	if !ok || r.Sign() <= 0 {
+		return fx.Quote{}, apperror.Internal(fmt.Errorf("invalid stored rate %q for %s/%s", rate.Rate, to, from))
+	}
+	return fx.Quote{From: from, To: to, Rate: r.Inv(r).FloatString(10), RateDate: rate.Date.Format(fx.DateLayout)}, nil
+}
+
+func validateRate(r *fx.Rate) error {
+	r.Base = strings.ToUpper(strings.TrimSpace(r.Base))
+	r.Quote = strings.ToUpper(strings.TrimSpace(r.Quote))
+	r.Rate = strings.TrimSpace(r.Rate)
+
+	if r.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	if !money.ValidCurrency(r.Base) || !money.ValidCurrency(r.Quote) {
+		return errors.New("base and quote must be ISO 4217 currency codes")
+	}
+	if r.Base == r.Quote {
+		return errors.New("base and quote must differ")
+	}
+	if !isDecimal(r.Rate) {
+		return errors.New("rate must be a plain decimal number")
+	}
+	if v, _ := new(big.Rat).SetString(r.Rate); v.Sign() <= 0 {
+		return errors.New("rate must be greater than zero")
+	}
+	return nil
+}
+
+func isDecimal(s string) bool {
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" || (hasDot && fracPart == "") {
+		return false
+	}
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package fx_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFxRepository struct {
+	mock.Mock
+}
+
+func (m *MockFxRepository) FindLatest(base, quote string, on time.Time) (fx.Rate, error) {
+	args := m.Called(base, quote, on)
+	return args.Get(0).(fx.Rate), args.Error(1)
+}
+
+func (m *MockFxRepository) SaveBatch(rates []fx.Rate) error {
+	args := m.Called(rates)
+	return args.Error(0)
+}
+
+func TestQuote(t *testing.T) {
+	on := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	rateDay := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	t.Run("SameCurrency", func(t *testing.T) {
+		mockRepo := new(MockFxRepository)
+		usecase := uc.New(mockRepo, nil)
+
+		q, err := usecase.Quote("IDR", "IDR", on)
+
+		assert.NoError(t, err)
+		assert.Equal(t, money.FromMajor(5), q.Apply(money.FromMajor(5)))
+		assert.Empty(t, q.RateDate)
+		mockRepo.AssertNotCalled(t, "FindLatest", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Direct", func(t *testing.T) {
+		mockRepo := new(MockFxRepository)
+		usecase := uc.New(mockRepo, nil)
+
+		mockRepo.On("FindLatest", "USD", "IDR", on).Return(fx.Rate{Date: rateDay, Base: "USD", Quote: "IDR", Rate: "16250.5"}, nil).Once()
+
+		q, err := usecase.Quote("USD", "IDR", on)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "2026-10-16", q.RateDate)
+		assert.Equal(t, "32501.00", q.Apply(money.FromMajor(2)).String())
+	})
+
+	t.Run("Inverse", func(t *testing.T) {
+		mockRepo := new(MockFxRepository)
+		usecase := uc.New(mockRepo, nil)
+
+		mockRepo.On("FindLatest", "IDR", "USD", on).Return(fx.Rate{}, fx.ErrRateNotFound).Once()
+		mockRepo.On("FindLatest", "USD", "IDR", on).Return(fx.Rate{Date: rateDay, Base: "USD", Quote: "IDR", Rate: "16000"}, nil).Once()
+
+		q, err := usecase.Quote("IDR", "USD", on)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "2026-10-16", q.RateDate)
+		assert.Equal(t, "3.00", q.Apply(money.FromMajor(48000)).String())
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		mockRepo := new(MockFxRepository)
+		usecase := uc.New(mockRepo, nil)
+
+		mockRepo.On("FindLatest", "SGD", "IDR", on).Return(fx.Rate{}, fx.ErrRateNotFound).Once()
+		mockRepo.On("FindLatest", "IDR", "SGD", on).Return(fx.Rate{}, fx.ErrRateNotFound).Once()
+
+		_, err := usecase.Quote("SGD", "IDR", on)
+
+		var appErr *apperror.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, apperror.FxRateNotFound, appErr.ErrorCode)
+	})
+}
+
+func TestImportRates(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockFxRepository)
+		usecase := uc.New(mockRepo, nil)
+
+		mockRepo.On("SaveBatch", mock.MatchedBy(func(rates []fx.Rate) bool {
+			return len(rates) == 1 && rates[0].Base == "USD" && rates[0].Quote == "IDR"
+		})).Return(nil).Once()
+
+		n, err := usecase.ImportRates([]fx.Rate{{Date: day, Base: " usd", Quote: "idr", Rate: "16250.50"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		mockRepo.AssertExpectations(t)
+	})
+
+	invalid := map[string]fx.Rate{
+		"NoDate":       {Base: "USD", Quote: "IDR", Rate: "1"},
+		"BadCurrency":  {Date: day, Base: "US", Quote: "IDR", Rate: "1"},
+		"SamePair":     {Date: day, Base: "USD", Quote: "USD", Rate: "1"},
+		"Exponent":     {Date: day, Base: "USD", Quote: "IDR", Rate: "1e4"},
+		"Zero":         {Date: day, Base: "USD", Quote: "IDR", Rate: "0"},
+		"Negative":     {Date: day, Base: "USD", Quote: "IDR", Rate: "-1"},
+		"TrailingDot":  {Date: day, Base: "USD", Quote: "IDR", Rate: "1."},
+		"NotANumber":   {Date: day, Base: "USD", Quote: "IDR", Rate: "abc"},
+		"EmptyRateStr": {Date: day, Base: "USD", Quote: "IDR", Rate: ""},
+	}
+	for name, rate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(MockFxRepository)
+			usecase := uc.New(mockRepo, nil)
+
+			_, err := usecase.ImportRates([]fx.Rate{rate})
+
+			assert.Error(t, err)
+			mockRepo.AssertNotCalled(t, "SaveBatch", mock.Anything)
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	in := "Date,Base,Quote,Rate\n2026-10-01,USD,IDR,16250.50\n2026-10-01, SGD, IDR, 12100\n"
+
+	rates, err := uc.ParseCSV(strings.NewReader(in))
+
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+	assert.Equal(t, "16250.50", rates[0].Rate)
+	assert.Equal(t, "SGD", rates[1].Base)
+
+	_, err = uc.ParseCSV(strings.NewReader("date,base,rate\n"))
+	assert.Error(t, err)
+
+	_, err = uc.ParseCSV(strings.NewReader("date,base,quote,rate\n01/10/2026,USD,IDR,1\n"))
+	assert.Error(t, err)
+}
+
+func TestParseJSON(t *testing.T) {
+	in := `[{"date": "2026-10-01", "base": "USD", "quote": "IDR", "rate": 16250.50},
+		{"date": "2026-10-01", "base": "SGD", "quote": "IDR", "rate": "12100.125"}]`
+
+	rates, err := uc.ParseJSON(strings.NewReader(in))
+
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+	assert.Equal(t, "16250.50", rates[0].Rate)
+	assert.Equal(t, "12100.125", rates[1].Rate)
+
+	_, err = uc.ParseJSON(strings.NewReader(`[{"date": "yesterday", "base": "USD", "quote": "IDR", "rate": 1}]`))
+	assert.Error(t, err)
+}
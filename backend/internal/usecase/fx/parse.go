@@ -0,0 +1,93 @@
+package fx
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+)
+
+// ParseCSV reads daily rates from a CSV file with a header row naming the
+// date, base, quote and rate columns, e.g.
+//
+//	date,base,quote,rate
+//	2026-10-01,USD,IDR,16250.50
+func ParseCSV(r io.Reader) ([]fx.Rate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	var rates []fx.Rate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		date, err := time.Parse(fx.DateLayout, strings.TrimSpace(record[cols["date"]]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date: %w", line, err)
+		}
+		rates = append(rates, fx.Rate{
+			Date:  date,
+			Base:  record[cols["base"]],
+			Quote: record[cols["quote"]],
+			Rate:  record[cols["rate"]],
+		})
+	}
+	return rates, nil
+}
+
+type jsonRate struct {
+	Date  string      `json:"date"`
+	Base  string      `json:"base"`
+	Quote string      `json:"quote"`
+	Rate  json.Number `json:"rate"`
+}
+
+// ParseJSON reads daily rates from a JSON array such as
+//
+//	[{"date": "2026-10-01", "base": "USD", "quote": "IDR", "rate": 16250.50}]
+//
+// The rate may be a number or a string; it is kept verbatim either way.
+func ParseJSON(r io.Reader) ([]fx.Rate, error) {
+	var raw []jsonRate
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	rates := make([]fx.Rate, 0, len(raw))
+	for i, item := range raw {
+		date, err := time.Parse(fx.DateLayout, item.Date)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: invalid date: %w", i+1, err)
+		}
+		rates = append(rates, fx.Rate{
+			Date:  date,
+			Base:  item.Base,
+			Quote: item.Quote,
+			Rate:  item.Rate.String(),
+		})
+	}
+	return rates, nil
+}
@@ -0,0 +1,317 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+)
+
+// MaxRows caps the number of rows read from one statement file.
+const MaxRows = 5000
+
+// ValidateMapping checks m and fills in its defaults.
+func ValidateMapping(m *importer.Mapping) error {
+	switch m.Delimiter {
+	case "":
+		m.Delimiter = ","
+	case ",", ";", "|":
+	case "\t", "tab":
+		m.Delimiter = "\t"
+	default:
+		return errors.New(`delimiter must be one of ",", ";", "|" or "tab"`)
+	}
+	if m.SkipRows < 0 {
+		return errors.New("skip_rows cannot be negative")
+	}
+
+	if strings.TrimSpace(m.DateColumn) == "" {
+		return errors.New("date_column is required")
+	}
+	if m.DateFormat == "" {
+		m.DateFormat = "YYYY-MM-DD"
+	}
+
+	if m.AmountColumn == "" && m.DebitColumn == "" && m.CreditColumn == "" {
+		return errors.New("set amount_column, or debit_column and credit_column")
+	}
+	if m.AmountColumn != "" && (m.DebitColumn != "" || m.CreditColumn != "") {
+		return errors.New("use either amount_column or debit_column/credit_column, not both")
+	}
+	switch m.AmountSign {
+	case "":
+		m.AmountSign = importer.SignIncomePositive
+	case importer.SignIncomePositive, importer.SignExpensePositive:
+	default:
+		return errors.New(`amount_sign must be "income_positive" or "expense_positive"`)
+	}
+
+	switch m.DecimalSeparator {
+	case "":
+		m.DecimalSeparator = "."
+	case ".", ",":
+	default:
+		return errors.New(`decimal_separator must be "." or ","`)
+	}
+	return nil
+}
+
+// ParseCSV reads a bank statement exported as CSV using the column mapping
+// m. Problems with individual lines are reported on the row so they can be
+// shown in the preview; only an unreadable file or a header that does not
+// match the mapping fails the whole parse.
+func ParseCSV(r io.Reader, m importer.Mapping) ([]importer.Row, error) {
+	if err := ValidateMapping(&m); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(r)
+	for i := 0; i < m.SkipRows; i++ {
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, errors.New("file has fewer lines than skip_rows")
+		}
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = rune(m.Delimiter[0])
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff") // UTF-8 byte order mark
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	index := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := cols[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("column %q not found in header", name)
+		}
+		return i, nil
+	}
+	var dateCol, descCol, amountCol, debitCol, creditCol, categoryCol int
+	for _, c := range []struct {
+		name string
+		dst  *int
+	}{
+		{m.DateColumn, &dateCol},
+		{m.DescriptionColumn, &descCol},
+		{m.AmountColumn, &amountCol},
+		{m.DebitColumn, &debitCol},
+		{m.CreditColumn, &creditCol},
+		{m.CategoryColumn, &categoryCol},
+	} {
+		if *c.dst, err = index(c.name); err != nil {
+			return nil, err
+		}
+	}
+
+	layout := dateLayout(m.DateFormat)
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []importer.Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		line += m.SkipRows
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if isBlank(record) {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("file has more than %d rows", MaxRows)
+		}
+
+		row := importer.Row{
+			Line:         line,
+			Description:  field(record, descCol),
+			CategoryName: field(record, categoryCol),
+		}
+
+		row.Date, err = parseDate(field(record, dateCol), layout)
+		if err != nil {
+			row.Error = "invalid date " + strconv.Quote(field(record, dateCol))
+			rows = append(rows, row)
+			continue
+		}
+
+		if amountCol >= 0 {
+			err = signedAmount(&row, field(record, amountCol), m)
+		} else {
+			err = debitCreditAmount(&row, field(record, debitCol), field(record, creditCol), m.DecimalSeparator)
+		}
+		if err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func signedAmount(row *importer.Row, value string, m importer.Mapping) error {
+	if value == "" {
+		return errors.New("amount is empty")
+	}
+	v, err := parseAmount(value, m.DecimalSeparator)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s", strconv.Quote(value))
+	}
+	if v == 0 {
+		return errors.New("amount is zero")
+	}
+	if m.AmountSign == importer.SignExpensePositive {
+		v = -v
+	}
+
+	row.Amount = v.Abs()
+	if v > 0 {
+		row.Type = transaction.TypeIncome
+	} else {
+		row.Type = transaction.TypeExpense
+	}
+	return nil
+}
+
+func debitCreditAmount(row *importer.Row, debit, credit, decimalSep string) error {
+	var d, c money.Amount
+	var err error
+	if debit != "" {
+		if d, err = parseAmount(debit, decimalSep); err != nil {
+			return fmt.Errorf("invalid debit %s", strconv.Quote(debit))
+		}
+	}
+	if credit != "" {
+		if c, err = parseAmount(credit, decimalSep); err != nil {
+			return fmt.Errorf("invalid credit %s", strconv.Quote(credit))
+		}
+	}
+
+	switch {
+	case d != 0 && c != 0:
+		return errors.New("both debit and credit are set")
+	case d != 0:
+		row.Amount = d.Abs()
+		row.Type = transaction.TypeExpense
+	case c != 0:
+		row.Amount = c.Abs()
+		row.Type = transaction.TypeIncome
+	default:
+		return errors.New("amount is empty")
+	}
+	return nil
+}
+
+// parseAmount reads amounts the way banks print them: with thousands
+// separators, a currency prefix, negative numbers in parentheses or with a
+// trailing minus, and "CR"/"DB" markers.
+func parseAmount(s, decimalSep string) (money.Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+
+	upper := strings.ToUpper(s)
+	switch {
+	case strings.HasSuffix(upper, "CR"):
+		s = strings.TrimSpace(s[:len(s)-2])
+	case strings.HasSuffix(upper, "DB"), strings.HasSuffix(upper, "DR"):
+		neg = true
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = s[1 : len(s)-1]
+	}
+	if strings.HasSuffix(s, "-") {
+		neg = true
+		s = s[:len(s)-1]
+	}
+
+	// drop a currency prefix such as "Rp", "$" or "IDR "
+	s = strings.TrimLeftFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9') && r != '-' && r != '+' && string(r) != decimalSep
+	})
+
+	thousands := ","
+	if decimalSep == "," {
+		thousands = "."
+	}
+	s = strings.NewReplacer(thousands, "", " ", "", "\u00a0", "", "'", "").Replace(s)
+	if decimalSep == "," {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+
+	v, err := money.Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		v = -v.Abs()
+	}
+	return v, nil
+}
+
+// dateLayout turns a format such as "DD/MM/YYYY" into a Go time layout.
+// Go layouts are accepted as they are.
+func dateLayout(format string) string {
+	if strings.ContainsAny(format, "0123456789") {
+		return format
+	}
+	return strings.NewReplacer(
+		"YYYY", "2006",
+		"YY", "06",
+		"MMM", "Jan",
+		"MM", "01",
+		"M", "1",
+		"DD", "02",
+		"D", "2",
+	).Replace(strings.ToUpper(format))
+}
+
+func parseDate(value, layout string) (time.Time, error) {
+	t, err := time.Parse(layout, value)
+	if err == nil {
+		return t, nil
+	}
+	// some banks append the time of day; keep only the date part
+	if !strings.Contains(layout, " ") {
+		if date, _, ok := strings.Cut(value, " "); ok {
+			return time.Parse(layout, date)
+		}
+	}
+	return t, err
+}
+
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
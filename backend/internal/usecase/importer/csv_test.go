@@ -0,0 +1,112 @@
+package importer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/importer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSVSignedAmount(t *testing.T) {
+	in := "Statement of account 1234\n" +
+		"Tanggal;Keterangan;Jumlah\n" +
+		"01/10/2026;GAJI OKTOBER;15.000.000,00\n" +
+		"02/10/2026;INDOMARET 123;-125.500,50\n" +
+		"\n" +
+		"03/10/2026;TRANSFER;(1.000,00)\n" +
+		"31/09/2026;BAD DATE;1,00\n"
+
+	rows, err := uc.ParseCSV(strings.NewReader(in), importer.Mapping{
+		Delimiter:         ";",
+		SkipRows:          1,
+		DateColumn:        "tanggal",
+		DateFormat:        "DD/MM/YYYY",
+		DescriptionColumn: "Keterangan",
+		AmountColumn:      "Jumlah",
+		DecimalSeparator:  ",",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), rows[0].Date)
+	assert.Equal(t, "income", rows[0].Type)
+	assert.Equal(t, money.FromMajor(15000000), rows[0].Amount)
+	assert.Equal(t, 3, rows[0].Line)
+
+	assert.Equal(t, "expense", rows[1].Type)
+	assert.Equal(t, "125500.50", rows[1].Amount.String())
+	assert.Equal(t, "INDOMARET 123", rows[1].Description)
+
+	assert.Equal(t, "expense", rows[2].Type)
+	assert.Equal(t, money.FromMajor(1000), rows[2].Amount)
+
+	assert.Contains(t, rows[3].Error, "invalid date")
+	assert.Equal(t, 7, rows[3].Line)
+}
+
+func TestParseCSVExpensePositive(t *testing.T) {
+	in := "date,description,amount\n2026-10-05,Coffee,4.50\n2026-10-06,Refund,-4.50\n"
+
+	rows, err := uc.ParseCSV(strings.NewReader(in), importer.Mapping{
+		DateColumn:        "date",
+		DescriptionColumn: "description",
+		AmountColumn:      "amount",
+		AmountSign:        importer.SignExpensePositive,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "expense", rows[0].Type)
+	assert.Equal(t, "income", rows[1].Type)
+	assert.Equal(t, "4.50", rows[1].Amount.String())
+}
+
+func TestParseCSVDebitCredit(t *testing.T) {
+	in := "Date,Description,Debit,Credit,Category\n" +
+		"2026-10-01,Salary,,\"5,000.00\",Salary\n" +
+		"2026-10-02,Groceries,\"Rp 250,000.00\",,Food\n" +
+		"2026-10-03,Broken,10.00,20.00,\n" +
+		"2026-10-04,Nothing,,,\n"
+
+	rows, err := uc.ParseCSV(strings.NewReader(in), importer.Mapping{
+		DateColumn:        "Date",
+		DescriptionColumn: "Description",
+		DebitColumn:       "Debit",
+		CreditColumn:      "Credit",
+		CategoryColumn:    "Category",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, "income", rows[0].Type)
+	assert.Equal(t, money.FromMajor(5000), rows[0].Amount)
+	assert.Equal(t, "Salary", rows[0].CategoryName)
+	assert.Equal(t, "expense", rows[1].Type)
+	assert.Equal(t, money.FromMajor(250000), rows[1].Amount)
+	assert.Equal(t, "both debit and credit are set", rows[2].Error)
+	assert.Equal(t, "amount is empty", rows[3].Error)
+}
+
+func TestParseCSVAmountMarkers(t *testing.T) {
+	in := "date,amount\n2026-10-01,\"1,500.00 DB\"\n2026-10-02,\"2,000.00 CR\"\n2026-10-03,12.00-\n2026-10-04,1.005\n"
+
+	rows, err := uc.ParseCSV(strings.NewReader(in), importer.Mapping{DateColumn: "date", AmountColumn: "amount"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "expense", rows[0].Type)
+	assert.Equal(t, "income", rows[1].Type)
+	assert.Equal(t, "expense", rows[2].Type)
+	assert.Contains(t, rows[3].Error, "invalid amount")
+}
+
+func TestParseCSVHeaderMismatch(t *testing.T) {
+	_, err := uc.ParseCSV(strings.NewReader("date,value\n"), importer.Mapping{DateColumn: "date", AmountColumn: "amount"})
+	assert.Error(t, err)
+
+	_, err = uc.ParseCSV(strings.NewReader("date,amount\n"), importer.Mapping{DateColumn: "date"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,527 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	accountUC "github.com/afandimsr/cashbook-backend/internal/usecase/account"
+	categoryUC "github.com/afandimsr/cashbook-backend/internal/usecase/category"
+)
+
+// maxExisting bounds how many existing transactions are loaded to look for
+// duplicates of one statement.
+const maxExisting = 10000
+
+type Usecase interface {
+	GetProfiles(userID int64) ([]importer.Profile, error)
+	GetProfile(userID, id int64) (importer.Profile, error)
+	CreateProfile(userID int64, p importer.Profile) (importer.Profile, error)
+	UpdateProfile(userID, id int64, p importer.Profile) error
+	DeleteProfile(userID, id int64) error
+	// Preview resolves categories and flags likely duplicates without
+	// writing anything.
+	Preview(userID int64, rows []importer.Row, opts importer.Options) (importer.Preview, error)
+	// Commit saves the rows in a single database transaction. Nothing is
+	// written when any row is invalid.
+	Commit(userID int64, rows []importer.Row, opts importer.Options) (importer.Result, error)
+	// PreviewStatements is Preview for OFX and QIF files, which may hold
+	// several accounts. Each statement is matched to an account through
+	// opts.Accounts.
+	PreviewStatements(userID int64, stmts []importer.Statement, opts importer.Options) ([]importer.StatementPreview, error)
+	// CommitStatements saves all statements in a single database
+	// transaction. Rows whose ExternalID was imported before are always
+	// skipped.
+	CommitStatements(userID int64, stmts []importer.Statement, opts importer.Options) (importer.Result, error)
+}
+
+type usecase struct {
+	profileRepo  importer.ProfileRepository
+	txRepo       transaction.Repository
+	accountRepo  account.Repository
+	categoryRepo category.Repository
+}
+
+func New(profileRepo importer.ProfileRepository, txRepo transaction.Repository, accountRepo account.Repository, categoryRepo category.Repository) Usecase {
+	return &usecase{
+		profileRepo:  profileRepo,
+		txRepo:       txRepo,
+		accountRepo:  accountRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+func (u *usecase) GetProfiles(userID int64) ([]importer.Profile, error) {
+	return u.profileRepo.FindAllByUserID(userID)
+}
+
+func (u *usecase) GetProfile(userID, id int64) (importer.Profile, error) {
+	p, err := u.profileRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, importer.ErrProfileNotFound) {
+			return importer.Profile{}, apperror.NotFound("import profile not found", err)
+		}
+		return importer.Profile{}, err
+	}
+	if p.UserID != userID {
+		return importer.Profile{}, apperror.NotFound("import profile not found", importer.ErrProfileNotFound)
+	}
+	return p, nil
+}
+
+func (u *usecase) CreateProfile(userID int64, p importer.Profile) (importer.Profile, error) {
+	if err := validateProfile(&p); err != nil {
+		return importer.Profile{}, err
+	}
+	p.UserID = userID
+	if err := u.profileRepo.Save(&p); err != nil {
+		return importer.Profile{}, err
+	}
+	return p, nil
+}
+
+func (u *usecase) UpdateProfile(userID, id int64, p importer.Profile) error {
+	existing, err := u.GetProfile(userID, id)
+	if err != nil {
+		return err
+	}
+	if err := validateProfile(&p); err != nil {
+		return err
+	}
+	existing.Name = p.Name
+	existing.Mapping = p.Mapping
+	return u.profileRepo.Update(&existing)
+}
+
+func (u *usecase) DeleteProfile(userID, id int64) error {
+	if _, err := u.GetProfile(userID, id); err != nil {
+		return err
+	}
+	return u.profileRepo.Delete(id)
+}
+
+func validateProfile(p *importer.Profile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return apperror.BadRequest("name is required", nil)
+	}
+	if err := ValidateMapping(&p.Mapping); err != nil {
+		return apperror.BadRequest(err.Error(), err)
+	}
+	return nil
+}
+
+func (u *usecase) Preview(userID int64, rows []importer.Row, opts importer.Options) (importer.Preview, error) {
+	if _, err := u.prepare(userID, rows, &opts); err != nil {
+		return importer.Preview{}, err
+	}
+	return summarize(rows), nil
+}
+
+func (u *usecase) Commit(userID int64, rows []importer.Row, opts importer.Options) (importer.Result, error) {
+	acc, err := u.prepare(userID, rows, &opts)
+	if err != nil {
+		return importer.Result{}, err
+	}
+	if err := checkRows(rows); err != nil {
+		return importer.Result{}, err
+	}
+
+	var result importer.Result
+	err = u.txRepo.InTx(func(repo transaction.Repository) error {
+		return saveRows(repo, userID, acc, rows, opts.IncludeDuplicates, &result)
+	})
+	if err != nil {
+		return importer.Result{}, apperror.Internal(err)
+	}
+	return result, nil
+}
+
+func (u *usecase) PreviewStatements(userID int64, stmts []importer.Statement, opts importer.Options) ([]importer.StatementPreview, error) {
+	prepared, err := u.prepareStatements(userID, stmts, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]importer.StatementPreview, 0, len(prepared))
+	for _, p := range prepared {
+		previews = append(previews, importer.StatementPreview{
+			AccountRef: p.ref,
+			AccountID:  p.account.ID,
+			Preview:    summarize(p.rows),
+		})
+	}
+	return previews, nil
+}
+
+func (u *usecase) CommitStatements(userID int64, stmts []importer.Statement, opts importer.Options) (importer.Result, error) {
+	prepared, err := u.prepareStatements(userID, stmts, opts)
+	if err != nil {
+		return importer.Result{}, err
+	}
+	for _, p := range prepared {
+		if err := checkRows(p.rows); err != nil {
+			return importer.Result{}, err
+		}
+	}
+
+	var result importer.Result
+	err = u.txRepo.InTx(func(repo transaction.Repository) error {
+		for _, p := range prepared {
+			if err := saveRows(repo, userID, p.account, p.rows, opts.IncludeDuplicates, &result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return importer.Result{}, apperror.Internal(err)
+	}
+	return result, nil
+}
+
+func summarize(rows []importer.Row) importer.Preview {
+	preview := importer.Preview{Rows: rows, Total: len(rows)}
+	for _, row := range rows {
+		switch {
+		case row.Error != "":
+			preview.Invalid++
+		case row.Duplicate:
+			preview.Duplicates++
+		default:
+			preview.Valid++
+		}
+	}
+	return preview
+}
+
+func checkRows(rows []importer.Row) error {
+	for _, row := range rows {
+		if row.Error != "" {
+			return apperror.BadRequest(fmt.Sprintf("line %d: %s", row.Line, row.Error), nil)
+		}
+	}
+	return nil
+}
+
+func saveRows(repo transaction.Repository, userID int64, acc account.Account, rows []importer.Row, includeDuplicates bool, result *importer.Result) error {
+	for _, row := range rows {
+		if row.AlreadyImported || (row.Duplicate && !includeDuplicates) {
+			result.Skipped++
+			continue
+		}
+		t := transaction.Transaction{
+			UserID:     userID,
+			AccountID:  acc.ID,
+			CategoryID: row.CategoryID,
+			Amount:     row.Amount,
+			Currency:   acc.Currency,
+			Note:       row.Description,
+			Date:       row.Date,
+			Type:       row.Type,
+			ExternalID: row.ExternalID,
+		}
+		if err := repo.Save(&t); err != nil {
+			// imported by a concurrent run since the rows were flagged
+			if errors.Is(err, transaction.ErrDuplicateExternalID) {
+				result.Skipped++
+				continue
+			}
+			return err
+		}
+		result.Imported++
+	}
+	return nil
+}
+
+type preparedStatement struct {
+	ref     string
+	account account.Account
+	rows    []importer.Row
+}
+
+func (u *usecase) prepareStatements(userID int64, stmts []importer.Statement, opts importer.Options) ([]preparedStatement, error) {
+	if len(stmts) == 0 {
+		return nil, apperror.BadRequest("the file contains no transactions", nil)
+	}
+
+	prepared := make([]preparedStatement, 0, len(stmts))
+	for _, s := range stmts {
+		o := opts
+		if id, ok := opts.Accounts[s.AccountRef]; ok && s.AccountRef != "" {
+			o.AccountID = id
+		}
+
+		rows := statementRows(s)
+		acc, err := u.prepare(userID, rows, &o)
+		if err != nil {
+			return nil, err
+		}
+		if s.Currency != "" && !strings.EqualFold(s.Currency, acc.Currency) {
+			return nil, apperror.BadRequest(fmt.Sprintf("statement %q is in %s but account %q is in %s", s.AccountRef, strings.ToUpper(s.Currency), acc.Name, acc.Currency), nil)
+		}
+		prepared = append(prepared, preparedStatement{ref: s.AccountRef, account: acc, rows: rows})
+	}
+	flagRepeatedIDs(prepared)
+	return prepared, nil
+}
+
+// flagRepeatedIDs marks rows whose ExternalID already appears in an earlier
+// statement for the same account as already imported. Repeats within one
+// statement are flagged by flagImported.
+func flagRepeatedIDs(prepared []preparedStatement) {
+	seen := make(map[int64]map[string]bool)
+	for _, p := range prepared {
+		ids := seen[p.account.ID]
+		if ids == nil {
+			ids = make(map[string]bool)
+			seen[p.account.ID] = ids
+		}
+		for i := range p.rows {
+			row := &p.rows[i]
+			if row.ExternalID == "" || row.Error != "" {
+				continue
+			}
+			if ids[row.ExternalID] {
+				row.Duplicate = true
+				row.AlreadyImported = true
+			}
+			ids[row.ExternalID] = true
+		}
+	}
+}
+
+// statementRows turns the entries of a statement into rows. Line is the
+// position of the entry within the statement.
+func statementRows(s importer.Statement) []importer.Row {
+	rows := make([]importer.Row, len(s.Entries))
+	for i, e := range s.Entries {
+		rows[i] = importer.Row{
+			Line:         i + 1,
+			Date:         e.Date,
+			Description:  e.Note,
+			Amount:       e.Amount,
+			Type:         e.Type,
+			CategoryName: e.CategoryName,
+			ExternalID:   e.ExternalID,
+		}
+	}
+	return rows
+}
+
+// prepare validates the options, flags likely duplicates and assigns a
+// category to every row, all in place.
+func (u *usecase) prepare(userID int64, rows []importer.Row, opts *importer.Options) (account.Account, error) {
+	if len(rows) == 0 {
+		return account.Account{}, apperror.BadRequest("the file contains no transactions", nil)
+	}
+
+	acc, err := accountUC.Resolve(u.accountRepo, userID, opts.AccountID)
+	if err != nil {
+		return account.Account{}, err
+	}
+	opts.AccountID = acc.ID
+
+	if opts.CategoryID != 0 {
+		if err := categoryUC.Check(u.categoryRepo, userID, opts.CategoryID); err != nil {
+			return account.Account{}, err
+		}
+	}
+
+	if err := u.flagDuplicates(userID, acc.ID, rows); err != nil {
+		return account.Account{}, err
+	}
+
+	categories, err := u.categoryRepo.FindAllByUserID(userID)
+	if err != nil {
+		return account.Account{}, err
+	}
+	for i := range rows {
+		if rows[i].Error == "" {
+			assignCategory(&rows[i], categories, opts.CategoryID)
+		}
+	}
+	return acc, nil
+}
+
+// assignCategory matches the row's category name against the user's
+// categories, preferring one of the same type, and falls back to the
+// default category.
+func assignCategory(row *importer.Row, categories []category.Category, fallback int64) {
+	if row.CategoryID != 0 {
+		return
+	}
+	if row.CategoryName != "" {
+		var match int64
+		for _, c := range categories {
+			if !strings.EqualFold(c.Name, row.CategoryName) {
+				continue
+			}
+			if c.Type == row.Type {
+				match = c.ID
+				break
+			}
+			if match == 0 {
+				match = c.ID
+			}
+		}
+		if match != 0 {
+			row.CategoryID = match
+			return
+		}
+	}
+	if fallback == 0 {
+		row.Error = "no category: pick a default category_id or map a category column"
+		return
+	}
+	row.CategoryID = fallback
+}
+
+// flagDuplicates marks rows that match an existing transaction on the same
+// account with the same date, direction and amount and a similar note. Each
+// existing transaction matches at most one row, so genuinely repeated
+// purchases on one day are only flagged as often as they were recorded.
+// Rows whose ExternalID is already on the account are matched exactly.
+func (u *usecase) flagDuplicates(userID, accountID int64, rows []importer.Row) error {
+	if err := u.flagImported(accountID, rows); err != nil {
+		return err
+	}
+
+	var from, to time.Time
+	for _, row := range rows {
+		if row.Error != "" || row.AlreadyImported {
+			continue
+		}
+		if from.IsZero() || row.Date.Before(from) {
+			from = row.Date
+		}
+		if row.Date.After(to) {
+			to = row.Date
+		}
+	}
+	if from.IsZero() {
+		return nil
+	}
+
+	existing, err := u.txRepo.FindAllByUserID(userID, maxExisting, 0, transaction.Filter{
+		AccountID: accountID,
+		StartDate: from,
+		EndDate:   to.Add(24*time.Hour - time.Nanosecond),
+	})
+	if err != nil {
+		return err
+	}
+
+	used := make(map[int64]bool)
+	for i := range rows {
+		row := &rows[i]
+		if row.Error != "" || row.AlreadyImported {
+			continue
+		}
+		for _, t := range existing {
+			if used[t.ID] || !sameDay(t.Date, row.Date) || t.Amount != row.Amount || !sameDirection(t.Type, row.Type) {
+				continue
+			}
+			if !similarNote(t.Note, row.Description) {
+				continue
+			}
+			used[t.ID] = true
+			row.Duplicate = true
+			row.DuplicateOf = t.ID
+			break
+		}
+	}
+	return nil
+}
+
+// flagImported marks rows whose ExternalID already exists on the account, or
+// appears earlier in the same file, as already imported.
+func (u *usecase) flagImported(accountID int64, rows []importer.Row) error {
+	var ids []string
+	for _, row := range rows {
+		if row.ExternalID != "" && row.Error == "" {
+			ids = append(ids, row.ExternalID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	found, err := u.txRepo.FindByExternalIDs(accountID, ids)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for i := range rows {
+		row := &rows[i]
+		if row.ExternalID == "" || row.Error != "" {
+			continue
+		}
+		if id, ok := found[row.ExternalID]; ok || seen[row.ExternalID] {
+			row.Duplicate = true
+			row.AlreadyImported = true
+			row.DuplicateOf = id
+		}
+		seen[row.ExternalID] = true
+	}
+	return nil
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.UTC().Format("2006-01-02") == b.UTC().Format("2006-01-02")
+}
+
+func sameDirection(existing, imported string) bool {
+	if imported == transaction.TypeIncome {
+		return existing == transaction.TypeIncome || existing == transaction.TypeTransferIn
+	}
+	return existing == transaction.TypeExpense || existing == transaction.TypeTransferOut
+}
+
+// similarNote reports whether two descriptions likely describe the same
+// transaction: either is empty, one contains the other, or they share at
+// least half of their words.
+func similarNote(a, b string) bool {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return true
+	}
+	na, nb := strings.Join(wa, " "), strings.Join(wb, " ")
+	if strings.Contains(na, nb) || strings.Contains(nb, na) {
+		return true
+	}
+
+	set := make(map[string]bool, len(wa))
+	for _, w := range wa {
+		set[w] = true
+	}
+	common := 0
+	union := len(set)
+	seen := make(map[string]bool, len(wb))
+	for _, w := range wb {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if set[w] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return common*2 >= union
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
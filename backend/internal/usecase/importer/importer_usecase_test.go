@@ -0,0 +1,488 @@
+package importer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/importer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTransactionRepository struct {
+	mock.Mock
+	inTx bool
+}
+
+func (m *MockTransactionRepository) FindAllByUserID(userID int64, limit, offset int, filter transaction.Filter) ([]transaction.Transaction, error) {
+	args := m.Called(userID, limit, offset, filter)
+	return args.Get(0).([]transaction.Transaction), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Count(userID int64, filter transaction.Filter) (int64, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SumByCurrency(userID int64, filter transaction.Filter) ([]transaction.CurrencySummary, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]transaction.CurrencySummary), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SumByCategory(userID int64, filter transaction.Filter) ([]transaction.CategoryTotal, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]transaction.CategoryTotal), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Trend(userID int64, query transaction.TrendQuery) ([]transaction.TrendRow, error) {
+	args := m.Called(userID, query)
+	return args.Get(0).([]transaction.TrendRow), args.Error(1)
+}
+
+func (m *MockTransactionRepository) FindByIDForUser(userID, id int64) (transaction.Transaction, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(transaction.Transaction), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Save(t *transaction.Transaction) error {
+	args := m.Called(t, m.inTx)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) SaveOccurrence(t *transaction.Transaction) (bool, error) {
+	args := m.Called(t, m.inTx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Update(t *transaction.Transaction) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) Delete(userID, id int64) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) StreamReport(userID int64, filter transaction.Filter, fn func(transaction.ReportTransaction) error) error {
+	args := m.Called(userID, filter)
+	if rows, ok := args.Get(0).([]transaction.ReportTransaction); ok {
+		for _, t := range rows {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockTransactionRepository) FindSplits(transactionIDs []int64) (map[int64][]transaction.Split, error) {
+	args := m.Called(transactionIDs)
+	return args.Get(0).(map[int64][]transaction.Split), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SaveSplits(transactionID int64, splits []transaction.Split) error {
+	args := m.Called(transactionID, splits)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) FindTags(transactionIDs []int64) (map[int64][]string, error) {
+	args := m.Called(transactionIDs)
+	return args.Get(0).(map[int64][]string), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SetTags(transactionID, userID int64, names []string) error {
+	args := m.Called(transactionID, userID, names)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) FindByExternalIDs(accountID int64, ids []string) (map[string]int64, error) {
+	args := m.Called(accountID, ids)
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockTransactionRepository) InTx(fn func(repo transaction.Repository) error) error {
+	m.inTx = true
+	defer func() { m.inTx = false }()
+	return fn(m)
+}
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) FindAllByUserID(userID int64) ([]account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) FindByID(id int64) (account.Account, error) {
+	args := m.Called(id)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) FindDefault(userID int64) (account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) Save(a *account.Account) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Update(a *account.Account) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) FindTransferByID(id int64) (account.Transfer, error) {
+	args := m.Called(id)
+	return args.Get(0).(account.Transfer), args.Error(1)
+}
+
+func (m *MockAccountRepository) SaveTransfer(tr *account.Transfer) error {
+	args := m.Called(tr)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) DeleteTransfer(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) FindAllByUserID(userID int64) ([]category.Category, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]category.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindByIDForUser(userID, id int64) (category.Category, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(category.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Save(c *category.Category) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Update(c *category.Category) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Delete(userID, id int64) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+type MockProfileRepository struct {
+	mock.Mock
+}
+
+func (m *MockProfileRepository) FindAllByUserID(userID int64) ([]importer.Profile, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]importer.Profile), args.Error(1)
+}
+
+func (m *MockProfileRepository) FindByID(id int64) (importer.Profile, error) {
+	args := m.Called(id)
+	return args.Get(0).(importer.Profile), args.Error(1)
+}
+
+func (m *MockProfileRepository) Save(p *importer.Profile) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) Update(p *importer.Profile) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+const userID = int64(1)
+
+var (
+	day1 = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	day2 = time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+)
+
+type fixture struct {
+	txRepo       *MockTransactionRepository
+	accountRepo  *MockAccountRepository
+	categoryRepo *MockCategoryRepository
+	usecase      uc.Usecase
+}
+
+// newFixture sets up account 5 (USD), categories "Food" (10) and "Salary"
+// (11), and one existing "Coffee shop" expense of 4.50 on day1.
+func newFixture() fixture {
+	f := fixture{
+		txRepo:       new(MockTransactionRepository),
+		accountRepo:  new(MockAccountRepository),
+		categoryRepo: new(MockCategoryRepository),
+	}
+	f.usecase = uc.New(new(MockProfileRepository), f.txRepo, f.accountRepo, f.categoryRepo)
+
+	f.accountRepo.On("FindByID", int64(5)).Return(account.Account{ID: 5, UserID: userID, Currency: "USD"}, nil)
+	f.categoryRepo.On("FindByIDForUser", userID, int64(10)).Return(category.Category{ID: 10, UserID: userID, Name: "Food", Type: "expense"}, nil)
+	f.categoryRepo.On("FindAllByUserID", userID).Return([]category.Category{
+		{ID: 10, UserID: userID, Name: "Food", Type: "expense"},
+		{ID: 11, UserID: userID, Name: "Salary", Type: "income"},
+	}, nil)
+	f.txRepo.On("FindAllByUserID", userID, mock.Anything, 0, mock.MatchedBy(func(filter transaction.Filter) bool {
+		return filter.AccountID == 5 && filter.StartDate.Equal(day1) && filter.EndDate.After(filter.StartDate)
+	})).Return([]transaction.Transaction{
+		{ID: 99, UserID: userID, AccountID: 5, Amount: money.FromMinor(450), Note: "Coffee shop", Date: day1, Type: "expense"},
+	}, nil)
+	return f
+}
+
+func rows() []importer.Row {
+	return []importer.Row{
+		{Line: 2, Date: day1, Description: "COFFEE SHOP #12", Amount: money.FromMinor(450), Type: "expense"},
+		{Line: 3, Date: day1, Description: "Coffee shop", Amount: money.FromMinor(450), Type: "expense"},
+		{Line: 4, Date: day2, Description: "October salary", Amount: money.FromMajor(1000), Type: "income", CategoryName: "salary"},
+	}
+}
+
+func TestPreview(t *testing.T) {
+	f := newFixture()
+
+	preview, err := f.usecase.Preview(userID, rows(), importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, preview.Total)
+	assert.Equal(t, 1, preview.Duplicates)
+	assert.Equal(t, 2, preview.Valid)
+
+	// the existing coffee matches only the first of the two identical rows
+	assert.True(t, preview.Rows[0].Duplicate)
+	assert.Equal(t, int64(99), preview.Rows[0].DuplicateOf)
+	assert.False(t, preview.Rows[1].Duplicate)
+
+	assert.Equal(t, int64(10), preview.Rows[0].CategoryID)
+	assert.Equal(t, int64(11), preview.Rows[2].CategoryID)
+	f.txRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestPreviewWithoutCategory(t *testing.T) {
+	f := newFixture()
+
+	preview, err := f.usecase.Preview(userID, rows(), importer.Options{AccountID: 5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, preview.Invalid)
+	assert.NotEmpty(t, preview.Rows[1].Error)
+}
+
+func TestCommit(t *testing.T) {
+	f := newFixture()
+
+	f.txRepo.On("Save", mock.MatchedBy(func(tx *transaction.Transaction) bool {
+		return tx.AccountID == 5 && tx.Currency == "USD" && tx.UserID == userID && tx.CategoryID != 0
+	}), true).Return(nil).Twice()
+
+	result, err := f.usecase.Commit(userID, rows(), importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+	f.txRepo.AssertExpectations(t)
+}
+
+func TestCommitIncludeDuplicates(t *testing.T) {
+	f := newFixture()
+
+	f.txRepo.On("Save", mock.Anything, true).Return(nil).Times(3)
+
+	result, err := f.usecase.Commit(userID, rows(), importer.Options{AccountID: 5, CategoryID: 10, IncludeDuplicates: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+	f.txRepo.AssertExpectations(t)
+}
+
+func TestCommitRejectsInvalidRows(t *testing.T) {
+	f := newFixture()
+
+	in := rows()
+	in[2].Error = "invalid amount"
+
+	_, err := f.usecase.Commit(userID, in, importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.Error(t, err)
+	f.txRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestCommitFailsAsAWhole(t *testing.T) {
+	f := newFixture()
+
+	f.txRepo.On("Save", mock.Anything, true).Return(nil).Once()
+	f.txRepo.On("Save", mock.Anything, true).Return(errors.New("db down")).Once()
+
+	_, err := f.usecase.Commit(userID, rows(), importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.Error(t, err)
+}
+
+func TestPreviewRejectsForeignCategory(t *testing.T) {
+	f := newFixture()
+	f.categoryRepo.On("FindByIDForUser", userID, int64(20)).Return(category.Category{}, category.ErrNotFound)
+
+	_, err := f.usecase.Preview(userID, rows(), importer.Options{AccountID: 5, CategoryID: 20})
+
+	assert.Error(t, err)
+}
+
+func TestCreateProfileValidatesMapping(t *testing.T) {
+	profileRepo := new(MockProfileRepository)
+	usecase := uc.New(profileRepo, nil, nil, nil)
+
+	_, err := usecase.CreateProfile(userID, importer.Profile{Name: "BCA", Mapping: importer.Mapping{DateColumn: "date"}})
+	assert.Error(t, err)
+
+	profileRepo.On("Save", mock.MatchedBy(func(p *importer.Profile) bool {
+		return p.UserID == userID && p.Mapping.Delimiter == "," && p.Mapping.AmountSign == importer.SignIncomePositive
+	})).Return(nil).Once()
+
+	_, err = usecase.CreateProfile(userID, importer.Profile{Name: "BCA", Mapping: importer.Mapping{DateColumn: "date", AmountColumn: "amount"}})
+	assert.NoError(t, err)
+	profileRepo.AssertExpectations(t)
+}
+
+func TestProfileOwnership(t *testing.T) {
+	profileRepo := new(MockProfileRepository)
+	usecase := uc.New(profileRepo, nil, nil, nil)
+
+	profileRepo.On("FindByID", int64(3)).Return(importer.Profile{ID: 3, UserID: 2}, nil)
+
+	_, err := usecase.GetProfile(userID, 3)
+	assert.Error(t, err)
+
+	err = usecase.DeleteProfile(userID, 3)
+	assert.Error(t, err)
+	profileRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func statement() importer.Statement {
+	return importer.Statement{
+		AccountRef: "000123456",
+		Currency:   "USD",
+		Entries: []importer.Entry{
+			{Transaction: transaction.Transaction{Date: day1, Amount: money.FromMinor(450), Type: "expense", Note: "COFFEE SHOP", ExternalID: "F1"}},
+			{Transaction: transaction.Transaction{Date: day2, Amount: money.FromMajor(1000), Type: "income", Note: "Payroll", ExternalID: "F2"}, CategoryName: "Salary"},
+		},
+	}
+}
+
+func TestCommitStatementsSkipsImportedFITIDs(t *testing.T) {
+	f := newFixture()
+	f.txRepo.On("FindByExternalIDs", int64(5), []string{"F1", "F2"}).Return(map[string]int64{"F1": 99}, nil)
+	f.txRepo.On("FindAllByUserID", userID, mock.Anything, 0, mock.MatchedBy(func(filter transaction.Filter) bool {
+		return filter.StartDate.Equal(day2)
+	})).Return([]transaction.Transaction{}, nil)
+	f.txRepo.On("Save", mock.MatchedBy(func(tx *transaction.Transaction) bool {
+		return tx.ExternalID == "F2" && tx.AccountID == 5 && tx.CategoryID == 11
+	}), true).Return(nil).Once()
+
+	// already imported rows are skipped even when duplicates are included
+	result, err := f.usecase.CommitStatements(userID, []importer.Statement{statement()}, importer.Options{
+		Accounts:          map[string]int64{"000123456": 5},
+		CategoryID:        10,
+		IncludeDuplicates: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+	f.txRepo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestCommitStatementsSavesRepeatedFITIDOnce(t *testing.T) {
+	f := newFixture()
+	f.txRepo.On("FindByExternalIDs", int64(5), mock.Anything).Return(map[string]int64{}, nil)
+	f.txRepo.On("FindAllByUserID", userID, mock.Anything, 0, mock.MatchedBy(func(filter transaction.Filter) bool {
+		return filter.StartDate.Equal(day2)
+	})).Return([]transaction.Transaction{}, nil)
+	f.txRepo.On("Save", mock.MatchedBy(func(tx *transaction.Transaction) bool {
+		return tx.ExternalID == "F2"
+	}), true).Return(nil).Once()
+
+	// both statements of the file are mapped onto account 5 and list F2
+	first := statement()
+	first.Entries = first.Entries[1:]
+	second := statement()
+	second.AccountRef = "000654321"
+	second.Entries = second.Entries[1:]
+	result, err := f.usecase.CommitStatements(userID, []importer.Statement{first, second}, importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+	f.txRepo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestCommitStatementsSkipsConcurrentlyImportedFITID(t *testing.T) {
+	f := newFixture()
+	f.txRepo.On("FindByExternalIDs", int64(5), []string{"F2"}).Return(map[string]int64{}, nil)
+	f.txRepo.On("FindAllByUserID", userID, mock.Anything, 0, mock.MatchedBy(func(filter transaction.Filter) bool {
+		return filter.StartDate.Equal(day2)
+	})).Return([]transaction.Transaction{}, nil)
+	f.txRepo.On("Save", mock.Anything, true).Return(transaction.ErrDuplicateExternalID).Once()
+
+	s := statement()
+	s.Entries = s.Entries[1:]
+	result, err := f.usecase.CommitStatements(userID, []importer.Statement{s}, importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+}
+
+func TestPreviewStatements(t *testing.T) {
+	f := newFixture()
+	f.txRepo.On("FindByExternalIDs", int64(5), []string{"F1", "F2"}).Return(map[string]int64{}, nil)
+
+	previews, err := f.usecase.PreviewStatements(userID, []importer.Statement{statement()}, importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.NoError(t, err)
+	assert.Len(t, previews, 1)
+	assert.Equal(t, int64(5), previews[0].AccountID)
+	assert.Equal(t, "000123456", previews[0].AccountRef)
+	// no FITID match, so the coffee is only a likely duplicate
+	assert.True(t, previews[0].Rows[0].Duplicate)
+	assert.False(t, previews[0].Rows[0].AlreadyImported)
+	assert.Equal(t, 1, previews[0].Valid)
+}
+
+func TestPreviewStatementsRejectsCurrencyMismatch(t *testing.T) {
+	f := newFixture()
+	f.txRepo.On("FindByExternalIDs", int64(5), mock.Anything).Return(map[string]int64{}, nil)
+
+	s := statement()
+	s.Currency = "EUR"
+	_, err := f.usecase.PreviewStatements(userID, []importer.Statement{s}, importer.Options{AccountID: 5, CategoryID: 10})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+)
+
+// ParseOFX reads an OFX or QFX file. Both the SGML flavour of OFX 1.x, where
+// leaf elements have no closing tags, and the XML flavour of OFX 2.x are
+// supported. Every bank or credit card statement in the file becomes one
+// Statement and each STMTTRN one entry, with its FITID as ExternalID.
+func ParseOFX(r io.Reader) ([]importer.Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body := string(data)
+	start := strings.Index(strings.ToUpper(body), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("not an OFX file")
+	}
+	body = body[start:]
+
+	var (
+		stmts      []importer.Statement
+		stmt       = -1 // index of the statement being read
+		entry      *ofxEntry
+		inAcctFrom bool
+		count      int
+	)
+
+	for {
+		lt := strings.IndexByte(body, '<')
+		if lt < 0 {
+			break
+		}
+		gt := strings.IndexByte(body[lt:], '>')
+		if gt < 0 {
+			return nil, errors.New("unterminated tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(body[lt+1 : lt+gt]))
+		body = body[lt+gt+1:]
+
+		next := strings.IndexByte(body, '<')
+		if next < 0 {
+			next = len(body)
+		}
+		value := strings.TrimSpace(html.UnescapeString(body[:next]))
+
+		switch tag {
+		case "STMTRS", "CCSTMTRS":
+			stmts = append(stmts, importer.Statement{})
+			stmt = len(stmts) - 1
+		case "/STMTRS", "/CCSTMTRS":
+			stmt = -1
+		case "BANKACCTFROM", "CCACCTFROM":
+			inAcctFrom = true
+		case "/BANKACCTFROM", "/CCACCTFROM":
+			inAcctFrom = false
+		case "CURDEF":
+			if stmt >= 0 {
+				stmts[stmt].Currency = strings.ToUpper(value)
+			}
+		case "ACCTID":
+			if stmt >= 0 && inAcctFrom {
+				stmts[stmt].AccountRef = value
+			}
+		case "STMTTRN":
+			if stmt < 0 {
+				return nil, errors.New("STMTTRN outside of a statement")
+			}
+			entry = &ofxEntry{}
+		case "/STMTTRN":
+			if entry == nil {
+				continue
+			}
+			count++
+			if count > MaxRows {
+				return nil, fmt.Errorf("file has more than %d transactions", MaxRows)
+			}
+			e, err := entry.toEntry()
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %w", count, err)
+			}
+			stmts[stmt].Entries = append(stmts[stmt].Entries, e)
+			entry = nil
+		default:
+			if entry != nil {
+				entry.set(tag, value)
+			}
+		}
+	}
+
+	if len(stmts) == 0 {
+		return nil, errors.New("no bank or credit card statement found")
+	}
+	return stmts, nil
+}
+
+type ofxEntry struct {
+	posted, amount, fitID, name, memo string
+}
+
+func (e *ofxEntry) set(tag, value string) {
+	switch tag {
+	case "DTPOSTED":
+		e.posted = value
+	case "TRNAMT":
+		e.amount = value
+	case "FITID":
+		e.fitID = value
+	case "NAME":
+		e.name = value
+	case "MEMO":
+		e.memo = value
+	}
+}
+
+func (e *ofxEntry) toEntry() (importer.Entry, error) {
+	// DTPOSTED is YYYYMMDD optionally followed by a time and time zone
+	if len(e.posted) < 8 {
+		return importer.Entry{}, fmt.Errorf("invalid DTPOSTED %q", e.posted)
+	}
+	date, err := time.Parse("20060102", e.posted[:8])
+	if err != nil {
+		return importer.Entry{}, fmt.Errorf("invalid DTPOSTED %q", e.posted)
+	}
+
+	decimalSep := "."
+	if strings.Contains(e.amount, ",") && !strings.Contains(e.amount, ".") {
+		decimalSep = ","
+	}
+	amount, err := parseAmount(e.amount, decimalSep)
+	if err != nil || amount == 0 {
+		return importer.Entry{}, fmt.Errorf("invalid TRNAMT %q", e.amount)
+	}
+
+	note := e.name
+	if e.memo != "" && !strings.EqualFold(e.memo, e.name) {
+		if note != "" {
+			note += " - "
+		}
+		note += e.memo
+	}
+
+	t := transaction.Transaction{
+		Amount:     amount.Abs(),
+		Note:       note,
+		Date:       date,
+		Type:       transaction.TypeIncome,
+		ExternalID: e.fitID,
+	}
+	if amount < 0 {
+		t.Type = transaction.TypeExpense
+	}
+	return importer.Entry{Transaction: t}, nil
+}
@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/importer"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+)
+
+// qifSections are the QIF transaction list types that are imported. Other
+// sections (categories, classes, memorised transactions, investments) are
+// skipped.
+var qifSections = map[string]bool{
+	"bank":  true,
+	"cash":  true,
+	"ccard": true,
+	"oth a": true,
+	"oth l": true,
+}
+
+// ParseQIF reads a QIF file. QIF dates carry no format marker, so dayFirst
+// selects between MM/DD (the Quicken default) and DD/MM. Accounts declared
+// with !Account start a new statement; transactions before any declaration
+// belong to a statement without an AccountRef.
+func ParseQIF(r io.Reader, dayFirst bool) ([]importer.Statement, error) {
+	var (
+		stmts     []importer.Statement
+		account   string // name from the last !Account block
+		inAccount bool   // inside an !Account block
+		inList    bool   // inside an importable transaction list
+		entry     qifEntry
+		count     int
+		line      int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if text[0] == '!' {
+			header := strings.ToLower(strings.TrimSpace(text[1:]))
+			inAccount = header == "account"
+			inList = false
+			if kind, ok := strings.CutPrefix(header, "type:"); ok && qifSections[strings.TrimSpace(kind)] {
+				inList = true
+				stmts = append(stmts, importer.Statement{AccountRef: account})
+			}
+			entry = qifEntry{}
+			continue
+		}
+
+		code, value := text[0], strings.TrimSpace(text[1:])
+		switch {
+		case inAccount:
+			if code == 'N' {
+				account = value
+			}
+		case !inList:
+			continue
+		case code == '^':
+			if entry.empty() {
+				continue
+			}
+			count++
+			if count > MaxRows {
+				return nil, fmt.Errorf("file has more than %d transactions", MaxRows)
+			}
+			e, err := entry.toEntry(dayFirst)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			last := &stmts[len(stmts)-1]
+			last.Entries = append(last.Entries, e)
+			entry = qifEntry{}
+		default:
+			entry.set(code, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// drop lists that held no transactions, such as an empty opening section
+	out := stmts[:0]
+	for _, s := range stmts {
+		if len(s.Entries) > 0 {
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no transactions found")
+	}
+	return out, nil
+}
+
+type qifEntry struct {
+	date, amount, payee, memo, category string
+}
+
+func (e *qifEntry) set(code byte, value string) {
+	switch code {
+	case 'D':
+		e.date = value
+	case 'T', 'U':
+		e.amount = value
+	case 'P':
+		e.payee = value
+	case 'M':
+		e.memo = value
+	case 'L':
+		// "[Savings]" names the other side of a transfer, not a category
+		if !strings.HasPrefix(value, "[") {
+			// "Food:Groceries" is a subcategory; keep the top level
+			e.category, _, _ = strings.Cut(value, ":")
+		}
+	}
+}
+
+func (e qifEntry) empty() bool {
+	return e == qifEntry{}
+}
+
+func (e qifEntry) toEntry(dayFirst bool) (importer.Entry, error) {
+	date, err := parseQIFDate(e.date, dayFirst)
+	if err != nil {
+		return importer.Entry{}, fmt.Errorf("invalid date %q", e.date)
+	}
+	amount, err := parseAmount(e.amount, ".")
+	if err != nil || amount == 0 {
+		return importer.Entry{}, fmt.Errorf("invalid amount %q", e.amount)
+	}
+
+	note := e.payee
+	if e.memo != "" && !strings.EqualFold(e.memo, e.payee) {
+		if note != "" {
+			note += " - "
+		}
+		note += e.memo
+	}
+
+	t := transaction.Transaction{
+		Amount: amount.Abs(),
+		Note:   note,
+		Date:   date,
+		Type:   transaction.TypeIncome,
+	}
+	if amount < 0 {
+		t.Type = transaction.TypeExpense
+	}
+	return importer.Entry{Transaction: t, CategoryName: strings.TrimSpace(e.category)}, nil
+}
+
+// parseQIFDate accepts the date styles Quicken and other tools write:
+// "10/01/2026", "10/1/26", "10/ 1'26" and ISO "2026-10-01".
+func parseQIFDate(s string, dayFirst bool) (time.Time, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\''
+	})
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("expected three date parts")
+	}
+
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, err
+		}
+		nums[i] = n
+	}
+
+	var year, month, day int
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = nums[0], nums[1], nums[2]
+	case dayFirst:
+		day, month, year = nums[0], nums[1], nums[2]
+	default:
+		month, day, year = nums[0], nums[1], nums[2]
+	}
+	if len(parts[2]) <= 2 && len(parts[0]) != 4 {
+		year += 2000
+		if year > time.Now().Year()+1 {
+			year -= 100
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, errors.New("date out of range")
+	}
+	return t, nil
+}
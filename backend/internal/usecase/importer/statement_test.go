@@ -0,0 +1,113 @@
+package importer_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/importer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOFXSGML(t *testing.T) {
+	f, err := os.Open("testdata/statement.ofx")
+	require.NoError(t, err)
+	defer f.Close()
+
+	stmts, err := uc.ParseOFX(f)
+
+	require.NoError(t, err)
+	require.Len(t, stmts, 2)
+
+	bank := stmts[0]
+	assert.Equal(t, "000123456", bank.AccountRef)
+	assert.Equal(t, "USD", bank.Currency)
+	require.Len(t, bank.Entries, 3)
+
+	salary := bank.Entries[0]
+	assert.Equal(t, "income", salary.Type)
+	assert.Equal(t, money.FromMajor(2500), salary.Amount)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), salary.Date)
+	assert.Equal(t, "20261001-001", salary.ExternalID)
+	assert.Equal(t, "ACME CORP PAYROLL - October salary", salary.Note)
+
+	assert.Equal(t, "expense", bank.Entries[1].Type)
+	assert.Equal(t, "42.15", bank.Entries[1].Amount.String())
+	assert.Equal(t, "Smith & Sons Grocery", bank.Entries[1].Note)
+	assert.Equal(t, "COFFEE SHOP", bank.Entries[2].Note)
+
+	card := stmts[1]
+	assert.Equal(t, "4111XXXXXXXX1111", card.AccountRef)
+	require.Len(t, card.Entries, 1)
+	assert.Equal(t, "CC-777", card.Entries[0].ExternalID)
+}
+
+func TestParseOFXXML(t *testing.T) {
+	f, err := os.Open("testdata/statement_v2.ofx")
+	require.NoError(t, err)
+	defer f.Close()
+
+	stmts, err := uc.ParseOFX(f)
+
+	require.NoError(t, err)
+	require.Len(t, stmts, 1)
+	assert.Equal(t, "DE89370400440532013000", stmts[0].AccountRef)
+	assert.Equal(t, "EUR", stmts[0].Currency)
+	require.Len(t, stmts[0].Entries, 2)
+	assert.Equal(t, money.FromMajor(1250), stmts[0].Entries[0].Amount)
+	assert.Equal(t, "Miete - Oktober", stmts[0].Entries[0].Note)
+	assert.Equal(t, "A2", stmts[0].Entries[1].ExternalID)
+}
+
+func TestParseOFXRejectsOtherFiles(t *testing.T) {
+	_, err := uc.ParseOFX(strings.NewReader("date,amount\n2026-10-01,1.00\n"))
+	assert.Error(t, err)
+
+	_, err = uc.ParseOFX(strings.NewReader("<OFX><STMTRS><STMTTRN><DTPOSTED>2026<TRNAMT>1</STMTTRN></STMTRS></OFX>"))
+	assert.Error(t, err)
+}
+
+func TestParseQIF(t *testing.T) {
+	f, err := os.Open("testdata/statement.qif")
+	require.NoError(t, err)
+	defer f.Close()
+
+	stmts, err := uc.ParseQIF(f, false)
+
+	require.NoError(t, err)
+	require.Len(t, stmts, 2)
+
+	checking := stmts[0]
+	assert.Equal(t, "Checking", checking.AccountRef)
+	require.Len(t, checking.Entries, 3)
+	assert.Equal(t, money.FromMajor(2500), checking.Entries[0].Amount)
+	assert.Equal(t, "income", checking.Entries[0].Type)
+	assert.Equal(t, "Salary", checking.Entries[0].CategoryName)
+	assert.Equal(t, "ACME Corp - October salary", checking.Entries[0].Note)
+
+	assert.Equal(t, time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), checking.Entries[1].Date)
+	assert.Equal(t, "Food", checking.Entries[1].CategoryName)
+	assert.Equal(t, "expense", checking.Entries[1].Type)
+
+	// a transfer names the other account, not a category
+	assert.Empty(t, checking.Entries[2].CategoryName)
+
+	assert.Equal(t, "Visa", stmts[1].AccountRef)
+	require.Len(t, stmts[1].Entries, 1)
+	assert.Equal(t, "19.99", stmts[1].Entries[0].Amount.String())
+}
+
+func TestParseQIFDayFirst(t *testing.T) {
+	in := "!Type:Cash\nD05/10/2026\nT-10.00\nPLunch\n^\n"
+
+	stmts, err := uc.ParseQIF(strings.NewReader(in), true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC), stmts[0].Entries[0].Date)
+	assert.Empty(t, stmts[0].AccountRef)
+
+	_, err = uc.ParseQIF(strings.NewReader("!Type:Cash\nD31/10/2026\nT-10.00\n^\n"), false)
+	assert.Error(t, err)
+}
@@ -1,75 +1,500 @@
 package recurring_transaction
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
 	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
 	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	accountUC "github.com/afandimsr/cashbook-backend/internal/usecase/account"
+	categoryUC "github.com/afandimsr/cashbook-backend/internal/usecase/category"
+)
+
+const (
+	// maxOccurrences caps the upcoming occurrences listed at once.
+	maxOccurrences = 100
+	// maxRuns caps the runs listed at once.
+	maxRuns = 100
 )
 
 type Usecase interface {
 	GetRecurring(userID int64) ([]recurring_transaction.RecurringTransaction, error)
 	CreateRecurring(userID int64, rt recurring_transaction.RecurringTransaction) (recurring_transaction.RecurringTransaction, error)
-	DeleteRecurring(id int64) error
-	ProcessDueTransactions() error
+	// UpdateRecurring replaces the template. The change applies to the
+	// occurrences not generated yet.
+	UpdateRecurring(userID, id int64, rt recurring_transaction.RecurringTransaction) (recurring_transaction.RecurringTransaction, error)
+	DeleteRecurring(userID, id int64) error
+	// PauseRecurring stops generating the transactions of the template
+	// until it is resumed.
+	PauseRecurring(userID, id int64) (recurring_transaction.RecurringTransaction, error)
+	// ResumeRecurring generates the transactions of the template again from
+	// now on. The occurrences that passed while it was paused are skipped.
+	ResumeRecurring(userID, id int64, now time.Time) (recurring_transaction.RecurringTransaction, error)
+	// GetOccurrences returns the next occurrences not generated yet, at most
+	// limit of them, with their exceptions applied.
+	GetOccurrences(userID, id int64, limit int) ([]recurring_transaction.Occurrence, error)
+	// GetUpcoming returns the occurrences of the user's active templates
+	// that are not generated yet, up to and including until, oldest first,
+	// with their exceptions applied.
+	GetUpcoming(userID int64, until time.Time) ([]recurring_transaction.Upcoming, error)
+	// SetException skips an occurrence not generated yet or changes its
+	// amount.
+	SetException(userID, id int64, e recurring_transaction.Exception) (recurring_transaction.Exception, error)
+	// DeleteException generates the occurrence of day as the template says
+	// again.
+	DeleteException(userID, id int64, day time.Time) error
+	// GetEvents returns the audit log of the template, newest first.
+	GetEvents(userID, id int64) ([]recurring_transaction.Event, error)
+	// ProcessDue generates a transaction for every occurrence of the
+	// user's templates due by now, including the ones missed while nothing
+	// ran, each dated when it was scheduled. Occurrences that already have
+	// a transaction are skipped, so it is safe to run concurrently and to
+	// retry. It reports what it did with each occurrence; a run that fails
+	// on some is saved so it can be inspected.
+	ProcessDue(userID int64, now time.Time) (recurring_transaction.Run, error)
+	// ProcessAllDue is ProcessDue for the templates of every user.
+	ProcessAllDue(now time.Time) (recurring_transaction.Run, error)
+	// GetRuns returns the last runs that failed on some occurrence, at
+	// most limit of them, newest first.
+	GetRuns(limit int) ([]recurring_transaction.Run, error)
 }
 
 type usecase struct {
-	repo   recurring_transaction.Repository
-	txRepo transaction.Repository
+	repo         recurring_transaction.Repository
+	txRepo       transaction.Repository
+	accountRepo  account.Repository
+	categoryRepo category.Repository
 }
 
-func New(repo recurring_transaction.Repository, txRepo transaction.Repository) Usecase {
-	return &usecase{repo: repo, txRepo: txRepo}
+func New(repo recurring_transaction.Repository, txRepo transaction.Repository, accountRepo account.Repository, categoryRepo category.Repository) Usecase {
+	return &usecase{repo: repo, txRepo: txRepo, accountRepo: accountRepo, categoryRepo: categoryRepo}
 }
 
 func (u *usecase) GetRecurring(userID int64) ([]recurring_transaction.RecurringTransaction, error) {
 	return u.repo.FindAllByUserID(userID)
 }
 
+func (u *usecase) find(userID, id int64) (recurring_transaction.RecurringTransaction, error) {
+	rt, err := u.repo.FindByIDForUser(userID, id)
+	if errors.Is(err, recurring_transaction.ErrNotFound) {
+		return rt, apperror.NotFound("recurring transaction not found", err)
+	}
+	return rt, err
+}
+
 func (u *usecase) CreateRecurring(userID int64, rt recurring_transaction.RecurringTransaction) (recurring_transaction.RecurringTransaction, error) {
 	rt.UserID = userID
-	if rt.StartDate.IsZero() {
-		rt.StartDate = time.Now()
+	rt.LastProcessed = time.Time{}
+	rt.Paused = false
+	if err := u.prepare(&rt); err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
 	}
 	if err := u.repo.Save(&rt); err != nil {
 		return recurring_transaction.RecurringTransaction{}, err
 	}
+	u.record(recurring_transaction.Event{RecurringID: rt.ID, Action: recurring_transaction.EventCreated, Detail: rt.Rule})
 	return rt, nil
 }
 
-func (u *usecase) DeleteRecurring(id int64) error {
-	return u.repo.Delete(id)
+func (u *usecase) UpdateRecurring(userID, id int64, rt recurring_transaction.RecurringTransaction) (recurring_transaction.RecurringTransaction, error) {
+	old, err := u.find(userID, id)
+	if err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	rt.ID, rt.UserID = old.ID, userID
+	rt.LastProcessed, rt.Paused = old.LastProcessed, old.Paused
+	if rt.StartDate.IsZero() {
+		rt.StartDate = old.StartDate
+	}
+	if err := u.prepare(&rt); err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	if err := u.repo.Update(&rt); err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	u.record(recurring_transaction.Event{RecurringID: id, Action: recurring_transaction.EventUpdated, Detail: changes(old, rt)})
+	return rt, nil
 }
 
-func (u *usecase) ProcessDueTransactions() error {
-	now := time.Now()
-	due, err := u.repo.FindDue(now)
+// prepare validates rt and fills in its defaults and schedule.
+func (u *usecase) prepare(rt *recurring_transaction.RecurringTransaction) error {
+	if transaction.IsTransfer(rt.Type) {
+		return apperror.BadRequest("recurring transfers are not supported", nil)
+	}
+	if rt.StartDate.IsZero() {
+		rt.StartDate = time.Now()
+	}
+	if err := schedule(rt); err != nil {
+		return err
+	}
+	if err := categoryUC.Check(u.categoryRepo, rt.UserID, rt.CategoryID); err != nil {
+		return err
+	}
+	acc, err := accountUC.Resolve(u.accountRepo, rt.UserID, rt.AccountID)
 	if err != nil {
 		return err
 	}
+	rt.AccountID = acc.ID
+	return nil
+}
 
-	for _, rt := range due {
-		// Create a real transaction
-		tx := &transaction.Transaction{
-			UserID:     rt.UserID,
-			CategoryID: rt.CategoryID,
-			Amount:     rt.Amount,
-			Note:       rt.Note + " (Auto-generated)",
-			Date:       now,
-			Type:       rt.Type,
+// schedule normalizes the rule of rt, which defaults to its frequency, and
+// sets its next occurrence.
+func schedule(rt *recurring_transaction.RecurringTransaction) error {
+	rule, err := rt.Recurrence()
+	if err != nil {
+		return apperror.BadRequest("invalid recurrence rule: "+err.Error(), err)
+	}
+	rt.Frequency = rule.Freq
+	rt.Rule = rule.String()
+	after := rt.LastProcessed
+	if after.IsZero() {
+		after = rt.StartDate.Add(-time.Nanosecond)
+	}
+	rt.NextOccurrence = rule.After(rt.StartDate, after)
+	if rt.NextOccurrence.IsZero() && rt.LastProcessed.IsZero() {
+		return apperror.BadRequest("the recurrence rule has no occurrence", nil)
+	}
+	return nil
+}
+
+// changes describes what an update changed, for the audit log.
+func changes(old, rt recurring_transaction.RecurringTransaction) string {
+	var c []string
+	field := func(name string, from, to interface{}) {
+		if from != to {
+			c = append(c, fmt.Sprintf("%s from %v to %v", name, from, to))
 		}
+	}
+	field("amount", old.Amount.String(), rt.Amount.String())
+	field("type", old.Type, rt.Type)
+	field("account", old.AccountID, rt.AccountID)
+	field("category", old.CategoryID, rt.CategoryID)
+	field("note", fmt.Sprintf("%q", old.Note), fmt.Sprintf("%q", rt.Note))
+	field("rule", old.Rule, rt.Rule)
+	field("start date", old.StartDate.UTC().Format(time.RFC3339), rt.StartDate.UTC().Format(time.RFC3339))
+	if len(c) == 0 {
+		return "no changes"
+	}
+	return strings.Join(c, "; ")
+}
+
+func (u *usecase) DeleteRecurring(userID, id int64) error {
+	if _, err := u.find(userID, id); err != nil {
+		return err
+	}
+	return u.repo.Delete(userID, id)
+}
+
+func (u *usecase) PauseRecurring(userID, id int64) (recurring_transaction.RecurringTransaction, error) {
+	rt, err := u.find(userID, id)
+	if err != nil || rt.Paused {
+		return rt, err
+	}
+	rt.Paused = true
+	if err := u.repo.Update(&rt); err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	u.record(recurring_transaction.Event{RecurringID: id, Action: recurring_transaction.EventPaused})
+	return rt, nil
+}
+
+func (u *usecase) ResumeRecurring(userID, id int64, now time.Time) (recurring_transaction.RecurringTransaction, error) {
+	rt, err := u.find(userID, id)
+	if err != nil || !rt.Paused {
+		return rt, err
+	}
+	missed, next, err := rt.Schedule(now)
+	if err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	event := recurring_transaction.Event{RecurringID: id, Action: recurring_transaction.EventResumed}
+	if n := len(missed); n > 0 {
+		rt.LastProcessed = missed[n-1]
+		event.Detail = fmt.Sprintf("skipped %d occurrences missed while paused, from %s to %s", n, missed[0].Format(dayLayout), missed[n-1].Format(dayLayout))
+	}
+	rt.NextOccurrence = next
+	rt.Paused = false
+	if err := u.repo.Update(&rt); err != nil {
+		return recurring_transaction.RecurringTransaction{}, err
+	}
+	u.record(event)
+	return rt, nil
+}
+
+// dayLayout formats occurrence days in the audit log.
+const dayLayout = "2006-01-02"
+
+func (u *usecase) GetOccurrences(userID, id int64, limit int) ([]recurring_transaction.Occurrence, error) {
+	rt, err := u.find(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	limit = min(max(limit, 1), maxOccurrences)
+	occurrences := []recurring_transaction.Occurrence{}
+	err = u.pending(rt, func(o recurring_transaction.Occurrence) bool {
+		occurrences = append(occurrences, o)
+		return len(occurrences) < limit
+	})
+	return occurrences, err
+}
 
-		if err := u.txRepo.Save(tx); err != nil {
-			// In production, log error and continue
+func (u *usecase) GetUpcoming(userID int64, until time.Time) ([]recurring_transaction.Upcoming, error) {
+	rts, err := u.repo.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := []recurring_transaction.Upcoming{}
+	for _, rt := range rts {
+		if rt.Paused {
 			continue
 		}
+		err := u.pending(rt, func(o recurring_transaction.Occurrence) bool {
+			if o.Date.After(until) {
+				return false
+			}
+			upcoming = append(upcoming, recurring_transaction.Upcoming{
+				Occurrence:  o,
+				RecurringID: rt.ID,
+				AccountID:   rt.AccountID,
+				CategoryID:  rt.CategoryID,
+				Type:        rt.Type,
+				Note:        rt.Note,
+			})
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("recurring transaction %d: %w", rt.ID, err)
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].Date.Before(upcoming[j].Date) })
+	return upcoming, nil
+}
 
-		// Update last processed date
-		if err := u.repo.UpdateLastProcessed(rt.ID, now); err != nil {
+// pending calls yield with the occurrences of rt not generated yet, oldest
+// first and with their exceptions applied, until it returns false.
+func (u *usecase) pending(rt recurring_transaction.RecurringTransaction, yield func(recurring_transaction.Occurrence) bool) error {
+	rule, err := rt.Recurrence()
+	if err != nil {
+		return err
+	}
+	from := rt.StartDate
+	if !rt.LastProcessed.IsZero() {
+		from = rt.LastProcessed
+	}
+	exceptions, err := u.repo.FindExceptions(rt.ID, from)
+	if err != nil {
+		return err
+	}
+	byDay := exceptionsByDay(exceptions)
+
+	for on := range rule.Occurrences(rt.StartDate) {
+		if !rt.LastProcessed.IsZero() && !on.After(rt.LastProcessed) {
 			continue
 		}
+		o := recurring_transaction.Occurrence{Date: on, Amount: rt.Amount}
+		if e, ok := byDay[recurring_transaction.Day(on)]; ok {
+			o.Skip = e.Skip
+			if e.Amount != nil {
+				o.Amount = *e.Amount
+			}
+		}
+		if !yield(o) {
+			break
+		}
+	}
+	return nil
+}
+
+func exceptionsByDay(exceptions []recurring_transaction.Exception) map[time.Time]recurring_transaction.Exception {
+	byDay := make(map[time.Time]recurring_transaction.Exception, len(exceptions))
+	for _, e := range exceptions {
+		byDay[recurring_transaction.Day(e.Date)] = e
+	}
+	return byDay
+}
+
+func (u *usecase) SetException(userID, id int64, e recurring_transaction.Exception) (recurring_transaction.Exception, error) {
+	rt, err := u.find(userID, id)
+	if err != nil {
+		return recurring_transaction.Exception{}, err
+	}
+	day := recurring_transaction.Day(e.Date)
+	on, ok, err := rt.OccurrenceOn(day)
+	if err != nil {
+		return recurring_transaction.Exception{}, err
+	}
+	if !ok {
+		return recurring_transaction.Exception{}, apperror.BadRequest("the template has no occurrence on "+day.Format(dayLayout), nil)
+	}
+	if !rt.LastProcessed.IsZero() && !on.After(rt.LastProcessed) {
+		return recurring_transaction.Exception{}, apperror.BadRequest("the occurrence on "+day.Format(dayLayout)+" was already processed", nil)
 	}
 
+	event := recurring_transaction.Event{RecurringID: id, Action: recurring_transaction.EventException, Occurrence: &on, Detail: "skip"}
+	if e.Skip {
+		e.Amount = nil
+	} else if e.Amount == nil || *e.Amount <= 0 {
+		return recurring_transaction.Exception{}, apperror.BadRequest("either skip the occurrence or give a positive amount", nil)
+	} else {
+		event.Detail = "amount " + e.Amount.String()
+	}
+	e.RecurringID, e.Date = id, day
+	if err := u.repo.SaveException(&e); err != nil {
+		return recurring_transaction.Exception{}, err
+	}
+	u.record(event)
+	return e, nil
+}
+
+func (u *usecase) DeleteException(userID, id int64, day time.Time) error {
+	if _, err := u.find(userID, id); err != nil {
+		return err
+	}
+	day = recurring_transaction.Day(day)
+	if err := u.repo.DeleteException(id, day); err != nil {
+		return err
+	}
+	u.record(recurring_transaction.Event{RecurringID: id, Action: recurring_transaction.EventRestored, Occurrence: &day})
 	return nil
 }
+
+func (u *usecase) GetEvents(userID, id int64) ([]recurring_transaction.Event, error) {
+	if _, err := u.find(userID, id); err != nil {
+		return nil, err
+	}
+	return u.repo.FindEvents(id)
+}
+
+// record adds e to the audit log. What it records has already happened, so
+// a failure is only logged.
+func (u *usecase) record(e recurring_transaction.Event) {
+	if err := u.repo.SaveEvent(&e); err != nil {
+		log.Printf("recurring transaction %d: %s not added to the audit log: %v", e.RecurringID, e.Action, err)
+	}
+}
+
+func (u *usecase) ProcessDue(userID int64, now time.Time) (recurring_transaction.Run, error) {
+	run := newRun(&userID)
+	due, err := u.repo.FindDueByUserID(userID, now)
+	if err != nil {
+		return run, err
+	}
+	return u.process(run, due, now), nil
+}
+
+func (u *usecase) ProcessAllDue(now time.Time) (recurring_transaction.Run, error) {
+	run := newRun(nil)
+	due, err := u.repo.FindDue(now)
+	if err != nil {
+		return run, err
+	}
+	return u.process(run, due, now), nil
+}
+
+func (u *usecase) GetRuns(limit int) ([]recurring_transaction.Run, error) {
+	if limit <= 0 || limit > maxRuns {
+		limit = maxRuns
+	}
+	return u.repo.FindRuns(limit)
+}
+
+func newRun(userID *int64) recurring_transaction.Run {
+	return recurring_transaction.Run{UserID: userID, StartedAt: time.Now(), Items: []recurring_transaction.RunItem{}}
+}
+
+// process generates the due occurrences of the templates due and saves the
+// run when some failed.
+func (u *usecase) process(run recurring_transaction.Run, due []recurring_transaction.RecurringTransaction, now time.Time) recurring_transaction.Run {
+	for _, rt := range due {
+		u.generate(&run, rt, now)
+	}
+	run.FinishedAt = time.Now()
+	if run.Failed > 0 {
+		if err := u.repo.SaveRun(&run); err != nil {
+			log.Printf("recurring run: %d failures not saved: %v", run.Failed, err)
+		}
+	}
+	return run
+}
+
+// generate creates the transactions of the occurrences of rt due by now,
+// oldest first, applying their exceptions, and adds them to run. Each
+// transaction is saved in the same database transaction as the schedule
+// update recording its occurrence as processed. When an occurrence fails,
+// the template stops there and it is retried by the next run.
+func (u *usecase) generate(run *recurring_transaction.Run, rt recurring_transaction.RecurringTransaction, now time.Time) {
+	fail := func(on *time.Time, err error) {
+		run.Add(recurring_transaction.RunItem{RecurringID: rt.ID, UserID: rt.UserID, Occurrence: on, Status: recurring_transaction.RunFailed, Detail: err.Error()})
+	}
+
+	due, next, err := rt.Schedule(now)
+	if err != nil {
+		fail(nil, err)
+		return
+	}
+	if len(due) == 0 {
+		if err := u.repo.UpdateSchedule(rt.ID, rt.LastProcessed, next); err != nil {
+			fail(nil, err)
+		}
+		return
+	}
+	exceptions, err := u.repo.FindExceptions(rt.ID, due[0])
+	if err != nil {
+		fail(nil, err)
+		return
+	}
+	byDay := exceptionsByDay(exceptions)
+
+	for i, on := range due {
+		item := recurring_transaction.RunItem{RecurringID: rt.ID, UserID: rt.UserID, Occurrence: &on, Status: recurring_transaction.EventSkipped, Detail: "skipped as requested"}
+		following := next
+		if i+1 < len(due) {
+			following = due[i+1]
+		}
+
+		err := u.repo.InTx(func(repo recurring_transaction.Repository, txRepo transaction.Repository) error {
+			e, ok := byDay[recurring_transaction.Day(on)]
+			if !ok || !e.Skip {
+				tx := &transaction.Transaction{
+					UserID:      rt.UserID,
+					AccountID:   rt.AccountID,
+					CategoryID:  rt.CategoryID,
+					Amount:      rt.Amount,
+					Note:        rt.Note + " (Auto-generated)",
+					Date:        on,
+					Type:        rt.Type,
+					RecurringID: rt.ID,
+				}
+				if ok && e.Amount != nil {
+					tx.Amount = *e.Amount
+				}
+				saved, err := txRepo.SaveOccurrence(tx)
+				if err != nil {
+					return err
+				}
+				if saved {
+					item.Status, item.TransactionID, item.Detail = recurring_transaction.EventGenerated, tx.ID, fmt.Sprintf("created transaction %d of %s", tx.ID, tx.Amount)
+				} else {
+					item.Status, item.Detail = recurring_transaction.EventExisted, "a transaction already exists for this occurrence"
+				}
+			}
+			return repo.UpdateSchedule(rt.ID, on, following)
+		})
+		if err != nil {
+			fail(&on, err)
+			return
+		}
+		run.Add(item)
+		u.record(recurring_transaction.Event{RecurringID: rt.ID, Action: item.Status, Occurrence: &on, Detail: item.Detail})
+	}
+}
@@ -0,0 +1,478 @@
+package recurring_transaction_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/recurring_transaction"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/recurring_transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRecurringRepository struct {
+	recurring_transaction.Repository
+	mock.Mock
+	events []recurring_transaction.Event
+	// txRepo is the transaction repository handed out by InTx.
+	txRepo transaction.Repository
+}
+
+func (m *MockRecurringRepository) InTx(fn func(repo recurring_transaction.Repository, txRepo transaction.Repository) error) error {
+	return fn(m, m.txRepo)
+}
+
+func (m *MockRecurringRepository) FindByIDForUser(userID, id int64) (recurring_transaction.RecurringTransaction, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(recurring_transaction.RecurringTransaction), args.Error(1)
+}
+
+func (m *MockRecurringRepository) FindDue(now time.Time) ([]recurring_transaction.RecurringTransaction, error) {
+	args := m.Called(now)
+	return args.Get(0).([]recurring_transaction.RecurringTransaction), args.Error(1)
+}
+
+func (m *MockRecurringRepository) FindDueByUserID(userID int64, now time.Time) ([]recurring_transaction.RecurringTransaction, error) {
+	args := m.Called(userID, now)
+	return args.Get(0).([]recurring_transaction.RecurringTransaction), args.Error(1)
+}
+
+func (m *MockRecurringRepository) SaveRun(r *recurring_transaction.Run) error {
+	args := m.Called(r)
+	return args.Error(0)
+}
+
+func (m *MockRecurringRepository) Save(rt *recurring_transaction.RecurringTransaction) error {
+	args := m.Called(rt)
+	return args.Error(0)
+}
+
+func (m *MockRecurringRepository) Update(rt *recurring_transaction.RecurringTransaction) error {
+	args := m.Called(rt)
+	return args.Error(0)
+}
+
+func (m *MockRecurringRepository) UpdateSchedule(id int64, lastProcessed, nextOccurrence time.Time) error {
+	args := m.Called(id, lastProcessed, nextOccurrence)
+	return args.Error(0)
+}
+
+func (m *MockRecurringRepository) FindExceptions(id int64, from time.Time) ([]recurring_transaction.Exception, error) {
+	args := m.Called(id, from)
+	return args.Get(0).([]recurring_transaction.Exception), args.Error(1)
+}
+
+func (m *MockRecurringRepository) SaveException(e *recurring_transaction.Exception) error {
+	args := m.Called(e)
+	return args.Error(0)
+}
+
+// SaveEvent keeps the audit log for the tests to check.
+func (m *MockRecurringRepository) SaveEvent(e *recurring_transaction.Event) error {
+	m.events = append(m.events, *e)
+	return nil
+}
+
+// actions returns the actions of the audit log, oldest first.
+func (m *MockRecurringRepository) actions() []string {
+	var actions []string
+	for _, e := range m.events {
+		actions = append(actions, e.Action)
+	}
+	return actions
+}
+
+// MockTransactionRepository only implements saving generated occurrences.
+type MockTransactionRepository struct {
+	transaction.Repository
+	mock.Mock
+}
+
+func (m *MockTransactionRepository) SaveOccurrence(t *transaction.Transaction) (bool, error) {
+	args := m.Called(t)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockCategoryRepository struct {
+	category.Repository
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) FindByIDForUser(userID, id int64) (category.Category, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(category.Category), args.Error(1)
+}
+
+type MockAccountRepository struct {
+	account.Repository
+	mock.Mock
+}
+
+func (m *MockAccountRepository) FindByID(id int64) (account.Account, error) {
+	args := m.Called(id)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func at(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func TestSchedule(t *testing.T) {
+	rt := recurring_transaction.RecurringTransaction{Frequency: recurring_transaction.Monthly, StartDate: at(2026, 1, 15)}
+
+	// down for three months
+	due, next, err := rt.Schedule(at(2026, 4, 15))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{at(2026, 1, 15), at(2026, 2, 15), at(2026, 3, 15), at(2026, 4, 15)}, due)
+	assert.Equal(t, at(2026, 5, 15), next)
+
+	rt.LastProcessed = at(2026, 2, 15)
+	due, next, _ = rt.Schedule(at(2026, 4, 14))
+	assert.Equal(t, []time.Time{at(2026, 3, 15)}, due)
+	assert.Equal(t, at(2026, 4, 15), next)
+
+	// before the start nothing is due
+	rt.LastProcessed = time.Time{}
+	due, next, _ = rt.Schedule(at(2026, 1, 1))
+	assert.Empty(t, due)
+	assert.Equal(t, at(2026, 1, 15), next)
+}
+
+func TestScheduleEnds(t *testing.T) {
+	rt := recurring_transaction.RecurringTransaction{Rule: "FREQ=WEEKLY;COUNT=2", StartDate: at(2026, 1, 1)}
+
+	due, next, err := rt.Schedule(at(2026, 3, 1))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{at(2026, 1, 1), at(2026, 1, 8)}, due)
+	assert.True(t, next.IsZero())
+}
+
+func TestCreateRecurringSchedulesFirstOccurrence(t *testing.T) {
+	repo := new(MockRecurringRepository)
+	categoryRepo := new(MockCategoryRepository)
+	accountRepo := new(MockAccountRepository)
+	usecase := uc.New(repo, nil, accountRepo, categoryRepo)
+	categoryRepo.On("FindByIDForUser", int64(7), int64(3)).Return(category.Category{ID: 3, UserID: 7}, nil).Once()
+	accountRepo.On("FindByID", int64(2)).Return(account.Account{ID: 2, UserID: 7}, nil).Once()
+	repo.On("Save", mock.Anything).Return(nil).Once()
+
+	// the last business day of January 2026 is Friday the 30th
+	rt, err := usecase.CreateRecurring(7, recurring_transaction.RecurringTransaction{
+		AccountID:  2,
+		CategoryID: 3,
+		Type:       transaction.TypeIncome,
+		Rule:       "rrule:freq=monthly;byday=mo,tu,we,th,fr;bysetpos=-1",
+		StartDate:  at(2026, 1, 1),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, recurring_transaction.Monthly, rt.Frequency)
+	assert.Equal(t, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", rt.Rule)
+	assert.Equal(t, at(2026, 1, 30), rt.NextOccurrence)
+	repo.AssertExpectations(t)
+}
+
+func TestCreateRecurringRejectsInvalidRule(t *testing.T) {
+	usecase := uc.New(nil, nil, nil, nil)
+
+	for _, rule := range []string{"FREQ=HOURLY", "FREQ=MONTHLY;BYMONTHDAY=32", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30"} {
+		_, err := usecase.CreateRecurring(7, recurring_transaction.RecurringTransaction{Type: transaction.TypeExpense, Rule: rule, StartDate: at(2026, 1, 1)})
+
+		var appErr *apperror.AppError
+		if assert.ErrorAs(t, err, &appErr, rule) {
+			assert.Equal(t, 400, appErr.Code, rule)
+		}
+	}
+}
+
+// statuses returns the statuses of the items of run, in order.
+func statuses(run recurring_transaction.Run) []string {
+	var statuses []string
+	for _, item := range run.Items {
+		statuses = append(statuses, item.Status)
+	}
+	return statuses
+}
+
+func TestProcessDueCatchesUp(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	repo := &MockRecurringRepository{txRepo: txRepo}
+	usecase := uc.New(repo, txRepo, nil, nil)
+
+	now := at(2026, 4, 20)
+	rent := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, AccountID: 2, CategoryID: 3, Amount: money.FromMajor(500), Type: transaction.TypeExpense, Note: "rent", Frequency: recurring_transaction.Monthly, StartDate: at(2026, 1, 1), LastProcessed: at(2026, 1, 1)}
+	repo.On("FindDueByUserID", int64(7), now).Return([]recurring_transaction.RecurringTransaction{rent}, nil).Once()
+	repo.On("FindExceptions", int64(1), at(2026, 2, 1)).Return([]recurring_transaction.Exception(nil), nil).Once()
+
+	var dates []time.Time
+	txRepo.On("SaveOccurrence", mock.MatchedBy(func(tx *transaction.Transaction) bool {
+		return tx.RecurringID == 1 && tx.UserID == 7 && tx.AccountID == 2 && tx.Amount == money.FromMajor(500) && !tx.Date.Equal(at(2026, 3, 1))
+	})).Run(func(args mock.Arguments) {
+		tx := args.Get(0).(*transaction.Transaction)
+		tx.ID = int64(len(dates) + 10)
+		dates = append(dates, tx.Date)
+	}).Return(true, nil).Times(2)
+	// March was generated by a run that stopped before recording it
+	txRepo.On("SaveOccurrence", mock.MatchedBy(func(tx *transaction.Transaction) bool { return tx.Date.Equal(at(2026, 3, 1)) })).Return(false, nil).Once()
+	repo.On("UpdateSchedule", int64(1), at(2026, 2, 1), at(2026, 3, 1)).Return(nil).Once()
+	repo.On("UpdateSchedule", int64(1), at(2026, 3, 1), at(2026, 4, 1)).Return(nil).Once()
+	repo.On("UpdateSchedule", int64(1), at(2026, 4, 1), at(2026, 5, 1)).Return(nil).Once()
+
+	run, err := usecase.ProcessDue(7, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), *run.UserID)
+	assert.Equal(t, 2, run.Generated)
+	assert.Equal(t, 1, run.Skipped)
+	assert.Zero(t, run.Failed)
+	assert.Equal(t, []string{recurring_transaction.EventGenerated, recurring_transaction.EventExisted, recurring_transaction.EventGenerated}, statuses(run))
+	assert.Equal(t, int64(11), run.Items[2].TransactionID)
+	assert.Equal(t, at(2026, 3, 1), *run.Items[1].Occurrence)
+	assert.Equal(t, []time.Time{at(2026, 2, 1), at(2026, 4, 1)}, dates, "dated when scheduled")
+	assert.Equal(t, statuses(run), repo.actions())
+	repo.AssertNotCalled(t, "SaveRun", mock.Anything)
+	repo.AssertExpectations(t)
+	txRepo.AssertExpectations(t)
+}
+
+func TestProcessAllDueReportsAndSavesFailures(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	repo := &MockRecurringRepository{txRepo: txRepo}
+	usecase := uc.New(repo, txRepo, nil, nil)
+
+	now := at(2026, 1, 3)
+	broken := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, Frequency: recurring_transaction.Daily, StartDate: at(2026, 1, 1)}
+	fine := recurring_transaction.RecurringTransaction{ID: 2, UserID: 8, Frequency: recurring_transaction.Weekly, StartDate: at(2026, 1, 2)}
+	invalid := recurring_transaction.RecurringTransaction{ID: 3, UserID: 9, Rule: "FREQ=HOURLY", StartDate: at(2026, 1, 1)}
+	repo.On("FindDue", now).Return([]recurring_transaction.RecurringTransaction{broken, fine, invalid}, nil).Once()
+	repo.On("FindExceptions", mock.Anything, mock.Anything).Return([]recurring_transaction.Exception(nil), nil)
+	txRepo.On("SaveOccurrence", mock.MatchedBy(func(tx *transaction.Transaction) bool { return tx.RecurringID == 1 })).Return(false, errors.New("account closed")).Once()
+	txRepo.On("SaveOccurrence", mock.MatchedBy(func(tx *transaction.Transaction) bool { return tx.RecurringID == 2 })).Return(true, nil).Once()
+	repo.On("UpdateSchedule", int64(2), at(2026, 1, 2), at(2026, 1, 9)).Return(nil).Once()
+	var saved recurring_transaction.Run
+	repo.On("SaveRun", mock.Anything).Run(func(args mock.Arguments) {
+		saved = *args.Get(0).(*recurring_transaction.Run)
+	}).Return(nil).Once()
+
+	run, err := usecase.ProcessAllDue(now)
+
+	// the broken template is retried from its first occurrence next time
+	assert.NoError(t, err)
+	assert.Nil(t, run.UserID)
+	assert.Equal(t, 1, run.Generated)
+	assert.Equal(t, 2, run.Failed)
+	assert.Equal(t, []string{recurring_transaction.RunFailed, recurring_transaction.EventGenerated, recurring_transaction.RunFailed}, statuses(run))
+	assert.Equal(t, "account closed", run.Items[0].Detail)
+	assert.Equal(t, at(2026, 1, 1), *run.Items[0].Occurrence)
+	assert.Nil(t, run.Items[2].Occurrence, "the template failed before its occurrences")
+	assert.Equal(t, run, saved)
+	repo.AssertNotCalled(t, "UpdateSchedule", int64(1), mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+	txRepo.AssertExpectations(t)
+}
+
+func TestProcessDueFailsOccurrenceWhenScheduleUpdateFails(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	repo := &MockRecurringRepository{txRepo: txRepo}
+	usecase := uc.New(repo, txRepo, nil, nil)
+
+	now := at(2026, 1, 2)
+	rt := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, Frequency: recurring_transaction.Daily, StartDate: at(2026, 1, 1)}
+	repo.On("FindDueByUserID", int64(7), now).Return([]recurring_transaction.RecurringTransaction{rt}, nil).Once()
+	repo.On("FindExceptions", int64(1), at(2026, 1, 1)).Return([]recurring_transaction.Exception(nil), nil).Once()
+	txRepo.On("SaveOccurrence", mock.Anything).Return(true, nil).Once()
+	repo.On("UpdateSchedule", int64(1), at(2026, 1, 1), at(2026, 1, 2)).Return(errors.New("connection reset")).Once()
+	repo.On("SaveRun", mock.Anything).Return(nil).Once()
+
+	run, err := usecase.ProcessDue(7, now)
+
+	// the transaction was rolled back along with the schedule update
+	assert.NoError(t, err)
+	assert.Zero(t, run.Generated)
+	assert.Equal(t, []string{recurring_transaction.RunFailed}, statuses(run))
+	assert.Empty(t, repo.actions(), "nothing happened to record")
+	repo.AssertExpectations(t)
+}
+
+func TestProcessDueAdvancesPastProcessedOccurrences(t *testing.T) {
+	repo := new(MockRecurringRepository)
+	usecase := uc.New(repo, nil, nil, nil)
+
+	// next_occurrence is stale, e.g. right after migrating
+	now := at(2026, 2, 10)
+	rt := recurring_transaction.RecurringTransaction{ID: 1, Frequency: recurring_transaction.Monthly, StartDate: at(2026, 1, 1), LastProcessed: at(2026, 2, 1), NextOccurrence: at(2026, 2, 1)}
+	repo.On("FindDueByUserID", int64(7), now).Return([]recurring_transaction.RecurringTransaction{rt}, nil).Once()
+	repo.On("UpdateSchedule", int64(1), at(2026, 2, 1), at(2026, 3, 1)).Return(nil).Once()
+
+	run, err := usecase.ProcessDue(7, now)
+
+	assert.NoError(t, err)
+	assert.Empty(t, run.Items)
+	repo.AssertExpectations(t)
+}
+
+func TestProcessDueAppliesExceptions(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	repo := &MockRecurringRepository{txRepo: txRepo}
+	usecase := uc.New(repo, txRepo, nil, nil)
+
+	now := at(2026, 3, 10)
+	bill := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, Amount: money.FromMajor(50), Type: transaction.TypeExpense, Frequency: recurring_transaction.Monthly, StartDate: at(2026, 1, 1)}
+	higher := money.FromMajor(80)
+	repo.On("FindDueByUserID", int64(7), now).Return([]recurring_transaction.RecurringTransaction{bill}, nil).Once()
+	repo.On("FindExceptions", int64(1), at(2026, 1, 1)).Return([]recurring_transaction.Exception{
+		{RecurringID: 1, Date: recurring_transaction.Day(at(2026, 2, 1)), Skip: true},
+		{RecurringID: 1, Date: recurring_transaction.Day(at(2026, 3, 1)), Amount: &higher},
+	}, nil).Once()
+	var amounts []money.Amount
+	txRepo.On("SaveOccurrence", mock.Anything).Run(func(args mock.Arguments) {
+		amounts = append(amounts, args.Get(0).(*transaction.Transaction).Amount)
+	}).Return(true, nil).Twice()
+	repo.On("UpdateSchedule", int64(1), mock.Anything, mock.Anything).Return(nil).Times(3)
+
+	run, err := usecase.ProcessDue(7, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, run.Generated)
+	assert.Equal(t, 1, run.Skipped)
+	assert.Equal(t, []money.Amount{money.FromMajor(50), higher}, amounts)
+	assert.Equal(t, []string{recurring_transaction.EventGenerated, recurring_transaction.EventSkipped, recurring_transaction.EventGenerated}, repo.actions())
+	assert.Equal(t, at(2026, 2, 1), *repo.events[1].Occurrence)
+	repo.AssertExpectations(t)
+}
+
+func TestUpdateRecurringKeepsProgress(t *testing.T) {
+	repo := new(MockRecurringRepository)
+	categoryRepo := new(MockCategoryRepository)
+	accountRepo := new(MockAccountRepository)
+	usecase := uc.New(repo, nil, accountRepo, categoryRepo)
+
+	old := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, AccountID: 2, CategoryID: 3, Amount: money.FromMajor(50), Type: transaction.TypeExpense, Frequency: recurring_transaction.Monthly, Rule: "FREQ=MONTHLY", StartDate: at(2026, 1, 1), LastProcessed: at(2026, 3, 1), Paused: true}
+	repo.On("FindByIDForUser", int64(7), int64(1)).Return(old, nil).Once()
+	categoryRepo.On("FindByIDForUser", int64(7), int64(3)).Return(category.Category{ID: 3, UserID: 7}, nil).Once()
+	accountRepo.On("FindByID", int64(2)).Return(account.Account{ID: 2, UserID: 7}, nil).Once()
+	repo.On("Update", mock.Anything).Return(nil).Once()
+
+	rt, err := usecase.UpdateRecurring(7, 1, recurring_transaction.RecurringTransaction{
+		UserID: 9, AccountID: 2, CategoryID: 3, Amount: money.FromMajor(60), Type: transaction.TypeExpense, Rule: "FREQ=MONTHLY;BYMONTHDAY=15",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), rt.UserID)
+	assert.Equal(t, at(2026, 1, 1), rt.StartDate, "kept when not given")
+	assert.Equal(t, at(2026, 3, 1), rt.LastProcessed)
+	assert.Equal(t, at(2026, 3, 15), rt.NextOccurrence)
+	assert.True(t, rt.Paused)
+	assert.Equal(t, []string{recurring_transaction.EventUpdated}, repo.actions())
+	assert.Equal(t, "amount from 50.00 to 60.00; rule from FREQ=MONTHLY to FREQ=MONTHLY;BYMONTHDAY=15", repo.events[0].Detail)
+	repo.AssertExpectations(t)
+}
+
+func TestUpdateRecurringNotFound(t *testing.T) {
+	repo := new(MockRecurringRepository)
+	usecase := uc.New(repo, nil, nil, nil)
+	repo.On("FindByIDForUser", int64(7), int64(1)).Return(recurring_transaction.RecurringTransaction{}, recurring_transaction.ErrNotFound).Once()
+
+	_, err := usecase.UpdateRecurring(7, 1, recurring_transaction.RecurringTransaction{Frequency: recurring_transaction.Daily})
+
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, 404, appErr.Code)
+	}
+	repo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestResumeRecurringSkipsMissedOccurrences(t *testing.T) {
+	repo := new(MockRecurringRepository)
+	usecase := uc.New(repo, nil, nil, nil)
+
+	paused := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, Frequency: recurring_transaction.Weekly, StartDate: at(2026, 1, 1), LastProcessed: at(2026, 1, 8), Paused: true}
+	repo.On("FindByIDForUser", int64(7), int64(1)).Return(paused, nil).Once()
+	repo.On("Update", mock.Anything).Return(nil).Once()
+
+	rt, err := usecase.ResumeRecurring(7, 1, at(2026, 1, 30))
+
+	assert.NoError(t, err)
+	assert.False(t, rt.Paused)
+	assert.Equal(t, at(2026, 1, 29), rt.LastProcessed)
+	assert.Equal(t, at(2026, 2, 5), rt.NextOccurrence)
+	assert.Equal(t, "skipped 3 occurrences missed while paused, from 2026-01-15 to 2026-01-29", repo.events[0].Detail)
+	repo.AssertExpectations(t)
+}
+
+func TestPauseRecurringIsIdempotent(t *testing.T) {
+	repo := new(MockRecurringRepository)
+	usecase := uc.New(repo, nil, nil, nil)
+	repo.On("FindByIDForUser", int64(7), int64(1)).Return(recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, Paused: true}, nil).Once()
+
+	rt, err := usecase.PauseRecurring(7, 1)
+
+	assert.NoError(t, err)
+	assert.True(t, rt.Paused)
+	assert.Empty(t, repo.events)
+	repo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestSetException(t *testing.T) {
+	rt := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, Amount: money.FromMajor(50), Rule: "FREQ=MONTHLY;BYMONTHDAY=25", StartDate: at(2026, 1, 1), LastProcessed: at(2026, 2, 25)}
+	higher := money.FromMajor(80)
+	zero := money.Amount(0)
+	invalid := map[string]recurring_transaction.Exception{
+		"not an occurrence":   {Date: recurring_transaction.Day(at(2026, 3, 24)), Skip: true},
+		"already processed":   {Date: recurring_transaction.Day(at(2026, 2, 25)), Skip: true},
+		"neither skip":        {Date: recurring_transaction.Day(at(2026, 3, 25))},
+		"nor positive amount": {Date: recurring_transaction.Day(at(2026, 3, 25)), Amount: &zero},
+	}
+	for name, e := range invalid {
+		repo := new(MockRecurringRepository)
+		repo.On("FindByIDForUser", int64(7), int64(1)).Return(rt, nil).Once()
+
+		_, err := uc.New(repo, nil, nil, nil).SetException(7, 1, e)
+
+		var appErr *apperror.AppError
+		if assert.ErrorAs(t, err, &appErr, name) {
+			assert.Equal(t, 400, appErr.Code, name)
+		}
+	}
+
+	repo := new(MockRecurringRepository)
+	repo.On("FindByIDForUser", int64(7), int64(1)).Return(rt, nil).Once()
+	repo.On("SaveException", &recurring_transaction.Exception{RecurringID: 1, Date: recurring_transaction.Day(at(2026, 3, 25)), Amount: &higher}).Return(nil).Once()
+
+	e, err := uc.New(repo, nil, nil, nil).SetException(7, 1, recurring_transaction.Exception{Date: at(2026, 3, 25), Amount: &higher})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), e.RecurringID)
+	assert.Equal(t, "amount 80.00", repo.events[0].Detail)
+	assert.Equal(t, at(2026, 3, 25), *repo.events[0].Occurrence)
+	repo.AssertExpectations(t)
+}
+
+func TestGetOccurrences(t *testing.T) {
+	repo := new(MockRecurringRepository)
+	usecase := uc.New(repo, nil, nil, nil)
+
+	rt := recurring_transaction.RecurringTransaction{ID: 1, UserID: 7, Amount: money.FromMajor(50), Rule: "FREQ=MONTHLY;BYMONTHDAY=25", StartDate: at(2026, 1, 1), LastProcessed: at(2026, 1, 25), NextOccurrence: at(2026, 2, 25)}
+	higher := money.FromMajor(80)
+	repo.On("FindByIDForUser", int64(7), int64(1)).Return(rt, nil).Once()
+	repo.On("FindExceptions", int64(1), at(2026, 1, 25)).Return([]recurring_transaction.Exception{
+		{RecurringID: 1, Date: recurring_transaction.Day(at(2026, 2, 25)), Skip: true},
+		{RecurringID: 1, Date: recurring_transaction.Day(at(2026, 3, 25)), Amount: &higher},
+	}, nil).Once()
+
+	occurrences, err := usecase.GetOccurrences(7, 1, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []recurring_transaction.Occurrence{
+		{Date: at(2026, 2, 25), Amount: money.FromMajor(50), Skip: true},
+		{Date: at(2026, 3, 25), Amount: higher},
+		{Date: at(2026, 4, 25), Amount: money.FromMajor(50)},
+	}, occurrences)
+}
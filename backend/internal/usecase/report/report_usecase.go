@@ -1,55 +1,399 @@
 package report
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
 	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	fxUC "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
 )
 
+// CategoryReport is the spending of one category in the user's base
+// currency. Conversions lists the rates applied to expenses recorded in
+// other currencies.
 type CategoryReport struct {
-	CategoryID   int64   `json:"category_id"`
-	CategoryName string  `json:"category_name"`
-	TotalAmount  float64 `json:"total_amount"`
-	Color        string  `json:"color"`
+	CategoryID   int64        `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	TotalAmount  money.Amount `json:"total_amount"`
+	Currency     string       `json:"currency"`
+	Color        string       `json:"color"`
+	Conversions  []fx.Quote   `json:"conversions,omitempty"`
+}
+
+// TagReport is the spending of one tag in the user's base currency. A
+// transaction with several tags counts towards each of them, so tag totals
+// can add up to more than the month's spending.
+type TagReport struct {
+	TagID       int64        `json:"tag_id"`
+	TagName     string       `json:"tag_name"`
+	TotalAmount money.Amount `json:"total_amount"`
+	Currency    string       `json:"currency"`
+	Conversions []fx.Quote   `json:"conversions,omitempty"`
+}
+
+// TrendPoint is the income, expense and net of the period starting on
+// Period, in the user's base currency.
+type TrendPoint struct {
+	Period  string       `json:"period"` // YYYY-MM-DD
+	Income  money.Amount `json:"income"`
+	Expense money.Amount `json:"expense"`
+	Net     money.Amount `json:"net"`
+}
+
+// TrendSeries is the trend of one category, account or transaction type.
+type TrendSeries struct {
+	GroupID   int64        `json:"group_id,omitempty"`
+	GroupName string       `json:"group_name"`
+	Points    []TrendPoint `json:"points"`
 }
 
+// TrendReport has a point for every period between From and To, including
+// the periods without transactions. Series is only filled when GroupBy is
+// set. Each period is converted with the rate of its last day, or today's
+// for the running period.
+type TrendReport struct {
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Interval    string        `json:"interval"`
+	GroupBy     string        `json:"group_by,omitempty"`
+	Currency    string        `json:"currency"`
+	Totals      []TrendPoint  `json:"totals"`
+	Series      []TrendSeries `json:"series,omitempty"`
+	Conversions []fx.Quote    `json:"conversions,omitempty"`
+}
+
+// MaxTrendPeriods caps the length of a trend, e.g. a little under three
+// years of days.
+const MaxTrendPeriods = 1000
+
 type Usecase interface {
 	GetCategorySpending(userID int64, month, year int) ([]CategoryReport, error)
+	GetTagSpending(userID int64, month, year int) ([]TagReport, error)
+	// GetTrend reports income and expense over time. A zero query.To means
+	// today and a zero query.From the start of the period eleven intervals
+	// earlier; the interval defaults to month.
+	GetTrend(userID int64, query transaction.TrendQuery) (TrendReport, error)
 }
 
 type usecase struct {
-	txRepo transaction.Repository
+	txRepo  transaction.Repository
+	tagRepo tag.Repository
+	fx      fxUC.Usecase
 }
 
-func New(txRepo transaction.Repository) Usecase {
-	return &usecase{txRepo: txRepo}
+func New(txRepo transaction.Repository, tagRepo tag.Repository, fx fxUC.Usecase) Usecase {
+	return &usecase{txRepo: txRepo, tagRepo: tagRepo, fx: fx}
 }
 
 func (u *usecase) GetCategorySpending(userID int64, month, year int) ([]CategoryReport, error) {
-	// For simplicity, aggregate in memory. Optimized with DB queries in production.
-	txs, err := u.txRepo.GetCategorySpending(userID, 1000, 0, transaction.Filter{})
+	from, to := monthRange(month, year)
+	rows, err := u.txRepo.SumByCategory(userID, transaction.Filter{Type: transaction.TypeExpense, StartDate: from, EndDate: to})
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := u.newConverter(userID, month, year)
 	if err != nil {
 		return nil, err
 	}
 
-	spending := make(map[int64]*CategoryReport)
+	// rows come per category and currency, largest first
+	index := make(map[int64]int)
+	res := []CategoryReport{}
+	for _, row := range rows {
+		i, ok := index[row.CategoryID]
+		if !ok {
+			i = len(res)
+			index[row.CategoryID] = i
+			res = append(res, CategoryReport{CategoryID: row.CategoryID, CategoryName: row.CategoryName, Color: row.Color, Currency: conv.base})
+		}
+		report := &res[i]
 
-	for _, tx := range txs {
-		if tx.Type == "expense" && int(tx.Date.Month()) == month && tx.Date.Year() == year {
-			if _, ok := spending[tx.CategoryID]; !ok {
-				spending[tx.CategoryID] = &CategoryReport{
-					CategoryID:   tx.CategoryID,
-					CategoryName: tx.CategoryName,
-					Color:        tx.Color,
-					TotalAmount:  0,
-				}
-			}
-			spending[tx.CategoryID].TotalAmount += tx.Amount
+		amount, quote, err := conv.convert(row.Amount, row.Currency)
+		if err != nil {
+			return nil, err
 		}
+		report.TotalAmount += amount
+		if quote != nil {
+			report.Conversions = append(report.Conversions, *quote)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].TotalAmount > res[j].TotalAmount })
+	return res, nil
+}
+
+func (u *usecase) GetTagSpending(userID int64, month, year int) ([]TagReport, error) {
+	from, to := monthRange(month, year)
+	rows, err := u.tagRepo.GetSpending(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := u.newConverter(userID, month, year)
+	if err != nil {
+		return nil, err
 	}
 
-	var res []CategoryReport
-	for _, v := range spending {
-		res = append(res, *v)
+	// rows come per tag and currency, largest first
+	index := make(map[int64]int)
+	res := []TagReport{}
+	for _, row := range rows {
+		i, ok := index[row.TagID]
+		if !ok {
+			i = len(res)
+			index[row.TagID] = i
+			res = append(res, TagReport{TagID: row.TagID, TagName: row.TagName, Currency: conv.base})
+		}
+		report := &res[i]
+
+		amount, quote, err := conv.convert(row.Amount, row.Currency)
+		if err != nil {
+			return nil, err
+		}
+		report.TotalAmount += amount
+		if quote != nil {
+			report.Conversions = append(report.Conversions, *quote)
+		}
 	}
 
+	sort.SliceStable(res, func(i, j int) bool { return res[i].TotalAmount > res[j].TotalAmount })
 	return res, nil
 }
+
+func (u *usecase) GetTrend(userID int64, query transaction.TrendQuery) (TrendReport, error) {
+	query, err := normalizeTrendQuery(query, time.Now())
+	if err != nil {
+		return TrendReport{}, err
+	}
+
+	rows, err := u.txRepo.Trend(userID, query)
+	if err != nil {
+		return TrendReport{}, err
+	}
+
+	base, err := u.fx.BaseCurrency(userID)
+	if err != nil {
+		return TrendReport{}, err
+	}
+
+	report := TrendReport{
+		From:     query.From.Format(dateLayout),
+		To:       query.To.Format(dateLayout),
+		Interval: query.Interval,
+		GroupBy:  query.GroupBy,
+		Currency: base,
+		Totals:   []TrendPoint{},
+	}
+	converters := make(map[time.Time]*converter)
+	series := make(map[trendGroup]int)
+	for _, row := range rows {
+		conv, ok := converters[row.Period]
+		if !ok {
+			conv = u.converterAt(base, nextPeriod(row.Period, query.Interval).AddDate(0, 0, -1))
+			converters[row.Period] = conv
+		}
+		income, quote, err := conv.convert(row.Income, row.Currency)
+		if err != nil {
+			return TrendReport{}, err
+		}
+		expense, _, err := conv.convert(row.Expense, row.Currency)
+		if err != nil {
+			return TrendReport{}, err
+		}
+		if quote != nil && !hasQuote(report.Conversions, *quote) {
+			report.Conversions = append(report.Conversions, *quote)
+		}
+
+		period := row.Period.Format(dateLayout)
+		report.Totals = addToTrend(report.Totals, period, income, expense)
+		if query.GroupBy == "" || row.Currency == "" {
+			// no grouping, or a period of a range without transactions
+			continue
+		}
+		key := trendGroup{row.GroupID, row.GroupName}
+		i, ok := series[key]
+		if !ok {
+			i = len(report.Series)
+			series[key] = i
+			report.Series = append(report.Series, TrendSeries{GroupID: row.GroupID, GroupName: row.GroupName})
+		}
+		report.Series[i].Points = addToTrend(report.Series[i].Points, period, income, expense)
+	}
+	return report, nil
+}
+
+type trendGroup struct {
+	id   int64
+	name string
+}
+
+// addToTrend adds to the last point of points when it is for period and
+// starts a new point otherwise. Rows come ordered by period, one per
+// currency, so a period's amounts arrive together.
+func addToTrend(points []TrendPoint, period string, income, expense money.Amount) []TrendPoint {
+	if n := len(points); n == 0 || points[n-1].Period != period {
+		points = append(points, TrendPoint{Period: period})
+	}
+	p := &points[len(points)-1]
+	p.Income += income
+	p.Expense += expense
+	p.Net = p.Income - p.Expense
+	return points
+}
+
+const dateLayout = "2006-01-02"
+
+var trendIntervals = map[string]bool{
+	transaction.IntervalDay:     true,
+	transaction.IntervalWeek:    true,
+	transaction.IntervalMonth:   true,
+	transaction.IntervalQuarter: true,
+	transaction.IntervalYear:    true,
+}
+
+var trendGroupings = map[string]bool{
+	"":                          true,
+	transaction.GroupByCategory: true,
+	transaction.GroupByType:     true,
+	transaction.GroupByAccount:  true,
+}
+
+// normalizeTrendQuery fills in the defaults, truncates the bounds to days
+// and rejects invalid or too long ranges.
+func normalizeTrendQuery(query transaction.TrendQuery, now time.Time) (transaction.TrendQuery, error) {
+	if query.Interval == "" {
+		query.Interval = transaction.IntervalMonth
+	}
+	if !trendIntervals[query.Interval] {
+		return query, apperror.BadRequest("interval must be one of day, week, month, quarter or year", nil)
+	}
+	if !trendGroupings[query.GroupBy] {
+		return query, apperror.BadRequest("group_by must be one of category, type or account", nil)
+	}
+
+	if query.To.IsZero() {
+		query.To = now
+	}
+	query.To = day(query.To)
+	if query.From.IsZero() {
+		query.From = periodStart(query.To, query.Interval)
+		for i := 0; i < 11; i++ {
+			query.From = periodStart(query.From.AddDate(0, 0, -1), query.Interval)
+		}
+	}
+	query.From = day(query.From)
+	if query.From.After(query.To) {
+		return query, apperror.BadRequest("from must not be after to", nil)
+	}
+
+	periods := 0
+	for p := periodStart(query.From, query.Interval); !p.After(query.To); p = nextPeriod(p, query.Interval) {
+		if periods++; periods > MaxTrendPeriods {
+			return query, apperror.BadRequest(fmt.Sprintf("the range has more than %d periods, use a longer interval", MaxTrendPeriods), nil)
+		}
+	}
+	return query, nil
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// periodStart truncates t like PostgreSQL's date_trunc.
+func periodStart(t time.Time, interval string) time.Time {
+	t = day(t)
+	switch interval {
+	case transaction.IntervalWeek:
+		return t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+	case transaction.IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case transaction.IntervalQuarter:
+		return time.Date(t.Year(), (t.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case transaction.IntervalYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+// nextPeriod returns the start of the period after the one starting at
+// start.
+func nextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case transaction.IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case transaction.IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	case transaction.IntervalQuarter:
+		return start.AddDate(0, 3, 0)
+	case transaction.IntervalYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// monthRange returns the first and the last instant of a month.
+func monthRange(month, year int) (time.Time, time.Time) {
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, 0).Add(-time.Nanosecond)
+}
+
+// converter turns amounts into the user's base currency, fetching each
+// rate once.
+type converter struct {
+	fx     fxUC.Usecase
+	base   string
+	on     time.Time
+	quotes map[string]fx.Quote
+}
+
+// newConverter uses the last rate of the month, or today's for the running
+// month.
+func (u *usecase) newConverter(userID int64, month, year int) (*converter, error) {
+	base, err := u.fx.BaseCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+	return u.converterAt(base, time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)), nil
+}
+
+// converterAt uses the rates of rateDay, or today's when it is still to
+// come.
+func (u *usecase) converterAt(base string, rateDay time.Time) *converter {
+	if now := time.Now(); rateDay.After(now) {
+		rateDay = now
+	}
+	return &converter{fx: u.fx, base: base, on: rateDay, quotes: make(map[string]fx.Quote)}
+}
+
+// convert returns amount in the base currency and the quote used, which is
+// nil when amount already is in the base currency.
+func (c *converter) convert(amount money.Amount, currency string) (money.Amount, *fx.Quote, error) {
+	if currency == "" || currency == c.base {
+		return amount, nil, nil
+	}
+	quote, ok := c.quotes[currency]
+	if !ok {
+		var err error
+		quote, err = c.fx.Quote(currency, c.base, c.on)
+		if err != nil {
+			return 0, nil, err
+		}
+		c.quotes[currency] = quote
+	}
+	return quote.Apply(amount), &quote, nil
+}
+
+func hasQuote(quotes []fx.Quote, q fx.Quote) bool {
+	for _, existing := range quotes {
+		if existing.From == q.From && existing.RateDate == q.RateDate {
+			return true
+		}
+	}
+	return false
+}
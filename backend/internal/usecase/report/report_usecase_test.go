@@ -0,0 +1,187 @@
+package report_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTransactionRepository only implements the aggregates the report
+// usecase needs.
+type MockTransactionRepository struct {
+	transaction.Repository
+	mock.Mock
+}
+
+func (m *MockTransactionRepository) SumByCategory(userID int64, filter transaction.Filter) ([]transaction.CategoryTotal, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]transaction.CategoryTotal), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Trend(userID int64, query transaction.TrendQuery) ([]transaction.TrendRow, error) {
+	args := m.Called(userID, query)
+	return args.Get(0).([]transaction.TrendRow), args.Error(1)
+}
+
+type MockFxUsecase struct {
+	mock.Mock
+}
+
+func (m *MockFxUsecase) ImportRates(rates []fx.Rate) (int, error) {
+	args := m.Called(rates)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFxUsecase) BaseCurrency(userID int64) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFxUsecase) Quote(from, to string, on time.Time) (fx.Quote, error) {
+	args := m.Called(from, to, on)
+	return args.Get(0).(fx.Quote), args.Error(1)
+}
+
+func TestGetCategorySpending(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(txRepo, nil, mockFx)
+
+	// the month is pushed down to the query
+	filter := transaction.Filter{
+		Type:      transaction.TypeExpense,
+		StartDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond),
+	}
+	txRepo.On("SumByCategory", int64(1), filter).Return([]transaction.CategoryTotal{
+		{CategoryID: 4, CategoryName: "food", Color: "#f00", Currency: "IDR", Amount: money.FromMajor(300)},
+		{CategoryID: 5, CategoryName: "rent", Color: "#0f0", Currency: "IDR", Amount: money.FromMajor(200)},
+		{CategoryID: 5, CategoryName: "rent", Color: "#0f0", Currency: "USD", Amount: money.FromMajor(20)},
+	}, nil).Once()
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil).Once()
+	mockFx.On("Quote", "USD", "IDR", time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)).Return(fx.Quote{From: "USD", To: "IDR", Rate: "10"}, nil).Once()
+
+	report, err := usecase.GetCategorySpending(1, 3, 2026)
+
+	assert.NoError(t, err)
+	if assert.Len(t, report, 2) {
+		// rent overtakes food once the dollars are converted
+		assert.Equal(t, int64(5), report[0].CategoryID)
+		assert.Equal(t, money.FromMajor(400), report[0].TotalAmount)
+		assert.Equal(t, "IDR", report[0].Currency)
+		assert.Len(t, report[0].Conversions, 1)
+		assert.Equal(t, "food", report[1].CategoryName)
+		assert.Equal(t, money.FromMajor(300), report[1].TotalAmount)
+	}
+	txRepo.AssertExpectations(t)
+	mockFx.AssertExpectations(t)
+}
+
+func TestGetTrend(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(txRepo, nil, mockFx)
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	query := transaction.TrendQuery{From: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 2, 20, 0, 0, 0, 0, time.UTC), Interval: transaction.IntervalMonth, GroupBy: transaction.GroupByAccount}
+	txRepo.On("Trend", int64(1), query).Return([]transaction.TrendRow{
+		{Period: jan, GroupID: 2, GroupName: "bank", Currency: "IDR", Income: money.FromMajor(500), Expense: money.FromMajor(100)},
+		{Period: jan, GroupID: 3, GroupName: "wallet", Currency: "USD", Expense: money.FromMajor(5)},
+		{Period: feb, GroupID: 2, GroupName: "bank", Currency: "IDR"},
+		{Period: feb, GroupID: 3, GroupName: "wallet", Currency: "USD", Income: money.FromMajor(1)},
+	}, nil).Once()
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil).Once()
+	// each period is converted at the rate of its last day
+	mockFx.On("Quote", "USD", "IDR", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)).Return(fx.Quote{From: "USD", To: "IDR", Rate: "10", RateDate: "2025-01-31"}, nil).Once()
+	mockFx.On("Quote", "USD", "IDR", time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)).Return(fx.Quote{From: "USD", To: "IDR", Rate: "12", RateDate: "2025-02-28"}, nil).Once()
+
+	report, err := usecase.GetTrend(1, query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-10", report.From)
+	assert.Equal(t, "IDR", report.Currency)
+	assert.Equal(t, []uc.TrendPoint{
+		{Period: "2025-01-01", Income: money.FromMajor(500), Expense: money.FromMajor(150), Net: money.FromMajor(350)},
+		{Period: "2025-02-01", Income: money.FromMajor(12), Net: money.FromMajor(12)},
+	}, report.Totals)
+	if assert.Len(t, report.Series, 2) {
+		assert.Equal(t, "bank", report.Series[0].GroupName)
+		assert.Len(t, report.Series[0].Points, 2, "gaps are kept")
+		assert.Equal(t, int64(3), report.Series[1].GroupID)
+		assert.Equal(t, money.FromMajor(-50), report.Series[1].Points[0].Net)
+	}
+	assert.Len(t, report.Conversions, 2)
+	mockFx.AssertExpectations(t)
+}
+
+func TestGetTrendEmptyRange(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(txRepo, nil, mockFx)
+
+	query := transaction.TrendQuery{From: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC), Interval: transaction.IntervalWeek, GroupBy: transaction.GroupByCategory}
+	txRepo.On("Trend", int64(1), query).Return([]transaction.TrendRow{
+		{Period: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)},
+		{Period: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{Period: time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)},
+	}, nil).Once()
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil).Once()
+
+	report, err := usecase.GetTrend(1, query)
+
+	assert.NoError(t, err)
+	assert.Len(t, report.Totals, 3)
+	assert.Equal(t, "2024-12-30", report.Totals[0].Period)
+	assert.Empty(t, report.Series)
+}
+
+func TestGetTrendDefaults(t *testing.T) {
+	txRepo := new(MockTransactionRepository)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(txRepo, nil, mockFx)
+
+	today := time.Now().UTC()
+	var got transaction.TrendQuery
+	txRepo.On("Trend", int64(1), mock.Anything).Run(func(args mock.Arguments) {
+		got = args.Get(1).(transaction.TrendQuery)
+	}).Return([]transaction.TrendRow{}, nil).Once()
+	mockFx.On("BaseCurrency", int64(1)).Return("IDR", nil).Once()
+
+	_, err := usecase.GetTrend(1, transaction.TrendQuery{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, transaction.IntervalMonth, got.Interval)
+	assert.Equal(t, today.Format("2006-01-02"), got.To.Format("2006-01-02"))
+	assert.Equal(t, time.Date(today.Year(), today.Month()-11, 1, 0, 0, 0, 0, time.UTC), got.From)
+}
+
+func TestGetTrendRejectsInvalidQueries(t *testing.T) {
+	usecase := uc.New(new(MockTransactionRepository), nil, new(MockFxUsecase))
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]transaction.TrendQuery{
+		"interval":   {Interval: "hour"},
+		"group_by":   {GroupBy: "tag"},
+		"reversed":   {From: jan.AddDate(0, 1, 0), To: jan},
+		"too long":   {From: jan, To: jan.AddDate(3, 0, 0), Interval: transaction.IntervalDay},
+		"far future": {From: jan, To: jan.AddDate(100, 0, 0), Interval: transaction.IntervalWeek},
+	}
+	for name, query := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := usecase.GetTrend(1, query)
+
+			var appErr *apperror.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, http.StatusBadRequest, appErr.Code)
+		})
+	}
+}
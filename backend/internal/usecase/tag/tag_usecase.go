@@ -0,0 +1,117 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
+)
+
+type Usecase interface {
+	GetAllByUserID(userID int64) ([]tag.Tag, error)
+	// Rename changes a tag's name. Renaming onto the name of another tag is
+	// rejected; merge the two instead.
+	Rename(userID, id int64, name string) (tag.Tag, error)
+	// Merge moves the transactions of the source tags to the target tag and
+	// deletes the sources.
+	Merge(userID int64, sourceIDs []int64, targetID int64) (tag.Tag, error)
+	Delete(userID, id int64) error
+}
+
+type usecase struct {
+	repo tag.Repository
+}
+
+func New(repo tag.Repository) Usecase {
+	return &usecase{repo: repo}
+}
+
+func (u *usecase) GetAllByUserID(userID int64) ([]tag.Tag, error) {
+	return u.repo.FindAllByUserID(userID)
+}
+
+// find returns the user's tag, or 404 when it does not exist or belongs to
+// someone else.
+func (u *usecase) find(userID, id int64) (tag.Tag, error) {
+	t, err := u.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, tag.ErrTagNotFound) {
+			return tag.Tag{}, apperror.NotFound("tag not found", err)
+		}
+		return tag.Tag{}, err
+	}
+	if t.UserID != userID {
+		return tag.Tag{}, apperror.NotFound("tag not found", tag.ErrTagNotFound)
+	}
+	return t, nil
+}
+
+func (u *usecase) Rename(userID, id int64, name string) (tag.Tag, error) {
+	t, err := u.find(userID, id)
+	if err != nil {
+		return tag.Tag{}, err
+	}
+
+	name = tag.NormalizeName(name)
+	if name == "" {
+		return tag.Tag{}, apperror.BadRequest("name is required", nil)
+	}
+	if len(name) > tag.MaxNameLength {
+		return tag.Tag{}, apperror.BadRequest(fmt.Sprintf("name is longer than %d characters", tag.MaxNameLength), nil)
+	}
+	if name == t.Name {
+		return t, nil
+	}
+
+	other, err := u.repo.FindByName(userID, name)
+	if err == nil {
+		msg := fmt.Sprintf("tag %q already exists; merge tag %d into %d instead", name, t.ID, other.ID)
+		return tag.Tag{}, apperror.New(http.StatusConflict, msg, nil).WithCode(apperror.ConflictError)
+	}
+	if !errors.Is(err, tag.ErrTagNotFound) {
+		return tag.Tag{}, err
+	}
+
+	if err := u.repo.Rename(t.ID, name); err != nil {
+		return tag.Tag{}, err
+	}
+	t.Name = name
+	return t, nil
+}
+
+func (u *usecase) Merge(userID int64, sourceIDs []int64, targetID int64) (tag.Tag, error) {
+	target, err := u.find(userID, targetID)
+	if err != nil {
+		return tag.Tag{}, err
+	}
+
+	var sources []int64
+	seen := map[int64]bool{targetID: true}
+	for _, id := range sourceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := u.find(userID, id); err != nil {
+			return tag.Tag{}, err
+		}
+		sources = append(sources, id)
+	}
+	if len(sources) == 0 {
+		return tag.Tag{}, apperror.BadRequest("source_ids must name at least one tag other than the target", nil)
+	}
+
+	if err := u.repo.Merge(sources, targetID); err != nil {
+		return tag.Tag{}, apperror.Internal(err)
+	}
+	return u.repo.FindByID(target.ID)
+}
+
+func (u *usecase) Delete(userID, id int64) error {
+	if _, err := u.find(userID, id); err != nil {
+		return err
+	}
+	return u.repo.Delete(id)
+}
@@ -0,0 +1,132 @@
+package tag_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/tag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagRepository struct {
+	mock.Mock
+}
+
+func (m *MockTagRepository) FindAllByUserID(userID int64) ([]tag.Tag, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]tag.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) FindByID(id int64) (tag.Tag, error) {
+	args := m.Called(id)
+	return args.Get(0).(tag.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) FindByName(userID int64, name string) (tag.Tag, error) {
+	args := m.Called(userID, name)
+	return args.Get(0).(tag.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) Rename(id int64, name string) error {
+	args := m.Called(id, name)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Merge(sourceIDs []int64, targetID int64) error {
+	args := m.Called(sourceIDs, targetID)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) GetSpending(userID int64, from, to time.Time) ([]tag.Spending, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]tag.Spending), args.Error(1)
+}
+
+func newRepo() *MockTagRepository {
+	repo := new(MockTagRepository)
+	repo.On("FindByID", int64(1)).Return(tag.Tag{ID: 1, UserID: 7, Name: "bali"}, nil).Maybe()
+	repo.On("FindByID", int64(2)).Return(tag.Tag{ID: 2, UserID: 7, Name: "trip-bali-2026"}, nil).Maybe()
+	repo.On("FindByID", int64(3)).Return(tag.Tag{ID: 3, UserID: 8, Name: "other"}, nil).Maybe()
+	return repo
+}
+
+func TestRename(t *testing.T) {
+	repo := newRepo()
+	usecase := uc.New(repo)
+	repo.On("FindByName", int64(7), "holiday bali").Return(tag.Tag{}, tag.ErrTagNotFound).Once()
+	repo.On("Rename", int64(1), "holiday bali").Return(nil).Once()
+
+	renamed, err := usecase.Rename(7, 1, "  Holiday   Bali ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "holiday bali", renamed.Name)
+	repo.AssertExpectations(t)
+}
+
+func TestRenameOntoExistingTagConflicts(t *testing.T) {
+	repo := newRepo()
+	usecase := uc.New(repo)
+	repo.On("FindByName", int64(7), "trip-bali-2026").Return(tag.Tag{ID: 2, UserID: 7}, nil).Once()
+
+	_, err := usecase.Rename(7, 1, "Trip-Bali-2026")
+
+	var appErr *apperror.AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.Code)
+	repo.AssertNotCalled(t, "Rename", mock.Anything, mock.Anything)
+}
+
+func TestRenameForeignTag(t *testing.T) {
+	usecase := uc.New(newRepo())
+
+	_, err := usecase.Rename(7, 3, "mine")
+
+	var appErr *apperror.AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusNotFound, appErr.Code)
+}
+
+func TestMerge(t *testing.T) {
+	repo := newRepo()
+	usecase := uc.New(repo)
+	repo.On("Merge", []int64{1}, int64(2)).Return(nil).Once()
+
+	// the target and repeated ids are ignored
+	merged, err := usecase.Merge(7, []int64{1, 2, 1}, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), merged.ID)
+	repo.AssertExpectations(t)
+}
+
+func TestMergeRejectsForeignOrEmptySources(t *testing.T) {
+	repo := newRepo()
+	usecase := uc.New(repo)
+
+	_, err := usecase.Merge(7, []int64{3}, 2)
+	assert.Error(t, err)
+
+	_, err = usecase.Merge(7, []int64{2}, 2)
+	assert.Error(t, err)
+
+	repo.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything)
+}
+
+func TestDeleteForeignTag(t *testing.T) {
+	repo := newRepo()
+	usecase := uc.New(repo)
+
+	err := usecase.Delete(7, 3)
+
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "Delete", mock.Anything)
+}
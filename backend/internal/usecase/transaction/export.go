@@ -0,0 +1,187 @@
+package transaction
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/xlsx"
+)
+
+// Export formats accepted by NewExporter.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatJSON = "json"
+)
+
+// exportContentTypes maps each export format to its MIME type.
+var exportContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatJSON: "application/json; charset=utf-8",
+}
+
+// ExportContentType returns the MIME type of format, or a bad request error
+// for unknown formats.
+func ExportContentType(format string) (string, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return "", apperror.BadRequest("format must be csv, xlsx or json", nil)
+	}
+	return contentType, nil
+}
+
+// exportDateLayout is used for the date column of CSV and XLSX exports.
+const exportDateLayout = "2006-01-02"
+
+var exportHeader = []string{"id", "date", "type", "amount", "currency", "account_id", "account", "category_id", "category", "color", "note"}
+
+// Exporter writes transactions in one file format. Close must be called
+// after the last row to complete the file.
+type Exporter interface {
+	Write(t transaction.ReportTransaction) error
+	Close() error
+}
+
+// NewExporter returns the exporter for format writing to w.
+func NewExporter(format string, w io.Writer) (Exporter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVExporter(w)
+	case FormatXLSX:
+		return newXLSXExporter(w)
+	case FormatJSON:
+		return &jsonExporter{w: bufio.NewWriter(w)}, nil
+	default:
+		return nil, apperror.BadRequest("format must be csv, xlsx or json", nil)
+	}
+}
+
+func exportRecord(t transaction.ReportTransaction) []string {
+	return []string{
+		strconv.FormatInt(t.ID, 10),
+		t.Date.Format(exportDateLayout),
+		t.Type,
+		t.Amount.String(),
+		t.Currency,
+		strconv.FormatInt(t.AccountID, 10),
+		t.AccountName,
+		strconv.FormatInt(t.CategoryID, 10),
+		t.CategoryName,
+		t.Color,
+		t.Note,
+	}
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) (*csvExporter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return &csvExporter{w: cw}, nil
+}
+
+func (e *csvExporter) Write(t transaction.ReportTransaction) error {
+	t.AccountName = csvText(t.AccountName)
+	t.CategoryName = csvText(t.CategoryName)
+	t.Note = csvText(t.Note)
+	return e.w.Write(exportRecord(t))
+}
+
+// csvText keeps text typed in by users from being run as a formula when the
+// file is opened in a spreadsheet, by prefixing it with an apostrophe.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type xlsxExporter struct {
+	w *xlsx.Writer
+}
+
+func newXLSXExporter(w io.Writer) (*xlsxExporter, error) {
+	xw, err := xlsx.NewWriter(w, "Transactions")
+	if err != nil {
+		return nil, err
+	}
+	header := make([]interface{}, len(exportHeader))
+	for i, h := range exportHeader {
+		header[i] = h
+	}
+	if err := xw.WriteRow(header...); err != nil {
+		return nil, err
+	}
+	return &xlsxExporter{w: xw}, nil
+}
+
+func (e *xlsxExporter) Write(t transaction.ReportTransaction) error {
+	return e.w.WriteRow(
+		xlsx.Number(strconv.FormatInt(t.ID, 10)),
+		t.Date.Format(exportDateLayout),
+		t.Type,
+		xlsx.Number(t.Amount.String()),
+		t.Currency,
+		xlsx.Number(strconv.FormatInt(t.AccountID, 10)),
+		t.AccountName,
+		xlsx.Number(strconv.FormatInt(t.CategoryID, 10)),
+		t.CategoryName,
+		t.Color,
+		t.Note,
+	)
+}
+
+func (e *xlsxExporter) Close() error {
+	return e.w.Close()
+}
+
+// jsonExporter writes a JSON array one element at a time.
+type jsonExporter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (e *jsonExporter) Write(t transaction.ReportTransaction) error {
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	e.count++
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if _, err := e.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := e.w.WriteString(end); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
@@ -0,0 +1,106 @@
+package transaction_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportRows() []transaction.ReportTransaction {
+	return []transaction.ReportTransaction{
+		{ID: 2, AccountID: 1, AccountName: "Wallet", CategoryID: 3, CategoryName: "Food", Color: "#ff0000", Amount: money.FromMinor(12550), Currency: "IDR", Note: `Lunch, "warung"`, Date: time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), Type: "expense"},
+		{ID: 1, AccountID: 1, AccountName: "Wallet", Amount: money.FromMajor(50), Currency: "IDR", Date: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Type: "transfer_in"},
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+	filter := transaction.Filter{Type: "expense"}
+	mockRepo.On("StreamReport", int64(1), filter).Return(exportRows(), nil)
+
+	var buf bytes.Buffer
+	err := usecase.Export(1, filter, uc.FormatCSV, &buf)
+
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "id,date,type,amount,currency,account_id,account,category_id,category,color,note", lines[0])
+	assert.Equal(t, `2,2026-10-02,expense,125.50,IDR,1,Wallet,3,Food,#ff0000,"Lunch, ""warung"""`, lines[1])
+	assert.Equal(t, "1,2026-10-01,transfer_in,50.00,IDR,1,Wallet,0,,,", lines[2])
+}
+
+func TestExportCSVEscapesFormulas(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+	rows := []transaction.ReportTransaction{
+		{ID: 3, AccountID: 1, AccountName: "@Wallet", CategoryID: 3, CategoryName: "+Food", Amount: money.FromMajor(5), Currency: "IDR", Note: `=HYPERLINK("http://evil.example","x")`, Date: time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), Type: "expense"},
+		{ID: 4, AccountID: 1, AccountName: "Wallet", CategoryID: 3, CategoryName: "Food", Amount: money.FromMajor(5), Currency: "IDR", Note: "-5 refund", Date: time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC), Type: "expense"},
+	}
+	mockRepo.On("StreamReport", int64(1), transaction.Filter{}).Return(rows, nil)
+
+	var buf bytes.Buffer
+	require.NoError(t, usecase.Export(1, transaction.Filter{}, uc.FormatCSV, &buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, `3,2026-10-03,expense,5.00,IDR,1,'@Wallet,3,'+Food,,"'=HYPERLINK(""http://evil.example"",""x"")"`, lines[1])
+	assert.Equal(t, "4,2026-10-04,expense,5.00,IDR,1,Wallet,3,Food,,'-5 refund", lines[2])
+}
+
+func TestExportJSON(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+	mockRepo.On("StreamReport", int64(1), transaction.Filter{}).Return(exportRows(), nil)
+
+	var buf bytes.Buffer
+	err := usecase.Export(1, transaction.Filter{}, uc.FormatJSON, &buf)
+
+	assert.NoError(t, err)
+	var out []transaction.ReportTransaction
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, exportRows(), out)
+}
+
+func TestExportEmpty(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+	mockRepo.On("StreamReport", int64(1), transaction.Filter{}).Return(nil, nil)
+
+	var buf bytes.Buffer
+	assert.NoError(t, usecase.Export(1, transaction.Filter{}, uc.FormatJSON, &buf))
+	assert.Equal(t, "[]\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, usecase.Export(1, transaction.Filter{}, uc.FormatXLSX, &buf))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("PK")))
+}
+
+func TestExportWritesNothingOnQueryError(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+	mockRepo.On("StreamReport", int64(1), transaction.Filter{}).Return(nil, errors.New("db down"))
+
+	var buf bytes.Buffer
+	err := usecase.Export(1, transaction.Filter{}, uc.FormatXLSX, &buf)
+
+	assert.Error(t, err)
+	assert.Zero(t, buf.Len())
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	usecase := uc.New(new(MockTransactionRepository), new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+
+	err := usecase.Export(1, transaction.Filter{}, "pdf", new(bytes.Buffer))
+
+	assert.Error(t, err)
+}
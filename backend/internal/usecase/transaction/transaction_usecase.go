@@ -1,27 +1,60 @@
 package transaction
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"time"
 
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/tag"
 	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
+	accountUC "github.com/afandimsr/cashbook-backend/internal/usecase/account"
+	categoryUC "github.com/afandimsr/cashbook-backend/internal/usecase/category"
+	fxUC "github.com/afandimsr/cashbook-backend/internal/usecase/fx"
 )
 
 type Usecase interface {
 	GetAllByUserID(userID int64, page, limit int, filter transaction.Filter) (transaction.PaginatedTransactions, error)
-	GetByID(id int64) (transaction.Transaction, error)
+	GetByID(userID, id int64) (transaction.Transaction, error)
 	Create(t transaction.Transaction) error
-	Update(id int64, t transaction.Transaction) error
-	Delete(id int64) error
+	Update(userID, id int64, t transaction.Transaction) error
+	Delete(userID, id int64) error
 	GetDashboardSummary(userID int64) (transaction.DashboardSummary, error)
+	// Export streams the user's transactions matching filter to w as csv,
+	// xlsx or json. Nothing is written to w before the first row has been
+	// read, so query errors can still be reported to the client.
+	Export(userID int64, filter transaction.Filter, format string, w io.Writer) error
+}
+
+// AttachmentCleaner removes the attachments of a deleted transaction.
+type AttachmentCleaner interface {
+	GetAll(userID, transactionID int64) ([]attachment.Attachment, error)
+	Purge(attachments []attachment.Attachment) (int, error)
 }
 
 type usecase struct {
-	repo transaction.Repository
+	repo         transaction.Repository
+	accountRepo  account.Repository
+	categoryRepo category.Repository
+	fx           fxUC.Usecase
+	attachments  AttachmentCleaner
 }
 
-func New(repo transaction.Repository) Usecase {
+// New returns the transaction usecase. attachments may be nil when no
+// attachment storage is configured.
+func New(repo transaction.Repository, accountRepo account.Repository, categoryRepo category.Repository, fx fxUC.Usecase, attachments AttachmentCleaner) Usecase {
 	return &usecase{
-		repo: repo,
+		repo:         repo,
+		accountRepo:  accountRepo,
+		categoryRepo: categoryRepo,
+		fx:           fx,
+		attachments:  attachments,
 	}
 }
 
@@ -32,64 +65,311 @@ func (u *usecase) GetAllByUserID(userID int64, page, limit int, filter transacti
 		return transaction.PaginatedTransactions{}, err
 	}
 
-	total, sum, err := u.repo.GetTotalAndSum(userID, filter)
+	if err := u.attachDetails(txs); err != nil {
+		return transaction.PaginatedTransactions{}, err
+	}
+
+	total, err := u.repo.Count(userID, filter)
+	if err != nil {
+		return transaction.PaginatedTransactions{}, err
+	}
+
+	totals, err := u.repo.SumByCurrency(userID, filter)
 	if err != nil {
 		return transaction.PaginatedTransactions{}, err
 	}
+	if totals == nil {
+		totals = []transaction.CurrencySummary{}
+	}
 
 	return transaction.PaginatedTransactions{
 		Transactions: txs,
 		Total:        total,
-		TotalAmount:  sum,
+		Totals:       totals,
 	}, nil
 }
 
-func (u *usecase) GetByID(id int64) (transaction.Transaction, error) {
-	return u.repo.FindByID(id)
+// find returns the user's transaction, or 404 when it does not exist or
+// belongs to someone else.
+func (u *usecase) find(userID, id int64) (transaction.Transaction, error) {
+	t, err := u.repo.FindByIDForUser(userID, id)
+	if err != nil {
+		if errors.Is(err, transaction.ErrNotFound) {
+			return transaction.Transaction{}, apperror.NotFound("transaction not found", err)
+		}
+		return transaction.Transaction{}, err
+	}
+	return t, nil
+}
+
+func (u *usecase) GetByID(userID, id int64) (transaction.Transaction, error) {
+	t, err := u.find(userID, id)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+	txs := []transaction.Transaction{t}
+	if err := u.attachDetails(txs); err != nil {
+		return transaction.Transaction{}, err
+	}
+	return txs[0], nil
+}
+
+// attachDetails loads the splits and tags of txs with one query each.
+func (u *usecase) attachDetails(txs []transaction.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(txs))
+	for i, t := range txs {
+		ids[i] = t.ID
+	}
+	splits, err := u.repo.FindSplits(ids)
+	if err != nil {
+		return err
+	}
+	tags, err := u.repo.FindTags(ids)
+	if err != nil {
+		return err
+	}
+	for i := range txs {
+		txs[i].Splits = splits[txs[i].ID]
+		txs[i].Tags = tags[txs[i].ID]
+	}
+	return nil
+}
+
+func normalizeTags(t *transaction.Transaction) error {
+	t.Tags = tag.NormalizeNames(t.Tags)
+	for _, name := range t.Tags {
+		if len(name) > tag.MaxNameLength {
+			return apperror.BadRequest(fmt.Sprintf("tag %q is longer than %d characters", name, tag.MaxNameLength), nil)
+		}
+	}
+	return nil
+}
+
+// validateSplits checks that the splits of t add up to its amount and makes
+// the first split's category the category of t.
+func validateSplits(t *transaction.Transaction) error {
+	if len(t.Splits) == 0 {
+		return nil
+	}
+	if len(t.Splits) < 2 {
+		return apperror.BadRequest("a split transaction needs at least two splits", nil)
+	}
+
+	var sum money.Amount
+	for i, s := range t.Splits {
+		if s.CategoryID == 0 {
+			return apperror.BadRequest(fmt.Sprintf("split %d: category_id is required", i+1), nil)
+		}
+		if s.Amount <= 0 {
+			return apperror.BadRequest(fmt.Sprintf("split %d: amount must be positive", i+1), nil)
+		}
+		sum += s.Amount
+	}
+	if sum != t.Amount {
+		return apperror.BadRequest(fmt.Sprintf("splits add up to %s but the amount is %s", sum, t.Amount), nil)
+	}
+
+	t.CategoryID = t.Splits[0].CategoryID
+	return nil
+}
+
+// checkCategories makes sure t and its splits only use the user's own
+// categories.
+func (u *usecase) checkCategories(t transaction.Transaction) error {
+	checked := map[int64]bool{}
+	ids := []int64{t.CategoryID}
+	for _, s := range t.Splits {
+		ids = append(ids, s.CategoryID)
+	}
+	for _, id := range ids {
+		if id == 0 || checked[id] {
+			continue
+		}
+		checked[id] = true
+		if err := categoryUC.Check(u.categoryRepo, t.UserID, id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (u *usecase) Create(t transaction.Transaction) error {
+	if transaction.IsTransfer(t.Type) {
+		return apperror.BadRequest("use the transfer endpoint to move money between accounts", nil)
+	}
 	if t.Date.IsZero() {
 		t.Date = time.Now()
 	}
-	return u.repo.Save(&t)
+	if err := validateSplits(&t); err != nil {
+		return err
+	}
+	if err := normalizeTags(&t); err != nil {
+		return err
+	}
+	if err := u.checkCategories(t); err != nil {
+		return err
+	}
+
+	acc, err := accountUC.Resolve(u.accountRepo, t.UserID, t.AccountID)
+	if err != nil {
+		return err
+	}
+	t.AccountID = acc.ID
+	t.Currency = acc.Currency
+
+	if len(t.Splits) == 0 && len(t.Tags) == 0 {
+		return u.repo.Save(&t)
+	}
+	return u.repo.InTx(func(repo transaction.Repository) error {
+		if err := repo.Save(&t); err != nil {
+			return err
+		}
+		if len(t.Splits) > 0 {
+			if err := repo.SaveSplits(t.ID, t.Splits); err != nil {
+				return err
+			}
+		}
+		if len(t.Tags) > 0 {
+			return repo.SetTags(t.ID, t.UserID, t.Tags)
+		}
+		return nil
+	})
 }
 
-func (u *usecase) Update(id int64, t transaction.Transaction) error {
-	existing, err := u.repo.FindByID(id)
+func (u *usecase) Update(userID, id int64, t transaction.Transaction) error {
+	existing, err := u.find(userID, id)
 	if err != nil {
 		return err
 	}
+	if transaction.IsTransfer(existing.Type) {
+		return apperror.BadRequest("transfer legs can only be changed through their transfer", nil)
+	}
+	if transaction.IsTransfer(t.Type) {
+		return apperror.BadRequest("use the transfer endpoint to move money between accounts", nil)
+	}
+
+	if t.AccountID != 0 && t.AccountID != existing.AccountID {
+		acc, err := accountUC.Resolve(u.accountRepo, existing.UserID, t.AccountID)
+		if err != nil {
+			return err
+		}
+		existing.AccountID = acc.ID
+		existing.Currency = acc.Currency
+	}
 
 	existing.CategoryID = t.CategoryID
 	existing.Amount = t.Amount
 	existing.Note = t.Note
 	existing.Date = t.Date
 	existing.Type = t.Type
+	existing.Splits = t.Splits
+	existing.Tags = t.Tags
+	if err := validateSplits(&existing); err != nil {
+		return err
+	}
+	if err := normalizeTags(&existing); err != nil {
+		return err
+	}
+	if err := u.checkCategories(existing); err != nil {
+		return err
+	}
 
-	return u.repo.Update(&existing)
+	// splits and tags are replaced as a whole; an update without them
+	// removes any the transaction had
+	return u.repo.InTx(func(repo transaction.Repository) error {
+		if err := repo.Update(&existing); err != nil {
+			return err
+		}
+		if err := repo.SaveSplits(existing.ID, existing.Splits); err != nil {
+			return err
+		}
+		return repo.SetTags(existing.ID, existing.UserID, existing.Tags)
+	})
 }
 
-func (u *usecase) Delete(id int64) error {
-	return u.repo.Delete(id)
+func (u *usecase) Delete(userID, id int64) error {
+	existing, err := u.find(userID, id)
+	if err != nil {
+		return err
+	}
+	if transaction.IsTransfer(existing.Type) {
+		return apperror.BadRequest("transfer legs can only be deleted through their transfer", nil)
+	}
+
+	// the attachment rows outlive the transaction so their files can be
+	// removed once it is gone; whatever is left over, like the attachments
+	// of transactions deleted along with their transfer, is swept up by the
+	// background purge
+	var attachments []attachment.Attachment
+	if u.attachments != nil {
+		if attachments, err = u.attachments.GetAll(userID, id); err != nil {
+			return err
+		}
+	}
+	if err := u.repo.Delete(userID, id); err != nil {
+		return err
+	}
+	if len(attachments) > 0 {
+		if _, err := u.attachments.Purge(attachments); err != nil {
+			log.Printf("transaction %d deleted but its attachments were not removed: %v", id, err)
+		}
+	}
+	return nil
 }
 
 func (u *usecase) GetDashboardSummary(userID int64) (transaction.DashboardSummary, error) {
-	// For simplicity, fetch all (or a large range) and calculate
-	// In production, this should be a DB aggregation query
-	txs, err := u.repo.FindAllByUserID(userID, 1000, 0, transaction.Filter{})
+	totals, err := u.repo.SumByCurrency(userID, transaction.Filter{})
+	if err != nil {
+		return transaction.DashboardSummary{}, err
+	}
+
+	base, err := u.fx.BaseCurrency(userID)
 	if err != nil {
 		return transaction.DashboardSummary{}, err
 	}
 
-	var summary transaction.DashboardSummary
-	for _, tx := range txs {
-		if tx.Type == "income" {
-			summary.TotalIncome += tx.Amount
-		} else {
-			summary.TotalExpense += tx.Amount
+	summary := transaction.DashboardSummary{Currency: base, ByCurrency: []transaction.CurrencySummary{}}
+	now := time.Now()
+	for _, cs := range totals {
+		quote, err := u.fx.Quote(cs.Currency, base, now)
+		if err != nil {
+			return transaction.DashboardSummary{}, err
 		}
+		cs.Conversion = quote
+		summary.TotalIncome += quote.Apply(cs.TotalIncome)
+		summary.TotalExpense += quote.Apply(cs.TotalExpense)
+		summary.ByCurrency = append(summary.ByCurrency, cs)
 	}
 	summary.Balance = summary.TotalIncome - summary.TotalExpense
 	return summary, nil
 }
+
+func (u *usecase) Export(userID int64, filter transaction.Filter, format string, w io.Writer) error {
+	if _, err := ExportContentType(format); err != nil {
+		return err
+	}
+
+	var exporter Exporter
+	err := u.repo.StreamReport(userID, filter, func(t transaction.ReportTransaction) error {
+		if exporter == nil {
+			var err error
+			if exporter, err = NewExporter(format, w); err != nil {
+				return err
+			}
+		}
+		return exporter.Write(t)
+	})
+	if err != nil {
+		return err
+	}
+
+	if exporter == nil {
+		if exporter, err = NewExporter(format, w); err != nil {
+			return err
+		}
+	}
+	return exporter.Close()
+}
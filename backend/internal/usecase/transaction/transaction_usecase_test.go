@@ -1,10 +1,18 @@
 package transaction_test
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/afandimsr/cashbook-backend/internal/domain/account"
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/attachment"
+	"github.com/afandimsr/cashbook-backend/internal/domain/category"
+	"github.com/afandimsr/cashbook-backend/internal/domain/fx"
 	"github.com/afandimsr/cashbook-backend/internal/domain/transaction"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
 	uc "github.com/afandimsr/cashbook-backend/internal/usecase/transaction"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -19,19 +27,29 @@ func (m *MockTransactionRepository) FindAllByUserID(userID int64, limit, offset
 	return args.Get(0).([]transaction.Transaction), args.Error(1)
 }
 
-// Mock GetTotalAndSum
-func (m *MockTransactionRepository) GetTotalAndSum(userID int64, filter transaction.Filter) (int64, float64, error) {
+// Mock Count
+func (m *MockTransactionRepository) Count(userID int64, filter transaction.Filter) (int64, error) {
 	args := m.Called(userID, filter)
-	return args.Get(0).(int64), args.Get(1).(float64), args.Error(2)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockTransactionRepository) GetCategorySpending(userID int64, limit, offset int, filter transaction.Filter) ([]transaction.ReportTransaction, error) {
-	args := m.Called(userID, limit, offset, filter)
-	return args.Get(0).([]transaction.ReportTransaction), args.Error(1)
+func (m *MockTransactionRepository) SumByCurrency(userID int64, filter transaction.Filter) ([]transaction.CurrencySummary, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]transaction.CurrencySummary), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindByID(id int64) (transaction.Transaction, error) {
-	args := m.Called(id)
+func (m *MockTransactionRepository) SumByCategory(userID int64, filter transaction.Filter) ([]transaction.CategoryTotal, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).([]transaction.CategoryTotal), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Trend(userID int64, query transaction.TrendQuery) ([]transaction.TrendRow, error) {
+	args := m.Called(userID, query)
+	return args.Get(0).([]transaction.TrendRow), args.Error(1)
+}
+
+func (m *MockTransactionRepository) FindByIDForUser(userID, id int64) (transaction.Transaction, error) {
+	args := m.Called(userID, id)
 	return args.Get(0).(transaction.Transaction), args.Error(1)
 }
 
@@ -40,19 +58,169 @@ func (m *MockTransactionRepository) Save(t *transaction.Transaction) error {
 	return args.Error(0)
 }
 
+func (m *MockTransactionRepository) SaveOccurrence(t *transaction.Transaction) (bool, error) {
+	args := m.Called(t)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockTransactionRepository) Update(t *transaction.Transaction) error {
 	args := m.Called(t)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRepository) Delete(id int64) error {
+func (m *MockTransactionRepository) Delete(userID, id int64) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) StreamReport(userID int64, filter transaction.Filter, fn func(transaction.ReportTransaction) error) error {
+	args := m.Called(userID, filter)
+	if rows, ok := args.Get(0).([]transaction.ReportTransaction); ok {
+		for _, t := range rows {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockTransactionRepository) FindSplits(transactionIDs []int64) (map[int64][]transaction.Split, error) {
+	args := m.Called(transactionIDs)
+	return args.Get(0).(map[int64][]transaction.Split), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SaveSplits(transactionID int64, splits []transaction.Split) error {
+	args := m.Called(transactionID, splits)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) FindTags(transactionIDs []int64) (map[int64][]string, error) {
+	args := m.Called(transactionIDs)
+	return args.Get(0).(map[int64][]string), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SetTags(transactionID, userID int64, names []string) error {
+	args := m.Called(transactionID, userID, names)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) FindByExternalIDs(accountID int64, ids []string) (map[string]int64, error) {
+	args := m.Called(accountID, ids)
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockTransactionRepository) InTx(fn func(repo transaction.Repository) error) error {
+	return fn(m)
+}
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) FindAllByUserID(userID int64) ([]account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) FindByID(id int64) (account.Account, error) {
+	args := m.Called(id)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) FindDefault(userID int64) (account.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (m *MockAccountRepository) Save(a *account.Account) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Update(a *account.Account) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) FindTransferByID(id int64) (account.Transfer, error) {
 	args := m.Called(id)
+	return args.Get(0).(account.Transfer), args.Error(1)
+}
+
+func (m *MockAccountRepository) SaveTransfer(tr *account.Transfer) error {
+	args := m.Called(tr)
 	return args.Error(0)
 }
 
+func (m *MockAccountRepository) DeleteTransfer(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) FindAllByUserID(userID int64) ([]category.Category, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]category.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindByIDForUser(userID, id int64) (category.Category, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(category.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Save(c *category.Category) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Update(c *category.Category) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Delete(userID, id int64) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+// ownCategories accepts every category as belonging to the user.
+func ownCategories() *MockCategoryRepository {
+	m := new(MockCategoryRepository)
+	m.On("FindByIDForUser", mock.Anything, mock.Anything).Return(category.Category{}, nil).Maybe()
+	return m
+}
+
+type MockFxUsecase struct {
+	mock.Mock
+}
+
+func (m *MockFxUsecase) ImportRates(rates []fx.Rate) (int, error) {
+	args := m.Called(rates)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockFxUsecase) BaseCurrency(userID int64) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFxUsecase) Quote(from, to string, on time.Time) (fx.Quote, error) {
+	args := m.Called(from, to, on)
+	return args.Get(0).(fx.Quote), args.Error(1)
+}
+
 func TestGetAllByUserIDWithFilters(t *testing.T) {
 	mockRepo := new(MockTransactionRepository)
-	usecase := uc.New(mockRepo)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
 
 	userID := int64(1)
 	page := 1
@@ -61,7 +229,8 @@ func TestGetAllByUserIDWithFilters(t *testing.T) {
 	t.Run("FilterByCategoryID", func(t *testing.T) {
 		filter := transaction.Filter{CategoryID: 5}
 		mockRepo.On("FindAllByUserID", userID, limit, 0, filter).Return([]transaction.Transaction{}, nil).Once()
-		mockRepo.On("GetTotalAndSum", userID, filter).Return(int64(0), float64(0), nil).Once()
+		mockRepo.On("Count", userID, filter).Return(int64(0), nil).Once()
+		mockRepo.On("SumByCurrency", userID, filter).Return([]transaction.CurrencySummary(nil), nil).Once()
 
 		_, err := usecase.GetAllByUserID(userID, page, limit, filter)
 
@@ -72,7 +241,8 @@ func TestGetAllByUserIDWithFilters(t *testing.T) {
 	t.Run("FilterByType", func(t *testing.T) {
 		filter := transaction.Filter{Type: "expense"}
 		mockRepo.On("FindAllByUserID", userID, limit, 0, filter).Return([]transaction.Transaction{}, nil).Once()
-		mockRepo.On("GetTotalAndSum", userID, filter).Return(int64(0), float64(0), nil).Once()
+		mockRepo.On("Count", userID, filter).Return(int64(0), nil).Once()
+		mockRepo.On("SumByCurrency", userID, filter).Return([]transaction.CurrencySummary(nil), nil).Once()
 
 		_, err := usecase.GetAllByUserID(userID, page, limit, filter)
 
@@ -85,7 +255,8 @@ func TestGetAllByUserIDWithFilters(t *testing.T) {
 		end := time.Now()
 		filter := transaction.Filter{StartDate: start, EndDate: end}
 		mockRepo.On("FindAllByUserID", userID, limit, 0, filter).Return([]transaction.Transaction{}, nil).Once()
-		mockRepo.On("GetTotalAndSum", userID, filter).Return(int64(0), float64(0), nil).Once()
+		mockRepo.On("Count", userID, filter).Return(int64(0), nil).Once()
+		mockRepo.On("SumByCurrency", userID, filter).Return([]transaction.CurrencySummary(nil), nil).Once()
 
 		_, err := usecase.GetAllByUserID(userID, page, limit, filter)
 
@@ -101,7 +272,8 @@ func TestGetAllByUserIDWithFilters(t *testing.T) {
 			StartDate:  time.Now(),
 		}
 		mockRepo.On("FindAllByUserID", userID, limit, 0, filter).Return([]transaction.Transaction{}, nil).Once()
-		mockRepo.On("GetTotalAndSum", userID, filter).Return(int64(0), float64(0), nil).Once()
+		mockRepo.On("Count", userID, filter).Return(int64(0), nil).Once()
+		mockRepo.On("SumByCurrency", userID, filter).Return([]transaction.CurrencySummary(nil), nil).Once()
 
 		_, err := usecase.GetAllByUserID(userID, page, limit, filter)
 
@@ -110,15 +282,37 @@ func TestGetAllByUserIDWithFilters(t *testing.T) {
 	})
 }
 
+func TestGetAllByUserIDTotalsPerCurrency(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+
+	filter := transaction.Filter{Type: transaction.TypeExpense}
+	totals := []transaction.CurrencySummary{
+		{Currency: "IDR", TotalExpense: money.FromMajor(150000)},
+		{Currency: "USD", TotalExpense: money.FromMajor(20)},
+	}
+	mockRepo.On("FindAllByUserID", int64(1), 10, 0, filter).Return([]transaction.Transaction{}, nil).Once()
+	mockRepo.On("Count", int64(1), filter).Return(int64(3), nil).Once()
+	mockRepo.On("SumByCurrency", int64(1), filter).Return(totals, nil).Once()
+
+	result, err := usecase.GetAllByUserID(1, 1, 10, filter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), result.Total)
+	assert.Equal(t, totals, result.Totals)
+}
+
 func TestGetByID(t *testing.T) {
 	mockRepo := new(MockTransactionRepository)
-	usecase := uc.New(mockRepo)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
 	id := int64(1)
 
 	expected := transaction.Transaction{ID: id, Note: "Test"}
-	mockRepo.On("FindByID", id).Return(expected, nil).Once()
+	mockRepo.On("FindByIDForUser", int64(1), id).Return(expected, nil).Once()
+	mockRepo.On("FindSplits", []int64{id}).Return(map[int64][]transaction.Split{}, nil).Once()
+	mockRepo.On("FindTags", []int64{id}).Return(map[int64][]string{}, nil).Once()
 
-	result, err := usecase.GetByID(id)
+	result, err := usecase.GetByID(1, id)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
@@ -127,33 +321,61 @@ func TestGetByID(t *testing.T) {
 
 func TestCreate(t *testing.T) {
 	mockRepo := new(MockTransactionRepository)
-	usecase := uc.New(mockRepo)
+	mockAccountRepo := new(MockAccountRepository)
+	usecase := uc.New(mockRepo, mockAccountRepo, ownCategories(), new(MockFxUsecase), nil)
 
-	tx := transaction.Transaction{Note: "Test"}
+	tx := transaction.Transaction{UserID: 1, Note: "Test"}
+	mockAccountRepo.On("FindDefault", int64(1)).Return(account.Account{ID: 3, UserID: 1, Currency: "USD"}, nil).Once()
 	mockRepo.On("Save", mock.MatchedBy(func(t *transaction.Transaction) bool {
-		return t.Note == "Test"
+		return t.Note == "Test" && t.AccountID == 3 && t.Currency == "USD"
 	})).Return(nil).Once()
 
 	err := usecase.Create(tx)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	mockAccountRepo.AssertExpectations(t)
+}
+
+func TestCreateRejectsForeignAccount(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	usecase := uc.New(mockRepo, mockAccountRepo, ownCategories(), new(MockFxUsecase), nil)
+
+	mockAccountRepo.On("FindByID", int64(9)).Return(account.Account{ID: 9, UserID: 2}, nil).Once()
+
+	err := usecase.Create(transaction.Transaction{UserID: 1, AccountID: 9, Type: transaction.TypeExpense})
+
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestCreateRejectsTransferType(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+
+	err := usecase.Create(transaction.Transaction{UserID: 1, Type: transaction.TypeTransferIn})
+
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything)
 }
 
 func TestUpdate(t *testing.T) {
 	mockRepo := new(MockTransactionRepository)
-	usecase := uc.New(mockRepo)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
 	id := int64(1)
 
-	existing := transaction.Transaction{ID: id, Note: "Old"}
+	existing := transaction.Transaction{ID: id, UserID: 1, Note: "Old"}
 	updateData := transaction.Transaction{Note: "New"}
 
-	mockRepo.On("FindByID", id).Return(existing, nil).Once()
+	mockRepo.On("FindByIDForUser", int64(1), id).Return(existing, nil).Once()
 	mockRepo.On("Update", mock.MatchedBy(func(t *transaction.Transaction) bool {
 		return t.Note == "New"
 	})).Return(nil).Once()
+	mockRepo.On("SaveSplits", id, []transaction.Split(nil)).Return(nil).Once()
+	mockRepo.On("SetTags", id, int64(1), []string{}).Return(nil).Once()
 
-	err := usecase.Update(id, updateData)
+	err := usecase.Update(1, id, updateData)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
@@ -161,34 +383,251 @@ func TestUpdate(t *testing.T) {
 
 func TestDelete(t *testing.T) {
 	mockRepo := new(MockTransactionRepository)
-	usecase := uc.New(mockRepo)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
 	id := int64(1)
 
-	mockRepo.On("Delete", id).Return(nil).Once()
+	mockRepo.On("FindByIDForUser", int64(1), id).Return(transaction.Transaction{ID: id, UserID: 1, Type: transaction.TypeExpense}, nil).Once()
+	mockRepo.On("Delete", int64(1), id).Return(nil).Once()
 
-	err := usecase.Delete(id)
+	err := usecase.Delete(1, id)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
 
+type fakeAttachmentCleaner struct {
+	attachments map[int64][]attachment.Attachment
+	purged      []attachment.Attachment
+}
+
+func (f *fakeAttachmentCleaner) GetAll(userID, transactionID int64) ([]attachment.Attachment, error) {
+	return f.attachments[transactionID], nil
+}
+
+func (f *fakeAttachmentCleaner) Purge(attachments []attachment.Attachment) (int, error) {
+	f.purged = append(f.purged, attachments...)
+	return len(attachments), nil
+}
+
+func TestDeletePurgesAttachments(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	own := []attachment.Attachment{{ID: 5, TransactionID: 1}, {ID: 6, TransactionID: 1}}
+	cleaner := &fakeAttachmentCleaner{attachments: map[int64][]attachment.Attachment{
+		1: own,
+		2: {{ID: 7, TransactionID: 2}},
+	}}
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), cleaner)
+	id := int64(1)
+
+	mockRepo.On("FindByIDForUser", int64(1), id).Return(transaction.Transaction{ID: id, UserID: 1, Type: transaction.TypeExpense}, nil).Once()
+	mockRepo.On("Delete", int64(1), id).Return(nil).Once()
+
+	assert.NoError(t, usecase.Delete(1, id))
+	assert.Equal(t, own, cleaner.purged, "only the deleted transaction's attachments")
+}
+
+func TestForeignTransactionIsNotFound(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+	mockRepo.On("FindByIDForUser", int64(2), int64(1)).Return(transaction.Transaction{}, transaction.ErrNotFound)
+
+	_, err := usecase.GetByID(2, 1)
+	assertNotFound(t, err)
+	assertNotFound(t, usecase.Update(2, 1, transaction.Transaction{Note: "mine now"}))
+	assertNotFound(t, usecase.Delete(2, 1))
+
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestCreateRejectsForeignCategory(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	categoryRepo := new(MockCategoryRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), categoryRepo, new(MockFxUsecase), nil)
+	categoryRepo.On("FindByIDForUser", int64(1), int64(4)).Return(category.Category{ID: 4, UserID: 1}, nil)
+	categoryRepo.On("FindByIDForUser", int64(1), int64(8)).Return(category.Category{}, category.ErrNotFound)
+
+	err := usecase.Create(transaction.Transaction{UserID: 1, CategoryID: 8, Amount: money.FromMajor(10), Type: transaction.TypeExpense})
+	assert.Error(t, err)
+
+	// a foreign category hidden in a split is rejected too
+	err = usecase.Create(transaction.Transaction{UserID: 1, Amount: money.FromMajor(10), Type: transaction.TypeExpense, Splits: []transaction.Split{
+		{CategoryID: 4, Amount: money.FromMajor(4)},
+		{CategoryID: 8, Amount: money.FromMajor(6)},
+	}})
+	assert.Error(t, err)
+
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func assertNotFound(t *testing.T, err error) {
+	t.Helper()
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, http.StatusNotFound, appErr.Code)
+	}
+}
+
+func TestDeleteRejectsTransferLeg(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+	id := int64(1)
+
+	mockRepo.On("FindByIDForUser", int64(1), id).Return(transaction.Transaction{ID: id, UserID: 1, TransferID: 4, Type: transaction.TypeTransferOut}, nil).Once()
+
+	err := usecase.Delete(1, id)
+
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Delete", int64(1), id)
+}
+
 func TestGetDashboardSummary(t *testing.T) {
 	mockRepo := new(MockTransactionRepository)
-	usecase := uc.New(mockRepo)
+	mockFx := new(MockFxUsecase)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), mockFx, nil)
 	userID := int64(1)
 
-	txs := []transaction.Transaction{
-		{Type: "income", Amount: 100},
-		{Type: "expense", Amount: 40},
+	totals := []transaction.CurrencySummary{
+		{Currency: "IDR", TotalIncome: money.FromMajor(100), TotalExpense: money.FromMajor(40)},
+		{Currency: "USD", TotalExpense: money.FromMajor(2)},
 	}
 
-	mockRepo.On("FindAllByUserID", userID, 1000, 0, transaction.Filter{}).Return(txs, nil).Once()
+	mockRepo.On("SumByCurrency", userID, transaction.Filter{}).Return(totals, nil).Once()
+	mockFx.On("BaseCurrency", userID).Return("IDR", nil).Once()
+	mockFx.On("Quote", "IDR", "IDR", mock.Anything).Return(fx.Quote{From: "IDR", To: "IDR", Rate: "1"}, nil).Once()
+	mockFx.On("Quote", "USD", "IDR", mock.Anything).Return(fx.Quote{From: "USD", To: "IDR", Rate: "10.5", RateDate: "2026-10-17"}, nil).Once()
 
 	summary, err := usecase.GetDashboardSummary(userID)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 100.0, summary.TotalIncome)
-	assert.Equal(t, 40.0, summary.TotalExpense)
-	assert.Equal(t, 60.0, summary.Balance)
+	assert.Equal(t, "IDR", summary.Currency)
+	assert.Equal(t, money.FromMajor(100), summary.TotalIncome)
+	assert.Equal(t, money.FromMajor(61), summary.TotalExpense)
+	assert.Equal(t, money.FromMajor(39), summary.Balance)
+	assert.Len(t, summary.ByCurrency, 2)
+	assert.Equal(t, "USD", summary.ByCurrency[1].Currency)
+	assert.Equal(t, money.FromMajor(2), summary.ByCurrency[1].TotalExpense)
+	assert.Equal(t, "2026-10-17", summary.ByCurrency[1].Conversion.RateDate)
 	mockRepo.AssertExpectations(t)
+	mockFx.AssertExpectations(t)
+}
+
+func TestCreateSplit(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	usecase := uc.New(mockRepo, mockAccountRepo, ownCategories(), new(MockFxUsecase), nil)
+
+	splits := []transaction.Split{
+		{CategoryID: 4, Amount: money.FromMajor(60), Note: "groceries"},
+		{CategoryID: 7, Amount: money.FromMajor(40), Note: "household"},
+	}
+	mockAccountRepo.On("FindDefault", int64(1)).Return(account.Account{ID: 3, UserID: 1, Currency: "IDR"}, nil).Once()
+	mockRepo.On("Save", mock.MatchedBy(func(t *transaction.Transaction) bool {
+		t.ID = 12
+		return t.CategoryID == 4 && t.Amount == money.FromMajor(100)
+	})).Return(nil).Once()
+	mockRepo.On("SaveSplits", int64(12), splits).Return(nil).Once()
+
+	err := usecase.Create(transaction.Transaction{UserID: 1, Amount: money.FromMajor(100), Type: transaction.TypeExpense, Splits: splits})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateSplitValidation(t *testing.T) {
+	usecase := uc.New(new(MockTransactionRepository), new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+
+	cases := map[string][]transaction.Split{
+		"sum mismatch": {
+			{CategoryID: 4, Amount: money.FromMajor(60)},
+			{CategoryID: 7, Amount: money.FromMajor(30)},
+		},
+		"single split": {
+			{CategoryID: 4, Amount: money.FromMajor(100)},
+		},
+		"missing category": {
+			{CategoryID: 4, Amount: money.FromMajor(60)},
+			{Amount: money.FromMajor(40)},
+		},
+		"non-positive amount": {
+			{CategoryID: 4, Amount: money.FromMajor(110)},
+			{CategoryID: 7, Amount: money.FromMajor(-10)},
+		},
+	}
+	for name, splits := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := usecase.Create(transaction.Transaction{UserID: 1, Amount: money.FromMajor(100), Type: transaction.TypeExpense, Splits: splits})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUpdateReplacesSplits(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+
+	splits := []transaction.Split{
+		{CategoryID: 7, Amount: money.FromMajor(20)},
+		{CategoryID: 4, Amount: money.FromMajor(30)},
+	}
+	mockRepo.On("FindByIDForUser", int64(1), int64(5)).Return(transaction.Transaction{ID: 5, UserID: 1, CategoryID: 4, Amount: money.FromMajor(50), Type: transaction.TypeExpense}, nil).Once()
+	mockRepo.On("Update", mock.MatchedBy(func(t *transaction.Transaction) bool {
+		return t.CategoryID == 7
+	})).Return(nil).Once()
+	mockRepo.On("SaveSplits", int64(5), splits).Return(nil).Once()
+	mockRepo.On("SetTags", int64(5), int64(1), []string{}).Return(nil).Once()
+
+	err := usecase.Update(1, 5, transaction.Transaction{Amount: money.FromMajor(50), Type: transaction.TypeExpense, Splits: splits})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetAllByUserIDAttachesSplits(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	usecase := uc.New(mockRepo, new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+
+	split := transaction.Split{ID: 1, TransactionID: 2, CategoryID: 4, Amount: money.FromMajor(10)}
+	mockRepo.On("FindAllByUserID", int64(1), 10, 0, transaction.Filter{}).Return([]transaction.Transaction{{ID: 2}, {ID: 3}}, nil).Once()
+	mockRepo.On("FindSplits", []int64{2, 3}).Return(map[int64][]transaction.Split{2: {split}}, nil).Once()
+	mockRepo.On("FindTags", []int64{2, 3}).Return(map[int64][]string{3: {"reimbursable"}}, nil).Once()
+	mockRepo.On("Count", int64(1), transaction.Filter{}).Return(int64(2), nil).Once()
+	mockRepo.On("SumByCurrency", int64(1), transaction.Filter{}).Return([]transaction.CurrencySummary(nil), nil).Once()
+
+	result, err := usecase.GetAllByUserID(1, 1, 10, transaction.Filter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []transaction.Split{split}, result.Transactions[0].Splits)
+	assert.Empty(t, result.Transactions[1].Splits)
+	assert.Equal(t, []string{"reimbursable"}, result.Transactions[1].Tags)
+}
+
+func TestCreateWithTags(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	usecase := uc.New(mockRepo, mockAccountRepo, ownCategories(), new(MockFxUsecase), nil)
+
+	mockAccountRepo.On("FindDefault", int64(1)).Return(account.Account{ID: 3, UserID: 1, Currency: "IDR"}, nil).Once()
+	mockRepo.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*transaction.Transaction).ID = 8
+	}).Return(nil).Once()
+	mockRepo.On("SetTags", int64(8), int64(1), []string{"trip bali", "reimbursable"}).Return(nil).Once()
+
+	err := usecase.Create(transaction.Transaction{
+		UserID: 1,
+		Type:   transaction.TypeExpense,
+		Tags:   []string{" Trip  Bali", "reimbursable", "trip bali", ""},
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SaveSplits", mock.Anything, mock.Anything)
+}
+
+func TestCreateRejectsLongTag(t *testing.T) {
+	usecase := uc.New(new(MockTransactionRepository), new(MockAccountRepository), ownCategories(), new(MockFxUsecase), nil)
+
+	err := usecase.Create(transaction.Transaction{UserID: 1, Type: transaction.TypeExpense, Tags: []string{strings.Repeat("x", 101)}})
+
+	assert.Error(t, err)
 }
@@ -9,27 +9,40 @@ import (
 	"io"
 	"time"
 
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
 	"github.com/afandimsr/cashbook-backend/internal/domain/user"
 	"github.com/afandimsr/cashbook-backend/internal/infrastructure/auth"
-	"github.com/afandimsr/cashbook-backend/internal/pkg/jwt"
 )
 
+// LoginCodeTTL is how long the frontend has to exchange the code of a
+// Google login for its session.
+const LoginCodeTTL = time.Minute
+
 type OAuthUsecase interface {
 	GetGoogleAuthURL(ip, userAgent string) (string, error)
+	// HandleGoogleCallback identifies the Google user, creating their
+	// account on first login, and returns a single-use login code.
 	HandleGoogleCallback(code, state, ip, userAgent string) (string, error)
+	// ExchangeLoginCode starts the session of the login the code was
+	// issued for. A code works once, within LoginCodeTTL.
+	ExchangeLoginCode(code, ip, userAgent string) (*user.LoginResponse, error)
 }
 
 type oauthUsecase struct {
 	userRepo       user.UserRepository
 	oauthStateRepo user.OauthStateRepository
 	googleAuth     auth.GoogleAuth
+	tokens         user.TokenRepository
+	sessions       *SessionUsecase
 }
 
-func NewOAuthUsecase(userRepo user.UserRepository, oauthStateRepo user.OauthStateRepository, googleAuth auth.GoogleAuth) OAuthUsecase {
+func NewOAuthUsecase(userRepo user.UserRepository, oauthStateRepo user.OauthStateRepository, googleAuth auth.GoogleAuth, tokens user.TokenRepository, sessions *SessionUsecase) OAuthUsecase {
 	return &oauthUsecase{
 		userRepo:       userRepo,
 		oauthStateRepo: oauthStateRepo,
 		googleAuth:     googleAuth,
+		tokens:         tokens,
+		sessions:       sessions,
 	}
 }
 
@@ -123,6 +136,11 @@ func (u *oauthUsecase) HandleGoogleCallback(code, state, ip, userAgent string) (
 	if googleUser.Email == "" {
 		return "", errors.New("google email is empty")
 	}
+	// an unverified Google address proves nothing about who owns it, so it
+	// can neither log in nor be matched against an existing account
+	if !googleUser.VerifiedEmail {
+		return "", errors.New("google email is not verified")
+	}
 
 	// 8. Find or Create User (Logic remains largely same)
 	existingUser, err := u.userRepo.FindByGoogleID(googleUser.ID)
@@ -130,11 +148,12 @@ func (u *oauthUsecase) HandleGoogleCallback(code, state, ip, userAgent string) (
 		existingUser, err = u.userRepo.FindByEmail(googleUser.Email)
 		if err != nil {
 			newUser := user.User{
-				Name:     googleUser.Name,
-				Email:    googleUser.Email,
-				GoogleID: googleUser.ID,
-				Roles:    []string{"USER"},
-				IsActive: true,
+				Name:          googleUser.Name,
+				Email:         googleUser.Email,
+				GoogleID:      googleUser.ID,
+				Roles:         []string{"USER"},
+				IsActive:      true,
+				EmailVerified: true,
 			}
 			err = u.userRepo.Save(newUser)
 			if err != nil {
@@ -143,6 +162,17 @@ func (u *oauthUsecase) HandleGoogleCallback(code, state, ip, userAgent string) (
 			// Fetch again to get ID
 			existingUser, _ = u.userRepo.FindByGoogleID(googleUser.ID)
 		} else {
+			// whoever set the password of an unverified account never
+			// proved they own the address Google just vouched for
+			if !existingUser.EmailVerified {
+				if googleUser.Name != "" {
+					existingUser.Name = googleUser.Name
+				}
+				existingUser.Password = ""
+				existingUser.TOTPSecret = ""
+				existingUser.TOTPEnabled = false
+				existingUser.EmailVerified = true
+			}
 			existingUser.GoogleID = googleUser.ID
 			existingUser.IsActive = true
 			if err := u.userRepo.Update(existingUser); err != nil {
@@ -151,12 +181,33 @@ func (u *oauthUsecase) HandleGoogleCallback(code, state, ip, userAgent string) (
 		}
 	}
 
-	jwtToken, err := jwt.GenerateToken(existingUser.ID, existingUser.Email, existingUser.Name, existingUser.Roles)
+	loginCode, err := generateRandomString(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate login code: %w", err)
+	}
+	if err := u.tokens.Create(existingUser.ID, user.TokenOAuthLogin, hashString(loginCode), time.Now().Add(LoginCodeTTL)); err != nil {
+		return "", fmt.Errorf("failed to save login code: %w", err)
+	}
+	return loginCode, nil
+}
+
+func (u *oauthUsecase) ExchangeLoginCode(code, ip, userAgent string) (*user.LoginResponse, error) {
+	userID, err := u.tokens.Consume(user.TokenOAuthLogin, hashString(code), time.Now())
+	if errors.Is(err, user.ErrTokenNotFound) {
+		return nil, apperror.Unauthorized("invalid or expired login code", nil)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, apperror.Internal(err)
 	}
 
-	return jwtToken, nil
+	existingUser, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if !existingUser.IsActive {
+		return nil, apperror.Unauthorized("user is inactive", nil)
+	}
+	return u.sessions.Start(existingUser, user.Device{UserAgent: userAgent, IP: ip})
 }
 
 func hashString(s string) string {
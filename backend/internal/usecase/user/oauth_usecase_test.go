@@ -0,0 +1,184 @@
+package user_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+	"github.com/afandimsr/cashbook-backend/internal/infrastructure/auth"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/jwt"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// MockOauthStateRepository keeps oauth states in memory.
+type MockOauthStateRepository struct {
+	states map[string]user.OauthState
+}
+
+func (m *MockOauthStateRepository) Save(state user.OauthState) error {
+	m.states[state.State] = state
+	return nil
+}
+
+func (m *MockOauthStateRepository) FindByState(state string) (*user.OauthState, error) {
+	s, ok := m.states[state]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &s, nil
+}
+
+func (m *MockOauthStateRepository) Update(state user.OauthState) error {
+	m.states[state.State] = state
+	return nil
+}
+
+// fakeGoogleAuth signs in as googleUser whatever the code.
+type fakeGoogleAuth struct {
+	googleUser auth.GoogleUser
+}
+
+func (f *fakeGoogleAuth) GetAuthURL(state string) string {
+	return "https://accounts.google.com/o/oauth2/auth?state=" + state
+}
+
+func (f *fakeGoogleAuth) ExchangeCode(code string) (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: "google-access"}, nil
+}
+
+func (f *fakeGoogleAuth) GetUserData(token *oauth2.Token) (*auth.GoogleUser, error) {
+	u := f.googleUser
+	return &u, nil
+}
+
+type oauthFixture struct {
+	userRepo *MockUserRepository
+	tokens   *MockTokenRepository
+	google   *fakeGoogleAuth
+	usecase  uc.OAuthUsecase
+}
+
+func newOAuthFixture(googleUser auth.GoogleUser) oauthFixture {
+	jwt.SetSecret("oauth-test")
+	f := oauthFixture{
+		userRepo: new(MockUserRepository),
+		tokens:   newMockTokenRepository(),
+		google:   &fakeGoogleAuth{googleUser: googleUser},
+	}
+	sessions := uc.NewSessionUsecase(newMockSessionRepository(), f.userRepo)
+	states := &MockOauthStateRepository{states: map[string]user.OauthState{}}
+	f.usecase = uc.NewOAuthUsecase(f.userRepo, states, f.google, f.tokens, sessions)
+	return f
+}
+
+// callback goes through the Google login from the same device.
+func (f oauthFixture) callback(t *testing.T) (string, error) {
+	t.Helper()
+	authURL, err := f.usecase.GetGoogleAuthURL("10.0.0.1", "laptop")
+	require.NoError(t, err)
+	state := authURL[len("https://accounts.google.com/o/oauth2/auth?state="):]
+	return f.usecase.HandleGoogleCallback("google-code", state, "10.0.0.1", "laptop")
+}
+
+func TestGoogleLoginCodeExchange(t *testing.T) {
+	f := newOAuthFixture(auth.GoogleUser{ID: "g-7", Email: "alice@example.com", VerifiedEmail: true, Name: "Alice"})
+	alice := user.User{ID: 7, Email: "alice@example.com", GoogleID: "g-7", Roles: []string{"USER"}, IsActive: true, EmailVerified: true}
+	f.userRepo.On("FindByGoogleID", "g-7").Return(alice, nil)
+	f.userRepo.On("FindByID", int64(7)).Return(alice, nil)
+
+	code, err := f.callback(t)
+	require.NoError(t, err)
+	assert.NotContains(t, f.tokens.tokens, code, "only the hash is stored")
+	assert.Equal(t, user.TokenOAuthLogin, f.tokens.tokens[hexSHA256(code)].purpose)
+
+	login, err := f.usecase.ExchangeLoginCode(code, "10.0.0.1", "laptop")
+	require.NoError(t, err)
+	assert.NotEmpty(t, login.Token)
+	assert.NotEmpty(t, login.RefreshToken)
+
+	_, err = f.usecase.ExchangeLoginCode(code, "10.0.0.1", "laptop")
+	assertAppError(t, err, 401)
+}
+
+func TestGoogleLoginCodeRejectsInactiveUser(t *testing.T) {
+	f := newOAuthFixture(auth.GoogleUser{ID: "g-7", Email: "alice@example.com", VerifiedEmail: true})
+	alice := user.User{ID: 7, Email: "alice@example.com", GoogleID: "g-7", IsActive: true, EmailVerified: true}
+	f.userRepo.On("FindByGoogleID", "g-7").Return(alice, nil)
+	// deactivated between the callback and the exchange
+	f.userRepo.On("FindByID", int64(7)).Return(user.User{ID: 7}, nil)
+
+	code, err := f.callback(t)
+	require.NoError(t, err)
+
+	_, err = f.usecase.ExchangeLoginCode(code, "10.0.0.1", "laptop")
+	assertAppError(t, err, 401)
+}
+
+func TestGoogleLoginRejectsUnverifiedEmail(t *testing.T) {
+	f := newOAuthFixture(auth.GoogleUser{ID: "g-9", Email: "alice@example.com", VerifiedEmail: false, Name: "Mallory"})
+
+	_, err := f.callback(t)
+
+	assert.Error(t, err)
+	assert.Empty(t, f.tokens.tokens)
+	f.userRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+	f.userRepo.AssertNotCalled(t, "Save", mock.Anything)
+	f.userRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestGoogleLoginLinksVerifiedAccount(t *testing.T) {
+	f := newOAuthFixture(auth.GoogleUser{ID: "g-7", Email: "alice@example.com", VerifiedEmail: true, Name: "Alice"})
+	alice := user.User{ID: 7, Name: "Alice", Email: "alice@example.com", Password: "alice-hash", IsActive: true, EmailVerified: true}
+	f.userRepo.On("FindByGoogleID", "g-7").Return(user.User{}, errors.New("user not found"))
+	f.userRepo.On("FindByEmail", "alice@example.com").Return(alice, nil)
+	f.userRepo.On("Update", mock.MatchedBy(func(u user.User) bool {
+		return u.ID == 7 && u.GoogleID == "g-7" && u.Password == "alice-hash"
+	})).Return(nil).Once()
+
+	_, err := f.callback(t)
+
+	require.NoError(t, err)
+	f.userRepo.AssertExpectations(t)
+}
+
+func TestGoogleLoginClearsCredentialsOfUnverifiedAccount(t *testing.T) {
+	f := newOAuthFixture(auth.GoogleUser{ID: "g-7", Email: "alice@example.com", VerifiedEmail: true, Name: "Alice"})
+	// signed up by someone who never verified the address
+	squatter := user.User{ID: 7, Name: "Mallory", Email: "alice@example.com", Password: "mallory-hash", TOTPSecret: "secret", TOTPEnabled: true, IsActive: true}
+	f.userRepo.On("FindByGoogleID", "g-7").Return(user.User{}, errors.New("user not found"))
+	f.userRepo.On("FindByEmail", "alice@example.com").Return(squatter, nil)
+	var updated user.User
+	f.userRepo.On("Update", mock.AnythingOfType("user.User")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(user.User)
+	}).Return(nil).Once()
+
+	_, err := f.callback(t)
+
+	require.NoError(t, err)
+	assert.Equal(t, "g-7", updated.GoogleID)
+	assert.Equal(t, "Alice", updated.Name)
+	assert.Empty(t, updated.Password)
+	assert.Empty(t, updated.TOTPSecret)
+	assert.False(t, updated.TOTPEnabled)
+	assert.True(t, updated.EmailVerified)
+}
+
+func TestGoogleLoginCreatesVerifiedUser(t *testing.T) {
+	f := newOAuthFixture(auth.GoogleUser{ID: "g-8", Email: "bob@example.com", VerifiedEmail: true, Name: "Bob"})
+	f.userRepo.On("FindByGoogleID", "g-8").Return(user.User{}, errors.New("user not found")).Once()
+	f.userRepo.On("FindByEmail", "bob@example.com").Return(user.User{}, errors.New("user not found"))
+	f.userRepo.On("Save", mock.MatchedBy(func(u user.User) bool {
+		return u.GoogleID == "g-8" && u.EmailVerified && u.Password == ""
+	})).Return(nil).Once()
+	f.userRepo.On("FindByGoogleID", "g-8").Return(user.User{ID: 8, GoogleID: "g-8", IsActive: true, EmailVerified: true}, nil).Once()
+
+	code, err := f.callback(t)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), f.tokens.tokens[hexSHA256(code)].userID)
+	f.userRepo.AssertExpectations(t)
+}
@@ -0,0 +1,162 @@
+package user
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/jwt"
+)
+
+// SessionTTL is how long a session lasts without being refreshed.
+const SessionTTL = 30 * 24 * time.Hour
+
+type SessionUsecase struct {
+	repo     user.SessionRepository
+	userRepo user.UserRepository
+}
+
+func NewSessionUsecase(repo user.SessionRepository, userRepo user.UserRepository) *SessionUsecase {
+	return &SessionUsecase{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// Start creates a session for a user who just logged in on device and
+// returns its access and refresh tokens.
+func (u *SessionUsecase) Start(existingUser user.User, device user.Device) (*user.LoginResponse, error) {
+	refreshToken, err := generateRandomString(32)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	session := user.Session{
+		UserID:    existingUser.ID,
+		Device:    device,
+		ExpiresAt: time.Now().Add(SessionTTL),
+	}
+	if err := u.repo.Create(&session, hashString(refreshToken)); err != nil {
+		return nil, apperror.Internal(err)
+	}
+
+	return tokens(existingUser, session.ID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token, which replaces it. A refresh token that was already
+// exchanged revokes its session: either it leaked or the one that
+// replaced it did.
+func (u *SessionUsecase) Refresh(refreshToken string, device user.Device) (*user.LoginResponse, error) {
+	hash := hashString(refreshToken)
+	session, used, err := u.repo.FindByTokenHash(hash)
+	if errors.Is(err, user.ErrSessionNotFound) {
+		return nil, apperror.Unauthorized("invalid refresh token", err)
+	}
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if !session.Active(time.Now()) {
+		return nil, apperror.Unauthorized("session expired or revoked", nil)
+	}
+	if used {
+		return nil, u.reused(session)
+	}
+
+	existingUser, err := u.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, apperror.Unauthorized("user not found", err)
+	}
+	if !existingUser.IsActive {
+		return nil, apperror.Unauthorized("account is disabled", nil)
+	}
+
+	next, err := generateRandomString(32)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	rotated, err := u.repo.Rotate(session.ID, hash, hashString(next), device, time.Now().Add(SessionTTL))
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	if !rotated {
+		// another refresh used the token in the meantime
+		return nil, u.reused(session)
+	}
+
+	return tokens(existingUser, session.ID, next)
+}
+
+// reused revokes the session whose rotated refresh token was presented.
+func (u *SessionUsecase) reused(session user.Session) error {
+	if err := u.repo.Revoke(session.UserID, session.ID); err != nil && !errors.Is(err, user.ErrSessionNotFound) {
+		return apperror.Internal(err)
+	}
+	log.Printf("session %d of user %d revoked: refresh token reused", session.ID, session.UserID)
+	return apperror.Unauthorized("refresh token already used, the session has been revoked", nil)
+}
+
+// Logout revokes the session of refreshToken. Unknown tokens are ignored,
+// so logging out twice succeeds.
+func (u *SessionUsecase) Logout(refreshToken string) error {
+	session, _, err := u.repo.FindByTokenHash(hashString(refreshToken))
+	if errors.Is(err, user.ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if err := u.repo.Revoke(session.UserID, session.ID); err != nil && !errors.Is(err, user.ErrSessionNotFound) {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// GetSessions returns the user's active sessions, marking currentID, the
+// session of the request.
+func (u *SessionUsecase) GetSessions(userID, currentID int64) ([]user.Session, error) {
+	sessions, err := u.repo.FindActiveByUserID(userID, time.Now())
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// Revoke ends one of the user's sessions. Its access tokens stay valid
+// until they expire, which is at most jwt.AccessTokenTTL later.
+func (u *SessionUsecase) Revoke(userID, id int64) error {
+	err := u.repo.Revoke(userID, id)
+	if errors.Is(err, user.ErrSessionNotFound) {
+		return apperror.NotFound("session not found", err)
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// RevokeOthers ends the user's sessions but currentID, the session of the
+// request.
+func (u *SessionUsecase) RevokeOthers(userID, currentID int64) error {
+	if err := u.repo.RevokeAllByUserID(userID, currentID); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+func tokens(existingUser user.User, sessionID int64, refreshToken string) (*user.LoginResponse, error) {
+	token, err := jwt.GenerateToken(existingUser.ID, sessionID, existingUser.Email, existingUser.Name, existingUser.Roles)
+	if err != nil {
+		return nil, apperror.Internal(err)
+	}
+	return &user.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(jwt.AccessTokenTTL / time.Second),
+	}, nil
+}
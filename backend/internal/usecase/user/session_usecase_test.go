@@ -0,0 +1,210 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
+	"github.com/afandimsr/cashbook-backend/internal/domain/user"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/jwt"
+	uc "github.com/afandimsr/cashbook-backend/internal/usecase/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSessionRepository keeps sessions and refresh token hashes in memory.
+type MockSessionRepository struct {
+	sessions map[int64]user.Session
+	// tokens maps refresh token hashes to their session; used records
+	// which of them were already rotated.
+	tokens map[string]int64
+	used   map[string]bool
+}
+
+func newMockSessionRepository() *MockSessionRepository {
+	return &MockSessionRepository{sessions: map[int64]user.Session{}, tokens: map[string]int64{}, used: map[string]bool{}}
+}
+
+func (m *MockSessionRepository) Create(s *user.Session, tokenHash string) error {
+	s.ID = int64(len(m.sessions) + 1)
+	s.CreatedAt, s.LastUsedAt = time.Now(), time.Now()
+	m.sessions[s.ID] = *s
+	m.tokens[tokenHash] = s.ID
+	return nil
+}
+
+func (m *MockSessionRepository) FindByTokenHash(tokenHash string) (user.Session, bool, error) {
+	id, ok := m.tokens[tokenHash]
+	if !ok {
+		return user.Session{}, false, user.ErrSessionNotFound
+	}
+	return m.sessions[id], m.used[tokenHash], nil
+}
+
+func (m *MockSessionRepository) Rotate(id int64, oldHash, newHash string, device user.Device, expiresAt time.Time) (bool, error) {
+	if m.used[oldHash] {
+		return false, nil
+	}
+	m.used[oldHash] = true
+	m.tokens[newHash] = id
+	s := m.sessions[id]
+	s.Device, s.ExpiresAt, s.LastUsedAt = device, expiresAt, time.Now()
+	m.sessions[id] = s
+	return true, nil
+}
+
+func (m *MockSessionRepository) FindActiveByUserID(userID int64, now time.Time) ([]user.Session, error) {
+	var sessions []user.Session
+	for id := int64(1); id <= int64(len(m.sessions)); id++ {
+		if s := m.sessions[id]; s.UserID == userID && s.Active(now) {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *MockSessionRepository) Revoke(userID, id int64) error {
+	s, ok := m.sessions[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return user.ErrSessionNotFound
+	}
+	now := time.Now()
+	s.RevokedAt = &now
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *MockSessionRepository) RevokeAllByUserID(userID, exceptID int64) error {
+	for id := range m.sessions {
+		if id != exceptID {
+			m.Revoke(userID, id)
+		}
+	}
+	return nil
+}
+
+func assertAppError(t *testing.T, err error, code int) {
+	t.Helper()
+	var appErr *apperror.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, code, appErr.Code)
+	}
+}
+
+func TestSessionRefreshRotatesToken(t *testing.T) {
+	jwt.SetSecret("session-test")
+	repo := newMockSessionRepository()
+	userRepo := new(MockUserRepository)
+	sessions := uc.NewSessionUsecase(repo, userRepo)
+	alice := user.User{ID: 7, Email: "alice@example.com", Roles: []string{"USER"}, IsActive: true}
+	userRepo.On("FindByID", int64(7)).Return(alice, nil)
+
+	login, err := sessions.Start(alice, user.Device{UserAgent: "laptop", IP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, login.RefreshToken)
+	assert.Equal(t, 900, login.ExpiresIn)
+	claims, err := jwt.ValidateToken(login.Token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.SessionID)
+	assert.NotContains(t, repo.tokens, login.RefreshToken, "only the hash is stored")
+
+	refreshed, err := sessions.Refresh(login.RefreshToken, user.Device{UserAgent: "laptop", IP: "10.0.0.2"})
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+	assert.Equal(t, "10.0.0.2", repo.sessions[1].IP)
+
+	again, err := sessions.Refresh(refreshed.RefreshToken, user.Device{})
+	require.NoError(t, err)
+	assert.Nil(t, repo.sessions[1].RevokedAt)
+	assert.NotEmpty(t, again.Token)
+}
+
+func TestSessionRefreshTokenReuseRevokesSession(t *testing.T) {
+	jwt.SetSecret("session-test")
+	repo := newMockSessionRepository()
+	userRepo := new(MockUserRepository)
+	sessions := uc.NewSessionUsecase(repo, userRepo)
+	alice := user.User{ID: 7, IsActive: true}
+	userRepo.On("FindByID", int64(7)).Return(alice, nil)
+
+	login, err := sessions.Start(alice, user.Device{})
+	require.NoError(t, err)
+	refreshed, err := sessions.Refresh(login.RefreshToken, user.Device{})
+	require.NoError(t, err)
+
+	// an attacker replays the first token
+	_, err = sessions.Refresh(login.RefreshToken, user.Device{})
+	assertAppError(t, err, 401)
+	assert.NotNil(t, repo.sessions[1].RevokedAt)
+
+	// which logs the legitimate client out too
+	_, err = sessions.Refresh(refreshed.RefreshToken, user.Device{})
+	assertAppError(t, err, 401)
+}
+
+func TestSessionRefreshRejects(t *testing.T) {
+	repo := newMockSessionRepository()
+	userRepo := new(MockUserRepository)
+	sessions := uc.NewSessionUsecase(repo, userRepo)
+	userRepo.On("FindByID", int64(7)).Return(user.User{ID: 7, IsActive: false}, nil)
+
+	_, err := sessions.Refresh("unknown", user.Device{})
+	assertAppError(t, err, 401)
+
+	login, err := sessions.Start(user.User{ID: 7}, user.Device{})
+	require.NoError(t, err)
+	_, err = sessions.Refresh(login.RefreshToken, user.Device{})
+	assertAppError(t, err, 401)
+	assert.False(t, repo.used[firstToken(repo)], "a disabled account does not rotate its token")
+
+	expired := repo.sessions[1]
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	repo.sessions[1] = expired
+	_, err = sessions.Refresh(login.RefreshToken, user.Device{})
+	assertAppError(t, err, 401)
+}
+
+func firstToken(repo *MockSessionRepository) string {
+	for hash := range repo.tokens {
+		return hash
+	}
+	return ""
+}
+
+func TestSessionLogoutAndRevoke(t *testing.T) {
+	repo := newMockSessionRepository()
+	sessions := uc.NewSessionUsecase(repo, nil)
+
+	laptop, err := sessions.Start(user.User{ID: 7}, user.Device{UserAgent: "laptop"})
+	require.NoError(t, err)
+	_, err = sessions.Start(user.User{ID: 7}, user.Device{UserAgent: "phone"})
+	require.NoError(t, err)
+	_, err = sessions.Start(user.User{ID: 7}, user.Device{UserAgent: "tablet"})
+	require.NoError(t, err)
+	_, err = sessions.Start(user.User{ID: 8}, user.Device{UserAgent: "other user"})
+	require.NoError(t, err)
+
+	list, err := sessions.GetSessions(7, 2)
+	require.NoError(t, err)
+	if assert.Len(t, list, 3) {
+		assert.False(t, list[0].Current)
+		assert.True(t, list[1].Current)
+	}
+
+	require.NoError(t, sessions.Logout(laptop.RefreshToken))
+	require.NoError(t, sessions.Logout(laptop.RefreshToken), "logging out twice succeeds")
+	require.NoError(t, sessions.Logout("unknown"))
+
+	assertAppError(t, sessions.Revoke(7, 4), 404)
+	require.NoError(t, sessions.Revoke(7, 3))
+	assertAppError(t, sessions.Revoke(7, 3), 404)
+
+	list, _ = sessions.GetSessions(7, 2)
+	assert.Len(t, list, 1)
+
+	require.NoError(t, sessions.RevokeOthers(7, 2))
+	list, _ = sessions.GetSessions(7, 2)
+	assert.Len(t, list, 1)
+	list, _ = sessions.GetSessions(8, 0)
+	assert.Len(t, list, 1, "other users keep their sessions")
+}
@@ -15,12 +15,14 @@ import (
 type TwoFAUsecase struct {
 	userRepo       user.UserRepository
 	backupCodeRepo user.MFABackupCodeRepository
+	sessions       *SessionUsecase
 }
 
-func NewTwoFAUsecase(userRepo user.UserRepository, backupCodeRepo user.MFABackupCodeRepository) *TwoFAUsecase {
+func NewTwoFAUsecase(userRepo user.UserRepository, backupCodeRepo user.MFABackupCodeRepository, sessions *SessionUsecase) *TwoFAUsecase {
 	return &TwoFAUsecase{
 		userRepo:       userRepo,
 		backupCodeRepo: backupCodeRepo,
+		sessions:       sessions,
 	}
 }
 
@@ -95,28 +97,23 @@ func (u *TwoFAUsecase) Disable(userID int64) error {
 	return nil
 }
 
-// VerifyLogin validates the TOTP code during login and returns the full JWT.
-func (u *TwoFAUsecase) VerifyLogin(tempToken, code string) (string, error) {
+// VerifyLogin validates the TOTP code during login and starts a session.
+func (u *TwoFAUsecase) VerifyLogin(tempToken, code string, device user.Device) (*user.LoginResponse, error) {
 	claims, err := jwt.ValidateTempToken(tempToken, "verify")
 	if err != nil {
-		return "", apperror.Unauthorized("invalid or expired 2FA token", err)
+		return nil, apperror.Unauthorized("invalid or expired 2FA token", err)
 	}
 
 	existingUser, err := u.userRepo.FindByID(claims.UserID)
 	if err != nil {
-		return "", apperror.Unauthorized("user not found", err)
+		return nil, apperror.Unauthorized("user not found", err)
 	}
 
 	if !totp.ValidateCode(existingUser.TOTPSecret, code) {
-		return "", apperror.Unauthorized("invalid TOTP code", nil)
+		return nil, apperror.Unauthorized("invalid TOTP code", nil)
 	}
 
-	token, err := jwt.GenerateToken(existingUser.ID, existingUser.Email, existingUser.Name, existingUser.Roles)
-	if err != nil {
-		return "", apperror.Internal(err)
-	}
-
-	return token, nil
+	return u.sessions.Start(existingUser, device)
 }
 
 // GenerateBackupCodes creates 10 new one-time backup codes.
@@ -157,16 +154,16 @@ func (u *TwoFAUsecase) GenerateBackupCodes(userID int64) ([]string, error) {
 	return plainCodes, nil
 }
 
-// VerifyBackupCode validates a backup code during login and returns the full JWT.
-func (u *TwoFAUsecase) VerifyBackupCode(tempToken, code string) (string, error) {
+// VerifyBackupCode validates a backup code during login and starts a session.
+func (u *TwoFAUsecase) VerifyBackupCode(tempToken, code string, device user.Device) (*user.LoginResponse, error) {
 	claims, err := jwt.ValidateTempToken(tempToken, "verify")
 	if err != nil {
-		return "", apperror.Unauthorized("invalid or expired backup code token", err)
+		return nil, apperror.Unauthorized("invalid or expired backup code token", err)
 	}
 
 	codes, err := u.backupCodeRepo.FindByUserID(claims.UserID)
 	if err != nil {
-		return "", apperror.Internal(err)
+		return nil, apperror.Internal(err)
 	}
 
 	for _, bc := range codes {
@@ -176,24 +173,19 @@ func (u *TwoFAUsecase) VerifyBackupCode(tempToken, code string) (string, error)
 		if err := bcrypt.CompareHashAndPassword([]byte(bc.CodeHash), []byte(code)); err == nil {
 			// Match found — mark as used
 			if err := u.backupCodeRepo.MarkUsed(bc.ID); err != nil {
-				return "", apperror.Internal(err)
+				return nil, apperror.Internal(err)
 			}
 
 			existingUser, err := u.userRepo.FindByID(claims.UserID)
 			if err != nil {
-				return "", apperror.Internal(err)
-			}
-
-			token, err := jwt.GenerateToken(existingUser.ID, existingUser.Email, existingUser.Name, existingUser.Roles)
-			if err != nil {
-				return "", apperror.Internal(err)
+				return nil, apperror.Internal(err)
 			}
 
-			return token, nil
+			return u.sessions.Start(existingUser, device)
 		}
 	}
 
-	return "", apperror.Unauthorized("invalid backup code", nil)
+	return nil, apperror.Unauthorized("invalid backup code", nil)
 }
 
 func generateBackupCode() (string, error) {
@@ -1,24 +1,48 @@
 package user
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	// time zones are validated against the embedded database, since the
+	// runtime image has none
+	_ "time/tzdata"
 
 	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
 	"github.com/afandimsr/cashbook-backend/internal/domain/user"
 	"github.com/afandimsr/cashbook-backend/internal/pkg/jwt"
+	"github.com/afandimsr/cashbook-backend/internal/pkg/money"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// VerificationTokenTTL is how long the link emailed to verify an
+	// address stays valid.
+	VerificationTokenTTL = 24 * time.Hour
+	// PasswordResetTokenTTL is how long the link emailed to reset a
+	// forgotten password stays valid.
+	PasswordResetTokenTTL = 30 * time.Minute
+)
+
 type Usecase struct {
 	repo            user.UserRepository
 	authService     user.AuthService
 	mfaSettingsRepo user.MFASettingsRepository
+	sessions        *SessionUsecase
+	tokens          user.TokenRepository
+	mailer          user.Mailer
+	appURL          string
 }
 
-func New(repo user.UserRepository, authService user.AuthService) *Usecase {
+func New(repo user.UserRepository, authService user.AuthService, sessions *SessionUsecase) *Usecase {
 	return &Usecase{
 		repo:        repo,
 		authService: authService,
+		sessions:    sessions,
 	}
 }
 
@@ -26,6 +50,14 @@ func (u *Usecase) SetMFASettingsRepo(repo user.MFASettingsRepository) {
 	u.mfaSettingsRepo = repo
 }
 
+// SetMailer enables the flows that email users a link with a single-use
+// token. Links point at the frontend served from appURL.
+func (u *Usecase) SetMailer(tokens user.TokenRepository, mailer user.Mailer, appURL string) {
+	u.tokens = tokens
+	u.mailer = mailer
+	u.appURL = strings.TrimRight(appURL, "/")
+}
+
 func (u *Usecase) GetAll(page, limit int) ([]user.User, error) {
 	offset := (page - 1) * limit
 	return u.repo.FindAll(limit, offset)
@@ -48,6 +80,8 @@ func (u *Usecase) Create(newUser user.User) error {
 		return apperror.Internal(err)
 	}
 	newUser.Password = string(hashedPassword)
+	// admins vouch for the addresses of the accounts they create
+	newUser.EmailVerified = true
 
 	if err := u.repo.Save(newUser); err != nil {
 		return apperror.Internal(err)
@@ -70,7 +104,12 @@ func (u *Usecase) Update(id int64, updatedUser user.User) error {
 	existingUser.Name = updatedUser.Name
 	existingUser.Email = updatedUser.Email
 	existingUser.IsActive = updatedUser.IsActive
-	existingUser.Roles = updatedUser.Roles
+	if updatedUser.BaseCurrency != "" {
+		if !money.ValidCurrency(updatedUser.BaseCurrency) {
+			return apperror.BadRequest("invalid base_currency", nil)
+		}
+		existingUser.BaseCurrency = updatedUser.BaseCurrency
+	}
 
 	if updatedUser.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updatedUser.Password), bcrypt.DefaultCost)
@@ -87,6 +126,40 @@ func (u *Usecase) Update(id int64, updatedUser user.User) error {
 	return nil
 }
 
+// roles are the roles accounts can be given.
+var roles = map[string]bool{"ADMIN": true, "USER": true}
+
+// SetRoles replaces the roles of user id. Admins cannot take the ADMIN role
+// away from themselves, so there is always one left.
+func (u *Usecase) SetRoles(adminID, id int64, newRoles []string) error {
+	var cleaned []string
+	seen := map[string]bool{}
+	for _, role := range newRoles {
+		role = strings.ToUpper(strings.TrimSpace(role))
+		if !roles[role] {
+			return apperror.BadRequest(fmt.Sprintf("unknown role %q", role), nil)
+		}
+		if !seen[role] {
+			seen[role] = true
+			cleaned = append(cleaned, role)
+		}
+	}
+	if len(cleaned) == 0 {
+		return apperror.BadRequest("roles are required", nil)
+	}
+	if id == adminID && !seen["ADMIN"] {
+		return apperror.BadRequest("you cannot remove your own ADMIN role", nil)
+	}
+
+	if _, err := u.repo.FindByID(id); err != nil {
+		return apperror.NotFound("user not found", err)
+	}
+	if err := u.repo.UpdateRoles(id, cleaned); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
 func (u *Usecase) Delete(id int64) error {
 	// Check if user exists
 	if _, err := u.repo.FindByID(id); err != nil {
@@ -100,7 +173,7 @@ func (u *Usecase) Delete(id int64) error {
 	return nil
 }
 
-func (u *Usecase) Login(email, password string) (*user.LoginResponse, error) {
+func (u *Usecase) Login(email, password string, device user.Device) (*user.LoginResponse, error) {
 	// 1. Find user by email
 	existingUser, err := u.repo.FindByEmail(email)
 	if err != nil {
@@ -130,6 +203,10 @@ func (u *Usecase) Login(email, password string) (*user.LoginResponse, error) {
 		log.Printf("Login: password verified for user id=%d", existingUser.ID)
 	}
 
+	if !existingUser.EmailVerified {
+		return nil, apperror.New(http.StatusForbidden, "email address is not verified", nil).WithCode(apperror.AuthEmailUnverified)
+	}
+
 	// 3. Check if totp secret null return to register 2fa
 	if existingUser.TOTPSecret == "" {
 		tempToken, err := jwt.GenerateTempToken(existingUser.ID, existingUser.Email, "setup")
@@ -159,25 +236,146 @@ func (u *Usecase) Login(email, password string) (*user.LoginResponse, error) {
 		settings, err := u.mfaSettingsRepo.Get()
 		if err == nil && settings != nil && settings.Enforce2FA && !existingUser.TOTPEnabled {
 			// Return a token but signal that 2FA setup is required
-			token, err := jwt.GenerateToken(existingUser.ID, existingUser.Email, existingUser.Name, existingUser.Roles)
-			if err != nil {
-				return nil, apperror.Internal(err)
-			}
-			return &user.LoginResponse{
-				Token: token,
-			}, nil
+			return u.sessions.Start(existingUser, device)
+		}
+	}
+
+	// 5. Start a session
+	return u.sessions.Start(existingUser, device)
+}
+
+// Register signs up a USER account that cannot log in until its address is
+// verified through the link emailed to it. When the address already has a
+// verified account its owner is emailed instead, so the response does not
+// reveal which addresses are registered. An unverified account is taken over
+// by the new signup, since nobody has proven they own the address yet.
+func (u *Usecase) Register(req user.RegisterRequest) error {
+	name, email := strings.TrimSpace(req.Name), strings.TrimSpace(req.Email)
+	if name == "" {
+		return apperror.BadRequest("name is required", nil)
+	}
+	if email == "" {
+		return apperror.BadRequest("email is required", nil)
+	}
+	if err := u.validatePassword(req.Password); err != nil {
+		return err
+	}
+	if u.mailer == nil {
+		return apperror.Internal(errors.New("mailer is not configured"))
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	if existingUser, err := u.repo.FindByEmail(email); err == nil {
+		if existingUser.EmailVerified {
+			err = u.mailer.Send(existingUser.Email, "You already have a Cashbook account", fmt.Sprintf(
+				"Hi %s,\n\nSomeone tried to sign up for Cashbook with this email address, which already has an account. "+
+					"You can log in with it as usual. If this was not you, no action is needed.\n",
+				existingUser.Name,
+			))
+		} else {
+			err = u.restartSignup(existingUser, name, string(hashedPassword))
+		}
+		if err != nil {
+			log.Printf("Register: signup for existing user id=%d failed: %v", existingUser.ID, err)
 		}
+		return nil
+	}
+
+	if err := u.repo.Save(user.User{
+		Name:     name,
+		Email:    email,
+		Password: string(hashedPassword),
+		Roles:    []string{"USER"},
+		IsActive: true,
+	}); err != nil {
+		return apperror.Internal(err)
+	}
+
+	// Fetch again to get ID
+	newUser, err := u.repo.FindByEmail(email)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if err := u.sendVerification(newUser); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// VerifyEmail marks the address the token was emailed to as verified. A
+// token verifies once, within VerificationTokenTTL.
+func (u *Usecase) VerifyEmail(token string) error {
+	userID, err := u.tokens.Consume(user.TokenVerifyEmail, hashString(token), time.Now())
+	if errors.Is(err, user.ErrTokenNotFound) {
+		return apperror.BadRequest("invalid or expired verification token", nil)
+	}
+	if err != nil {
+		return apperror.Internal(err)
 	}
 
-	// 5. Generate full Token
-	token, err := jwt.GenerateToken(existingUser.ID, existingUser.Email, existingUser.Name, existingUser.Roles)
+	existingUser, err := u.repo.FindByID(userID)
 	if err != nil {
-		return nil, apperror.Internal(err)
+		return apperror.Internal(err)
+	}
+	if existingUser.EmailVerified {
+		return nil
+	}
+	existingUser.EmailVerified = true
+	if err := u.repo.Update(existingUser); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// ResendVerification emails a new verification link, invalidating earlier
+// ones, when email belongs to an unverified account. It succeeds either way
+// so the response does not reveal which addresses are registered.
+func (u *Usecase) ResendVerification(email string) error {
+	if u.mailer == nil {
+		return apperror.Internal(errors.New("mailer is not configured"))
+	}
+	existingUser, err := u.repo.FindByEmail(strings.TrimSpace(email))
+	if err != nil || existingUser.EmailVerified || !existingUser.IsActive {
+		return nil
+	}
+	if err := u.sendVerification(existingUser); err != nil {
+		log.Printf("ResendVerification: emailing user id=%d failed: %v", existingUser.ID, err)
+	}
+	return nil
+}
+
+// restartSignup replaces the name and credentials of an unverified account
+// with those of a new signup and emails a fresh verification link, so whoever
+// signed up with the address before cannot log in once it is verified.
+func (u *Usecase) restartSignup(existingUser user.User, name, hashedPassword string) error {
+	existingUser.Name = name
+	existingUser.Password = hashedPassword
+	existingUser.GoogleID = ""
+	existingUser.TOTPSecret = ""
+	existingUser.TOTPEnabled = false
+	if err := u.repo.Update(existingUser); err != nil {
+		return err
 	}
+	return u.sendVerification(existingUser)
+}
 
-	return &user.LoginResponse{
-		Token: token,
-	}, nil
+func (u *Usecase) sendVerification(existingUser user.User) error {
+	token, err := generateRandomString(32)
+	if err != nil {
+		return err
+	}
+	if err := u.tokens.Create(existingUser.ID, user.TokenVerifyEmail, hashString(token), time.Now().Add(VerificationTokenTTL)); err != nil {
+		return err
+	}
+	return u.mailer.Send(existingUser.Email, "Verify your email address", fmt.Sprintf(
+		"Hi %s,\n\nConfirm your email address to finish signing up for Cashbook:\n\n%s/verify-email?token=%s\n\n"+
+			"The link expires in %d hours. If you did not sign up, you can ignore this email.\n",
+		existingUser.Name, u.appURL, token, int(VerificationTokenTTL.Hours()),
+	))
 }
 
 func (u *Usecase) ResetPassword(id int64, newPassword string) error {
@@ -204,6 +402,234 @@ func (u *Usecase) ResetPassword(id int64, newPassword string) error {
 	return nil
 }
 
+// ForgotPassword emails a link to choose a new password, invalidating
+// earlier ones, when email belongs to an active account. It succeeds either
+// way so the response does not reveal which addresses are registered.
+func (u *Usecase) ForgotPassword(email string) error {
+	if u.mailer == nil {
+		return apperror.Internal(errors.New("mailer is not configured"))
+	}
+	existingUser, err := u.repo.FindByEmail(strings.TrimSpace(email))
+	if err != nil || !existingUser.IsActive {
+		return nil
+	}
+
+	if err := u.sendPasswordReset(existingUser); err != nil {
+		log.Printf("ForgotPassword: emailing user id=%d failed: %v", existingUser.ID, err)
+	}
+	return nil
+}
+
+// ResetPasswordWithToken sets the password of the user a reset link was
+// emailed to and logs them out everywhere. A token works once, within
+// PasswordResetTokenTTL. Following the link proves the address belongs to
+// the user, so it also counts as verifying it.
+func (u *Usecase) ResetPasswordWithToken(token, newPassword string) error {
+	// checked first so a rejected password does not use up the token
+	if err := u.validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	userID, err := u.tokens.Consume(user.TokenResetPassword, hashString(token), time.Now())
+	if errors.Is(err, user.ErrTokenNotFound) {
+		return apperror.BadRequest("invalid or expired password reset token", nil)
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	existingUser, err := u.repo.FindByID(userID)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	existingUser.Password = string(hashedPassword)
+	existingUser.EmailVerified = true
+	if err := u.repo.Update(existingUser); err != nil {
+		return apperror.Internal(err)
+	}
+
+	return u.sessions.RevokeOthers(userID, 0)
+}
+
+func (u *Usecase) sendPasswordReset(existingUser user.User) error {
+	token, err := generateRandomString(32)
+	if err != nil {
+		return err
+	}
+	if err := u.tokens.Create(existingUser.ID, user.TokenResetPassword, hashString(token), time.Now().Add(PasswordResetTokenTTL)); err != nil {
+		return err
+	}
+	return u.mailer.Send(existingUser.Email, "Reset your Cashbook password", fmt.Sprintf(
+		"Hi %s,\n\nSomeone asked to reset the password of your Cashbook account. Choose a new one here:\n\n%s/reset-password?token=%s\n\n"+
+			"The link works once and expires in %d minutes. If you did not ask for this, you can ignore this email and your password stays the same.\n",
+		existingUser.Name, u.appURL, token, int(PasswordResetTokenTTL.Minutes()),
+	))
+}
+
+// GetProfile returns the caller's own account.
+func (u *Usecase) GetProfile(userID int64) (user.User, error) {
+	existingUser, err := u.repo.FindByID(userID)
+	if err != nil {
+		return user.User{}, apperror.NotFound("user not found", err)
+	}
+	existingUser.Password = ""
+	return existingUser, nil
+}
+
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// UpdateProfile changes the caller's own account. A new email address only
+// replaces the current one once it is verified through the link emailed to
+// it, so a typo cannot lock the user out; asking for the current address
+// again cancels the change.
+func (u *Usecase) UpdateProfile(userID int64, req user.ProfileUpdateRequest) (user.User, error) {
+	existingUser, err := u.repo.FindByID(userID)
+	if err != nil {
+		return user.User{}, apperror.NotFound("user not found", err)
+	}
+
+	if req.Name != nil {
+		existingUser.Name = strings.TrimSpace(*req.Name)
+		if existingUser.Name == "" {
+			return user.User{}, apperror.BadRequest("name is required", nil)
+		}
+	}
+	if req.Locale != nil {
+		if len(*req.Locale) > 35 || !localePattern.MatchString(*req.Locale) {
+			return user.User{}, apperror.BadRequest("invalid locale", nil)
+		}
+		existingUser.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			return user.User{}, apperror.BadRequest("invalid timezone", err)
+		}
+		existingUser.Timezone = *req.Timezone
+	}
+	if req.BaseCurrency != nil {
+		if !money.ValidCurrency(*req.BaseCurrency) {
+			return user.User{}, apperror.BadRequest("invalid base_currency", nil)
+		}
+		existingUser.BaseCurrency = *req.BaseCurrency
+	}
+
+	emailChanged := false
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		switch {
+		case email == "":
+			return user.User{}, apperror.BadRequest("email is required", nil)
+		case email == existingUser.Email:
+			existingUser.PendingEmail = ""
+		default:
+			if err := u.checkEmailAvailable(userID, email); err != nil {
+				return user.User{}, err
+			}
+			if u.mailer == nil {
+				return user.User{}, apperror.Internal(errors.New("mailer is not configured"))
+			}
+			existingUser.PendingEmail = email
+			emailChanged = true
+		}
+	}
+
+	if err := u.repo.Update(existingUser); err != nil {
+		return user.User{}, apperror.Internal(err)
+	}
+	if emailChanged {
+		if err := u.sendEmailChange(existingUser); err != nil {
+			return user.User{}, apperror.Internal(err)
+		}
+	}
+
+	existingUser.Password = ""
+	return existingUser, nil
+}
+
+// ConfirmEmailChange makes the pending address the token was emailed to the
+// user's email. A token works once, within VerificationTokenTTL.
+func (u *Usecase) ConfirmEmailChange(token string) error {
+	userID, err := u.tokens.Consume(user.TokenChangeEmail, hashString(token), time.Now())
+	if errors.Is(err, user.ErrTokenNotFound) {
+		return apperror.BadRequest("invalid or expired email change token", nil)
+	}
+	if err != nil {
+		return apperror.Internal(err)
+	}
+
+	existingUser, err := u.repo.FindByID(userID)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if existingUser.PendingEmail == "" {
+		return apperror.BadRequest("the email change was cancelled", nil)
+	}
+	if err := u.checkEmailAvailable(userID, existingUser.PendingEmail); err != nil {
+		return err
+	}
+
+	existingUser.Email = existingUser.PendingEmail
+	existingUser.PendingEmail = ""
+	existingUser.EmailVerified = true
+	if err := u.repo.Update(existingUser); err != nil {
+		return apperror.Internal(err)
+	}
+	return nil
+}
+
+// ChangePassword sets the caller's password after checking their current
+// one, and logs out every other session.
+func (u *Usecase) ChangePassword(userID, sessionID int64, req user.PasswordChangeRequest) error {
+	existingUser, err := u.repo.FindByID(userID)
+	if err != nil {
+		return apperror.NotFound("user not found", err)
+	}
+	if existingUser.Password == "" || bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(req.CurrentPassword)) != nil {
+		return apperror.BadRequest("current password is incorrect", nil).WithCode(apperror.AuthInvalidPassword)
+	}
+	if err := u.validatePassword(req.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	existingUser.Password = string(hashedPassword)
+	if err := u.repo.Update(existingUser); err != nil {
+		return apperror.Internal(err)
+	}
+
+	return u.sessions.RevokeOthers(userID, sessionID)
+}
+
+// checkEmailAvailable fails when email belongs to another account.
+func (u *Usecase) checkEmailAvailable(userID int64, email string) error {
+	if other, err := u.repo.FindByEmail(email); err == nil && other.ID != userID {
+		return apperror.New(http.StatusConflict, "email is already in use", nil).WithCode(apperror.UserAlreadyExists)
+	}
+	return nil
+}
+
+func (u *Usecase) sendEmailChange(existingUser user.User) error {
+	token, err := generateRandomString(32)
+	if err != nil {
+		return err
+	}
+	if err := u.tokens.Create(existingUser.ID, user.TokenChangeEmail, hashString(token), time.Now().Add(VerificationTokenTTL)); err != nil {
+		return err
+	}
+	return u.mailer.Send(existingUser.PendingEmail, "Confirm your new email address", fmt.Sprintf(
+		"Hi %s,\n\nConfirm this address to use it for your Cashbook account from now on:\n\n%s/confirm-email?token=%s\n\n"+
+			"The link expires in %d hours. Until then you keep logging in with %s. If you did not ask for this, you can ignore this email.\n",
+		existingUser.Name, u.appURL, token, int(VerificationTokenTTL.Hours()), existingUser.Email,
+	))
+}
+
 func (u *Usecase) validatePassword(password string) error {
 	if len(password) < 8 {
 		return apperror.BadRequest("password must be at least 8 characters long", nil)
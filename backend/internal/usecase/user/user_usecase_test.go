@@ -1,13 +1,20 @@
 package user_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/afandimsr/cashbook-backend/internal/domain/apperror"
 	"github.com/afandimsr/cashbook-backend/internal/domain/user"
 	uc "github.com/afandimsr/cashbook-backend/internal/usecase/user"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // MockUserRepository is a mock implementation of user.UserRepository
@@ -45,6 +52,11 @@ func (m *MockUserRepository) Update(u user.User) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdateRoles(id int64, roles []string) error {
+	args := m.Called(id, roles)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) DisableTOTP(u user.User) error {
 	args := m.Called(u)
 	return args.Error(0)
@@ -95,7 +107,7 @@ func (m *MockAuthService) Login(email, password string) (bool, error) {
 
 func TestGetByID(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	usecase := uc.New(mockRepo, nil)
+	usecase := uc.New(mockRepo, nil, nil)
 
 	mockUser := user.User{ID: 1, Name: "Test User", Email: "test@example.com"}
 
@@ -123,7 +135,7 @@ func TestGetByID(t *testing.T) {
 
 func TestCreate(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	usecase := uc.New(mockRepo, nil)
+	usecase := uc.New(mockRepo, nil, nil)
 
 	t.Run("Success", func(t *testing.T) {
 		newUser := user.User{Name: "New User", Email: "new@example.com", Password: "password123"}
@@ -148,7 +160,7 @@ func TestCreate(t *testing.T) {
 
 func TestResetPassword(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	usecase := uc.New(mockRepo, nil)
+	usecase := uc.New(mockRepo, nil, nil)
 
 	t.Run("Success", func(t *testing.T) {
 		id := int64(1)
@@ -173,7 +185,7 @@ func TestResetPassword(t *testing.T) {
 
 func TestValidatePassword(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	usecase := uc.New(mockRepo, nil)
+	usecase := uc.New(mockRepo, nil, nil)
 
 	tests := []struct {
 		name     string
@@ -208,26 +220,27 @@ func TestLoginWith2FA(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockAuth := new(MockAuthService)
 	mockMFA := new(MockMFASettingsRepository)
-	usecase := uc.New(mockRepo, mockAuth)
+	usecase := uc.New(mockRepo, mockAuth, nil)
 	usecase.SetMFASettingsRepo(mockMFA)
 
 	t.Run("Success - 2FA Setup Required (TOTPSecret empty)", func(t *testing.T) {
 		email := "test@example.com"
 		password := "password123"
 		mockUser := user.User{
-			ID:          1,
-			Name:        "Test User",
-			Email:       email,
-			Password:    "anypassword",
-			IsActive:    true,
-			TOTPSecret:  "",
-			TOTPEnabled: false,
+			ID:            1,
+			Name:          "Test User",
+			Email:         email,
+			Password:      "anypassword",
+			IsActive:      true,
+			EmailVerified: true,
+			TOTPSecret:    "",
+			TOTPEnabled:   false,
 		}
 
 		mockAuth.On("Login", email, password).Return(true, nil)
 		mockRepo.On("FindByEmail", email).Return(mockUser, nil)
 
-		response, err := usecase.Login(email, password)
+		response, err := usecase.Login(email, password, user.Device{})
 
 		assert.NoError(t, err)
 		assert.True(t, response.Requires2FA)
@@ -241,19 +254,20 @@ func TestLoginWith2FA(t *testing.T) {
 		email := "test2@example.com"
 		password := "password123"
 		mockUser := user.User{
-			ID:          2,
-			Name:        "Test User 2",
-			Email:       email,
-			Password:    "anypassword",
-			IsActive:    true,
-			TOTPSecret:  "JBSWY3DPEHPK3PXP",
-			TOTPEnabled: true,
+			ID:            2,
+			Name:          "Test User 2",
+			Email:         email,
+			Password:      "anypassword",
+			IsActive:      true,
+			EmailVerified: true,
+			TOTPSecret:    "JBSWY3DPEHPK3PXP",
+			TOTPEnabled:   true,
 		}
 
 		mockAuth.On("Login", email, password).Return(true, nil)
 		mockRepo.On("FindByEmail", email).Return(mockUser, nil)
 
-		response, err := usecase.Login(email, password)
+		response, err := usecase.Login(email, password, user.Device{})
 
 		assert.NoError(t, err)
 		assert.True(t, response.Requires2FA)
@@ -267,19 +281,20 @@ func TestLoginWith2FA(t *testing.T) {
 		email := "test3@example.com"
 		password := "password123"
 		mockUser := user.User{
-			ID:          3,
-			Name:        "Test User 3",
-			Email:       email,
-			Password:    "anypassword",
-			IsActive:    true,
-			TOTPSecret:  "JBSWY3DPEHPK3PXP",
-			TOTPEnabled: true,
+			ID:            3,
+			Name:          "Test User 3",
+			Email:         email,
+			Password:      "anypassword",
+			IsActive:      true,
+			EmailVerified: true,
+			TOTPSecret:    "JBSWY3DPEHPK3PXP",
+			TOTPEnabled:   true,
 		}
 
 		mockAuth.On("Login", email, password).Return(true, nil)
 		mockRepo.On("FindByEmail", email).Return(mockUser, nil)
 
-		response, err := usecase.Login(email, password)
+		response, err := usecase.Login(email, password, user.Device{})
 
 		assert.NoError(t, err)
 		assert.True(t, response.Requires2FA)
@@ -294,10 +309,464 @@ func TestLoginWith2FA(t *testing.T) {
 
 		mockRepo.On("FindByEmail", email).Return(user.User{}, errors.New("user not found"))
 
-		_, err := usecase.Login(email, password)
+		_, err := usecase.Login(email, password, user.Device{})
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid credentials")
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+// MockTokenRepository keeps emailed tokens in memory.
+type MockTokenRepository struct {
+	tokens map[string]mockToken
+}
+
+type mockToken struct {
+	userID    int64
+	purpose   string
+	expiresAt time.Time
+	used      bool
+}
+
+func newMockTokenRepository() *MockTokenRepository {
+	return &MockTokenRepository{tokens: map[string]mockToken{}}
+}
+
+func (m *MockTokenRepository) Create(userID int64, purpose, tokenHash string, expiresAt time.Time) error {
+	for hash, t := range m.tokens {
+		if t.userID == userID && t.purpose == purpose && !t.used {
+			delete(m.tokens, hash)
+		}
+	}
+	m.tokens[tokenHash] = mockToken{userID: userID, purpose: purpose, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *MockTokenRepository) Consume(purpose, tokenHash string, now time.Time) (int64, error) {
+	t, ok := m.tokens[tokenHash]
+	if !ok || t.used || t.purpose != purpose || !now.Before(t.expiresAt) {
+		return 0, user.ErrTokenNotFound
+	}
+	t.used = true
+	m.tokens[tokenHash] = t
+	return t.userID, nil
+}
+
+// MockMailer records the emails it is asked to send.
+type MockMailer struct {
+	sent []sentMail
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+func (m *MockMailer) Send(to, subject, body string) error {
+	m.sent = append(m.sent, sentMail{to, subject, body})
+	return nil
+}
+
+var verifyLink = regexp.MustCompile(`https://app\.example\.com/verify-email\?token=([0-9a-f]+)`)
+
+func TestRegister(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokens := newMockTokenRepository()
+	mailer := &MockMailer{}
+	usecase := uc.New(mockRepo, nil, nil)
+	usecase.SetMailer(tokens, mailer, "https://app.example.com/")
+
+	err := usecase.Register(user.RegisterRequest{Name: "Alice", Email: "alice@example.com", Password: "password"})
+	assertAppError(t, err, 400)
+
+	var saved user.User
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user.User{}, errors.New("user not found")).Once()
+	mockRepo.On("Save", mock.AnythingOfType("user.User")).Run(func(args mock.Arguments) {
+		saved = args.Get(0).(user.User)
+	}).Return(nil).Once()
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user.User{ID: 5, Name: "Alice", Email: "alice@example.com", IsActive: true}, nil).Once()
+
+	require.NoError(t, usecase.Register(user.RegisterRequest{Name: " Alice ", Email: " alice@example.com ", Password: "Secret#123"}))
+	assert.Equal(t, "Alice", saved.Name)
+	assert.Equal(t, []string{"USER"}, saved.Roles)
+	assert.True(t, saved.IsActive)
+	assert.False(t, saved.EmailVerified)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(saved.Password), []byte("Secret#123")))
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "alice@example.com", mailer.sent[0].to)
+	link := verifyLink.FindStringSubmatch(mailer.sent[0].body)
+	require.NotNil(t, link, mailer.sent[0].body)
+	assert.NotContains(t, tokens.tokens, link[1], "only the hash is stored")
+
+	mockRepo.On("FindByID", int64(5)).Return(user.User{ID: 5, Email: "alice@example.com", IsActive: true}, nil).Once()
+	mockRepo.On("Update", mock.MatchedBy(func(u user.User) bool { return u.ID == 5 && u.EmailVerified })).Return(nil).Once()
+	require.NoError(t, usecase.VerifyEmail(link[1]))
+	assertAppError(t, usecase.VerifyEmail(link[1]), 400)
+	assertAppError(t, usecase.VerifyEmail("unknown"), 400)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRegisterExistingEmail(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokens := newMockTokenRepository()
+	mailer := &MockMailer{}
+	usecase := uc.New(mockRepo, nil, nil)
+	usecase.SetMailer(tokens, mailer, "https://app.example.com")
+	req := user.RegisterRequest{Name: "Mallory", Email: "alice@example.com", Password: "Secret#123"}
+
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user.User{ID: 5, Name: "Alice", Email: "alice@example.com", IsActive: true, EmailVerified: true}, nil).Once()
+	require.NoError(t, usecase.Register(req))
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "You already have a Cashbook account", mailer.sent[0].subject)
+	assert.Nil(t, verifyLink.FindStringSubmatch(mailer.sent[0].body))
+	assert.Empty(t, tokens.tokens)
+
+	// an unverified account is taken over by the new signup and gets a
+	// fresh link, and earlier ones stop working
+	var updated user.User
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user.User{ID: 5, Name: "Alice", Email: "alice@example.com", Password: "alice-hash", GoogleID: "g-5", IsActive: true}, nil).Times(2)
+	mockRepo.On("Update", mock.AnythingOfType("user.User")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(user.User)
+	}).Return(nil).Once()
+	require.NoError(t, usecase.Register(req))
+	assert.Equal(t, int64(5), updated.ID)
+	assert.Equal(t, "Mallory", updated.Name)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("Secret#123")))
+	assert.Empty(t, updated.GoogleID)
+	assert.False(t, updated.EmailVerified)
+	require.NoError(t, usecase.ResendVerification("alice@example.com"))
+	require.Len(t, mailer.sent, 3)
+	first := verifyLink.FindStringSubmatch(mailer.sent[1].body)
+	second := verifyLink.FindStringSubmatch(mailer.sent[2].body)
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	assert.Len(t, tokens.tokens, 1)
+	assertAppError(t, usecase.VerifyEmail(first[1]), 400)
+
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestResendVerificationUnknownEmail(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mailer := &MockMailer{}
+	usecase := uc.New(mockRepo, nil, nil)
+	usecase.SetMailer(newMockTokenRepository(), mailer, "https://app.example.com")
+
+	mockRepo.On("FindByEmail", "nobody@example.com").Return(user.User{}, errors.New("user not found"))
+	require.NoError(t, usecase.ResendVerification("nobody@example.com"))
+	assert.Empty(t, mailer.sent)
+}
+
+func TestVerifyEmailRejectsExpiredToken(t *testing.T) {
+	tokens := newMockTokenRepository()
+	usecase := uc.New(new(MockUserRepository), nil, nil)
+	usecase.SetMailer(tokens, &MockMailer{}, "https://app.example.com")
+
+	hash := hexSHA256("expired")
+	require.NoError(t, tokens.Create(5, user.TokenVerifyEmail, hash, time.Now().Add(-time.Minute)))
+	assertAppError(t, usecase.VerifyEmail("expired"), 400)
+	assert.False(t, tokens.tokens[hash].used)
+}
+
+func TestLoginRejectsUnverifiedEmail(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	usecase := uc.New(mockRepo, nil, nil)
+	hashed, err := bcrypt.GenerateFromPassword([]byte("Secret#123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user.User{ID: 5, Email: "alice@example.com", Password: string(hashed), IsActive: true}, nil)
+
+	_, err = usecase.Login("alice@example.com", "wrong", user.Device{})
+	assertAppError(t, err, 401)
+
+	_, err = usecase.Login("alice@example.com", "Secret#123", user.Device{})
+	assertAppError(t, err, 403)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.AuthEmailUnverified, appErr.ErrorCode)
+}
+
+var resetLink = regexp.MustCompile(`https://app\.example\.com/reset-password\?token=([0-9a-f]+)`)
+
+func TestForgotPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokens := newMockTokenRepository()
+	mailer := &MockMailer{}
+	usecase := uc.New(mockRepo, nil, nil)
+	usecase.SetMailer(tokens, mailer, "https://app.example.com")
+
+	mockRepo.On("FindByEmail", "nobody@example.com").Return(user.User{}, errors.New("user not found"))
+	mockRepo.On("FindByEmail", "disabled@example.com").Return(user.User{ID: 6, Email: "disabled@example.com"}, nil)
+	require.NoError(t, usecase.ForgotPassword("nobody@example.com"))
+	require.NoError(t, usecase.ForgotPassword("disabled@example.com"))
+	assert.Empty(t, mailer.sent)
+	assert.Empty(t, tokens.tokens)
+
+	mockRepo.On("FindByEmail", "alice@example.com").Return(user.User{ID: 5, Name: "Alice", Email: "alice@example.com", IsActive: true}, nil)
+	require.NoError(t, usecase.ForgotPassword(" alice@example.com "))
+	require.NoError(t, usecase.ForgotPassword("alice@example.com"))
+	require.Len(t, mailer.sent, 2)
+	assert.Equal(t, "alice@example.com", mailer.sent[0].to)
+	assert.Contains(t, mailer.sent[0].body, "expires in 30 minutes")
+	require.NotNil(t, resetLink.FindStringSubmatch(mailer.sent[0].body))
+	require.NotNil(t, resetLink.FindStringSubmatch(mailer.sent[1].body))
+	assert.Len(t, tokens.tokens, 1, "a new link invalidates the earlier one")
+	for _, token := range tokens.tokens {
+		assert.Equal(t, user.TokenResetPassword, token.purpose)
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), token.expiresAt, time.Minute)
+	}
+}
+
+func TestResetPasswordWithToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokens := newMockTokenRepository()
+	mailer := &MockMailer{}
+	sessionRepo := newMockSessionRepository()
+	sessions := uc.NewSessionUsecase(sessionRepo, mockRepo)
+	usecase := uc.New(mockRepo, nil, sessions)
+	usecase.SetMailer(tokens, mailer, "https://app.example.com")
+
+	alice := user.User{ID: 5, Name: "Alice", Email: "alice@example.com", Password: "old-hash", IsActive: true}
+	_, err := sessions.Start(alice, user.Device{UserAgent: "laptop"})
+	require.NoError(t, err)
+	_, err = sessions.Start(alice, user.Device{UserAgent: "phone"})
+	require.NoError(t, err)
+
+	mockRepo.On("FindByEmail", "alice@example.com").Return(alice, nil)
+	require.NoError(t, usecase.ForgotPassword("alice@example.com"))
+	link := resetLink.FindStringSubmatch(mailer.sent[0].body)
+	require.NotNil(t, link)
+
+	assertAppError(t, usecase.ResetPasswordWithToken(link[1], "weak"), 400)
+	assertAppError(t, usecase.ResetPasswordWithToken("unknown", "N3w#password"), 400)
+
+	var updated user.User
+	mockRepo.On("FindByID", int64(5)).Return(alice, nil).Once()
+	mockRepo.On("Update", mock.AnythingOfType("user.User")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(user.User)
+	}).Return(nil).Once()
+	require.NoError(t, usecase.ResetPasswordWithToken(link[1], "N3w#password"), "a rejected password does not use up the token")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("N3w#password")))
+	assert.True(t, updated.EmailVerified)
+
+	active, err := sessions.GetSessions(5, 0)
+	require.NoError(t, err)
+	assert.Empty(t, active, "every session is logged out")
+
+	assertAppError(t, usecase.ResetPasswordWithToken(link[1], "An0ther#password"), 400)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestResetPasswordWithExpiredToken(t *testing.T) {
+	tokens := newMockTokenRepository()
+	usecase := uc.New(new(MockUserRepository), nil, nil)
+	usecase.SetMailer(tokens, &MockMailer{}, "https://app.example.com")
+
+	require.NoError(t, tokens.Create(5, user.TokenResetPassword, hexSHA256("expired"), time.Now().Add(-time.Second)))
+	assertAppError(t, usecase.ResetPasswordWithToken("expired", "N3w#password"), 400)
+}
+
+func TestUpdateKeepsRoles(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	usecase := uc.New(mockRepo, nil, nil)
+
+	mockRepo.On("FindByID", int64(5)).Return(user.User{ID: 5, Email: "alice@example.com", Roles: []string{"USER"}}, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(u user.User) bool {
+		return u.Name == "Alice" && assert.ObjectsAreEqual([]string{"USER"}, u.Roles)
+	})).Return(nil)
+
+	require.NoError(t, usecase.Update(5, user.User{Name: "Alice", Email: "alice@example.com", Roles: []string{"ADMIN"}}))
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateRoles", mock.Anything, mock.Anything)
+}
+
+func TestSetRoles(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	usecase := uc.New(mockRepo, nil, nil)
+
+	assertAppError(t, usecase.SetRoles(1, 5, []string{"ROOT"}), 400)
+	assertAppError(t, usecase.SetRoles(1, 5, []string{" "}), 400)
+	assertAppError(t, usecase.SetRoles(1, 1, []string{"USER"}), 400)
+
+	mockRepo.On("FindByID", int64(9)).Return(user.User{}, errors.New("user not found"))
+	assertAppError(t, usecase.SetRoles(1, 9, []string{"USER"}), 404)
+
+	mockRepo.On("FindByID", int64(5)).Return(user.User{ID: 5}, nil)
+	mockRepo.On("UpdateRoles", int64(5), []string{"ADMIN", "USER"}).Return(nil).Once()
+	require.NoError(t, usecase.SetRoles(1, 5, []string{"admin", "USER", "ADMIN"}))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetProfileHidesPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	usecase := uc.New(mockRepo, nil, nil)
+
+	mockRepo.On("FindByID", int64(5)).Return(user.User{ID: 5, Password: "hash"}, nil)
+	me, err := usecase.GetProfile(5)
+	require.NoError(t, err)
+	assert.Empty(t, me.Password)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	usecase := uc.New(mockRepo, nil, nil)
+	alice := user.User{ID: 5, Name: "Alice", Email: "alice@example.com", Password: "hash", Roles: []string{"USER"}, IsActive: true, EmailVerified: true, BaseCurrency: "IDR", Locale: "id-ID", Timezone: "Asia/Jakarta"}
+	mockRepo.On("FindByID", int64(5)).Return(alice, nil)
+	str := func(s string) *string { return &s }
+
+	for _, req := range []user.ProfileUpdateRequest{
+		{Name: str("  ")},
+		{Locale: str("english please")},
+		{Timezone: str("Mars/Olympus_Mons")},
+		{Timezone: str("")},
+		{Timezone: str("Local")},
+		{BaseCurrency: str("RUPIAH")},
+		{Email: str("")},
+	} {
+		_, err := usecase.UpdateProfile(5, req)
+		assertAppError(t, err, 400)
+	}
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+
+	var updated user.User
+	mockRepo.On("Update", mock.AnythingOfType("user.User")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(user.User)
+	}).Return(nil).Once()
+	me, err := usecase.UpdateProfile(5, user.ProfileUpdateRequest{
+		Name:         str(" Alice Liddell "),
+		Locale:       str("en-GB"),
+		Timezone:     str("Europe/London"),
+		BaseCurrency: str("GBP"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Liddell", updated.Name)
+	assert.Equal(t, "alice@example.com", updated.Email)
+	assert.Equal(t, "en-GB", updated.Locale)
+	assert.Equal(t, "Europe/London", updated.Timezone)
+	assert.Equal(t, "GBP", updated.BaseCurrency)
+	assert.Equal(t, []string{"USER"}, updated.Roles)
+	assert.Equal(t, "hash", updated.Password)
+	assert.Empty(t, me.Password)
+	mockRepo.AssertExpectations(t)
+}
+
+var confirmLink = regexp.MustCompile(`https://app\.example\.com/confirm-email\?token=([0-9a-f]+)`)
+
+func TestUpdateProfileEmailNeedsConfirmation(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokens := newMockTokenRepository()
+	mailer := &MockMailer{}
+	usecase := uc.New(mockRepo, nil, nil)
+	usecase.SetMailer(tokens, mailer, "https://app.example.com")
+	alice := user.User{ID: 5, Name: "Alice", Email: "alice@example.com", IsActive: true, EmailVerified: true}
+	str := func(s string) *string { return &s }
+
+	mockRepo.On("FindByEmail", "bob@example.com").Return(user.User{ID: 6, Email: "bob@example.com"}, nil)
+	mockRepo.On("FindByID", int64(5)).Return(alice, nil).Once()
+	_, err := usecase.UpdateProfile(5, user.ProfileUpdateRequest{Email: str("bob@example.com")})
+	assertAppError(t, err, 409)
+
+	var updated user.User
+	mockRepo.On("Update", mock.AnythingOfType("user.User")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(user.User)
+	}).Return(nil)
+	mockRepo.On("FindByEmail", "alice@new.example.com").Return(user.User{}, errors.New("user not found"))
+	mockRepo.On("FindByID", int64(5)).Return(alice, nil).Once()
+	me, err := usecase.UpdateProfile(5, user.ProfileUpdateRequest{Email: str(" alice@new.example.com ")})
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", me.Email, "the current address stays until the new one is confirmed")
+	assert.Equal(t, "alice@new.example.com", me.PendingEmail)
+	assert.True(t, updated.EmailVerified)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "alice@new.example.com", mailer.sent[0].to)
+	link := confirmLink.FindStringSubmatch(mailer.sent[0].body)
+	require.NotNil(t, link, mailer.sent[0].body)
+	assertAppError(t, usecase.VerifyEmail(link[1]), 400)
+
+	pending := alice
+	pending.PendingEmail = "alice@new.example.com"
+	mockRepo.On("FindByID", int64(5)).Return(pending, nil).Once()
+	require.NoError(t, usecase.ConfirmEmailChange(link[1]))
+	assert.Equal(t, "alice@new.example.com", updated.Email)
+	assert.Empty(t, updated.PendingEmail)
+	assert.True(t, updated.EmailVerified)
+	assertAppError(t, usecase.ConfirmEmailChange(link[1]), 400)
+}
+
+func TestUpdateProfileCancelsEmailChange(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokens := newMockTokenRepository()
+	mailer := &MockMailer{}
+	usecase := uc.New(mockRepo, nil, nil)
+	usecase.SetMailer(tokens, mailer, "https://app.example.com")
+	alice := user.User{ID: 5, Name: "Alice", Email: "alice@example.com", PendingEmail: "alice@new.example.com", EmailVerified: true}
+	current := "alice@example.com"
+
+	require.NoError(t, tokens.Create(5, user.TokenChangeEmail, hexSHA256("leftover"), time.Now().Add(time.Hour)))
+	mockRepo.On("FindByID", int64(5)).Return(alice, nil).Once()
+	mockRepo.On("Update", mock.MatchedBy(func(u user.User) bool { return u.PendingEmail == "" })).Return(nil).Once()
+	me, err := usecase.UpdateProfile(5, user.ProfileUpdateRequest{Email: &current})
+	require.NoError(t, err)
+	assert.Empty(t, me.PendingEmail)
+	assert.Empty(t, mailer.sent)
+
+	// a link sent before the change was cancelled no longer applies
+	cancelled := alice
+	cancelled.PendingEmail = ""
+	mockRepo.On("FindByID", int64(5)).Return(cancelled, nil).Once()
+	assertAppError(t, usecase.ConfirmEmailChange("leftover"), 400)
+	mockRepo.AssertExpectations(t)
+}
+
+func hexSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestChangePassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	sessionRepo := newMockSessionRepository()
+	sessions := uc.NewSessionUsecase(sessionRepo, mockRepo)
+	usecase := uc.New(mockRepo, nil, sessions)
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("Old#password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	alice := user.User{ID: 5, Email: "alice@example.com", Password: string(hashed), IsActive: true}
+	_, err = sessions.Start(alice, user.Device{UserAgent: "laptop"})
+	require.NoError(t, err)
+	_, err = sessions.Start(alice, user.Device{UserAgent: "phone"})
+	require.NoError(t, err)
+	mockRepo.On("FindByID", int64(5)).Return(alice, nil)
+
+	err = usecase.ChangePassword(5, 1, user.PasswordChangeRequest{CurrentPassword: "wrong", NewPassword: "N3w#password"})
+	assertAppError(t, err, 400)
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.AuthInvalidPassword, appErr.ErrorCode)
+	assertAppError(t, usecase.ChangePassword(5, 1, user.PasswordChangeRequest{CurrentPassword: "Old#password1", NewPassword: "weak"}), 400)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+
+	var updated user.User
+	mockRepo.On("Update", mock.AnythingOfType("user.User")).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(user.User)
+	}).Return(nil).Once()
+	require.NoError(t, usecase.ChangePassword(5, 1, user.PasswordChangeRequest{CurrentPassword: "Old#password1", NewPassword: "N3w#password"}))
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("N3w#password")))
+
+	active, err := sessions.GetSessions(5, 1)
+	require.NoError(t, err)
+	if assert.Len(t, active, 1, "other sessions are logged out") {
+		assert.True(t, active[0].Current)
+	}
+}
+
+func TestChangePasswordWithoutPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	usecase := uc.New(mockRepo, nil, nil)
+
+	// accounts created through Google have no password to check
+	mockRepo.On("FindByID", int64(5)).Return(user.User{ID: 5, GoogleID: "g-5"}, nil)
+	assertAppError(t, usecase.ChangePassword(5, 1, user.PasswordChangeRequest{CurrentPassword: "", NewPassword: "N3w#password"}), 400)
+}
@@ -0,0 +1,10 @@
+ALTER TABLE recurring_transactions DROP COLUMN IF EXISTS account_id;
+
+DELETE FROM transactions WHERE transfer_id IS NOT NULL;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_category_or_transfer;
+ALTER TABLE transactions ALTER COLUMN category_id SET NOT NULL;
+ALTER TABLE transactions DROP COLUMN IF EXISTS transfer_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS account_id;
+
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS accounts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL DEFAULT 'cash' CHECK (type IN ('cash', 'bank', 'ewallet', 'credit_card', 'other')),
+    opening_balance DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_user_id ON accounts(user_id);
+
+CREATE TABLE IF NOT EXISTS transfers (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    to_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    note TEXT,
+    date TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_account_id <> to_account_id)
+);
+
+-- every existing user gets a default cash account that owns their history
+INSERT INTO accounts (user_id, name, type) SELECT id, 'Cash', 'cash' FROM users;
+
+ALTER TABLE transactions ADD COLUMN account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE;
+UPDATE transactions t SET account_id = a.id FROM accounts a WHERE a.user_id = t.user_id;
+ALTER TABLE transactions ALTER COLUMN account_id SET NOT NULL;
+
+-- transfer legs carry a transfer_id instead of a category
+ALTER TABLE transactions ADD COLUMN transfer_id BIGINT REFERENCES transfers(id) ON DELETE CASCADE;
+ALTER TABLE transactions ALTER COLUMN category_id DROP NOT NULL;
+ALTER TABLE transactions ADD CONSTRAINT transactions_category_or_transfer
+    CHECK ((transfer_id IS NULL AND category_id IS NOT NULL) OR (transfer_id IS NOT NULL AND category_id IS NULL));
+
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions(account_id);
+
+ALTER TABLE recurring_transactions ADD COLUMN account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE;
+UPDATE recurring_transactions r SET account_id = a.id FROM accounts a WHERE a.user_id = r.user_id;
+ALTER TABLE recurring_transactions ALTER COLUMN account_id SET NOT NULL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS fx_rates;
+
+ALTER TABLE budgets DROP COLUMN IF EXISTS currency;
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
+ALTER TABLE accounts DROP COLUMN IF EXISTS currency;
+ALTER TABLE users DROP COLUMN IF EXISTS base_currency;
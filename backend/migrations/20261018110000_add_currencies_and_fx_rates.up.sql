@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN base_currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE accounts ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- a transaction is always in the currency of its account
+ALTER TABLE transactions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+UPDATE transactions t SET currency = a.currency FROM accounts a WHERE a.id = t.account_id;
+
+ALTER TABLE budgets ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- one unit of base is worth rate units of quote on rate_date
+CREATE TABLE IF NOT EXISTS fx_rates (
+    rate_date DATE NOT NULL,
+    base CHAR(3) NOT NULL,
+    quote CHAR(3) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (base, quote, rate_date),
+    CHECK (base <> quote)
+);
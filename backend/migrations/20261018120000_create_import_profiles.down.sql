@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_account_date;
+DROP TABLE IF EXISTS import_profiles;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS import_profiles (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    mapping JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
+-- duplicate detection looks up existing transactions by account and day
+CREATE INDEX IF NOT EXISTS idx_transactions_account_date ON transactions(account_id, date);
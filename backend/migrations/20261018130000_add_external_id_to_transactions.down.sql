@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_account_external_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,5 @@
+-- identifier assigned by the bank (OFX FITID); re-importing a statement must not duplicate it
+ALTER TABLE transactions ADD COLUMN external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_account_external_id
+    ON transactions(account_id, external_id) WHERE external_id IS NOT NULL;
@@ -0,0 +1,15 @@
+-- transaction_id is set to NULL rather than cascaded so that the stored files
+-- of deleted transactions can still be found and removed
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    transaction_id BIGINT REFERENCES transactions(id) ON DELETE SET NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_transaction_id ON attachments(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_orphaned ON attachments(id) WHERE transaction_id IS NULL;
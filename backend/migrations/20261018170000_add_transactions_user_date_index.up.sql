@@ -0,0 +1,2 @@
+-- dashboard and report totals are summed per user over a date range
+CREATE INDEX IF NOT EXISTS idx_transactions_user_date ON transactions(user_id, date);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_budgets_auto_copy;
+ALTER TABLE budgets DROP COLUMN IF EXISTS auto_copy;
+ALTER TABLE budgets DROP COLUMN IF EXISTS carried_over;
+ALTER TABLE budgets DROP COLUMN IF EXISTS rollover;
@@ -0,0 +1,6 @@
+ALTER TABLE budgets ADD COLUMN rollover VARCHAR(20) NOT NULL DEFAULT 'none' CHECK (rollover IN ('none', 'unspent', 'all'));
+ALTER TABLE budgets ADD COLUMN carried_over DECIMAL(15, 2) NOT NULL DEFAULT 0;
+ALTER TABLE budgets ADD COLUMN auto_copy BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- the month-start job looks for the auto-copy budgets of the previous month
+CREATE INDEX IF NOT EXISTS idx_budgets_auto_copy ON budgets(year, month) WHERE auto_copy;
@@ -0,0 +1,18 @@
+-- only the monthly category budgets fit the old table
+DELETE FROM budgets WHERE period <> 'month' OR category_id IS NULL;
+
+DROP INDEX IF EXISTS idx_budgets_auto_copy;
+DROP INDEX IF EXISTS idx_budgets_user_period_category;
+
+ALTER TABLE budgets ADD COLUMN month INTEGER CHECK (month >= 1 AND month <= 12);
+ALTER TABLE budgets ADD COLUMN year INTEGER;
+UPDATE budgets SET month = EXTRACT(MONTH FROM start_date), year = EXTRACT(YEAR FROM start_date);
+ALTER TABLE budgets ALTER COLUMN month SET NOT NULL;
+ALTER TABLE budgets ALTER COLUMN year SET NOT NULL;
+ALTER TABLE budgets ALTER COLUMN category_id SET NOT NULL;
+ALTER TABLE budgets ADD CONSTRAINT budgets_user_id_category_id_month_year_key UNIQUE (user_id, category_id, month, year);
+
+ALTER TABLE budgets DROP COLUMN start_date;
+ALTER TABLE budgets DROP COLUMN period;
+
+CREATE INDEX IF NOT EXISTS idx_budgets_auto_copy ON budgets(year, month) WHERE auto_copy;
@@ -0,0 +1,15 @@
+ALTER TABLE budgets ADD COLUMN period VARCHAR(10) NOT NULL DEFAULT 'month' CHECK (period IN ('week', 'month', 'quarter', 'year'));
+ALTER TABLE budgets ADD COLUMN start_date DATE;
+UPDATE budgets SET start_date = make_date(year, month, 1);
+ALTER TABLE budgets ALTER COLUMN start_date SET NOT NULL;
+
+-- a budget without a category limits the total spending of the period
+ALTER TABLE budgets ALTER COLUMN category_id DROP NOT NULL;
+
+DROP INDEX IF EXISTS idx_budgets_auto_copy;
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_user_id_category_id_month_year_key;
+ALTER TABLE budgets DROP COLUMN month;
+ALTER TABLE budgets DROP COLUMN year;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_period_category ON budgets(user_id, period, start_date, COALESCE(category_id, 0));
+CREATE INDEX IF NOT EXISTS idx_budgets_auto_copy ON budgets(period, start_date) WHERE auto_copy;
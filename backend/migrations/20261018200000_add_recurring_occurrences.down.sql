@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_recurring_occurrence;
+ALTER TABLE transactions DROP COLUMN IF EXISTS occurrence_date;
+ALTER TABLE transactions DROP COLUMN IF EXISTS recurring_id;
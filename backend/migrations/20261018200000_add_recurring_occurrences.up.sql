@@ -0,0 +1,5 @@
+-- each occurrence of a recurring template generates at most one transaction,
+-- so catching up on missed occurrences can safely be retried
+ALTER TABLE transactions ADD COLUMN recurring_id BIGINT REFERENCES recurring_transactions(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD COLUMN occurrence_date DATE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_recurring_occurrence ON transactions(recurring_id, occurrence_date);
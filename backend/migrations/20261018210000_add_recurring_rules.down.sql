@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_recurring_next_occurrence;
+ALTER TABLE recurring_transactions DROP COLUMN IF EXISTS next_occurrence;
+ALTER TABLE recurring_transactions DROP COLUMN IF EXISTS rule;
+DELETE FROM recurring_transactions WHERE frequency = 'yearly';
+ALTER TABLE recurring_transactions DROP CONSTRAINT IF EXISTS recurring_transactions_frequency_check;
+ALTER TABLE recurring_transactions ADD CONSTRAINT recurring_transactions_frequency_check CHECK (frequency IN ('daily', 'weekly', 'monthly'));
@@ -0,0 +1,10 @@
+-- templates follow an RRULE-style rule; next_occurrence is what the
+-- scheduler looks up and is NULL once the rule has ended
+ALTER TABLE recurring_transactions DROP CONSTRAINT IF EXISTS recurring_transactions_frequency_check;
+ALTER TABLE recurring_transactions ADD CONSTRAINT recurring_transactions_frequency_check CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly'));
+ALTER TABLE recurring_transactions ADD COLUMN rule TEXT NOT NULL DEFAULT '';
+ALTER TABLE recurring_transactions ADD COLUMN next_occurrence TIMESTAMP WITH TIME ZONE;
+UPDATE recurring_transactions SET rule = 'FREQ=' || UPPER(frequency);
+-- the next run recomputes the real next occurrence from last_processed
+UPDATE recurring_transactions SET next_occurrence = COALESCE(last_processed, start_date);
+CREATE INDEX IF NOT EXISTS idx_recurring_next_occurrence ON recurring_transactions(next_occurrence) WHERE next_occurrence IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS recurring_events;
+DROP TABLE IF EXISTS recurring_exceptions;
+DROP INDEX IF EXISTS idx_recurring_next_occurrence;
+ALTER TABLE recurring_transactions DROP COLUMN IF EXISTS paused;
+CREATE INDEX IF NOT EXISTS idx_recurring_next_occurrence ON recurring_transactions(next_occurrence) WHERE next_occurrence IS NOT NULL;
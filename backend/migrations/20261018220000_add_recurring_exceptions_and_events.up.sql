@@ -0,0 +1,25 @@
+ALTER TABLE recurring_transactions ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE;
+DROP INDEX IF EXISTS idx_recurring_next_occurrence;
+CREATE INDEX IF NOT EXISTS idx_recurring_next_occurrence ON recurring_transactions(next_occurrence) WHERE next_occurrence IS NOT NULL AND NOT paused;
+
+-- a skipped occurrence, or one generated with another amount
+CREATE TABLE recurring_exceptions (
+    recurring_id BIGINT NOT NULL REFERENCES recurring_transactions(id) ON DELETE CASCADE,
+    occurrence_date DATE NOT NULL,
+    skip BOOLEAN NOT NULL DEFAULT FALSE,
+    amount DECIMAL(15, 2),
+    PRIMARY KEY (recurring_id, occurrence_date),
+    CHECK (skip OR amount IS NOT NULL)
+);
+
+-- the audit log of changes to a template and of what happened to each of
+-- its occurrences
+CREATE TABLE recurring_events (
+    id BIGSERIAL PRIMARY KEY,
+    recurring_id BIGINT NOT NULL REFERENCES recurring_transactions(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    occurrence TIMESTAMP WITH TIME ZONE,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_events_recurring ON recurring_events(recurring_id, id);
@@ -0,0 +1,14 @@
+-- the processing runs that failed on some occurrence, with what they did
+-- with each one
+CREATE TABLE recurring_runs (
+    id BIGSERIAL PRIMARY KEY,
+    -- NULL when the run covered every user
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    generated INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    items JSONB NOT NULL DEFAULT '[]'
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_runs_started ON recurring_runs(started_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS session_tokens;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,14 @@
+-- accounts that existed before self-service signup count as verified
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+UPDATE users SET email_verified_at = NOW();
+
+-- single-use tokens emailed to users, by SHA-256 hash
+CREATE TABLE user_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens(user_id, purpose) WHERE used_at IS NULL;
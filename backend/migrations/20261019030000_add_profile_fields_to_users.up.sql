@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'id-ID';
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Jakarta';
+-- the address the user asked to change email to, until they verify it
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(255);
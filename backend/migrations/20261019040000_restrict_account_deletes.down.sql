@@ -0,0 +1,11 @@
+ALTER TABLE transfers DROP CONSTRAINT IF EXISTS transfers_to_account_id_fkey;
+ALTER TABLE transfers ADD CONSTRAINT transfers_to_account_id_fkey
+    FOREIGN KEY (to_account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+
+ALTER TABLE transfers DROP CONSTRAINT IF EXISTS transfers_from_account_id_fkey;
+ALTER TABLE transfers ADD CONSTRAINT transfers_from_account_id_fkey
+    FOREIGN KEY (from_account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_account_id_fkey;
+ALTER TABLE transactions ADD CONSTRAINT transactions_account_id_fkey
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
@@ -0,0 +1,13 @@
+-- an account that still owns history must not take it down with it; NO ACTION
+-- is checked at the end of the statement, so deleting a user still cascades
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_account_id_fkey;
+ALTER TABLE transactions ADD CONSTRAINT transactions_account_id_fkey
+    FOREIGN KEY (account_id) REFERENCES accounts(id);
+
+ALTER TABLE transfers DROP CONSTRAINT IF EXISTS transfers_from_account_id_fkey;
+ALTER TABLE transfers ADD CONSTRAINT transfers_from_account_id_fkey
+    FOREIGN KEY (from_account_id) REFERENCES accounts(id);
+
+ALTER TABLE transfers DROP CONSTRAINT IF EXISTS transfers_to_account_id_fkey;
+ALTER TABLE transfers ADD CONSTRAINT transfers_to_account_id_fkey
+    FOREIGN KEY (to_account_id) REFERENCES accounts(id);
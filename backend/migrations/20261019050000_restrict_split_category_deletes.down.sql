@@ -0,0 +1,3 @@
+ALTER TABLE transaction_splits DROP CONSTRAINT IF EXISTS transaction_splits_category_id_fkey;
+ALTER TABLE transaction_splits ADD CONSTRAINT transaction_splits_category_id_fkey
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- a split must not vanish from under its parent, or the splits stop adding up
+-- to the transaction amount; NO ACTION still lets a user delete cascade
+ALTER TABLE transaction_splits DROP CONSTRAINT IF EXISTS transaction_splits_category_id_fkey;
+ALTER TABLE transaction_splits ADD CONSTRAINT transaction_splits_category_id_fkey
+    FOREIGN KEY (category_id) REFERENCES categories(id);
@@ -0,0 +1,3 @@
+ALTER TABLE recurring_transactions DROP CONSTRAINT IF EXISTS recurring_transactions_account_id_fkey;
+ALTER TABLE recurring_transactions ADD CONSTRAINT recurring_transactions_account_id_fkey
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
@@ -7,7 +7,7 @@ export const useTransactions = () => {
     const {
         transactions,
         total,
-        totalAmount,
+        totals,
         isLoading,
         error,
         fetchTransactions,
@@ -69,7 +69,7 @@ export const useTransactions = () => {
     return {
         transactions,
         total,
-        totalAmount,
+        totals,
         isLoading,
         error,
         page,
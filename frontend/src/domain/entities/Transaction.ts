@@ -8,6 +8,13 @@ export interface Transaction {
     type: 'income' | 'expense';
 }
 
+// CurrencyTotal sums the filtered income and expense of one currency.
+export interface CurrencyTotal {
+    currency: string;
+    total_income: number;
+    total_expense: number;
+}
+
 export interface DashboardSummary {
     total_income: number;
     total_expense: number;
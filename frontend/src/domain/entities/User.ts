@@ -16,6 +16,8 @@ export interface TwoFASetupResponse {
 
 export interface LoginResponse {
     token?: string;
+    refresh_token?: string;
+    expires_in?: number;
     requires_2fa?: boolean;
     temp_token?: string;
 }
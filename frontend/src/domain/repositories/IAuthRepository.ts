@@ -6,6 +6,7 @@ export interface IAuthRepository {
     getUser(): Promise<User | null>;
     verify2FA(tempToken: string, code: string): Promise<{ token: string; user: User }>;
     verifyBackupCode(tempToken: string, code: string): Promise<{ token: string; user: User }>;
+    exchangeGoogleCode(code: string): Promise<{ token: string; user: User }>;
     setup2FA(): Promise<TwoFASetupResponse>;
     verifySetup2FA(code: string): Promise<void>;
     disable2FA(): Promise<void>;
@@ -4,7 +4,40 @@ import { useAuthStore } from '../state/authStore';
 
 const BASE_URL = config.API_URL || '/api/v1';
 
-async function request<T>(path: string, options: RequestInit = {}): Promise<T> {
+let refreshing: Promise<boolean> | null = null;
+
+// refreshSession swaps the refresh token for a new pair of tokens. Concurrent
+// callers share one request, since the backend revokes the session when a
+// refresh token is presented twice.
+function refreshSession(): Promise<boolean> {
+    const refreshToken = tokenStorage.getRefreshToken();
+    if (!refreshToken) return Promise.resolve(false);
+
+    if (!refreshing) {
+        refreshing = fetch(`${BASE_URL}/auth/refresh`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken }),
+        })
+            .then(async (response) => {
+                if (!response.ok) return false;
+                const data = (await response.json())?.data;
+                if (!data?.token || !data?.refresh_token) return false;
+
+                tokenStorage.setToken(data.token);
+                tokenStorage.setRefreshToken(data.refresh_token);
+                useAuthStore.setState({ token: data.token });
+                return true;
+            })
+            .catch(() => false)
+            .finally(() => {
+                refreshing = null;
+            });
+    }
+    return refreshing;
+}
+
+async function request<T>(path: string, options: RequestInit = {}, retried = false): Promise<T> {
     const token = tokenStorage.getToken();
     const headers = new Headers(options.headers);
 
@@ -21,8 +54,13 @@ async function request<T>(path: string, options: RequestInit = {}): Promise<T> {
         headers,
     });
 
-    // Handle 401 Unauthorized globally
+    // Handle 401 Unauthorized globally: the access token is short-lived, so
+    // try once with a refreshed one before ending the session
     if (response.status === 401) {
+        if (token && !retried && await refreshSession()) {
+            return request<T>(path, options, true);
+        }
+
         useAuthStore.getState().logout();
         throw new Error('Session expired. Please login again.');
     }
@@ -27,6 +27,9 @@ export class AuthRepository implements IAuthRepository {
         }
 
         tokenStorage.setToken(response.token);
+        if (response.refresh_token) {
+            tokenStorage.setRefreshToken(response.refresh_token);
+        }
         return { token: response.token };
     }
 
@@ -47,6 +50,9 @@ export class AuthRepository implements IAuthRepository {
         const user = mapJwtToUser(payload);
 
         tokenStorage.setToken(response.token);
+        if (response.refresh_token) {
+            tokenStorage.setRefreshToken(response.refresh_token);
+        }
         return { token: response.token, user };
     }
 
@@ -67,6 +73,29 @@ export class AuthRepository implements IAuthRepository {
         const user = mapJwtToUser(payload);
 
         tokenStorage.setToken(response.token);
+        if (response.refresh_token) {
+            tokenStorage.setRefreshToken(response.refresh_token);
+        }
+        return { token: response.token, user };
+    }
+
+    async exchangeGoogleCode(code: string): Promise<{ token: string; user: User }> {
+        const response = await apiClient.post<LoginResponse>('/auth/google/exchange', { code });
+
+        if (!response?.token) {
+            throw new Error('Google login failed: token not returned');
+        }
+
+        const payload = safeDecodeJwt(response.token);
+        if (!payload) {
+            throw new Error('Google login failed: invalid token');
+        }
+        const user = mapJwtToUser(payload);
+
+        tokenStorage.setToken(response.token);
+        if (response.refresh_token) {
+            tokenStorage.setRefreshToken(response.refresh_token);
+        }
         return { token: response.token, user };
     }
 
@@ -87,7 +116,17 @@ export class AuthRepository implements IAuthRepository {
     }
 
     async logout(): Promise<void> {
+        const refreshToken = tokenStorage.getRefreshToken();
         tokenStorage.clearToken();
+
+        // Revoke the session on the server too; the local tokens are gone either way
+        if (refreshToken) {
+            try {
+                await apiClient.post('/auth/logout', { refresh_token: refreshToken });
+            } catch (err) {
+                console.error('Failed to revoke session', err);
+            }
+        }
     }
 
     async getUser(): Promise<User | null> {
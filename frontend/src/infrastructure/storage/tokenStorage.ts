@@ -1,4 +1,5 @@
 const TOKEN_KEY = 'auth_token';
+const REFRESH_TOKEN_KEY = 'refresh_token';
 
 export const tokenStorage = {
     getToken: (): string | null => {
@@ -7,7 +8,14 @@ export const tokenStorage = {
     setToken: (token: string): void => {
         localStorage.setItem(TOKEN_KEY, token);
     },
+    getRefreshToken: (): string | null => {
+        return localStorage.getItem(REFRESH_TOKEN_KEY);
+    },
+    setRefreshToken: (token: string): void => {
+        localStorage.setItem(REFRESH_TOKEN_KEY, token);
+    },
     clearToken: (): void => {
         localStorage.removeItem(TOKEN_KEY);
+        localStorage.removeItem(REFRESH_TOKEN_KEY);
     },
 };
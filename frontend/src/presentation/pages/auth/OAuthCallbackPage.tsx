@@ -1,17 +1,23 @@
-import React, { useEffect } from 'react';
+import React, { useEffect, useRef } from 'react';
 import { useNavigate, useSearchParams } from 'react-router-dom';
 import { useAuthStore } from '../../../state/authStore';
 import { Box, CircularProgress, Typography, Stack } from '@mui/material';
 
 export const OAuthCallbackPage: React.FC = () => {
     const [searchParams] = useSearchParams();
-    const { handleOAuthSuccess } = useAuthStore();
+    const { handleOAuthCode } = useAuthStore();
     const navigate = useNavigate();
+    // The login code works only once, so don't exchange it again when the
+    // effect re-runs
+    const exchanged = useRef(false);
 
     useEffect(() => {
-        const token = searchParams.get('token');
-        if (token) {
-            handleOAuthSuccess(token)
+        if (exchanged.current) return;
+
+        const code = searchParams.get('code');
+        if (code) {
+            exchanged.current = true;
+            handleOAuthCode(code)
                 .then(() => {
                     navigate('/dashboard');
                 })
@@ -20,9 +26,9 @@ export const OAuthCallbackPage: React.FC = () => {
                     navigate('/login?error=oauth_failed');
                 });
         } else {
-            navigate('/login?error=token_missing');
+            navigate('/login?error=code_missing');
         }
-    }, [searchParams, handleOAuthSuccess, navigate]);
+    }, [searchParams, handleOAuthCode, navigate]);
 
     return (
         <Box
@@ -33,7 +33,7 @@ export const TransactionPage: React.FC = () => {
     const {
         transactions,
         total,
-        totalAmount,
+        totals,
         isLoading,
         page,
         limit,
@@ -245,9 +245,15 @@ export const TransactionPage: React.FC = () => {
                     <Typography variant="subtitle1" sx={{ fontWeight: 600 }}>
                         Filtered Total
                     </Typography>
-                    <Typography variant="h6" sx={{ fontWeight: 700 }}>
-                        {new Intl.NumberFormat('id-ID', { style: 'currency', currency: 'IDR' }).format(totalAmount)}
-                    </Typography>
+                    <Stack alignItems="flex-end">
+                        {totals.length === 0 ? (
+                            <Typography variant="h6" sx={{ fontWeight: 700 }}>-</Typography>
+                        ) : totals.map((t) => (
+                            <Typography key={t.currency} variant="h6" sx={{ fontWeight: 700 }}>
+                                {new Intl.NumberFormat('id-ID', { style: 'currency', currency: t.currency }).format(t.total_income - t.total_expense)}
+                            </Typography>
+                        ))}
+                    </Stack>
                 </Box>
             )}
 
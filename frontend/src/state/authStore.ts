@@ -25,7 +25,7 @@ interface AuthState {
     verifyBackupCode: (code: string) => Promise<void>;
     logout: () => void;
     initializeAuth: () => Promise<void>;
-    handleOAuthSuccess: (token: string) => Promise<void>;
+    handleOAuthCode: (code: string) => Promise<void>;
     clear2FAState: () => void;
 }
 
@@ -154,16 +154,11 @@ export const useAuthStore = create<AuthState>((set, get) => ({
         set({ user: null, token: null, isAuthenticated: false, requires2FA: false, tempToken: null });
     },
 
-    handleOAuthSuccess: async (token: string) => {
+    handleOAuthCode: async (code: string) => {
         set({ isLoading: true, error: null });
         try {
-            tokenStorage.setToken(token);
-            const user = await authRepository.getUser();
-            if (user) {
-                set({ user, token, isAuthenticated: true, isLoading: false });
-            } else {
-                throw new Error('Failed to fetch user data after OAuth');
-            }
+            const { user, token } = await authRepository.exchangeGoogleCode(code);
+            set({ user, token, isAuthenticated: true, isLoading: false });
         } catch (err: any) {
             tokenStorage.clearToken();
             set({ error: err.message || 'OAuth login failed', isLoading: false });
@@ -1,5 +1,5 @@
 import { create } from 'zustand';
-import type { Transaction, DashboardSummary } from '../domain/entities/Transaction';
+import type { Transaction, DashboardSummary, CurrencyTotal } from '../domain/entities/Transaction';
 import { apiClient } from '../infrastructure/apiClient';
 
 export interface TransactionFilter {
@@ -13,14 +13,14 @@ export interface TransactionFilter {
 export interface PaginatedTransactions {
     transactions: Transaction[];
     total: number;
-    total_amount: number;
+    totals: CurrencyTotal[];
 }
 
 interface TransactionState {
     transactions: Transaction[];
     summary: DashboardSummary | null;
     total: number;
-    totalAmount: number;
+    totals: CurrencyTotal[];
     isLoading: boolean;
     error: string | null;
     fetchTransactions: (page?: number, limit?: number, filters?: TransactionFilter) => Promise<void>;
@@ -34,7 +34,7 @@ export const useTransactionStore = create<TransactionState>((set, get) => ({
     transactions: [],
     summary: null,
     total: 0,
-    totalAmount: 0,
+    totals: [],
     isLoading: false,
     error: null,
 
@@ -52,7 +52,7 @@ export const useTransactionStore = create<TransactionState>((set, get) => ({
             set({
                 transactions: data.transactions || [],
                 total: data.total || 0,
-                totalAmount: data.total_amount || 0,
+                totals: data.totals || [],
                 isLoading: false
             });
         } catch (error: any) {